@@ -14,6 +14,7 @@ import (
 
 	"encoding/gob"
 	"fmt"
+	"time"
 )
 
 const (
@@ -43,7 +44,8 @@ func init() {
 //###################################//
 
 type sessionAuthData struct {
-	UserID string
+	UserID  string
+	LoginID string
 }
 
 //##############//
@@ -57,6 +59,9 @@ func Init() (err error) {
 	// Set the on new session hook.
 	sessions.OnNewSession(onNewSession)
 
+	// Set the broadcast user matcher.
+	sessions.SetUserMatcher(newBroadcastUserMatcher)
+
 	// Obtain the login template and prepare it.
 	t := templates.Templates.Lookup(loginTemplate)
 	if t == nil {
@@ -107,6 +112,11 @@ func NavigateToRegisterPage(s *sessions.Session) {
 
 // Logout logs out the user if authenticated.
 func Logout(s *sessions.Session) {
+	// Free the login session slot of the user.
+	if d, ok := getSessionAuthData(s); ok {
+		removeLoginSession(d.UserID, d.LoginID)
+	}
+
 	// Remove the authenticated user data if present.
 	s.Delete(sessionValueKeyIsAuth)
 
@@ -135,7 +145,8 @@ func IsAuth(i interface{}) bool {
 // If a context value is available, then always pass it instead of the session.
 // This will improve the performance and won't retrieve a user value multiple
 // times from the database during one template execution cycle.
-// This method returns nil, if the user is not enabled.
+// This method returns nil, if the user is not enabled or
+// if the authenticated session is expired.
 func GetUser(i interface{}) *User {
 	var s *sessions.Session
 	var c *template.Context
@@ -167,13 +178,7 @@ func GetUser(i interface{}) *User {
 	}
 
 	// Get the session data value.
-	i, ok := s.Get(sessionValueKeyIsAuth)
-	if !ok {
-		return nil
-	}
-
-	// Assertion.
-	d, ok := i.(*sessionAuthData)
+	d, ok := getSessionAuthData(s)
 	if !ok {
 		return nil
 	}
@@ -192,6 +197,25 @@ func GetUser(i interface{}) *User {
 		return nil
 	}
 
+	// Create the login session entry of legacy sessions.
+	// Revoked legacy sessions are logged out by the lifecycle loop.
+	if len(d.LoginID) == 0 {
+		if revoked, err := adoptLegacyLogin(u, s); err != nil {
+			log.L.Error("failed to adopt the legacy login session of user '%s': %v", u.LoginName, err)
+			return nil
+		} else if revoked {
+			return nil
+		} else if d, ok = getSessionAuthData(s); !ok {
+			return nil
+		}
+	}
+
+	// If the login session is expired, then abort.
+	// The session is logged out by the lifecycle loop.
+	if _, expired := u.loginExpired(d.LoginID, s.LastActivity()); expired {
+		return nil
+	}
+
 	// Create a new user value.
 	user := newUser(u)
 
@@ -272,6 +296,27 @@ func RemoveUser(id string) error {
 //###############//
 
 func onNewSession(s *sessions.Session) {
+	// Log out expired sessions, because the login might be
+	// expired since the last page load.
+	if d, ok := getSessionAuthData(s); ok {
+		u, err := dbGetUserByID(d.UserID)
+		if err != nil {
+			log.L.Error(err.Error())
+		} else if u == nil {
+			expireSession(s, d, ExpiredEvicted, false)
+		} else if len(d.LoginID) == 0 {
+			// Create the login session entry of legacy sessions.
+			// They are logged out if the logins of the user were revoked.
+			if revoked, err := adoptLegacyLogin(u, s); err != nil {
+				log.L.Error("failed to adopt the legacy login session of user '%s': %v", u.LoginName, err)
+			} else if revoked {
+				expireSession(s, d, ExpiredEvicted, false)
+			}
+		} else if reason, expired := u.loginExpired(d.LoginID, time.Time{}); expired {
+			expireSession(s, d, reason, false)
+		}
+	}
+
 	// if the session is authenticated, then trigger the onNewAuthenticatedSession event.
 	if IsAuth(s) {
 		triggerOnNewAuthenticatedSession(s)
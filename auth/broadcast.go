@@ -0,0 +1,67 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package auth
+
+import (
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+)
+
+//###############//
+//### Private ###//
+//###############//
+
+// newBroadcastUserMatcher returns a session matcher for the broadcast filter.
+// The group membership is looked up only once per user and broadcast.
+func newBroadcastUserMatcher(userID, group string) func(s *sessions.Session) bool {
+	inGroup := make(map[string]bool)
+
+	return func(s *sessions.Session) bool {
+		// Get the user ID of the authenticated session.
+		id := getSessionUserID(s)
+		if len(id) == 0 {
+			return false
+		} else if len(userID) > 0 && id != userID {
+			return false
+		} else if len(group) == 0 {
+			return true
+		}
+
+		// Check if already looked up.
+		if ok, found := inGroup[id]; found {
+			return ok
+		}
+
+		// Obtain the user from the database.
+		u, err := dbGetUserByID(id)
+		if err != nil {
+			log.L.Error(err.Error())
+		}
+
+		ok := err == nil && u != nil && u.Enabled && newUser(u).IsInGroup(group)
+		inGroup[id] = ok
+
+		return ok
+	}
+}
+
+// getSessionUserID returns the user ID of the authenticated session.
+// An empty string is returned, if the session is not authenticated.
+func getSessionUserID(s *sessions.Session) string {
+	// Get the session data value.
+	i, ok := s.Get(sessionValueKeyIsAuth)
+	if !ok {
+		return ""
+	}
+
+	// Assertion.
+	d, ok := i.(*sessionAuthData)
+	if !ok {
+		return ""
+	}
+
+	return d.UserID
+}
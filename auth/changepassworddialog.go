@@ -110,8 +110,8 @@ func (e *changePasswordDialogEvents) EventSubmit(c *template.Context, newPasswor
 	if opts == nil {
 		// Show a messagebox
 		messagebox.New().
-			SetTitle(tr.S("bud.auth.changePassword.error.changePasswordTitle")).
-			SetText(tr.S("bud.auth.changePassword.error.changePassword")).
+			SetTitle(tr.For(s).S("bud.auth.changePassword.error.changePasswordTitle")).
+			SetText(tr.For(s).S("bud.auth.changePassword.error.changePassword")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
@@ -147,8 +147,8 @@ func (e *changePasswordDialogEvents) EventSubmit(c *template.Context, newPasswor
 	if user == nil {
 		// Show a messagebox
 		messagebox.New().
-			SetTitle(tr.S("bud.auth.changePassword.error.changePasswordTitle")).
-			SetText(tr.S("bud.auth.changePassword.error.changePassword")).
+			SetTitle(tr.For(s).S("bud.auth.changePassword.error.changePasswordTitle")).
+			SetText(tr.For(s).S("bud.auth.changePassword.error.changePassword")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
@@ -158,8 +158,8 @@ func (e *changePasswordDialogEvents) EventSubmit(c *template.Context, newPasswor
 	if strings.TrimSpace(newPassword) == "" || len(newPassword) < minPasswordLength {
 		// Show a messagebox
 		messagebox.New().
-			SetTitle(tr.S("bud.auth.changePassword.error.shortPasswordTitle")).
-			SetText(tr.S("bud.auth.changePassword.error.shortPassword")).
+			SetTitle(tr.For(s).S("bud.auth.changePassword.error.shortPasswordTitle")).
+			SetText(tr.For(s).S("bud.auth.changePassword.error.shortPassword")).
 			SetType(messagebox.TypeWarning).
 			Show(s)
 		return
@@ -173,8 +173,8 @@ func (e *changePasswordDialogEvents) EventSubmit(c *template.Context, newPasswor
 
 		// Show a messagebox
 		messagebox.New().
-			SetTitle(tr.S("bud.auth.changePassword.error.changePasswordTitle")).
-			SetText(tr.S("bud.auth.changePassword.error.changePassword")).
+			SetTitle(tr.For(s).S("bud.auth.changePassword.error.changePasswordTitle")).
+			SetText(tr.For(s).S("bud.auth.changePassword.error.changePassword")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
@@ -194,8 +194,8 @@ func (e *changePasswordDialogEvents) EventSubmit(c *template.Context, newPasswor
 	// Show a success message box if defined to.
 	if opts.ShowSuccessMsgBox {
 		messagebox.New().
-			SetTitle(tr.S("bud.auth.changePassword.success.title")).
-			SetText(tr.S("bud.auth.changePassword.success.text")).
+			SetTitle(tr.For(s).S("bud.auth.changePassword.success.title")).
+			SetText(tr.For(s).S("bud.auth.changePassword.success.text")).
 			SetType(messagebox.TypeSuccess).
 			Show(s)
 	}
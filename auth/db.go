@@ -55,6 +55,14 @@ type dbUser struct {
 	LastLogin    int64
 	Created      int64
 	Groups       []string
+
+	// The active login sessions of the user.
+	// They are only written by dbUpdateLoginSessions.
+	Sessions []dbLoginSession `gorethink:",omitempty"`
+
+	// The unix time of the last logout of all user sessions.
+	// Legacy sessions without login ID are logged out if set.
+	LoginsRevokedAt int64 `gorethink:",omitempty"`
 }
 
 //#######################//
@@ -90,11 +98,15 @@ func createIndexes() error {
 func initDB() {
 	// Start the cleanup loop in a new goroutine.
 	go cleanupLoop()
+
+	// Start the session lifecycle loop.
+	go lifecycleLoop()
 }
 
 func releaseDB() {
-	// Stop the loop by triggering the quit trigger.
+	// Stop the loops by triggering the quit triggers.
 	close(stopCleanupLoop)
+	close(stopLifecycleLoop)
 }
 
 func dbUserExists(loginName string) (bool, error) {
@@ -246,7 +258,11 @@ func dbUpdateUser(u *dbUser) error {
 		}
 	}
 
-	_, err := r.Table(DBUserTable).Get(u.ID).Update(u).RunWrite(db.Session)
+	// Don't overwrite the login sessions with a possibly outdated state.
+	uCopy := *u
+	uCopy.Sessions = nil
+
+	_, err := r.Table(DBUserTable).Get(u.ID).Update(&uCopy).RunWrite(db.Session)
 	if err != nil {
 		return err
 	}
@@ -254,6 +270,35 @@ func dbUpdateUser(u *dbUser) error {
 	return nil
 }
 
+func dbUpdateLoginSessions(u *dbUser) error {
+	// Always write the slice, also if empty.
+	sessions := u.Sessions
+	if sessions == nil {
+		sessions = []dbLoginSession{}
+	}
+
+	_, err := r.Table(DBUserTable).Get(u.ID).Update(map[string]interface{}{
+		"Sessions": sessions,
+	}).RunWrite(db.Session)
+	if err != nil {
+		return fmt.Errorf("failed to update login sessions of user '%s': %v", u.LoginName, err)
+	}
+
+	return nil
+}
+
+func dbRevokeLoginSessions(u *dbUser) error {
+	_, err := r.Table(DBUserTable).Get(u.ID).Update(map[string]interface{}{
+		"Sessions":        []dbLoginSession{},
+		"LoginsRevokedAt": u.LoginsRevokedAt,
+	}).RunWrite(db.Session)
+	if err != nil {
+		return fmt.Errorf("failed to revoke login sessions of user '%s': %v", u.LoginName, err)
+	}
+
+	return nil
+}
+
 func dbRemoveUsers(ids ...string) error {
 	if len(ids) == 0 {
 		return nil
@@ -316,6 +361,31 @@ func dbGetUsersInGroup(group string) ([]*dbUser, error) {
 	return users, nil
 }
 
+// dbGetUsersWithLoginSessions retrieves the users with login sessions ordered
+// by their login names. The first offset users are skipped and at most
+// limit users are returned.
+func dbGetUsersWithLoginSessions(offset int, limit int) ([]*dbUser, error) {
+	// Execute the query.
+	rows, err := r.Table(DBUserTable).
+		OrderBy(r.OrderByOpts{Index: DBUserTableIndex}).
+		Filter(r.Row.Field("Sessions").Count().Gt(0)).
+		Skip(offset).
+		Limit(limit).
+		Run(db.Session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database users with login sessions: %v", err)
+	}
+
+	// Get the users from the query.
+	var users []*dbUser
+	err = rows.All(&users)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database users with login sessions: %v", err)
+	}
+
+	return users, nil
+}
+
 //########################//
 //### Password methods ###//
 //########################//
@@ -15,6 +15,7 @@ const (
 	onNewAuthenticatedSession = "OnNewAuthSession"
 	onEndAuthenticatedSession = "OnEndAuthSession"
 	onRemovedUser             = "OnRemovedUser"
+	onSessionExpired          = "OnSessionExpired"
 )
 
 var (
@@ -51,6 +52,18 @@ func OffEndAuthenticatedSession(f func(s *sessions.Session)) {
 	emitter.Off(onEndAuthenticatedSession, f)
 }
 
+// OnSessionExpired sets the function which is triggered if an authenticated
+// session is logged out, because it was idle, exceeded its lifetime or was evicted.
+// The OnEndAuthenticatedSession event is triggered additionally.
+func OnSessionExpired(f func(s *sessions.Session, reason ExpiredReason)) {
+	emitter.On(onSessionExpired, f)
+}
+
+// OffSessionExpired removes the listener again
+func OffSessionExpired(f func(s *sessions.Session, reason ExpiredReason)) {
+	emitter.Off(onSessionExpired, f)
+}
+
 // OnRemovedUser sets the function which is triggered if a user is removed.
 func OnRemovedUser(f func(userID string)) {
 	emitter.On(onRemovedUser, f)
@@ -77,6 +90,10 @@ func triggerOnEndAuthenticatedSession(s *sessions.Session) {
 	emitter.Emit(onEndAuthenticatedSession, s)
 }
 
+func triggerOnSessionExpired(s *sessions.Session, reason ExpiredReason) {
+	emitter.Emit(onSessionExpired, s, reason)
+}
+
 func triggerOnRemovedUser(userID string) {
 	emitter.Emit(onRemovedUser, userID)
 }
@@ -0,0 +1,563 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package auth
+
+import (
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/settings"
+	"github.com/desertbit/bulldozer/utils"
+
+	"sort"
+	"sync"
+	"time"
+)
+
+// Each login creates a login session entry in the user database document.
+// The entry is shared by all browser tabs with the same session cookie.
+// It tracks the login time and the last activity. A login session expires
+// if it is idle for too long, if it exceeds its maximum lifetime or if it
+// is evicted by a newer login of the same user or revoked. The lifecycle loop checks
+// all active sessions of this node and also writes their activity to the
+// database. Sessions of other cluster nodes or reloaded pages are checked
+// during GetUser calls.
+
+const (
+	lifecycleLoopTimeout = 1 * time.Minute
+
+	// Only write the last activity to the database if it changed at least this much.
+	lastActiveUpdateInterval = 60 // seconds
+
+	// Keep at most this many login sessions per user if no limit is set.
+	maxStoredLoginSessions = 100
+
+	loginIDLength = 20
+)
+
+const (
+	// ExpiredIdle is set if the session was idle longer than the idle timeout.
+	ExpiredIdle ExpiredReason = iota
+	// ExpiredLifetime is set if the session exceeded its maximum lifetime.
+	ExpiredLifetime
+	// ExpiredEvicted is set if a newer login of the user evicted the session
+	// or if the session was logged out from another session.
+	ExpiredEvicted
+)
+
+var (
+	stopLifecycleLoop chan struct{} = make(chan struct{})
+
+	// Lock the database login sessions during modifications.
+	loginSessionsMutex sync.Mutex
+)
+
+//######################//
+//### Expired reason ###//
+//######################//
+
+// ExpiredReason describes why an authenticated session expired.
+type ExpiredReason int
+
+func (r ExpiredReason) String() string {
+	switch r {
+	case ExpiredIdle:
+		return "idle"
+	case ExpiredLifetime:
+		return "lifetime"
+	case ExpiredEvicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+//#############################//
+//### Database login struct ###//
+//#############################//
+
+type dbLoginSession struct {
+	ID         string
+	Created    int64
+	LastActive int64
+	UserAgent  string
+	RemoteAddr string
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// getLoginSession returns the login session entry with the ID.
+func (u *dbUser) getLoginSession(loginID string) (*dbLoginSession, bool) {
+	if len(loginID) == 0 {
+		return nil, false
+	}
+
+	for i := range u.Sessions {
+		if u.Sessions[i].ID == loginID {
+			return &u.Sessions[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// loginExpired checks if the login session is expired.
+// The last activity of the active session is considered additionally.
+func (u *dbUser) loginExpired(loginID string, lastActivity time.Time) (ExpiredReason, bool) {
+	e, ok := u.getLoginSession(loginID)
+	if !ok {
+		return ExpiredEvicted, true
+	}
+
+	return e.expired(lastActivity.Unix(), time.Now().Unix())
+}
+
+func (e *dbLoginSession) expired(lastActive int64, now int64) (ExpiredReason, bool) {
+	if max := int64(settings.Settings.AuthSessionMaxLifetime); max > 0 && now-e.Created >= max {
+		return ExpiredLifetime, true
+	}
+
+	if e.LastActive > lastActive {
+		lastActive = e.LastActive
+	}
+
+	if idle := int64(settings.Settings.AuthSessionIdleTimeout); idle > 0 && now-lastActive >= idle {
+		return ExpiredIdle, true
+	}
+
+	return 0, false
+}
+
+// pruneLoginSessions removes all expired login sessions and the
+// sessions which outlived the session cookie.
+func (u *dbUser) pruneLoginSessions() {
+	now := time.Now().Unix()
+	maxAge := int64(settings.Settings.SessionMaxAge)
+
+	kept := u.Sessions[:0]
+	for _, e := range u.Sessions {
+		if _, expired := e.expired(e.LastActive, now); expired {
+			continue
+		} else if maxAge > 0 && now-e.LastActive >= maxAge {
+			continue
+		}
+
+		kept = append(kept, e)
+	}
+	u.Sessions = kept
+
+	// Sort by the creation time. The oldest session is the first.
+	sort.Sort(loginSessionsByCreated(u.Sessions))
+}
+
+// addLoginSession adds a new login session to the user and enforces the
+// concurrent sessions limit. The evicted login IDs are returned.
+// False is returned if the login is denied.
+// Be sure to lock the login sessions mutex.
+func (u *dbUser) addLoginSession(e dbLoginSession) (evicted []string, ok bool) {
+	u.pruneLoginSessions()
+
+	limit := settings.Settings.AuthMaxSessionsPerUser
+	if limit <= 0 {
+		limit = maxStoredLoginSessions
+	} else if len(u.Sessions) >= limit && settings.Settings.AuthSessionLimitPolicy == settings.AuthSessionLimitDeny {
+		return nil, false
+	}
+
+	// Evict the oldest sessions.
+	for len(u.Sessions) >= limit {
+		evicted = append(evicted, u.Sessions[0].ID)
+		u.Sessions = u.Sessions[1:]
+	}
+
+	u.Sessions = append(u.Sessions, e)
+
+	return evicted, true
+}
+
+// newLoginSession adds a new login session for the session to the database
+// user and logs out all evicted sessions of this node.
+// False is returned if the concurrent sessions limit denies the login.
+func newLoginSession(u *dbUser, s *sessions.Session) (loginID string, ok bool, err error) {
+	// Lock the mutex
+	loginSessionsMutex.Lock()
+	defer loginSessionsMutex.Unlock()
+
+	// Reload the user to get the current login sessions.
+	dbU, err := dbGetUserByID(u.ID)
+	if err != nil {
+		return "", false, err
+	} else if dbU != nil {
+		u.Sessions = dbU.Sessions
+	}
+
+	loginID = utils.RandomString(loginIDLength)
+	now := time.Now().Unix()
+
+	evicted, ok := u.addLoginSession(dbLoginSession{
+		ID:         loginID,
+		Created:    now,
+		LastActive: now,
+		UserAgent:  s.UserAgent(),
+		RemoteAddr: s.RemoteAddr(),
+	})
+	if !ok {
+		return "", false, nil
+	}
+
+	// Save the login sessions and set the last login time.
+	err = dbUpdateLoginSessions(u)
+	if err != nil {
+		return "", false, err
+	}
+
+	err = dbUpdateLastLogin(u)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Log out the evicted sessions of this node immediately.
+	// Sessions on other nodes are handled by their lifecycle loops.
+	if len(evicted) > 0 {
+		go expireLoginSessions(u.ID, evicted)
+	}
+
+	return loginID, true, nil
+}
+
+// adoptLoginSession adds a login session entry for a legacy session, which
+// was authenticated before login sessions were tracked. The concurrent sessions
+// limit is not enforced, because the login exists already.
+// False is returned, if the logins of the user were revoked. Legacy sessions
+// are older than any revocation, so they have to be logged out instead.
+// Be sure to lock the login sessions mutex.
+func (u *dbUser) adoptLoginSession(s *sessions.Session, d *sessionAuthData) (*dbLoginSession, bool) {
+	if u.LoginsRevokedAt > 0 {
+		return nil, false
+	}
+
+	u.pruneLoginSessions()
+
+	// Drop the oldest entries if the maximum is reached.
+	// Their sessions are logged out by the lifecycle loop.
+	for len(u.Sessions) >= maxStoredLoginSessions {
+		u.Sessions = u.Sessions[1:]
+	}
+
+	now := time.Now().Unix()
+	u.Sessions = append(u.Sessions, dbLoginSession{
+		ID:         utils.RandomString(loginIDLength),
+		Created:    now,
+		LastActive: now,
+		UserAgent:  s.UserAgent(),
+		RemoteAddr: s.RemoteAddr(),
+	})
+	e := &u.Sessions[len(u.Sessions)-1]
+
+	// Save the login ID to the session authentication data.
+	s.Set(sessionValueKeyIsAuth, &sessionAuthData{
+		UserID:  d.UserID,
+		LoginID: e.ID,
+	})
+
+	return e, true
+}
+
+// adoptLegacyLogin creates and saves the login session entry
+// of a legacy session without login ID. Revoked is true, if the
+// session has to be logged out, because the logins of the user were revoked.
+func adoptLegacyLogin(u *dbUser, s *sessions.Session) (revoked bool, err error) {
+	// Lock the mutex
+	loginSessionsMutex.Lock()
+	defer loginSessionsMutex.Unlock()
+
+	// Reload the user to get the current login sessions.
+	dbU, err := dbGetUserByID(u.ID)
+	if err != nil {
+		return false, err
+	} else if dbU != nil {
+		u.Sessions = dbU.Sessions
+		u.LoginsRevokedAt = dbU.LoginsRevokedAt
+	}
+
+	// The session might have been adopted in the meantime.
+	d, ok := getSessionAuthData(s)
+	if !ok || len(d.LoginID) > 0 {
+		return false, nil
+	}
+
+	if _, ok = u.adoptLoginSession(s, d); !ok {
+		return true, nil
+	}
+
+	return false, dbUpdateLoginSessions(u)
+}
+
+// revokeLoginSessions removes all login session entries of the database
+// user and saves the revocation time. Stored legacy sessions without
+// login ID are logged out instead of adopted as soon as they are loaded again.
+func revokeLoginSessions(userID string) error {
+	// Lock the mutex
+	loginSessionsMutex.Lock()
+	defer loginSessionsMutex.Unlock()
+
+	u, err := dbGetUserByID(userID)
+	if err != nil {
+		return err
+	} else if u == nil {
+		return nil
+	}
+
+	u.Sessions = nil
+	u.LoginsRevokedAt = time.Now().Unix()
+
+	return dbRevokeLoginSessions(u)
+}
+
+// removeLoginSession removes the login session entry from the database user.
+func removeLoginSession(userID string, loginID string) {
+	if len(loginID) == 0 {
+		return
+	}
+
+	_, err := removeLoginSessions(userID, func(id string) bool {
+		return id == loginID
+	})
+	if err != nil {
+		log.L.Error("failed to remove login session: %v", err)
+	}
+}
+
+// removeLoginSessions removes all login session entries of the
+// database user matched by the remove function.
+// The removed login IDs are returned.
+func removeLoginSessions(userID string, remove func(loginID string) bool) ([]string, error) {
+	// Lock the mutex
+	loginSessionsMutex.Lock()
+	defer loginSessionsMutex.Unlock()
+
+	u, err := dbGetUserByID(userID)
+	if err != nil {
+		return nil, err
+	} else if u == nil {
+		return nil, nil
+	}
+
+	var removed []string
+	kept := u.Sessions[:0]
+	for _, e := range u.Sessions {
+		if remove(e.ID) {
+			removed = append(removed, e.ID)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	u.Sessions = kept
+
+	if err = dbUpdateLoginSessions(u); err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
+
+// getSessionAuthData returns the authentication data of the session if present.
+func getSessionAuthData(s *sessions.Session) (*sessionAuthData, bool) {
+	i, ok := s.Get(sessionValueKeyIsAuth)
+	if !ok {
+		return nil, false
+	}
+
+	d, ok := i.(*sessionAuthData)
+	return d, ok
+}
+
+// expireSession logs out the expired session and triggers the events.
+func expireSession(s *sessions.Session, d *sessionAuthData, reason ExpiredReason, navigate bool) {
+	// Remove the authenticated user data.
+	s.Delete(sessionValueKeyIsAuth)
+
+	// Free the login session slot of the user.
+	if reason != ExpiredEvicted {
+		removeLoginSession(d.UserID, d.LoginID)
+	}
+
+	if navigate {
+		// Redirect to the default page.
+		s.NavigateHome()
+	}
+
+	// Trigger the events
+	triggerOnSessionExpired(s, reason)
+	triggerOnEndAuthenticatedSession(s)
+}
+
+// expireLoginSessions logs out all active sessions of
+// this node with the evicted login IDs.
+// All sessions of the user are logged out if the IDs are nil.
+func expireLoginSessions(userID string, loginIDs []string) {
+	for _, s := range activeSessions() {
+		d, ok := getSessionAuthData(s)
+		if !ok || d.UserID != userID {
+			continue
+		}
+
+		if loginIDs == nil {
+			expireSession(s, d, ExpiredEvicted, true)
+			continue
+		}
+
+		for _, id := range loginIDs {
+			if d.LoginID == id {
+				expireSession(s, d, ExpiredEvicted, true)
+				break
+			}
+		}
+	}
+}
+
+// activeSessions returns a copy of all active sessions of this node.
+func activeSessions() []*sessions.Session {
+	var list []*sessions.Session
+
+	sessions.GetSessions(func(m sessions.Sessions) {
+		list = make([]*sessions.Session, 0, len(m))
+		for _, s := range m {
+			list = append(list, s)
+		}
+	})
+
+	return list
+}
+
+//######################//
+//### Lifecycle Loop ###//
+//######################//
+
+func lifecycleLoop() {
+	// Create a new ticker
+	ticker := time.NewTicker(lifecycleLoopTimeout)
+
+	defer func() {
+		// Stop the ticker
+		ticker.Stop()
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Expire the sessions and save their activity.
+			checkActiveSessions()
+		case <-stopLifecycleLoop:
+			// Just exit the loop
+			return
+		}
+	}
+}
+
+func checkActiveSessions() {
+	// Group the authenticated sessions by their user IDs.
+	userSessions := make(map[string][]*sessions.Session)
+	for _, s := range activeSessions() {
+		if d, ok := getSessionAuthData(s); ok {
+			userSessions[d.UserID] = append(userSessions[d.UserID], s)
+		}
+	}
+
+	for userID, list := range userSessions {
+		checkUserSessions(userID, list)
+	}
+}
+
+func checkUserSessions(userID string, list []*sessions.Session) {
+	// Lock the mutex
+	loginSessionsMutex.Lock()
+	defer loginSessionsMutex.Unlock()
+
+	u, err := dbGetUserByID(userID)
+	if err != nil {
+		log.L.Error("auth lifecycle: %v", err)
+		return
+	}
+
+	var expired []func()
+	changed := false
+	now := time.Now().Unix()
+
+	for _, s := range list {
+		d, ok := getSessionAuthData(s)
+		if !ok {
+			continue
+		}
+
+		// The session expires if the user was removed.
+		// Legacy sessions without login ID are adopted,
+		// if the logins of the user were not revoked.
+		var e *dbLoginSession
+		if u == nil {
+			ok = false
+		} else if len(d.LoginID) == 0 {
+			if e, ok = u.adoptLoginSession(s, d); ok {
+				changed = true
+			}
+		} else {
+			e, ok = u.getLoginSession(d.LoginID)
+		}
+		if !ok {
+			expired = append(expired, newExpireFunc(s, d, ExpiredEvicted))
+			continue
+		}
+
+		lastActivity := s.LastActivity().Unix()
+		if reason, ok := e.expired(lastActivity, now); ok {
+			expired = append(expired, newExpireFunc(s, d, reason))
+			continue
+		}
+
+		// Save the activity from time to time.
+		if lastActivity-e.LastActive >= lastActiveUpdateInterval {
+			e.LastActive = lastActivity
+			e.RemoteAddr = s.RemoteAddr()
+			changed = true
+		}
+	}
+
+	if changed {
+		if err = dbUpdateLoginSessions(u); err != nil {
+			log.L.Error("auth lifecycle: %v", err)
+		}
+	}
+
+	// Expire the sessions without holding the mutex.
+	if len(expired) > 0 {
+		go func() {
+			for _, f := range expired {
+				f()
+			}
+		}()
+	}
+}
+
+func newExpireFunc(s *sessions.Session, d *sessionAuthData, reason ExpiredReason) func() {
+	return func() {
+		expireSession(s, d, reason, true)
+	}
+}
+
+//#######################//
+//### Sorting helpers ###//
+//#######################//
+
+type loginSessionsByCreated []dbLoginSession
+
+func (l loginSessionsByCreated) Len() int           { return len(l) }
+func (l loginSessionsByCreated) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l loginSessionsByCreated) Less(i, j int) bool { return l[i].Created < l[j].Created }
@@ -0,0 +1,63 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package auth
+
+import (
+	"github.com/desertbit/bulldozer/sessions"
+
+	"testing"
+	"time"
+)
+
+func newLegacyTestSession(userID string) *sessions.Session {
+	s := sessions.NewDummy(sessions.DummyOpts{RemoteAddr: "127.0.0.1", UserAgent: "authtest"})
+	s.Set(sessionValueKeyIsAuth, &sessionAuthData{UserID: userID})
+	return s
+}
+
+func TestAdoptLoginSession(t *testing.T) {
+	u := &dbUser{ID: "user", LoginName: "user"}
+
+	s := newLegacyTestSession(u.ID)
+	defer s.Close()
+
+	d, _ := getSessionAuthData(s)
+	e, ok := u.adoptLoginSession(s, d)
+	if !ok {
+		t.Fatalf("legacy session was not adopted")
+	}
+	if len(u.Sessions) != 1 || u.Sessions[0].ID != e.ID {
+		t.Fatalf("login session entry was not added: %+v", u.Sessions)
+	}
+
+	d, ok = getSessionAuthData(s)
+	if !ok || d.LoginID != e.ID {
+		t.Fatalf("login ID was not saved to the session: %+v", d)
+	}
+}
+
+func TestAdoptLoginSessionRevoked(t *testing.T) {
+	u := &dbUser{ID: "user", LoginName: "user", LoginsRevokedAt: time.Now().Unix()}
+
+	// The user logged in again after the revocation.
+	u.Sessions = []dbLoginSession{{ID: "login", Created: time.Now().Unix(), LastActive: time.Now().Unix()}}
+
+	s := newLegacyTestSession(u.ID)
+	defer s.Close()
+
+	d, _ := getSessionAuthData(s)
+	if _, ok := u.adoptLoginSession(s, d); ok {
+		t.Fatalf("legacy session of a revoked user was adopted")
+	}
+	if len(u.Sessions) != 1 || u.Sessions[0].ID != "login" {
+		t.Fatalf("login session entries were changed: %+v", u.Sessions)
+	}
+
+	d, ok := getSessionAuthData(s)
+	if !ok || len(d.LoginID) > 0 {
+		t.Fatalf("session authentication data was changed: %+v", d)
+	}
+}
@@ -85,8 +85,8 @@ func (e *loginEvents) EventLogin(c *template.Context, loginName string, password
 	if !u.Enabled {
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.auth.login.errorNotEnabled.title")).
-			SetText(tr.S("bud.auth.login.errorNotEnabled.text")).
+			SetTitle(tr.For(s).S("bud.auth.login.errorNotEnabled.title")).
+			SetText(tr.For(s).S("bud.auth.login.errorNotEnabled.text")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
@@ -162,15 +162,15 @@ func routeLoginPage(s *sessions.Session, req *mux.Request) {
 
 	// Set the body and title
 	req.Body = o
-	req.Title = tr.S("bud.auth.login.pageTitle")
+	req.Title = tr.For(s).S("bud.auth.login.pageTitle")
 	return
 }
 
 func showLoginErrorMsgBox(s *sessions.Session) {
 	// Show a messagebox
 	messagebox.New().
-		SetTitle(tr.S("bud.auth.login.error.title")).
-		SetText(tr.S("bud.auth.login.error.text")).
+		SetTitle(tr.For(s).S("bud.auth.login.error.title")).
+		SetText(tr.For(s).S("bud.auth.login.error.text")).
 		SetType(messagebox.TypeAlert).
 		Show(s)
 }
@@ -179,23 +179,55 @@ func finishLogin(s *sessions.Session, user *User) {
 	// Get the database user.
 	u := user.u
 
-	// Update the last login time
-	err := dbUpdateLastLogin(u)
+	// Create a new login session and update the last login time.
+	loginID, ok, err := newLoginSession(u, s)
 	if err != nil {
-		log.L.Error("failed to update last login time of user '%s': %v", u.LoginName, err)
+		log.L.Error("failed to create login session of user '%s': %v", u.LoginName, err)
+		showLoginErrorMsgBox(s)
+		return
+	} else if !ok {
+		// Show a messagebox.
+		messagebox.New().
+			SetTitle(tr.For(s).S("bud.auth.login.errorSessionLimit.title")).
+			SetText(tr.For(s).S("bud.auth.login.errorSessionLimit.text")).
+			SetType(messagebox.TypeAlert).
+			Show(s)
+		return
+	}
+
+	// Assign a new session ID to prevent session fixation.
+	err = s.RenewID()
+	if err != nil {
+		log.L.Error("failed to renew session ID during login of user '%s': %v", u.LoginName, err)
+		removeLoginSession(u.ID, loginID)
 		showLoginErrorMsgBox(s)
 		return
 	}
 
 	// Create a new session authentication data value.
 	d := &sessionAuthData{
-		UserID: u.ID,
+		UserID:  u.ID,
+		LoginID: loginID,
 	}
 
 	// Save the authentication data to the session values.
 	// This makes the user login public to the complete application.
 	s.Set(sessionValueKeyIsAuth, d)
 
+	// Use the preferred locale of the user if set.
+	if locale := user.PrefString(PrefKeyLocale, ""); len(locale) > 0 {
+		if err = tr.SetSessionLocale(s, locale); err != nil {
+			log.L.Warning("login of user '%s': %v", u.LoginName, err)
+		}
+	}
+
+	// Use the time zone of the user if set.
+	if name := user.PrefString(PrefKeyTimeZone, ""); len(name) > 0 {
+		if err = tr.SetSessionTimeZone(s, name); err != nil {
+			log.L.Warning("login of user '%s': %v", u.LoginName, err)
+		}
+	}
+
 	// Redirect to the default page.
 	s.NavigateHome()
 
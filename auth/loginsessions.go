@@ -0,0 +1,212 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package auth
+
+import (
+	"github.com/desertbit/bulldozer/sessions"
+
+	"fmt"
+	"sort"
+	"time"
+)
+
+//###########################//
+//### Login Session Types ###//
+//###########################//
+
+type LoginSessions []*LoginSession
+
+// LoginSession describes an authenticated session of a user.
+// It is shared by all browser tabs with the same session cookie.
+type LoginSession struct {
+	ID         string
+	UserAgent  string
+	RemoteAddr string
+	Created    time.Time
+	LastActive time.Time
+
+	// IsCurrent is true for the login session of the session
+	// passed to GetLoginSessions.
+	IsCurrent bool
+
+	// IsConnected is true if a browser tab of the login
+	// session is connected to this node.
+	IsConnected bool
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// LoginSessions returns the active login sessions of the user sorted by their login time.
+// This user value is not updated. Call user.Update() to get the latest state.
+func (u *User) LoginSessions() LoginSessions {
+	return newLoginSessions(u.u, nil)
+}
+
+// GetLoginSessions returns the active login sessions of the
+// authenticated user of the session.
+func GetLoginSessions(s *sessions.Session) (LoginSessions, error) {
+	d, ok := getSessionAuthData(s)
+	if !ok {
+		return nil, fmt.Errorf("failed to get login sessions: session is not authenticated!")
+	}
+
+	u, err := dbGetUserByID(d.UserID)
+	if err != nil {
+		return nil, err
+	} else if u == nil {
+		return nil, fmt.Errorf("failed to get login sessions: user with ID '%s' does not exists!", d.UserID)
+	}
+
+	return newLoginSessions(u, d), nil
+}
+
+// LogoutSession logs out the login session of the user with the login session ID.
+// The session is logged out on all cluster nodes within one minute.
+func LogoutSession(userID string, loginID string) error {
+	removed, err := removeLoginSessions(userID, func(id string) bool {
+		return id == loginID
+	})
+	if err != nil {
+		return fmt.Errorf("failed to logout session: %v", err)
+	}
+
+	expireLoginSessions(userID, removed)
+
+	return nil
+}
+
+// LogoutOtherSessions logs out all login sessions of the authenticated
+// user of the session, except the login session of the session itself.
+func LogoutOtherSessions(s *sessions.Session) error {
+	d, ok := getSessionAuthData(s)
+	if !ok {
+		return fmt.Errorf("failed to logout other sessions: session is not authenticated!")
+	}
+
+	removed, err := removeLoginSessions(d.UserID, func(id string) bool {
+		return id != d.LoginID
+	})
+	if err != nil {
+		return fmt.Errorf("failed to logout other sessions: %v", err)
+	}
+
+	expireLoginSessions(d.UserID, removed)
+
+	return nil
+}
+
+// LogoutUser logs out all sessions of the user.
+// Stored sessions are logged out as soon as they are loaded again.
+// This includes legacy sessions, which were authenticated
+// before login sessions were tracked.
+func LogoutUser(userID string) error {
+	err := revokeLoginSessions(userID)
+	if err != nil {
+		return fmt.Errorf("failed to logout user: %v", err)
+	}
+
+	// Also log out sessions of this node without a matching login session.
+	expireLoginSessions(userID, nil)
+
+	return nil
+}
+
+// GetUsersWithLoginSessions retreives the users with active login sessions
+// from the database ordered by their login names. The first offset users are
+// skipped and at most limit users are returned. More is true if further users
+// follow. Users with only expired login sessions are skipped, so a page might
+// contain less users.
+func GetUsersWithLoginSessions(offset int, limit int) (users Users, more bool, err error) {
+	if offset < 0 || limit <= 0 {
+		return nil, false, fmt.Errorf("failed to get users with login sessions: invalid offset %v or limit %v", offset, limit)
+	}
+
+	// Request one more user to check if further users follow.
+	dbUsers, err := dbGetUsersWithLoginSessions(offset, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(dbUsers) > limit {
+		dbUsers = dbUsers[:limit]
+		more = true
+	}
+
+	// Fill the users slice.
+	for _, u := range dbUsers {
+		if len(newLoginSessions(u, nil)) > 0 {
+			users = append(users, newUser(u))
+		}
+	}
+
+	return users, more, nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// newLoginSessions creates the login sessions of the database user.
+// Expired sessions are skipped. The current flag is set
+// for the login session of the authentication data if not nil.
+func newLoginSessions(u *dbUser, current *sessionAuthData) LoginSessions {
+	// Collect the sessions of this node.
+	connected := make(map[string]*sessions.Session)
+	for _, s := range activeSessions() {
+		d, ok := getSessionAuthData(s)
+		if !ok || d.UserID != u.ID {
+			continue
+		}
+
+		// Use the session with the latest activity.
+		if c, ok := connected[d.LoginID]; !ok || c.LastActivity().Before(s.LastActivity()) {
+			connected[d.LoginID] = s
+		}
+	}
+
+	now := time.Now().Unix()
+
+	var list LoginSessions
+	for _, e := range u.Sessions {
+		lastActive := e.LastActive
+		remoteAddr := e.RemoteAddr
+
+		s, isConnected := connected[e.ID]
+		if isConnected {
+			if a := s.LastActivity().Unix(); a > lastActive {
+				lastActive = a
+			}
+			remoteAddr = s.RemoteAddr()
+		}
+
+		if _, expired := e.expired(lastActive, now); expired {
+			continue
+		}
+
+		list = append(list, &LoginSession{
+			ID:          e.ID,
+			UserAgent:   e.UserAgent,
+			RemoteAddr:  remoteAddr,
+			Created:     time.Unix(e.Created, 0),
+			LastActive:  time.Unix(lastActive, 0),
+			IsCurrent:   current != nil && current.LoginID == e.ID,
+			IsConnected: isConnected,
+		})
+	}
+
+	// Sort by the login time.
+	sort.Sort(loginSessionsByLoginTime(list))
+
+	return list
+}
+
+type loginSessionsByLoginTime LoginSessions
+
+func (l loginSessionsByLoginTime) Len() int           { return len(l) }
+func (l loginSessionsByLoginTime) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l loginSessionsByLoginTime) Less(i, j int) bool { return l[i].Created.Before(l[j].Created) }
@@ -0,0 +1,293 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package auth
+
+import (
+	r "github.com/dancannon/gorethink"
+	db "github.com/desertbit/bulldozer/database"
+	tr "github.com/desertbit/bulldozer/translate"
+
+	"encoding/json"
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+)
+
+// The user preferences are stored in a separate table, one document per user.
+// The values are JSON encoded. Single values are merged into the document,
+// so concurrent changes of different keys don't overwrite each other.
+
+const (
+	DBUserPrefsTable = "userprefs"
+
+	// PrefKeyLocale is the preference key of the user's translation locale.
+	// It is applied to the session on login.
+	PrefKeyLocale = "locale"
+	// PrefKeyTimeZone is the preference key of the user's time zone.
+	// It is applied to the session on login and overrides the client time zone.
+	PrefKeyTimeZone = "timeZone"
+
+	maxPrefKeyLength = 100
+)
+
+func init() {
+	db.OnSetup(setupPrefsDB)
+
+	// Remove the preferences of removed users.
+	OnRemovedUser(func(userID string) {
+		if err := dbRemoveUserPrefs(userID); err != nil {
+			log.L.Error(err.Error())
+		}
+	})
+}
+
+//########################//
+//### Database Structs ###//
+//########################//
+
+type dbUserPrefs struct {
+	ID     string            `gorethink:"id"`
+	Values map[string]string `gorethink:",omitempty"`
+}
+
+//###############################//
+//### User preference methods ###//
+//###############################//
+
+// PrefGet decodes the user preference with the key into the passed pointer.
+// False is returned if the preference is not set.
+func (u *User) PrefGet(key string, value interface{}) (bool, error) {
+	prefs, err := u.getPrefs()
+	if err != nil {
+		return false, err
+	}
+
+	data, ok := prefs[key]
+	if !ok {
+		return false, nil
+	}
+
+	err = json.Unmarshal([]byte(data), value)
+	if err != nil {
+		return true, fmt.Errorf("failed to decode user preference '%s': %v", key, err)
+	}
+
+	return true, nil
+}
+
+// PrefSet encodes the value to JSON and saves it as user preference.
+// The change is persistent immediately.
+func (u *User) PrefSet(key string, value interface{}) error {
+	if len(key) == 0 || len(key) > maxPrefKeyLength {
+		return fmt.Errorf("failed to set user preference: invalid key '%s'", key)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode user preference '%s': %v", key, err)
+	}
+
+	prefs, err := u.getPrefs()
+	if err != nil {
+		return err
+	}
+
+	err = dbSetUserPref(u.u.ID, key, string(data))
+	if err != nil {
+		return err
+	}
+
+	prefs[key] = string(data)
+
+	return nil
+}
+
+// PrefDelete removes the user preference.
+// The change is persistent immediately.
+func (u *User) PrefDelete(key string) error {
+	prefs, err := u.getPrefs()
+	if err != nil {
+		return err
+	} else if _, ok := prefs[key]; !ok {
+		return nil
+	}
+
+	err = dbDeleteUserPref(u.u.ID, key)
+	if err != nil {
+		return err
+	}
+
+	delete(prefs, key)
+
+	return nil
+}
+
+// PrefString returns the user preference as string.
+// The default value is returned if not set or on error.
+func (u *User) PrefString(key string, def string) string {
+	var v string
+	if !u.prefOrDefault(key, &v) {
+		return def
+	}
+
+	return v
+}
+
+// PrefInt returns the user preference as integer.
+// The default value is returned if not set or on error.
+func (u *User) PrefInt(key string, def int) int {
+	var v int
+	if !u.prefOrDefault(key, &v) {
+		return def
+	}
+
+	return v
+}
+
+// PrefBool returns the user preference as boolean.
+// The default value is returned if not set or on error.
+func (u *User) PrefBool(key string, def bool) bool {
+	var v bool
+	if !u.prefOrDefault(key, &v) {
+		return def
+	}
+
+	return v
+}
+
+// PrefFloat returns the user preference as float.
+// The default value is returned if not set or on error.
+func (u *User) PrefFloat(key string, def float64) float64 {
+	var v float64
+	if !u.prefOrDefault(key, &v) {
+		return def
+	}
+
+	return v
+}
+
+// SetLocale saves the translation locale as user preference
+// and applies it to the session.
+func (u *User) SetLocale(s *sessions.Session, locale string) error {
+	if err := tr.SetSessionLocale(s, locale); err != nil {
+		return err
+	}
+
+	return u.PrefSet(PrefKeyLocale, locale)
+}
+
+// SetTimeZone saves the time zone as user preference
+// and applies it to the session.
+func (u *User) SetTimeZone(s *sessions.Session, name string) error {
+	if err := tr.SetSessionTimeZone(s, name); err != nil {
+		return err
+	}
+
+	return u.PrefSet(PrefKeyTimeZone, name)
+}
+
+// prefOrDefault decodes the preference and logs errors.
+// False is returned if the default value should be used.
+func (u *User) prefOrDefault(key string, value interface{}) bool {
+	ok, err := u.PrefGet(key, value)
+	if err != nil {
+		log.L.Error(err.Error())
+		return false
+	}
+
+	return ok
+}
+
+// getPrefs returns the preferences of the user.
+// They are loaded once from the database for each user value.
+func (u *User) getPrefs() (map[string]string, error) {
+	if u.prefs != nil {
+		return u.prefs, nil
+	}
+
+	prefs, err := dbGetUserPrefs(u.u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.prefs = prefs
+
+	return prefs, nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func setupPrefsDB() error {
+	// Create the user preferences table.
+	return db.CreateTable(DBUserPrefsTable)
+}
+
+func dbGetUserPrefs(userID string) (map[string]string, error) {
+	rows, err := r.Table(DBUserPrefsTable).Get(userID).Run(db.Session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user preferences of user '%s': %v", userID, err)
+	}
+
+	// Check if nothing was found.
+	if rows.IsNil() {
+		return make(map[string]string), nil
+	}
+
+	var p dbUserPrefs
+	err = rows.One(&p)
+	if err != nil {
+		// Check if nothing was found.
+		if err == r.ErrEmptyResult {
+			return make(map[string]string), nil
+		}
+
+		return nil, fmt.Errorf("failed to get user preferences of user '%s': %v", userID, err)
+	}
+
+	if p.Values == nil {
+		p.Values = make(map[string]string)
+	}
+
+	return p.Values, nil
+}
+
+func dbSetUserPref(userID string, key string, data string) error {
+	// The document is created if it doesn't exist. Otherwise the value is merged.
+	_, err := r.Table(DBUserPrefsTable).Insert(&dbUserPrefs{
+		ID:     userID,
+		Values: map[string]string{key: data},
+	}, r.InsertOpts{
+		Conflict: "update",
+	}).RunWrite(db.Session)
+	if err != nil {
+		return fmt.Errorf("failed to set user preference '%s' of user '%s': %v", key, userID, err)
+	}
+
+	return nil
+}
+
+func dbDeleteUserPref(userID string, key string) error {
+	_, err := r.Table(DBUserPrefsTable).Get(userID).
+		Replace(r.Row.Without(map[string]interface{}{
+			"Values": map[string]interface{}{key: true},
+		})).RunWrite(db.Session)
+	if err != nil {
+		return fmt.Errorf("failed to delete user preference '%s' of user '%s': %v", key, userID, err)
+	}
+
+	return nil
+}
+
+func dbRemoveUserPrefs(userID string) error {
+	_, err := r.Table(DBUserPrefsTable).Get(userID).Delete().RunWrite(db.Session)
+	if err != nil {
+		return fmt.Errorf("failed to remove user preferences of user '%s': %v", userID, err)
+	}
+
+	return nil
+}
@@ -42,7 +42,7 @@ func (e *registerEvents) EventRegister(c *template.Context, name string, loginNa
 
 	// Just be sure...
 	if settings.Settings.RegistrationDisabled {
-		showRegisterErrorMsgBox(s, tr.S("bud.auth.register.errorMsgBoxTextRegistrationDisabled"))
+		showRegisterErrorMsgBox(s, tr.For(s).S("bud.auth.register.errorMsgBoxTextRegistrationDisabled"))
 		return
 	}
 
@@ -54,7 +54,7 @@ func (e *registerEvents) EventRegister(c *template.Context, name string, loginNa
 	// Validate...
 	if len(name) == 0 || len(loginName) == 0 || len(email) == 0 || !strings.Contains(email, "@") ||
 		len(name) > maxLength || len(loginName) > maxLength || len(email) > maxLength {
-		showRegisterErrorMsgBox(s, tr.S("bud.auth.register.error.general"))
+		showRegisterErrorMsgBox(s, tr.For(s).S("bud.auth.register.error.general"))
 		return
 	}
 
@@ -62,10 +62,10 @@ func (e *registerEvents) EventRegister(c *template.Context, name string, loginNa
 	exist, err := dbUserExists(loginName)
 	if err != nil {
 		log.L.Error("failed to check if user '%s' exists: %v", loginName, err)
-		showRegisterErrorMsgBox(s, tr.S("bud.auth.register.error.generalShort"))
+		showRegisterErrorMsgBox(s, tr.For(s).S("bud.auth.register.error.generalShort"))
 		return
 	} else if exist {
-		showRegisterErrorMsgBox(s, tr.S("bud.auth.register.error.userAlreadyExists", loginName))
+		showRegisterErrorMsgBox(s, tr.For(s).S("bud.auth.register.error.userAlreadyExists", loginName))
 		return
 	}
 
@@ -76,15 +76,15 @@ func (e *registerEvents) EventRegister(c *template.Context, name string, loginNa
 	u, err := dbAddUser(loginName, name, email, password, true)
 	if err != nil {
 		log.L.Error("failed to add user '%s' to database: %v", loginName, err)
-		showRegisterErrorMsgBox(s, tr.S("bud.auth.register.error.generalShort"))
+		showRegisterErrorMsgBox(s, tr.For(s).S("bud.auth.register.error.generalShort"))
 		return
 	}
 
 	// Send the registration e-mail.
-	err = sendRegistrationEMail(u, password)
+	err = sendRegistrationEMail(tr.For(s), u, password)
 	if err != nil {
 		log.L.Error("%v", err)
-		showRegisterErrorMsgBox(s, tr.S("bud.auth.register.error.generalShort"))
+		showRegisterErrorMsgBox(s, tr.For(s).S("bud.auth.register.error.generalShort"))
 		return
 	}
 
@@ -98,8 +98,8 @@ func (e *registerEvents) EventRegister(c *template.Context, name string, loginNa
 
 	// Show a success message box.
 	messagebox.New().
-		SetTitle(tr.S("bud.auth.register.success.title")).
-		SetText(tr.S("bud.auth.register.success.text")).
+		SetTitle(tr.For(s).S("bud.auth.register.success.title")).
+		SetText(tr.For(s).S("bud.auth.register.success.text")).
 		SetType(messagebox.TypeSuccess).
 		Show(s)
 }
@@ -130,20 +130,20 @@ func routeRegisterPage(s *sessions.Session, req *mux.Request) {
 
 	// Set the body and title
 	req.Body = o
-	req.Title = tr.S("bud.auth.register.pageTitle")
+	req.Title = tr.For(s).S("bud.auth.register.pageTitle")
 	return
 }
 
 func showRegisterErrorMsgBox(s *sessions.Session, msg string) {
 	// Show a messagebox
 	messagebox.New().
-		SetTitle(tr.S("bud.auth.register.errorMsgBoxTitle")).
+		SetTitle(tr.For(s).S("bud.auth.register.errorMsgBoxTitle")).
 		SetText(msg).
 		SetType(messagebox.TypeAlert).
 		Show(s)
 }
 
-func sendRegistrationEMail(u *dbUser, password string) error {
+func sendRegistrationEMail(t *tr.Translator, u *dbUser, password string) error {
 	// Create the login url.
 	loginURL := settings.Settings.SiteUrl + LoginPageUrl
 
@@ -158,11 +158,11 @@ func sendRegistrationEMail(u *dbUser, password string) error {
 	// Create a new mail message.
 	m := mail.Message{
 		To:      []string{u.EMail},
-		Subject: replaceArgs(tr.S("bud.auth.register.mail.subject")),
+		Subject: replaceArgs(t.S("bud.auth.register.mail.subject")),
 	}
 
 	// Set the mail message body.
-	m.Body = replaceArgs(tr.S("bud.auth.register.mail.body"))
+	m.Body = replaceArgs(t.S("bud.auth.register.mail.body"))
 
 	// Send the e-mail
 	err := mail.Send(&m)
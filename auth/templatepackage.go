@@ -28,7 +28,7 @@ type templatePackage struct{}
 
 func (p *templatePackage) MustIsAuth(a *template.Action, c *template.Context) {
 	if !IsAuth(c) {
-		a.Error(tr.S("bud.auth.pkg.mustAuthErrorMessage"))
+		a.Error(tr.For(c.Session()).S("bud.auth.pkg.mustAuthErrorMessage"))
 	}
 }
 
@@ -58,6 +58,41 @@ func (p *templatePackage) UserName(c *template.Context) string {
 	return u.Name()
 }
 
+// Pref returns the decoded user preference or nil if not set.
+func (p *templatePackage) Pref(c *template.Context, key string) interface{} {
+	u := GetUser(c)
+	if u == nil {
+		return nil
+	}
+
+	var v interface{}
+	if !u.prefOrDefault(key, &v) {
+		return nil
+	}
+
+	return v
+}
+
+// PrefString returns the user preference as string or the default value.
+func (p *templatePackage) PrefString(c *template.Context, key string, def string) string {
+	u := GetUser(c)
+	if u == nil {
+		return def
+	}
+
+	return u.PrefString(key, def)
+}
+
+// PrefBool returns the user preference as boolean or false.
+func (p *templatePackage) PrefBool(c *template.Context, key string) bool {
+	u := GetUser(c)
+	if u == nil {
+		return false
+	}
+
+	return u.PrefBool(key, false)
+}
+
 func (p *templatePackage) Group(c *template.Context, groups ...string) bool {
 	// Get the user.
 	u := GetUser(c)
@@ -7,6 +7,7 @@ package auth
 
 import (
 	"fmt"
+	"time"
 )
 
 //###################//
@@ -17,6 +18,9 @@ type Users []*User
 
 type User struct {
 	u *dbUser
+
+	// The lazy loaded user preferences.
+	prefs map[string]string
 }
 
 func newUser(u *dbUser) *User {
@@ -53,6 +57,16 @@ func (u *User) Created() int64 {
 	return u.u.Created
 }
 
+// LastLoginTime returns the last login as time value.
+func (u *User) LastLoginTime() time.Time {
+	return time.Unix(u.u.LastLogin, 0)
+}
+
+// CreatedTime returns the creation date as time value.
+func (u *User) CreatedTime() time.Time {
+	return time.Unix(u.u.Created, 0)
+}
+
 // IsSysOp returns a boolean if the user is a system operator.
 func (u *User) IsSysOp() bool {
 	return u.IsInGroup(GroupSysOp)
@@ -104,6 +118,9 @@ func (u *User) Update() error {
 	// Set the new value.
 	u.u = dbUser
 
+	// Reload the preferences on the next access.
+	u.prefs = nil
+
 	return nil
 }
 
@@ -39,6 +39,11 @@ func main() {
 	// Set the maximum number of CPUs that can be executing simultaneously.
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
+	// Run the translate subcommand if requested.
+	if len(os.Args) > 1 && os.Args[1] == "translate" {
+		os.Exit(runTranslate(os.Args[2:]))
+	}
+
 	defer release()
 
 	// Catch interrupt signals
@@ -0,0 +1,476 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package main
+
+import (
+	"github.com/desertbit/bulldozer/translate/catalog"
+
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	translatePackagePath = "github.com/desertbit/bulldozer/translate"
+)
+
+//###############//
+//### Private ###//
+//###############//
+
+// translationIDs maps the message IDs to their locations.
+type translationIDs map[string][]string
+
+func (t translationIDs) add(id string, location string) {
+	t[id] = append(t[id], location)
+}
+
+func (t translationIDs) sorted() []string {
+	ids := make([]string, 0, len(t))
+	for id := range t {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// runTranslate runs the translate subcommand and returns the exit code.
+// It reports missing, unused and duplicate message IDs for each locale
+// and optionally writes stub entries for new locales.
+func runTranslate(args []string) int {
+	srcPath, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("failed to obtain current work directory path: %v\n", err)
+		return 1
+	}
+
+	var translationsPath, refLocale, stubLocales string
+
+	// Bind the variables to the flags.
+	flags := flag.NewFlagSet("translate", flag.ExitOnError)
+	flags.StringVar(&srcPath, "src", srcPath, "set the project source path, instead of using the current directory.")
+	flags.StringVar(&translationsPath, "translations", "", "set the translations path. Default: $src/translations")
+	flags.StringVar(&refLocale, "ref", "en", "set the reference locale for stub entries.")
+	flags.StringVar(&stubLocales, "stub", "", "write stub entries of missing messages for the comma separated locales.")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: bulldoze translate [flags]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	srcPath = filepath.Clean(srcPath)
+	if len(translationsPath) == 0 {
+		translationsPath = filepath.Join(srcPath, "translations")
+	}
+	translationsPath = filepath.Clean(translationsPath)
+
+	// Collect the message IDs used by the templates and Go sources.
+	used, err := scanTranslationUsage(srcPath, translationsPath)
+	if err != nil {
+		fmt.Printf("failed to scan sources: %v\n", err)
+		return 1
+	}
+
+	// Read the translation files of all locales.
+	catalogs, err := readTranslationCatalogs(translationsPath)
+	if err != nil {
+		fmt.Printf("failed to read translations: %v\n", err)
+		return 1
+	}
+
+	// Write the stub entries.
+	if len(stubLocales) > 0 {
+		for _, locale := range strings.Split(stubLocales, ",") {
+			locale = strings.TrimSpace(locale)
+			if len(locale) == 0 {
+				continue
+			}
+
+			if err = writeTranslationStubs(translationsPath, refLocale, locale, catalogs); err != nil {
+				fmt.Printf("failed to write stub entries for locale '%s': %v\n", locale, err)
+				return 1
+			}
+		}
+
+		// Reload the catalogs for the report.
+		catalogs, err = readTranslationCatalogs(translationsPath)
+		if err != nil {
+			fmt.Printf("failed to read translations: %v\n", err)
+			return 1
+		}
+	}
+
+	// Print the report and exit with an error if messages are missing.
+	if printTranslationReport(used, catalogs) {
+		return 1
+	}
+
+	return 0
+}
+
+// scanTranslationUsage collects the message IDs of all templates and Go sources.
+func scanTranslationUsage(srcPath string, translationsPath string) (translationIDs, error) {
+	used := make(translationIDs)
+
+	err := filepath.Walk(srcPath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip hidden, vendor and translation directories.
+		if f.IsDir() {
+			name := f.Name()
+			if path != srcPath && (strings.HasPrefix(name, ".") || name == "vendor" || path == translationsPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			rel = path
+		}
+
+		if strings.HasSuffix(path, TemplateSuffix) {
+			return scanTemplateFile(used, path, rel)
+		} else if strings.HasSuffix(path, GoSuffix) {
+			return scanGoFile(used, path, rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return used, nil
+}
+
+func scanTemplateFile(used translationIDs, path string, rel string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range catalog.TemplateIDs(string(data)) {
+		used.add(id, rel)
+	}
+
+	return nil
+}
+
+func scanGoFile(used translationIDs, path string, rel string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		fmt.Printf("warning: %v\n", err)
+		return nil
+	}
+
+	// Find the name of the translate package import.
+	// Dot imports call the functions without package name.
+	pkgName := ""
+	for _, i := range file.Imports {
+		if p, _ := strconv.Unquote(i.Path.Value); p == translatePackagePath {
+			pkgName = "tr"
+			if i.Name != nil {
+				pkgName = i.Name.Name
+			}
+		}
+	}
+	if len(pkgName) == 0 || pkgName == "_" {
+		return nil
+	}
+
+	// Find all S calls with a constant ID: tr.S("id"), tr.For(s).S("id"),
+	// tr.ForLocale("en").S("id") and chained In calls like tr.For(s).In(loc).S("id").
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 || !isTranslateCall(call.Fun, pkgName) {
+			return true
+		}
+
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		id, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+
+		used.add(id, fmt.Sprintf("%s:%d", rel, fset.Position(lit.Pos()).Line))
+
+		return true
+	})
+
+	return nil
+}
+
+// isTranslateCall checks if the function expression is the S function
+// of the translate package or the S method of a translator.
+func isTranslateCall(fun ast.Expr, pkgName string) bool {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		// S("id") of a dot import.
+		return pkgName == "." && f.Name == "S"
+	case *ast.SelectorExpr:
+		if f.Sel.Name != "S" {
+			return false
+		}
+
+		// tr.S("id")
+		if isPackageIdent(f.X, pkgName) {
+			return true
+		}
+
+		return isTranslatorExpr(f.X, pkgName)
+	}
+
+	return false
+}
+
+// isTranslatorExpr checks if the expression creates a translator:
+// tr.For(s), tr.ForLocale("en") and chained In calls.
+func isTranslatorExpr(e ast.Expr, pkgName string) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	switch f := call.Fun.(type) {
+	case *ast.Ident:
+		// For(s) of a dot import.
+		return pkgName == "." && (f.Name == "For" || f.Name == "ForLocale")
+	case *ast.SelectorExpr:
+		switch f.Sel.Name {
+		case "For", "ForLocale":
+			return isPackageIdent(f.X, pkgName)
+		case "In":
+			return isTranslatorExpr(f.X, pkgName)
+		}
+	}
+
+	return false
+}
+
+func isPackageIdent(e ast.Expr, pkgName string) bool {
+	ident, ok := e.(*ast.Ident)
+	return ok && pkgName != "." && ident.Name == pkgName
+}
+
+// readTranslationCatalogs reads the translation files of each locale directory.
+// The message IDs are mapped to the files defining them.
+func readTranslationCatalogs(translationsPath string) (map[string]translationIDs, error) {
+	entries, err := ioutil.ReadDir(translationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs := make(map[string]translationIDs)
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		locale := e.Name()
+		ids := make(translationIDs)
+		catalogs[locale] = ids
+
+		dir := filepath.Join(translationsPath, locale)
+		err = filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			} else if f.IsDir() || !strings.HasSuffix(path, catalog.Suffix) {
+				return nil
+			}
+
+			entries, err := catalog.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(translationsPath, path)
+			if err != nil {
+				rel = path
+			}
+
+			for _, m := range entries {
+				ids.add(m.ID, rel)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return catalogs, nil
+}
+
+// writeTranslationStubs appends the messages of the reference locale, which
+// are missing in the locale, to the files with the same names. The reference
+// text is used as placeholder.
+func writeTranslationStubs(translationsPath string, refLocale string, locale string, catalogs map[string]translationIDs) error {
+	refDir := filepath.Join(translationsPath, refLocale)
+	existing := catalogs[locale]
+
+	count := 0
+
+	err := filepath.Walk(refDir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if f.IsDir() || !strings.HasSuffix(path, catalog.Suffix) {
+			return nil
+		}
+
+		entries, err := catalog.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// Encode the missing entries.
+		var b bytes.Buffer
+		written := make(map[string]struct{})
+		for _, m := range entries {
+			if _, ok := existing[m.ID]; ok {
+				continue
+			} else if _, ok := written[m.ID]; ok {
+				continue
+			}
+			written[m.ID] = struct{}{}
+
+			if err = encodeTranslationEntry(&b, m); err != nil {
+				return err
+			}
+		}
+
+		if b.Len() == 0 {
+			return nil
+		}
+
+		// Append the entries to the file with the same name.
+		rel, err := filepath.Rel(refDir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(translationsPath, locale, rel)
+
+		if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err = file.Write(b.Bytes()); err != nil {
+			return err
+		}
+
+		count += len(written)
+		fmt.Printf(">  %s: added %d stub entries\n", dest, len(written))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(">  locale '%s': added %d stub entries\n", locale, count)
+
+	return nil
+}
+
+// encodeTranslationEntry writes the entry in the translation file format:
+// {"ID": "id", "Text": "text"}
+func encodeTranslationEntry(b *bytes.Buffer, e catalog.Entry) error {
+	encode := func(s string) (string, error) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(s); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+
+	id, err := encode(e.ID)
+	if err != nil {
+		return err
+	}
+	text, err := encode(e.Text)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b, "{\"ID\": %s, \"Text\": %s}\n", id, text)
+
+	return nil
+}
+
+// printTranslationReport prints the missing, unused and duplicate
+// message IDs of each locale. True is returned if messages are missing.
+func printTranslationReport(used translationIDs, catalogs map[string]translationIDs) (hasMissing bool) {
+	locales := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+
+	for _, locale := range locales {
+		ids := catalogs[locale]
+
+		var missing, unused, duplicate []string
+		for _, id := range used.sorted() {
+			if _, ok := ids[id]; !ok {
+				missing = append(missing, fmt.Sprintf("%s  (%s)", id, strings.Join(used[id], ", ")))
+			}
+		}
+		for _, id := range ids.sorted() {
+			if _, ok := used[id]; !ok {
+				unused = append(unused, fmt.Sprintf("%s  (%s)", id, strings.Join(ids[id], ", ")))
+			}
+			if len(ids[id]) > 1 {
+				duplicate = append(duplicate, fmt.Sprintf("%s  (%s)", id, strings.Join(ids[id], ", ")))
+			}
+		}
+
+		if len(missing) > 0 {
+			hasMissing = true
+		}
+
+		fmt.Printf("\nLocale '%s': %d messages\n", locale, len(ids))
+		printTranslationReportSection("missing", missing)
+		printTranslationReportSection("unused", unused)
+		printTranslationReportSection("duplicate", duplicate)
+	}
+
+	return hasMissing
+}
+
+func printTranslationReportSection(title string, lines []string) {
+	fmt.Printf("  %s: %d\n", title, len(lines))
+	for _, l := range lines {
+		fmt.Printf("    %s\n", l)
+	}
+}
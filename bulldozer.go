@@ -11,10 +11,11 @@ import (
 	tr "github.com/desertbit/bulldozer/translate"
 
 	"github.com/desertbit/bulldozer/auth"
+	"github.com/desertbit/bulldozer/cluster"
 	"github.com/desertbit/bulldozer/controlpanel"
 	"github.com/desertbit/bulldozer/database"
+	"github.com/desertbit/bulldozer/editmode"
 	"github.com/desertbit/bulldozer/log"
-	"github.com/desertbit/bulldozer/mux"
 	"github.com/desertbit/bulldozer/sessions"
 	"github.com/desertbit/bulldozer/settings"
 	"github.com/desertbit/bulldozer/store"
@@ -127,14 +128,22 @@ func Init() {
 		log.L.Fatal(err)
 	}
 
+	// Connect to the other cluster nodes.
+	if err = cluster.Init(); err != nil {
+		log.L.Fatal(err)
+	}
+
 	// Add the translation paths and load all translation files.
+	tr.SetLocale(settings.Settings.DefaultLocale)
+	for locale, fallbacks := range settings.Settings.LocaleFallbacks {
+		tr.SetFallbacks(locale, fallbacks...)
+	}
 	tr.Add(settings.Settings.BulldozerTranslationPath)
 	tr.Add(settings.Settings.TranslationPath)
 	tr.Load()
 
 	// Initialize the bulldozer sub packages.
 	sessions.Init(backendI)
-	mux.Init(backendI)
 
 	// Connect to the database server.
 	if err = database.Connect(); err != nil {
@@ -151,8 +160,9 @@ func Init() {
 		os.Exit(0)
 	}
 
-	// Initialize the store package.
+	// Initialize the store and edit mode packages.
 	store.Init()
+	editmode.Init()
 
 	log.L.Info("Parsing internal templates...")
 
@@ -183,6 +193,13 @@ func Init() {
 	// Don't handle the parse errors here. It will be shown by the server.
 	templates.Load("", settings.Settings.TemplatesPath)
 
+	// Reload the templates on changes if enabled.
+	if settings.Settings.WatchTemplates {
+		if err = templates.Watch(); err != nil {
+			log.L.Error(err.Error())
+		}
+	}
+
 	// Call the init hooks.
 	if err = triggerOnInit(); err != nil {
 		log.L.Fatalf("init hook error: %v", err)
@@ -238,8 +255,13 @@ func release() {
 	// Release the bulldozer sub packages
 	sessions.Release()
 	tr.Release()
+	templates.Release()
 	auth.Release()
 	store.Release()
+	editmode.Release()
+
+	// Disconnect from the other cluster nodes.
+	cluster.Release()
 
 	// Close the database
 	database.Close()
@@ -255,6 +277,7 @@ func createDirectories() (err error) {
 	// Create the slice of folder paths
 	dirs := [...]string{
 		settings.Settings.TmpPath,
+		settings.Settings.UploadPath,
 		settings.Settings.PublicPath,
 		settings.Settings.PagesPath,
 		settings.Settings.TemplatesPath,
@@ -0,0 +1,247 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+// Package cluster connects multiple bulldozer instances with a pub/sub bus.
+// Each instance is a node with a unique node ID. Messages published on a
+// channel are delivered to the subscribers of all other nodes.
+package cluster
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/gob"
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/settings"
+	"github.com/desertbit/bulldozer/utils"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	nodeIDLength = 20
+)
+
+var (
+	// The unique ID of this node.
+	nodeID = utils.RandomString(nodeIDLength)
+
+	// The in-process bus is used by default.
+	bus      Bus = newLocalBus()
+	busMutex sync.RWMutex
+
+	// Key: channel
+	subscribers      map[string][]func(data []byte) = make(map[string][]func(data []byte))
+	subscribersMutex sync.Mutex
+)
+
+//#################//
+//### Interface ###//
+//#################//
+
+// A Bus transports the messages between the cluster nodes.
+type Bus interface {
+	// Publish sends the message to all other nodes.
+	Publish(m *Message) error
+
+	// OnMessage sets the function which is called for each
+	// message received from another node.
+	OnMessage(f func(m *Message))
+
+	// Close the bus and all its connections.
+	Close() error
+}
+
+// A Message is send over the bus.
+type Message struct {
+	// The ID of the node which published the message.
+	Node    string
+	Channel string
+	Data    []byte
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// Init initializes the cluster bus defined by the settings.
+// If no cluster listen address is set, then the in-process bus is kept.
+// This is handled by the main bulldozer package.
+func Init() error {
+	if len(settings.Settings.ClusterListenAddress) == 0 {
+		return nil
+	}
+
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		return fmt.Errorf("cluster: failed to load the TLS config: %v", err)
+	}
+
+	b, err := NewNetBus(settings.Settings.ClusterNetwork,
+		settings.Settings.ClusterListenAddress,
+		settings.Settings.ClusterPeers,
+		settings.Settings.ClusterKey,
+		tlsConfig)
+	if err != nil {
+		return fmt.Errorf("cluster: failed to create the network bus: %v", err)
+	}
+
+	log.L.Info("Cluster node '%s' listening on '%s'", nodeID, settings.Settings.ClusterListenAddress)
+
+	SetBus(b)
+
+	return nil
+}
+
+// Release closes the current bus.
+// This is handled by the main bulldozer package.
+func Release() {
+	// Lock the mutex
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	if err := bus.Close(); err != nil {
+		log.L.Error("cluster: failed to close bus: %v", err)
+	}
+}
+
+// SetBus replaces the current bus. The previous bus is closed.
+// Use this to set a custom bus implementation.
+func SetBus(b Bus) {
+	// Set the message handler.
+	b.OnMessage(handleMessage)
+
+	// Lock the mutex
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	// Close the previous bus.
+	if err := bus.Close(); err != nil {
+		log.L.Error("cluster: failed to close previous bus: %v", err)
+	}
+
+	bus = b
+}
+
+// NodeID returns the unique ID of this node.
+func NodeID() string {
+	return nodeID
+}
+
+// IsEnabled returns a boolean whenever other nodes might be
+// reachable. This is false, if the in-process bus is used.
+func IsEnabled() bool {
+	// Lock the mutex
+	busMutex.RLock()
+	defer busMutex.RUnlock()
+
+	_, isLocal := bus.(*localBus)
+	return !isLocal
+}
+
+// Publish gob encodes the value and sends it to the
+// subscribers of the channel on all other nodes.
+func Publish(channel string, v interface{}) error {
+	// Encode the value.
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return fmt.Errorf("cluster: failed to encode message for channel '%s': %v", channel, err)
+	}
+
+	m := &Message{
+		Node:    nodeID,
+		Channel: channel,
+		Data:    buf.Bytes(),
+	}
+
+	// Lock the mutex
+	busMutex.RLock()
+	defer busMutex.RUnlock()
+
+	return bus.Publish(m)
+}
+
+// Subscribe adds the function to the channel subscribers.
+// It is called for each message published on the channel by another node.
+// Use Decode to decode the received data.
+func Subscribe(channel string, f func(data []byte)) {
+	// Lock the mutex
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+
+	subscribers[channel] = append(subscribers[channel], f)
+}
+
+// Decode decodes the received message data into the value.
+func Decode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// loadTLSConfig creates the TLS config defined by the settings.
+// Nil is returned, if TLS is disabled. The nodes verify each other's certificate.
+func loadTLSConfig() (*tls.Config, error) {
+	if len(settings.Settings.ClusterTLSCertFile) == 0 {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(settings.Settings.ClusterTLSCertFile, settings.Settings.ClusterTLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if len(settings.Settings.ClusterTLSCAFile) > 0 {
+		data, err := ioutil.ReadFile(settings.Settings.ClusterTLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA file '%s'", settings.Settings.ClusterTLSCAFile)
+		}
+
+		c.RootCAs = pool
+		c.ClientCAs = pool
+	}
+
+	return c, nil
+}
+
+func handleMessage(m *Message) {
+	// Skip own messages.
+	if m.Node == nodeID {
+		return
+	}
+
+	// Get the channel subscribers.
+	subscribersMutex.Lock()
+	funcs := subscribers[m.Channel]
+	subscribersMutex.Unlock()
+
+	for _, f := range funcs {
+		func() {
+			// Recover panics and log the error message.
+			defer func() {
+				if e := recover(); e != nil {
+					log.L.Error("cluster: channel '%s' subscriber panic: %v", m.Channel, e)
+				}
+			}()
+
+			f(m.Data)
+		}()
+	}
+}
@@ -0,0 +1,28 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package cluster
+
+//#################//
+//### Local Bus ###//
+//#################//
+
+// localBus is the default in-process bus.
+// There are no other nodes, so published messages are just dropped.
+type localBus struct{}
+
+func newLocalBus() *localBus {
+	return &localBus{}
+}
+
+func (b *localBus) Publish(m *Message) error {
+	return nil
+}
+
+func (b *localBus) OnMessage(f func(m *Message)) {}
+
+func (b *localBus) Close() error {
+	return nil
+}
@@ -0,0 +1,544 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package cluster
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	dialTimeout      = 5 * time.Second
+	writeTimeout     = 5 * time.Second
+	handshakeTimeout = 5 * time.Second
+	reconnectTimeout = 3 * time.Second
+
+	nonceLength = 32
+
+	// The labels separate the MACs of the handshake steps and messages.
+	macLabelAccept  = "accept"
+	macLabelDial    = "dial"
+	macLabelSession = "session"
+	macLabelMessage = "message"
+)
+
+//###############//
+//### Net Bus ###//
+//###############//
+
+// NetBus is a simple full mesh bus over tcp or unix sockets.
+// Each node listens for incoming connections and dials all its peers.
+// Every node has to list all other nodes as peers.
+// Messages published while a peer is not reachable are dropped.
+//
+// Both nodes of a connection prove the knowledge of the shared key with
+// a HMAC challenge-response handshake. The key itself is never sent.
+// All messages are authenticated with a key derived from the handshake.
+// Messages are only encrypted if TLS is used.
+type NetBus struct {
+	network   string
+	key       []byte
+	tlsConfig *tls.Config
+	listener  net.Listener
+	peers     []*netPeer
+
+	onMessage      func(m *Message)
+	onMessageMutex sync.Mutex
+
+	conns      map[net.Conn]struct{}
+	connsMutex sync.Mutex
+
+	stopLoops chan struct{}
+	closeOnce sync.Once
+}
+
+// The handshake is started by the dialing node with its nonce.
+type netHello struct {
+	Nonce []byte
+}
+
+// The accepting node replies with its nonce and proves the key.
+type netChallenge struct {
+	Nonce []byte
+	MAC   []byte
+}
+
+// The dialing node proves the key.
+type netResponse struct {
+	MAC []byte
+}
+
+// A netPacket is a message signed with the session key of the connection.
+type netPacket struct {
+	Message *Message
+	MAC     []byte
+}
+
+type netPeer struct {
+	addr  string
+	conn  net.Conn
+	enc   *gob.Encoder
+	mutex sync.Mutex
+
+	// The session key and the sequence number of the last sent message.
+	sessionKey []byte
+	seq        uint64
+
+	// Whenever a connection error was already logged.
+	errLogged bool
+
+	// Whenever the peer is closed and must not be connected again.
+	closed bool
+}
+
+// NewNetBus creates a new network bus. The network is either "tcp" or "unix".
+// The bus listens on the listen address and connects to the peer addresses.
+// Only nodes sharing the same key are allowed to connect.
+// Connections use TLS if the optional TLS config is set. It is used for
+// both the listener and the peer connections.
+func NewNetBus(network, listenAddr string, peers []string, key string, tlsConfig *tls.Config) (*NetBus, error) {
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("invalid network '%s': valid networks are 'tcp' and 'unix'", network)
+	}
+
+	// Remove a previous unix socket file.
+	if network == "unix" {
+		os.Remove(listenAddr)
+	}
+
+	// Start listening.
+	l, err := net.Listen(network, listenAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	b := &NetBus{
+		network:   network,
+		key:       []byte(key),
+		tlsConfig: tlsConfig,
+		listener:  l,
+		conns:     make(map[net.Conn]struct{}),
+		stopLoops: make(chan struct{}),
+	}
+
+	// Create the peers.
+	for _, addr := range peers {
+		b.peers = append(b.peers, &netPeer{addr: addr})
+	}
+
+	// Start the loops in a new goroutine.
+	go b.acceptLoop()
+	go b.reconnectLoop()
+
+	return b, nil
+}
+
+// Publish sends the message to all connected peers.
+func (b *NetBus) Publish(m *Message) error {
+	for _, p := range b.peers {
+		if err := p.write(m); err != nil {
+			log.L.Warning("cluster: failed to publish message to peer '%s': %v", p.addr, err)
+		}
+	}
+
+	return nil
+}
+
+// OnMessage sets the function which is called for received messages.
+func (b *NetBus) OnMessage(f func(m *Message)) {
+	// Lock the mutex
+	b.onMessageMutex.Lock()
+	defer b.onMessageMutex.Unlock()
+
+	b.onMessage = f
+}
+
+// Close the listener and all connections.
+func (b *NetBus) Close() error {
+	var err error
+
+	b.closeOnce.Do(func() {
+		// Stop the loops.
+		close(b.stopLoops)
+
+		err = b.listener.Close()
+
+		// Close all incoming connections.
+		b.connsMutex.Lock()
+		for c := range b.conns {
+			c.Close()
+		}
+		b.connsMutex.Unlock()
+
+		// Close all outgoing connections.
+		for _, p := range b.peers {
+			p.close()
+		}
+	})
+
+	return err
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func (b *NetBus) isClosed() bool {
+	select {
+	case <-b.stopLoops:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *NetBus) acceptLoop() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			if b.isClosed() {
+				return
+			}
+
+			log.L.Error("cluster: failed to accept connection: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		go b.handleConn(conn)
+	}
+}
+
+func (b *NetBus) handleConn(conn net.Conn) {
+	// Add the connection to the map.
+	b.connsMutex.Lock()
+	b.conns[conn] = struct{}{}
+	b.connsMutex.Unlock()
+
+	defer func() {
+		conn.Close()
+
+		// Remove the connection from the map.
+		b.connsMutex.Lock()
+		delete(b.conns, conn)
+		b.connsMutex.Unlock()
+	}()
+
+	dec := gob.NewDecoder(conn)
+
+	// Perform the handshake.
+	sessionKey, err := b.acceptHandshake(conn, dec)
+	if err != nil {
+		log.L.Warning("cluster: rejected connection from '%s': %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	var seq uint64
+
+	for {
+		var p netPacket
+		if err := dec.Decode(&p); err != nil {
+			if !b.isClosed() {
+				log.L.Info("cluster: connection from '%s' closed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		// Verify the message. The sequence number prevents replays.
+		seq++
+		if p.Message == nil || !hmac.Equal(p.MAC, messageMAC(sessionKey, seq, p.Message)) {
+			log.L.Warning("cluster: closed connection from '%s': invalid message authentication code", conn.RemoteAddr())
+			return
+		}
+		m := p.Message
+
+		// Get the message handler.
+		b.onMessageMutex.Lock()
+		f := b.onMessage
+		b.onMessageMutex.Unlock()
+
+		if f != nil {
+			f(m)
+		}
+	}
+}
+
+// acceptHandshake answers the challenge of the dialing node and verifies its
+// response. The session key of the connection is returned.
+func (b *NetBus) acceptHandshake(conn net.Conn, dec *gob.Decoder) ([]byte, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	var h netHello
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("failed to read handshake: %v", err)
+	}
+	if len(h.Nonce) != nonceLength {
+		return nil, fmt.Errorf("invalid nonce length")
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	err = gob.NewEncoder(conn).Encode(&netChallenge{
+		Nonce: nonce,
+		MAC:   netMAC(b.key, macLabelAccept, h.Nonce, nonce),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send handshake: %v", err)
+	}
+
+	var r netResponse
+	if err = dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to read handshake: %v", err)
+	}
+	if !hmac.Equal(r.MAC, netMAC(b.key, macLabelDial, h.Nonce, nonce)) {
+		return nil, fmt.Errorf("invalid key")
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return netMAC(b.key, macLabelSession, h.Nonce, nonce), nil
+}
+
+func (b *NetBus) reconnectLoop() {
+	// Connect immediately.
+	b.connectPeers()
+
+	// Create a new ticker
+	ticker := time.NewTicker(reconnectTimeout)
+
+	defer func() {
+		// Stop the ticker
+		ticker.Stop()
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.connectPeers()
+		case <-b.stopLoops:
+			// Just exit the loop
+			return
+		}
+	}
+}
+
+// connectPeers connects to all disconnected peers.
+func (b *NetBus) connectPeers() {
+	for _, p := range b.peers {
+		p.connect(b.network, b.key, b.tlsConfig)
+	}
+}
+
+//################//
+//### Net Peer ###//
+//################//
+
+func (p *netPeer) connect(network string, key []byte, tlsConfig *tls.Config) {
+	// Skip if already connected.
+	p.mutex.Lock()
+	connected := p.conn != nil || p.closed
+	p.mutex.Unlock()
+	if connected {
+		return
+	}
+
+	// Dial without holding the mutex. Otherwise writes
+	// to this peer would block during the dial timeout.
+	conn, enc, sessionKey, err := dialPeer(network, p.addr, key, tlsConfig)
+
+	// Lock the mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err != nil {
+		// Only log the first error until the peer is reachable again.
+		if !p.errLogged {
+			p.errLogged = true
+			log.L.Warning("cluster: failed to connect to peer '%s': %v", p.addr, err)
+		}
+		return
+	}
+
+	// Discard the new connection if the peer was closed in the meantime.
+	if p.closed || p.conn != nil {
+		conn.Close()
+		return
+	}
+
+	p.conn = conn
+	p.enc = enc
+	p.sessionKey = sessionKey
+	p.seq = 0
+	p.errLogged = false
+
+	log.L.Info("cluster: connected to peer '%s'", p.addr)
+}
+
+func (p *netPeer) write(m *Message) error {
+	// Lock the mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Drop the message if not connected.
+	// The reconnect loop will handle the connection.
+	if p.conn == nil {
+		return nil
+	}
+
+	p.seq++
+
+	p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	err := p.enc.Encode(&netPacket{
+		Message: m,
+		MAC:     messageMAC(p.sessionKey, p.seq, m),
+	})
+	if err != nil {
+		// Close the broken connection.
+		// It will be reconnected by the reconnect loop.
+		p.conn.Close()
+		p.conn = nil
+		p.enc = nil
+		return err
+	}
+
+	return nil
+}
+
+func (p *netPeer) close() {
+	// Lock the mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+		p.enc = nil
+	}
+}
+
+// dialPeer connects to the peer address and performs the handshake.
+// The session key of the connection is returned.
+func dialPeer(network, addr string, key []byte, tlsConfig *tls.Config) (net.Conn, *gob.Encoder, []byte, error) {
+	var conn net.Conn
+	var err error
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, network, addr, peerTLSConfig(network, addr, tlsConfig))
+	} else {
+		conn, err = dialer.Dial(network, addr)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	enc := gob.NewEncoder(conn)
+
+	sessionKey, err := dialHandshake(conn, enc, key)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	return conn, enc, sessionKey, nil
+}
+
+// dialHandshake challenges the accepting node and answers its challenge.
+func dialHandshake(conn net.Conn, enc *gob.Encoder, key []byte) ([]byte, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = enc.Encode(&netHello{Nonce: nonce}); err != nil {
+		return nil, err
+	}
+
+	var c netChallenge
+	if err = gob.NewDecoder(conn).Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to read handshake: %v", err)
+	}
+	if len(c.Nonce) != nonceLength || !hmac.Equal(c.MAC, netMAC(key, macLabelAccept, nonce, c.Nonce)) {
+		return nil, fmt.Errorf("invalid key")
+	}
+
+	if err = enc.Encode(&netResponse{MAC: netMAC(key, macLabelDial, nonce, c.Nonce)}); err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return netMAC(key, macLabelSession, nonce, c.Nonce), nil
+}
+
+// peerTLSConfig sets the server name of tcp peers, if not defined by the config.
+func peerTLSConfig(network, addr string, c *tls.Config) *tls.Config {
+	if len(c.ServerName) > 0 || network != "tcp" {
+		return c
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return c
+	}
+
+	c = c.Clone()
+	c.ServerName = host
+	return c
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to create nonce: %v", err)
+	}
+
+	return nonce, nil
+}
+
+// netMAC returns the HMAC-SHA256 of the label and the length prefixed values.
+func netMAC(key []byte, label string, values ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+
+	var l [8]byte
+	for _, v := range values {
+		binary.BigEndian.PutUint64(l[:], uint64(len(v)))
+		mac.Write(l[:])
+		mac.Write(v)
+	}
+
+	return mac.Sum(nil)
+}
+
+// messageMAC returns the authentication code of the message with the sequence number.
+func messageMAC(sessionKey []byte, seq uint64, m *Message) []byte {
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], seq)
+
+	return netMAC(sessionKey, macLabelMessage, s[:], []byte(m.Node), []byte(m.Channel), m.Data)
+}
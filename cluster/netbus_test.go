@@ -0,0 +1,300 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package cluster
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/gob"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	testKey     = "test-key"
+	testTimeout = 10 * time.Second
+)
+
+type testNode struct {
+	bus      *NetBus
+	received chan *Message
+}
+
+func newTestNode(t *testing.T, listenAddr string, peers []string, key string) *testNode {
+	return newTestNodeTLS(t, listenAddr, peers, key, nil)
+}
+
+func newTestNodeTLS(t *testing.T, listenAddr string, peers []string, key string, tlsConfig *tls.Config) *testNode {
+	b, err := NewNetBus("unix", listenAddr, peers, key, tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := &testNode{
+		bus:      b,
+		received: make(chan *Message, 64),
+	}
+
+	b.OnMessage(func(m *Message) {
+		n.received <- m
+	})
+
+	return n
+}
+
+// publishUntilReceived publishes the message until the receiver gets it.
+// Messages are dropped as long as the peers aren't connected.
+func publishUntilReceived(t *testing.T, from, to *testNode, m *Message) {
+	deadline := time.After(testTimeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if err := from.bus.Publish(m); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case r := <-to.received:
+			if r.Node != m.Node || r.Channel != m.Channel || !bytes.Equal(r.Data, m.Data) {
+				t.Fatalf("received invalid message: %+v", r)
+			}
+
+			// Remove the duplicates of the retries.
+			time.Sleep(100 * time.Millisecond)
+			for len(to.received) > 0 {
+				<-to.received
+			}
+			return
+		case <-ticker.C:
+		case <-deadline:
+			t.Fatalf("message on channel '%s' was not received", m.Channel)
+		}
+	}
+}
+
+func TestNetBusLoopback(t *testing.T) {
+	dir := t.TempDir()
+	addrA := filepath.Join(dir, "a.sock")
+	addrB := filepath.Join(dir, "b.sock")
+
+	a := newTestNode(t, addrA, []string{addrB}, testKey)
+	defer a.bus.Close()
+
+	b := newTestNode(t, addrB, []string{addrA}, testKey)
+
+	// Publish in both directions.
+	publishUntilReceived(t, a, b, &Message{Node: "a", Channel: "test", Data: []byte("a to b")})
+	publishUntilReceived(t, b, a, &Message{Node: "b", Channel: "test", Data: []byte("b to a")})
+
+	// Drop the peer. Publishing must not block and the messages are dropped.
+	if err := b.bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if err := a.bus.Publish(&Message{Node: "a", Channel: "dropped"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > writeTimeout {
+		t.Fatalf("publishing to a dropped peer blocked for %v", d)
+	}
+
+	select {
+	case m := <-b.received:
+		t.Fatalf("closed node received a message: %+v", m)
+	default:
+	}
+
+	// The peer is reconnected as soon as it is reachable again.
+	b = newTestNode(t, addrB, []string{addrA}, testKey)
+	defer b.bus.Close()
+
+	publishUntilReceived(t, a, b, &Message{Node: "a", Channel: "test", Data: []byte("reconnected")})
+}
+
+func TestNetBusInvalidKey(t *testing.T) {
+	dir := t.TempDir()
+	addrA := filepath.Join(dir, "a.sock")
+	addrB := filepath.Join(dir, "b.sock")
+
+	a := newTestNode(t, addrA, nil, testKey)
+	defer a.bus.Close()
+
+	b := newTestNode(t, addrB, []string{addrA}, "invalid-key")
+	defer b.bus.Close()
+
+	// The connection is rejected by a.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		b.bus.Publish(&Message{Node: "b", Channel: "test"})
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case m := <-a.received:
+		t.Fatalf("received a message from a node with an invalid key: %+v", m)
+	default:
+	}
+}
+
+func TestNetBusInvalidNetwork(t *testing.T) {
+	if _, err := NewNetBus("udp", "127.0.0.1:0", nil, testKey, nil); err == nil {
+		t.Fatal("expected an error for an invalid network")
+	}
+}
+
+func TestNetBusTLS(t *testing.T) {
+	dir := t.TempDir()
+	addrA := filepath.Join(dir, "a.sock")
+	addrB := filepath.Join(dir, "b.sock")
+
+	c := newTestTLSConfig(t)
+
+	a := newTestNodeTLS(t, addrA, []string{addrB}, testKey, c)
+	defer a.bus.Close()
+
+	b := newTestNodeTLS(t, addrB, []string{addrA}, testKey, c)
+	defer b.bus.Close()
+
+	publishUntilReceived(t, a, b, &Message{Node: "a", Channel: "test", Data: []byte("a to b")})
+	publishUntilReceived(t, b, a, &Message{Node: "b", Channel: "test", Data: []byte("b to a")})
+}
+
+func TestNetBusHandshake(t *testing.T) {
+	dir := t.TempDir()
+	addr := filepath.Join(dir, "a.sock")
+
+	a := newTestNode(t, addr, nil, testKey)
+	defer a.bus.Close()
+
+	// A wrong key is detected by the dialing node.
+	if _, _, _, err := dialPeer("unix", addr, []byte("invalid-key"), nil); err == nil {
+		t.Fatal("expected an error for an invalid key")
+	}
+
+	conn, enc, sessionKey, err := dialPeer("unix", addr, []byte(testKey), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Signed messages are received.
+	m := &Message{Node: "b", Channel: "test", Data: []byte("signed")}
+	if err = enc.Encode(&netPacket{Message: m, MAC: messageMAC(sessionKey, 1, m)}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-a.received:
+		if !bytes.Equal(r.Data, m.Data) {
+			t.Fatalf("received invalid message: %+v", r)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("signed message was not received")
+	}
+
+	// A replayed message closes the connection.
+	if err = enc.Encode(&netPacket{Message: m, MAC: messageMAC(sessionKey, 1, m)}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	if _, err = conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+
+	select {
+	case r := <-a.received:
+		t.Fatalf("received a replayed message: %+v", r)
+	default:
+	}
+}
+
+func TestNetBusHandshakeKeyNotSent(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "a.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Record the dialing node's handshake without answering it.
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var h netHello
+		gob.NewDecoder(conn).Decode(&h)
+		received <- h.Nonce
+	}()
+
+	go dialPeer("unix", l.Addr().String(), []byte(testKey), nil)
+
+	select {
+	case nonce := <-received:
+		if len(nonce) != nonceLength || bytes.Contains(nonce, []byte(testKey)) {
+			t.Fatalf("invalid handshake nonce: %v", nonce)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("handshake was not received")
+	}
+}
+
+// newTestTLSConfig creates a config with a self-signed certificate,
+// which is trusted by the listener and the dialer.
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ServerName:   "localhost",
+	}
+}
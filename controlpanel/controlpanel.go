@@ -8,6 +8,8 @@ package controlpanel
 import (
 	ht "html/template"
 
+	tr "github.com/desertbit/bulldozer/translate"
+
 	"github.com/desertbit/bulldozer/auth"
 	"github.com/desertbit/bulldozer/mux"
 	"github.com/desertbit/bulldozer/sessions"
@@ -43,6 +45,11 @@ func Init() (err error) {
 	// Set the custom ID.
 	t.SetStaticDomID("bud-ctrl")
 
+	// Add the sessions pages.
+	if err = addSessionsPages(); err != nil {
+		return err
+	}
+
 	// Add the control panel routes.
 	mux.Route(PageUrl, routePage)
 	mux.Route(PageUrl+"/*", routePage)
@@ -81,6 +88,9 @@ func routePage(s *sessions.Session, req *mux.Request) {
 	// The current page pointer.
 	var currentPage *Page
 
+	// The page titles are translated to the session's locale.
+	t := tr.For(s)
+
 	// Create a item slice of all items which the user has access to.
 	var items []item
 	var isActive bool
@@ -106,7 +116,7 @@ func routePage(s *sessions.Session, req *mux.Request) {
 		// Create a new item.
 		i := item{
 			Url:      PageUrl + "/" + page.ID,
-			Title:    page.Title,
+			Title:    page.title(t),
 			Icon:     page.Icon,
 			IsActive: isActive,
 		}
@@ -158,8 +168,8 @@ func routePage(s *sessions.Session, req *mux.Request) {
 	data.Body = ht.HTML(body)
 
 	// Set the control panel and page title.
-	req.Title = currentPage.Title
-	data.CurrentTitle = currentPage.Title
+	req.Title = currentPage.title(t)
+	data.CurrentTitle = req.Title
 
 	// Create the template execute options.
 	opts := template.ExecOpts{
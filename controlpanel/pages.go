@@ -6,6 +6,8 @@
 package controlpanel
 
 import (
+	tr "github.com/desertbit/bulldozer/translate"
+
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/template"
 )
@@ -23,8 +25,12 @@ type Pages []*Page
 type Page struct {
 	ID string
 
-	Title string
-	Icon  string
+	// Title is shown as is, if no TitleID is set.
+	// TitleID is the translation message ID of the title. It is
+	// translated to the session's locale on each render.
+	Title   string
+	TitleID string
+	Icon    string
 
 	AuthGroups []string
 	Template   *template.Template
@@ -39,7 +45,7 @@ type Page struct {
 func AddPage(page *Page) {
 	// Check if invalid ID.
 	if len(page.ID) == 0 {
-		log.L.Error("failed to add control panel page with title '%s': emtpy ID!", page.Title)
+		log.L.Error("failed to add control panel page with title '%s': emtpy ID!", page.title(tr.ForLocale(tr.Locale())))
 		return
 	}
 
@@ -54,3 +60,16 @@ func AddPage(page *Page) {
 	// Add the page to the slice.
 	pages = append(pages, page)
 }
+
+//###############//
+//### Private ###//
+//###############//
+
+// title returns the page title translated by the translator.
+func (p *Page) title(t *tr.Translator) string {
+	if len(p.TitleID) == 0 {
+		return p.Title
+	}
+
+	return t.S(p.TitleID)
+}
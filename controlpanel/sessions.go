@@ -0,0 +1,315 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package controlpanel
+
+import (
+	tr "github.com/desertbit/bulldozer/translate"
+
+	"github.com/desertbit/bulldozer/auth"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/template"
+	"github.com/desertbit/bulldozer/templates"
+	"github.com/desertbit/bulldozer/ui/messagebox"
+
+	"fmt"
+)
+
+const (
+	sessionsPageID     = "sessions"
+	userSessionsPageID = "usersessions"
+
+	sessionsTemplateName     = "bud/controlpanel/sessions"
+	userSessionsTemplateName = "bud/controlpanel/usersessions"
+
+	// The number of users shown per user sessions page.
+	userSessionsPageSize = 50
+
+	// The session instance value key of the current user sessions page offset.
+	sessionValueKeyUserSessionsOffset = "budCtrlUserSessionsOffset"
+)
+
+//###############//
+//### Private ###//
+//###############//
+
+// addSessionsPages adds the active sessions page for all users
+// and the user sessions page for administrators.
+func addSessionsPages() error {
+	// Obtain the sessions template and prepare it.
+	t := templates.Templates.Lookup(sessionsTemplateName)
+	if t == nil {
+		return fmt.Errorf("failed to lookup control panel sessions template!")
+	}
+	t.RegisterEvents(new(sessionsEvents)).
+		OnGetData(onSessionsGetData)
+
+	AddPage(&Page{
+		ID:       sessionsPageID,
+		TitleID:  "bud.controlpanel.sessions.title",
+		Icon:     "fa-desktop",
+		Template: t,
+	})
+
+	// Obtain the user sessions template and prepare it.
+	t = templates.Templates.Lookup(userSessionsTemplateName)
+	if t == nil {
+		return fmt.Errorf("failed to lookup control panel user sessions template!")
+	}
+	t.RegisterEvents(new(userSessionsEvents)).
+		OnGetData(onUserSessionsGetData)
+
+	AddPage(&Page{
+		ID:         userSessionsPageID,
+		TitleID:    "bud.controlpanel.userSessions.title",
+		Icon:       "fa-users",
+		AuthGroups: []string{auth.GroupSysOp, auth.GroupAdmin},
+		Template:   t,
+	})
+
+	return nil
+}
+
+type sessionItem struct {
+	ID          string
+	UserAgent   string
+	RemoteAddr  string
+	Created     string
+	LastActive  string
+	IsCurrent   bool
+	IsConnected bool
+}
+
+type userSessionsItem struct {
+	UserID    string
+	LoginName string
+	Name      string
+	Sessions  []sessionItem
+}
+
+func newSessionItems(t *tr.Translator, list auth.LoginSessions) []sessionItem {
+	items := make([]sessionItem, len(list))
+	for i, l := range list {
+		items[i] = sessionItem{
+			ID:          l.ID,
+			UserAgent:   l.UserAgent,
+			RemoteAddr:  l.RemoteAddr,
+			Created:     t.DateTime(l.Created),
+			LastActive:  t.RelativeTime(l.LastActive),
+			IsCurrent:   l.IsCurrent,
+			IsConnected: l.IsConnected,
+		}
+	}
+
+	return items
+}
+
+func onSessionsGetData(c *template.Context) interface{} {
+	list, err := auth.GetLoginSessions(c.Session())
+	if err != nil {
+		log.L.Error("control panel: %v", err)
+	}
+
+	return struct {
+		Sessions []sessionItem
+	}{
+		Sessions: newSessionItems(tr.For(c.Session()), list),
+	}
+}
+
+func onUserSessionsGetData(c *template.Context) interface{} {
+	// Only administrators are allowed to view all sessions.
+	u := auth.GetUser(c)
+	if u == nil || !u.IsInGroup(auth.GroupSysOp, auth.GroupAdmin) {
+		return nil
+	}
+
+	offset := getUserSessionsOffset(c)
+
+	users, more, err := auth.GetUsersWithLoginSessions(offset, userSessionsPageSize)
+	if err != nil {
+		log.L.Error("control panel: %v", err)
+	}
+
+	t := tr.For(c.Session())
+
+	items := make([]userSessionsItem, len(users))
+	for i, user := range users {
+		items[i] = userSessionsItem{
+			UserID:    user.ID(),
+			LoginName: user.LoginName(),
+			Name:      user.Name(),
+			Sessions:  newSessionItems(t, user.LoginSessions()),
+		}
+	}
+
+	return struct {
+		Users       []userSessionsItem
+		HasPrevious bool
+		HasNext     bool
+	}{
+		Users:       items,
+		HasPrevious: offset > 0,
+		HasNext:     more,
+	}
+}
+
+// getUserSessionsOffset returns the user sessions page offset of the session.
+func getUserSessionsOffset(c *template.Context) int {
+	i, ok := c.Session().InstanceGet(sessionValueKeyUserSessionsOffset)
+	if !ok {
+		return 0
+	}
+
+	offset, _ := i.(int)
+	return offset
+}
+
+// setUserSessionsOffset sets the user sessions page offset of the session.
+func setUserSessionsOffset(c *template.Context, offset int) {
+	if offset < 0 {
+		offset = 0
+	}
+
+	c.Session().InstanceSet(sessionValueKeyUserSessionsOffset, offset)
+}
+
+func showSessionsErrorMsgBox(c *template.Context) {
+	// Show a messagebox
+	messagebox.New().
+		SetTitle(tr.For(c.Session()).S("bud.controlpanel.sessions.error.title")).
+		SetText(tr.For(c.Session()).S("bud.controlpanel.sessions.error.text")).
+		SetType(messagebox.TypeAlert).
+		Show(c.Session())
+}
+
+//#######################//
+//### Sessions Events ###//
+//#######################//
+
+type sessionsEvents struct{}
+
+func (e *sessionsEvents) EventLogoutSession(c *template.Context, loginID string) {
+	// Hide the loading indicator on return.
+	defer c.Session().HideLoadingIndicator()
+
+	// Only sessions of the authenticated user itself are allowed.
+	u := auth.GetUser(c)
+	if u == nil {
+		return
+	}
+
+	if err := auth.LogoutSession(u.ID(), loginID); err != nil {
+		log.L.Error("control panel: %v", err)
+		showSessionsErrorMsgBox(c)
+	}
+
+	// Update the sessions list.
+	if err := c.Update(); err != nil {
+		log.L.Error("control panel: failed to update sessions template: %v", err)
+	}
+}
+
+func (e *sessionsEvents) EventLogoutOtherSessions(c *template.Context) {
+	// Hide the loading indicator on return.
+	defer c.Session().HideLoadingIndicator()
+
+	if err := auth.LogoutOtherSessions(c.Session()); err != nil {
+		log.L.Error("control panel: %v", err)
+		showSessionsErrorMsgBox(c)
+	}
+
+	// Update the sessions list.
+	if err := c.Update(); err != nil {
+		log.L.Error("control panel: failed to update sessions template: %v", err)
+	}
+}
+
+//############################//
+//### User Sessions Events ###//
+//############################//
+
+type userSessionsEvents struct{}
+
+// isAdmin checks whenever the authenticated user is an administrator.
+func (e *userSessionsEvents) isAdmin(c *template.Context) bool {
+	u := auth.GetUser(c)
+	if u == nil || !u.IsInGroup(auth.GroupSysOp, auth.GroupAdmin) {
+		log.L.Warning("control panel: user sessions event called without permission from remote address: '%s'", c.Session().RemoteAddr())
+		return false
+	}
+
+	return true
+}
+
+func (e *userSessionsEvents) EventLogoutSession(c *template.Context, userID string, loginID string) {
+	// Hide the loading indicator on return.
+	defer c.Session().HideLoadingIndicator()
+
+	if !e.isAdmin(c) {
+		return
+	}
+
+	if err := auth.LogoutSession(userID, loginID); err != nil {
+		log.L.Error("control panel: %v", err)
+		showSessionsErrorMsgBox(c)
+	}
+
+	// Update the user sessions list.
+	if err := c.Update(); err != nil {
+		log.L.Error("control panel: failed to update user sessions template: %v", err)
+	}
+}
+
+func (e *userSessionsEvents) EventLogoutUser(c *template.Context, userID string) {
+	// Hide the loading indicator on return.
+	defer c.Session().HideLoadingIndicator()
+
+	if !e.isAdmin(c) {
+		return
+	}
+
+	if err := auth.LogoutUser(userID); err != nil {
+		log.L.Error("control panel: %v", err)
+		showSessionsErrorMsgBox(c)
+	}
+
+	// Update the user sessions list.
+	if err := c.Update(); err != nil {
+		log.L.Error("control panel: failed to update user sessions template: %v", err)
+	}
+}
+
+func (e *userSessionsEvents) EventPreviousPage(c *template.Context) {
+	// Hide the loading indicator on return.
+	defer c.Session().HideLoadingIndicator()
+
+	if !e.isAdmin(c) {
+		return
+	}
+
+	setUserSessionsOffset(c, getUserSessionsOffset(c)-userSessionsPageSize)
+
+	// Update the user sessions list.
+	if err := c.Update(); err != nil {
+		log.L.Error("control panel: failed to update user sessions template: %v", err)
+	}
+}
+
+func (e *userSessionsEvents) EventNextPage(c *template.Context) {
+	// Hide the loading indicator on return.
+	defer c.Session().HideLoadingIndicator()
+
+	if !e.isAdmin(c) {
+		return
+	}
+
+	setUserSessionsOffset(c, getUserSessionsOffset(c)+userSessionsPageSize)
+
+	// Update the user sessions list.
+	if err := c.Update(); err != nil {
+		log.L.Error("control panel: failed to update user sessions template: %v", err)
+	}
+}
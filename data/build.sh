@@ -36,12 +36,17 @@ appendData "$(cat ./javascript/loadingindicator.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/connectionlost.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/websocket.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/ajaxsocket.js)" ./resources/js/bulldozer.js
+appendData "$(cat ./javascript/ssesocket.js)" ./resources/js/bulldozer.js
+appendData "$(cat ./javascript/socketauth.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/socket.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/core.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/auth.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/render.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/data.js)" ./resources/js/bulldozer.js
+appendData "$(cat ./javascript/broadcast.js)" ./resources/js/bulldozer.js
+appendData "$(cat ./javascript/upload.js)" ./resources/js/bulldozer.js
 appendData "$(cat ./javascript/topbar.js)" ./resources/js/bulldozer.js
+appendData "$(cat ./javascript/messages.js)" ./resources/js/bulldozer.js
 
 if [ "$DEBUG_BUILD" == "debug" ]; then
 	# Just copy the uncompressed bulldozer file
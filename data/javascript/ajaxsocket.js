@@ -14,6 +14,12 @@ Bulldozer.fn.AjaxSocket = new function () {
 
      var pollXhr = false;
      var sendXhr = false;
+     var sendQueue = [];
+
+     // Large messages are split into chunks by the server.
+     // Chunks followed by further chunks are marked with this prefix.
+     var chunkPrefix = "+";
+     var chunks = "";
 
      var Type = {
         Init: "init"
@@ -24,6 +30,10 @@ Bulldozer.fn.AjaxSocket = new function () {
      */
 
     var stopRequests = function() {
+        // Clear the send queue and the received chunks
+        sendQueue = [];
+        chunks = "";
+
         // Kill the ajax requests
         if (pollXhr) {
             pollXhr.abort();
@@ -62,6 +72,15 @@ Bulldozer.fn.AjaxSocket = new function () {
                 // Start the next poll request
                 poll();
 
+                // Collect the chunks until the last one is received
+                if (data.charAt(0) === chunkPrefix) {
+                    chunks += data.substr(1);
+                    return;
+                }
+
+                data = chunks + data;
+                chunks = "";
+
                 // Call the event
                 Bulldozer.AjaxSocket.onMessage(data);
             },
@@ -99,6 +118,17 @@ Bulldozer.fn.AjaxSocket = new function () {
     };
 
 
+    // flushSendQueue sends the queued messages one after another.
+    // This keeps the order of the message sequence numbers.
+    var flushSendQueue = function () {
+        if (sendXhr || sendQueue.length === 0) {
+            return;
+        }
+
+        send(sendQueue.shift(), flushSendQueue);
+    };
+
+
 
     /*
      * Public Methods
@@ -138,7 +168,8 @@ Bulldozer.fn.AjaxSocket = new function () {
 
     this.send = function (data) {
         // Always prepend the uid to the data
-        send(uid + "&" + data);
+        sendQueue.push(uid + "&" + data);
+        flushSendQueue();
     };
 
     this.reset = function() {
@@ -0,0 +1,88 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+
+
+/*
+ * Bulldozer broadcast Methods
+ */
+
+Bulldozer.fn.broadcast = new function () {
+    /*
+     * Private Variables
+     */
+
+    var listeners = {};
+
+
+
+    /*
+     * Public Methods
+     */
+
+    // Add a listener for the server pushed event.
+    this.on = function (event, f) {
+        if (!listeners[event]) {
+            listeners[event] = [];
+        }
+
+        listeners[event].push(f);
+    };
+
+    // Remove the listener. All listeners of the event
+    // are removed if no function is passed.
+    this.off = function (event, f) {
+        if (!f) {
+            delete listeners[event];
+            return;
+        }
+
+        var l = listeners[event];
+        if (!l) {
+            return;
+        }
+
+        for (var i = 0; i < l.length; i++) {
+            if (l[i] === f) {
+                l.splice(i, 1);
+                break;
+            }
+        }
+    };
+
+    // Subscribe this session to the server channel.
+    this.subscribe = function (channel) {
+        Bulldozer.socket.send('subscribe', {
+            channel: channel
+        });
+    };
+
+    // Unsubscribe this session from the server channel.
+    this.unsubscribe = function (channel) {
+        Bulldozer.socket.send('unsubscribe', {
+            channel: channel
+        });
+    };
+
+    // Trigger is called by the broadcast server message handler.
+    this.trigger = function (event, payload) {
+        var l = listeners[event];
+        if (!l) {
+            return;
+        }
+
+        // Copy the listeners. They might be removed during the call.
+        l = l.slice();
+
+        for (var i = 0; i < l.length; i++) {
+            try {
+                l[i](payload);
+            }
+            catch (e) {
+                console.log("Bulldozer: broadcast listener error: " + e.message);
+            }
+        }
+    };
+};
@@ -188,6 +188,26 @@ Bulldozer.fn.core = new function () {
 
 
 
+    //
+    // Time Zone
+    //
+
+    // Report the client time zone for localized dates and times.
+    $(document).on('bulldozer.ready', function () {
+        var name = "";
+        try {
+            name = Intl.DateTimeFormat().resolvedOptions().timeZone || "";
+        }
+        catch (err) {}
+
+        Bulldozer.socket.send('timeZone', {
+            name: name,
+            offset: -(new Date()).getTimezoneOffset()
+        });
+    });
+
+
+
     //
     // Exit Message
     //
@@ -209,6 +229,25 @@ Bulldozer.fn.core = new function () {
 
 
 
+    //
+    // Session Cookie
+    //
+
+    // Renew the session cookie with the one-time token.
+    // The server rotated the session ID.
+    this.renewSessionCookie = function (token) {
+        jQuery.ajax({
+            url: "/bulldozer/cookie",
+            type: "POST",
+            data: { token: token },
+            error: function () {
+                console.log("Bulldozer: failed to renew the session cookie!");
+            }
+        });
+    };
+
+
+
     //
     // JS Load & Unload
     //
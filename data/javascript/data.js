@@ -14,7 +14,117 @@ Bulldozer.fn.data = new function () {
      * Private Variables
      */
 
-     var values = {};
+    // All values are stored JSON encoded.
+    // Web storage and cookie keys are prefixed.
+    var values = {},
+        keyPrefix = "bud.";
+
+
+
+    /*
+     * Private Methods
+     */
+
+    var webStorage = function (st) {
+        // Accessing the storage might throw if disabled by the browser.
+        try {
+            if (st === "local") {
+                return window.localStorage;
+            }
+            return window.sessionStorage;
+        }
+        catch (err) {
+            console.log("Bulldozer.data: web storage not available: " + err.message);
+            return null;
+        }
+    };
+
+    var getCookie = function (key) {
+        var name = encodeURIComponent(keyPrefix + key) + "=",
+            cookies = document.cookie.split(";");
+
+        for (var i = 0; i < cookies.length; i++) {
+            var c = cookies[i].replace(/^\s+/, "");
+            if (c.indexOf(name) === 0) {
+                return decodeURIComponent(c.substring(name.length));
+            }
+        }
+
+        return undefined;
+    };
+
+    var setCookie = function (key, value, maxAge) {
+        var c = encodeURIComponent(keyPrefix + key) + "=" + encodeURIComponent(value) + "; path=/";
+        if (maxAge !== undefined) {
+            c += "; max-age=" + maxAge;
+        }
+        if (window.location.protocol === "https:") {
+            c += "; secure";
+        }
+
+        document.cookie = c;
+    };
+
+    // Returns the JSON encoded value or undefined.
+    var getRaw = function (key, st) {
+        if (!st || st === "memory") {
+            return values[key];
+        }
+        else if (st === "cookie") {
+            return getCookie(key);
+        }
+
+        var s = webStorage(st);
+        if (!s) {
+            return undefined;
+        }
+
+        var v = s.getItem(keyPrefix + key);
+        if (v === null) {
+            return undefined;
+        }
+
+        return v;
+    };
+
+    var setRaw = function (key, value, st) {
+        if (!st || st === "memory") {
+            values[key] = value;
+            return;
+        }
+        else if (st === "cookie") {
+            setCookie(key, value);
+            return;
+        }
+
+        var s = webStorage(st);
+        if (!s) {
+            return;
+        }
+
+        try {
+            s.setItem(keyPrefix + key, value);
+        }
+        catch (err) {
+            console.log("Bulldozer.data: failed to set value '" + key + "': " + err.message);
+        }
+    };
+
+    var deleteRaw = function (key, st) {
+        if (!st || st === "memory") {
+            delete values[key];
+            return;
+        }
+        else if (st === "cookie") {
+            setCookie(key, "", 0);
+            return;
+        }
+
+        var s = webStorage(st);
+        if (s) {
+            s.removeItem(keyPrefix + key);
+        }
+    };
 
 
 
@@ -22,33 +132,63 @@ Bulldozer.fn.data = new function () {
      * Public Methods
      */
 
-    this.set = function (key, data) {
-        values[key] = data; 
+    // The optional storage is one of memory, session, local or cookie.
+    // The memory storage is used by default.
+
+    this.set = function (key, data, st) {
+        setRaw(key, JSON.stringify(data), st);
+    };
+
+    this.setMulti = function (data, st) {
+        for (var key in data) {
+            if (data.hasOwnProperty(key)) {
+                this.set(key, data[key], st);
+            }
+        }
+    };
+
+    this.delete = function (key, st) {
+        deleteRaw(key, st);
     };
 
-    this.delete = function (key) {
-        delete values[key]; 
+    this.deleteMulti = function (keys, st) {
+        for (var i = 0; i < keys.length; i++) {
+            deleteRaw(keys[i], st);
+        }
     };
 
-    this.get = function (key) {
-        return values[key]; 
+    this.get = function (key, st) {
+        var v = getRaw(key, st);
+        if (v === undefined) {
+            return undefined;
+        }
+
+        try {
+            return JSON.parse(v);
+        }
+        catch (err) {
+            console.log("Bulldozer.data: failed to decode value '" + key + "': " + err.message);
+            return undefined;
+        }
     };
 
-    this.getAndReply = function (key, randomKey) {
-    	// Get the data.
-    	var d = values[key];
-    	if (!d) {
-    		d = "";
-    	}
+    this.getAndReply = function (keys, st, randomKey) {
+        // Get the JSON encoded values. Skip missing and invalid values.
+        var d = {};
+        for (var i = 0; i < keys.length; i++) {
+            var v = this.get(keys[i], st);
+            if (v !== undefined) {
+                d[keys[i]] = v;
+            }
+        }
 
         // Construct the data object be send
         var data = {
-            key: key,
             rand: randomKey,
-            data: d
+            data: JSON.stringify(d)
         };
 
         // Finally send the data
         Bulldozer.socket.send('clientData', data);
     };
-};
\ No newline at end of file
+};
@@ -0,0 +1,119 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+
+/*
+ * Bulldozer server message handlers
+ */
+
+(function () {
+    var on = Bulldozer.socket.onMessage;
+
+    // Raw javascript commands.
+    on('cmd', function (id, cmd) {
+        jQuery.globalEval(cmd);
+    });
+
+    // Loading indicator.
+    on('loading.show', function () {
+        Bulldozer.loadingIndicator.show();
+    });
+    on('loading.hide', function () {
+        Bulldozer.loadingIndicator.hide();
+    });
+
+    // Exit message.
+    on('exitMessage.set', function (id, msg) {
+        Bulldozer.core.setExitMessage(msg);
+    });
+    on('exitMessage.reset', function () {
+        Bulldozer.core.resetExitMessage();
+    });
+
+    // Scripts and stylesheets.
+    on('loadScript', function (id, d) {
+        var callback;
+        if (d.cmd) {
+            callback = function () {
+                jQuery.globalEval(d.cmd);
+            };
+        }
+
+        Bulldozer.core.loadScript(d.url, callback);
+    });
+    on('loadStyleSheet', function (id, url) {
+        Bulldozer.core.loadStyleSheet(url);
+    });
+
+    // Rendering.
+    on('render.page', function (id, d, body) {
+        Bulldozer.render.page(Bulldozer.socket.decodeText(body), d.title, d.path, d.patch);
+    });
+    on('render.template', function (id, d, body) {
+        Bulldozer.render.updateTemplate(id, Bulldozer.socket.decodeText(body), d);
+    });
+
+    // Server events.
+    on('event.emit', function (id, d) {
+        Bulldozer.core.emitServerEvent.apply(Bulldozer.core, [id, d.event].concat(d.args || []));
+    });
+    on('event.emitGlobal', function (id, d) {
+        Bulldozer.core.emitGlobalServerEvent.apply(Bulldozer.core, [d.event].concat(d.args || []));
+    });
+
+    // Client data.
+    on('data.get', function (id, d) {
+        Bulldozer.data.getAndReply(d.keys || [], d.st, d.rand);
+    });
+    on('data.set', function (id, d) {
+        Bulldozer.data.setMulti(d.values || {}, d.st);
+    });
+    on('data.delete', function (id, d) {
+        Bulldozer.data.deleteMulti(d.keys || [], d.st);
+    });
+
+    // Broadcasts.
+    on('broadcast', function (id, d) {
+        Bulldozer.broadcast.trigger(d.event, d.payload);
+    });
+
+    // Uploads.
+    on('upload.start', function (id, d) {
+        Bulldozer.upload.start(d.id, d.token);
+    });
+    on('upload.progress', function (id, d) {
+        Bulldozer.upload.progress(d.id, d.loaded, d.total);
+    });
+    on('upload.done', function (id, d) {
+        Bulldozer.upload.done(d.id);
+    });
+    on('upload.error', function (id, d) {
+        Bulldozer.upload.error(d.id, d.error);
+    });
+
+    // Session cookie renewal.
+    on('session.cookie', function (id, token) {
+        Bulldozer.core.renewSessionCookie(token);
+    });
+
+    // Dialogs.
+    on('dialog.show', function (id, d) {
+        Bulldozer.utils.addAndShowTmpModal(d.body, {
+            domId: id,
+            closable: d.closable,
+            class: d.class
+        });
+    });
+    on('dialog.close', function (id) {
+        var e = $('#' + id);
+        e.data('serverClosedDialog', true);
+        Kepler.modal.close(e);
+    });
+
+    // Topbar.
+    on('topbar.space', function (id, add) {
+        Bulldozer.topbar.space(add);
+    });
+})();
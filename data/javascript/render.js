@@ -16,6 +16,11 @@ Bulldozer.fn.render = new function () {
      var currentUrl;
      var manualHistoryChange = false;
 
+     // The last rendered outputs received from the server.
+     // Patches are applied to them. Key: DOM ID
+     var outputs = {};
+     var pageDomId = "bud-body";
+
 
 
     /*
@@ -40,11 +45,198 @@ Bulldozer.fn.render = new function () {
     });
 
 
+    // FNV-1a hash of the UTF-16 code units. The server calculates the same hash.
+    var textHash = function (s) {
+        var h = 0x811c9dc5;
+        for (var i = 0; i < s.length; i++) {
+            h ^= s.charCodeAt(i);
+            h += (h << 1) + (h << 4) + (h << 7) + (h << 8) + (h << 24);
+        }
+
+        return h >>> 0;
+    };
+
+    // applyPatch applies the patch operations to the previous output:
+    // positive number: copy, negative number: skip, string: insert.
+    var applyPatch = function (prev, ops) {
+        var out = [], pos = 0, op;
+        for (var i = 0; i < ops.length; i++) {
+            op = ops[i];
+            if (typeof op === "string") {
+                out.push(op);
+            } else if (op >= 0) {
+                out.push(prev.substr(pos, op));
+                pos += op;
+            } else {
+                pos -= op;
+            }
+        }
+
+        return out.join("");
+    };
+
+    // getOutput returns the new output of the message body and records it.
+    // False is returned if the patch can't be applied.
+    // The complete output is requested from the server in this case.
+    var getOutput = function (domId, body, patch) {
+        if (!patch) {
+            outputs[domId] = body;
+            return body;
+        }
+
+        var prev = outputs[domId];
+        var out;
+
+        try {
+            if (prev !== undefined && textHash(prev) === patch.base) {
+                out = applyPatch(prev, JSON.parse(body));
+            }
+        }
+        catch(err) {
+            console.log("failed to apply render patch: " + err.message);
+        }
+
+        if (out === undefined || textHash(out) !== patch.hash) {
+            delete outputs[domId];
+            Bulldozer.socket.send('render.resync', {
+                did: domId
+            });
+            return false;
+        }
+
+        outputs[domId] = out;
+        return out;
+    };
+
+    // parseHTML parses the HTML without executing the scripts.
+    // The scripts are removed and returned separately.
+    var parseHTML = function (html) {
+        var container = document.createElement("div");
+        container.innerHTML = html;
+
+        var scripts = [];
+        $(container).find("script").each(function () {
+            scripts.push({
+                src: this.src ? $(this).attr("src") : false,
+                text: this.text || this.textContent || this.innerHTML || ""
+            });
+            this.parentNode.removeChild(this);
+        });
+
+        return {
+            container: container,
+            scripts: scripts
+        };
+    };
+
+    // execScripts executes the scripts in order.
+    var execScripts = function (scripts) {
+        $.each(scripts, function (i, script) {
+            if (script.src) {
+                Bulldozer.core.loadScript(script.src);
+            } else {
+                jQuery.globalEval(script.text);
+            }
+        });
+    };
+
+    // isSameNode checks if the nodes can be morphed into each other.
+    var isSameNode = function (a, b) {
+        if (a.nodeType !== b.nodeType || a.nodeName !== b.nodeName) {
+            return false;
+        }
+
+        return a.nodeType !== 1 || (a.id || "") === (b.id || "");
+    };
+
+    // morphAttributes updates the attributes of the element.
+    // The values of the input elements are kept.
+    var morphAttributes = function (from, to) {
+        var i, attr;
+
+        for (i = from.attributes.length - 1; i >= 0; i--) {
+            attr = from.attributes[i];
+            if (!to.hasAttribute(attr.name)) {
+                from.removeAttribute(attr.name);
+            }
+        }
+
+        for (i = 0; i < to.attributes.length; i++) {
+            attr = to.attributes[i];
+            if (from.getAttribute(attr.name) !== attr.value) {
+                from.setAttribute(attr.name, attr.value);
+            }
+        }
+    };
+
+    // morphChildren morphs the child nodes of the element to the new child nodes.
+    // Elements with an ID are moved instead of created again.
+    var morphChildren = function (from, to) {
+        var keyed = {}, toChildren = [], i, cur, next, toChild, match;
+
+        for (cur = from.firstChild; cur; cur = cur.nextSibling) {
+            if (cur.nodeType === 1 && cur.id) {
+                keyed[cur.id] = cur;
+            }
+        }
+        for (toChild = to.firstChild; toChild; toChild = toChild.nextSibling) {
+            toChildren.push(toChild);
+        }
+
+        cur = from.firstChild;
+        for (i = 0; i < toChildren.length; i++) {
+            toChild = toChildren[i];
+
+            // Move the element with the same ID to the current position.
+            match = (toChild.nodeType === 1 && toChild.id) ? keyed[toChild.id] : undefined;
+            if (match && match !== cur && match.parentNode === from) {
+                from.insertBefore(match, cur);
+                cur = match;
+            }
+
+            if (cur && isSameNode(cur, toChild)) {
+                next = cur.nextSibling;
+                morphNode(cur, toChild);
+                cur = next;
+            } else {
+                from.insertBefore(toChild, cur);
+            }
+        }
+
+        // Remove the remaining old nodes.
+        while (cur) {
+            next = cur.nextSibling;
+            from.removeChild(cur);
+            cur = next;
+        }
+    };
+
+    // morphNode morphs the node to the new node.
+    var morphNode = function (from, to) {
+        if (from.nodeType !== 1) {
+            if (from.nodeValue !== to.nodeValue) {
+                from.nodeValue = to.nodeValue;
+            }
+            return;
+        }
+
+        morphAttributes(from, to);
+        morphChildren(from, to);
+    };
+
+    // cleanup removes all events and data of the element and its children.
+    // They are registered again by the scripts of the new output.
+    var cleanup = function (el) {
+        el.find("*").addBack().off().removeData();
+    };
+
+
+
     /*
      * Public Methods
      */
 
-    this.updateTemplate = function (domId, body) {
+    this.updateTemplate = function (domId, body, patch) {
         var obj = $("#" + domId);
         if (obj.length <= 0) {
             // Show an error message box
@@ -52,12 +244,34 @@ Bulldozer.fn.render = new function () {
             return;
         }
 
+        // Get the new output. Abort if the patch failed.
+        // The complete output is sent again.
+        body = getOutput(domId, body, patch);
+        if (body === false) {
+            return;
+        }
+
         // Trigger the unload event
         Bulldozer.core.execJsUnload(domId);
 
-        // Remove all the data attached to the object
-        // and replace the template body with the new template body
-        obj.removeData().replaceWith(body);
+        // Parse the new template body.
+        var parsed = parseHTML(body);
+        var newEl = parsed.container.firstChild;
+
+        // Replace the template body if it isn't a template wrapper with the same ID.
+        if (!newEl || parsed.container.childNodes.length !== 1 || !isSameNode(obj[0], newEl)) {
+            obj.removeData().replaceWith(body);
+            Kepler.init();
+            return;
+        }
+
+        // Remove all the data and events attached to the objects
+        // and morph the template body to keep the focus and the input states.
+        cleanup(obj);
+        morphNode(obj[0], newEl);
+
+        // Execute the scripts of the new template body.
+        execScripts(parsed.scripts);
 
         // Execute the kepler init method to apply all new kepler changes
         Kepler.init();
@@ -65,7 +279,24 @@ Bulldozer.fn.render = new function () {
 
 
 
-    this.page = function (body, title, url) {
+    this.page = function (body, title, url, patch) {
+        // Get the new output. Abort if the patch failed.
+        // The page is reloaded.
+        body = getOutput(pageDomId, body, patch);
+        if (body === false) {
+            return;
+        }
+
+        // Remove the template outputs. They are replaced by the page.
+        // The server removes them too.
+        outputs = {
+            "bud-body": body
+        };
+
+        // Morph the page if it is rendered again.
+        // This keeps the focus and the input states.
+        var morph = (url && currentUrl === url);
+
         // Trigger the global js unload event
         $(document).triggerHandler('bulldozer.execJsUnload');
 
@@ -103,17 +334,27 @@ Bulldozer.fn.render = new function () {
         // Reset the previous topbar space.
         Bulldozer.topbar.space();
 
-        // Create the new body.
-        var newBudBody = $('<div id="bud-body"></div>');
+        if (morph && budBody && budBody.length > 0) {
+            // Remove all the data and events attached to the objects.
+            cleanup(budBody);
 
-        // Append the page body.
-        newBudBody.append(body);
+            // Morph the page body and execute its scripts.
+            var parsed = parseHTML(body);
+            morphChildren(budBody[0], parsed.container);
+            execScripts(parsed.scripts);
+        } else {
+            // Create the new body.
+            var newBudBody = $('<div id="bud-body"></div>');
 
-        // Replace the bulldozer body.
-        budBody.replaceWith(newBudBody);
+            // Append the page body.
+            newBudBody.append(body);
 
-        // Scroll to the top of the page
-        window.scrollTo(0, 0);
+            // Replace the bulldozer body.
+            budBody.replaceWith(newBudBody);
+
+            // Scroll to the top of the page
+            window.scrollTo(0, 0);
+        }
 
         // Push the new current url to the browser history, if it is not the same url as the current one
         if (url && currentUrl !== url) {
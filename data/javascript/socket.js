@@ -15,17 +15,42 @@ Bulldozer.fn.socket = new function () {
 
     var reconnectAttempts = 3;
 
+    // Fall back to server-sent events after this
+    // count of failed websocket connections.
+    var websocketAttempts = 2;
+
+    // Sent messages are kept until the server acknowledges them.
+    // They are sent again after a reconnection.
+    var maxSentBuffer = 500;
+
+    // Acknowledge received messages after this delay,
+    // if no other message was sent in the meantime.
+    var ackDelay = 1000;
+
     var SocketKey = {
-        Task: "tsk"
+        SessionID:  "sid",
+        Token:      "tok",
+        PublicKey:  "dh",
+        Ack:        "ack",
+        Task:       "tsk"
     };
 
     var SocketData = {
         InvalidRequest:     "invalid_request",
         RefreshRequest:     "req_refresh",
         Ping:               "ping",
-        Pong:               "pong"
+        Pong:               "pong",
+        Ack:                "ack",
+        Visibility:         "visibility",
+        Suspend:            "socket.suspend"
     };
 
+    // Binary packets are base64 encoded and prefixed
+    // with this character on text based sockets.
+    var BinaryPrefix = "#";
+
+    var Colon = 58;
+
 
 
     /*
@@ -34,11 +59,20 @@ Bulldozer.fn.socket = new function () {
 
     var documentReady = false,
         socket = false,
+        socketType = false,
+        useFallback = false,
+        isReady = false,
+        isSuspended = false,
         sid, instanceID, token,
         timeoutConnectionLost = false,
         reconnectCount = 0,
-        sendQueue = [],
-        sendQueueTimeout = false;
+        sendSeq = 0,
+        recvSeq = 0,
+        ackSent = 0,
+        ackTimeout = false,
+        sentBuffer = [],
+        droppedSeq = 0,
+        messageHandlers = {};
 
 
 
@@ -47,36 +81,128 @@ Bulldozer.fn.socket = new function () {
      * Private Methods
      */
 
-    var prepareSendMsg = function(data) {
-        return "sid=" + sid + "&tok=" + token + "&" + data;
+    // prepareSendMsg encodes the buffered message and signs it with its
+    // sequence number. The current session ID and the acknowledgement
+    // of the received messages are added.
+    var prepareSendMsg = function(entry) {
+        var msg = entry.msg;
+        msg[SocketKey.SessionID] = sid;
+        msg[SocketKey.Ack] = String(recvSeq);
+        ackSent = recvSeq;
+
+        return Bulldozer.socketAuth.sign(entry.seq, JSON.stringify(msg));
     };
 
-    var stopConnectionLostTimeout = function() {
-        // Stop the timeout timer
-        if (timeoutConnectionLost !== false) {
-            clearTimeout(timeoutConnectionLost);
-            timeoutConnectionLost = false;
+    // releaseSent removes the messages acknowledged by the server.
+    var releaseSent = function(seq) {
+        var i = 0;
+        while (i < sentBuffer.length && sentBuffer[i].seq <= seq) {
+            i++;
         }
+
+        sentBuffer.splice(0, i);
     };
 
-    var stopSendQueueTimeout = function() {
-        // Stop the timeout timer
-        if (sendQueueTimeout !== false) {
-            clearTimeout(sendQueueTimeout);
-            sendQueueTimeout = false;
+    // scheduleAck acknowledges the received messages
+    // if no other message is sent in the meantime.
+    var scheduleAck = function() {
+        if (ackTimeout !== false) {
+            return;
         }
+
+        ackTimeout = setTimeout(function () {
+            ackTimeout = false;
+
+            if (isReady && recvSeq > ackSent) {
+                Bulldozer.socket.send(SocketData.Ack);
+            }
+        }, ackDelay);
     };
 
-    var clearSendQueueOnTimeout = function() {
-        stopSendQueueTimeout();
+    // decodePacket converts binary socket data to an Uint8Array.
+    // Text data is returned as it is.
+    var decodePacket = function(data) {
+        if (data instanceof ArrayBuffer) {
+            return new Uint8Array(data);
+        }
 
-        sendQueueTimeout = setTimeout(function () {
-            // Update the flag.
-            sendQueueTimeout = false;
+        data = String(data);
+        if (data.charAt(0) !== BinaryPrefix) {
+            return data;
+        }
+
+        var str = window.atob(data.substr(1));
+        var bytes = new Uint8Array(str.length);
+        for (var i = 0; i < str.length; i++) {
+            bytes[i] = str.charCodeAt(i);
+        }
+
+        return bytes;
+    };
+
+    // readLength reads the decimal length prefix of a frame.
+    // The position after the colon and the length are returned.
+    var readLength = function(bytes, pos) {
+        var n = 0, c;
+        for (; pos < bytes.length; pos++) {
+            c = bytes[pos];
+            if (c === Colon) {
+                return { pos: pos + 1, n: n };
+            }
+            if (c < 48 || c > 57) {
+                break;
+            }
+            n = n * 10 + (c - 48);
+        }
 
-            // Clear the queue.
-            sendQueue = [];
-        }, 6000);
+        throw new Error("invalid frame length");
+    };
+
+    // closeSocket closes the current socket without triggering any events.
+    // A boolean is returned, indicating if a socket was closed.
+    var closeSocket = function() {
+        isReady = false;
+
+        if (!socket) {
+            return false;
+        }
+
+        socket.onOpen = undefined;
+        socket.onClose = undefined;
+        socket.onMessage = undefined;
+        socket.onError = undefined;
+
+        // Reset the socket.
+        socket.reset();
+        socket = false;
+
+        return true;
+    };
+
+    // sendVisibility tells the server whenever the tab is visible.
+    // The server suspends the socket of background tabs after a timeout.
+    var sendVisibility = function() {
+        Bulldozer.socket.send(SocketData.Visibility, {
+            visible: !document.hidden
+        });
+    };
+
+    // resume reconnects the suspended socket session.
+    var resume = function() {
+        if (!isSuspended) {
+            return;
+        }
+
+        isSuspended = false;
+        Bulldozer.socket.reconnect();
+    };
+
+    var stopConnectionLostTimeout = function() {
+        // Stop the timeout timer
+        if (timeoutConnectionLost !== false) {
+            clearTimeout(timeoutConnectionLost);
+            timeoutConnectionLost = false;
+        }
     };
 
     var resetConnectionLostTimeout = function() {
@@ -106,42 +232,66 @@ Bulldozer.fn.socket = new function () {
 
         // Check if data is not empty
         if (data) {
+            data = decodePacket(data);
+
             // Check if the server has send an invalid request notification
             if (data === SocketData.InvalidRequest) {
                 console.log("The server replied with an invalid request notification! The previous request was invalid!");
                 return;
             }
 
-            // Split the new token from the rest of the data
-            var i = data.indexOf('&');
+            // Verify the message authentication code and the sequence number.
+            // The sequence number has to be the next one.
+            var packet = data;
+            data = (packet instanceof Uint8Array) ? Bulldozer.socketAuth.verify(packet) : false;
 
-            if (i < 0) {
+            if (data === false || data.seq !== recvSeq + 1) {
                 // Show an error message box
                 Bulldozer.utils.showErrorMessageBox("Error",
                     "Warning! Invalid data received from server! Please reload this webpage and notify the site administrator!",
-                    "Error data: '" + data + "'");
+                    "Error data length: " + packet.length);
                 return;
             }
 
-            // Set the new token and the data variable
-            token = data.substring(0, i);
-            data = data.substr(i + 1);
+            recvSeq = data.seq;
+            scheduleAck();
 
-            // Check if the server requests a pong reply
-            if (data === SocketData.Ping) {
-                // Send the pong message.
-                socket.send(prepareSendMsg(SocketKey.Task + "=" + SocketData.Pong + "&"));
+            // Dispatch the received messages
+            handleMessages(data.data);
+        }
+    };
+
+    // Parse the length-prefixed message frames
+    // and call the registered message handlers.
+    // Frame format: "<header length>:<JSON header><body length>:<body>"
+    var handleMessages = function(bytes) {
+        var pos = 0, l, msg, body, handler;
+
+        while (pos < bytes.length) {
+            try {
+                l = readLength(bytes, pos);
+                msg = JSON.parse(Bulldozer.socket.decodeText(bytes.subarray(l.pos, l.pos + l.n)));
+
+                l = readLength(bytes, l.pos + l.n);
+                body = (l.n > 0) ? bytes.subarray(l.pos, l.pos + l.n) : undefined;
+                pos = l.pos + l.n;
+            }
+            catch(err) {
+                console.log("failed to parse messages: " + err.message);
                 return;
             }
 
-            // Execute the received javascript code if not empty
-            if (data) {
-                try {
-                    jQuery.globalEval(data);
-                }
-                catch(err) {
-                    console.log("failed to execute request: " + err.message);
-                }  
+            handler = messageHandlers[msg.t];
+            if (!handler) {
+                console.log("no handler for message type '" + msg.t + "' registered!");
+                continue;
+            }
+
+            try {
+                handler(msg.id, msg.d, body);
+            }
+            catch(err) {
+                console.log("failed to handle message '" + msg.t + "': " + err.message);
             }
         }
     };
@@ -158,18 +308,37 @@ Bulldozer.fn.socket = new function () {
             return false;
         }
 
+        // The server can't replay the missed messages.
+        if (data === SocketData.RefreshRequest) {
+            window.location.reload();
+            return false;
+        }
+
         // Split the received data
         var list = data.split('&');
         
         // Check if enough elements exist
-        if (list.length < 2) {
+        if (list.length < 3) {
             console.log("Failed to initialize socket session! Received list length is invalid: '" + data + "'");
             return false;
         }
 
-        // Save the instance ID and the new token.
+        // Save the instance ID and agree on the key with the server public key.
         instanceID = list[0];
-        token = list[1];
+        if (!Bulldozer.socketAuth.exchange(list[1])) {
+            console.log("Failed to initialize socket session! Key exchange failed!");
+            return false;
+        }
+
+        // Release the messages received by the server. The remaining
+        // messages are sent again. Refresh the page if messages were lost.
+        var serverRecvSeq = Number(list[2]);
+        if (droppedSeq > serverRecvSeq) {
+            console.log("Failed to resume socket session! Sent messages were lost!");
+            window.location.reload();
+            return false;
+        }
+        releaseSent(serverRecvSeq);
 
         // Reset the reconnect count.
         reconnectCount = 0;
@@ -188,6 +357,9 @@ Bulldozer.fn.socket = new function () {
 
     // The callback function for connection errors.
     var connectionError = function() {
+        // Hold back messages until the session is resumed.
+        isReady = false;
+
         // Show the connection lost widget.
         Bulldozer.connectionLost.show();
 
@@ -197,27 +369,23 @@ Bulldozer.fn.socket = new function () {
         // Stop the reset timeout timer.
         stopConnectionLostTimeout();
 
-        // Force fallback flag.
-        var fallback = false;
-
         // Increment the count.
         reconnectCount += 1;
 
-        /* TODO: Currently disabled, because backend session allows only
-        // one socket type... See Bulldozer TODO.
-        // Fallback to the ajax socket if this is the last attempt.
-        if (reconnectCount == reconnectAttempts
-            && socket.type() !== Bulldozer.AjaxSocket.type()) {
-            // Reconnect with the ajax socket
-            console.log("falling back to ajax socket...");
-            fallback = true;
+        // Fall back to server-sent events if the websocket connection failed
+        // repeatedly. The fallback socket gets its own reconnect attempts.
+        if (!useFallback && reconnectCount >= websocketAttempts
+            && socketType === Bulldozer.WebSocket.type()
+            && Bulldozer.SSESocket.isSupported()) {
+            console.log("falling back to server-sent events...");
+            useFallback = true;
+            reconnectCount = 0;
         }
-        */
 
         if (reconnectCount <= reconnectAttempts) {
             // Try to reconnect
             setTimeout(function() {
-                Bulldozer.socket.reconnect(fallback);
+                Bulldozer.socket.reconnect();
             }, 1500);
         }
         else {
@@ -234,6 +402,22 @@ Bulldozer.fn.socket = new function () {
      * Public Methods
      */
 
+    // Register the handler for the server message type.
+    // The handler is called with the optional target DOM ID, the message data
+    // and the optional binary body as Uint8Array.
+    this.onMessage = function(type, handler) {
+        messageHandlers[type] = handler;
+    };
+
+    // decodeText decodes the UTF-8 encoded binary message body.
+    this.decodeText = function(bytes) {
+        if (!bytes) {
+            return "";
+        }
+
+        return new TextDecoder("utf-8").decode(bytes);
+    };
+
     this.hasSocket = function() {
         return !(socket === false);
     };
@@ -260,16 +444,7 @@ Bulldozer.fn.socket = new function () {
 
         // Function wich resets the previous socket if set.
         var resetSocket = function() {
-            if (socket) {
-                socket.onOpen = undefined;
-                socket.onClose = undefined;
-                socket.onMessage = undefined;
-                socket.onError = undefined;
-
-                // Reset the socket.
-                socket.reset();
-                socket = false;
-
+            if (closeSocket()) {
                 // Set the wait duration to a short timeout.
                 waitDuration = 300;
             }
@@ -280,18 +455,29 @@ Bulldozer.fn.socket = new function () {
 
         // Wait for a short timeout, if set.
         setTimeout(function() {
-            // Choose the socket layer depending on the browser support
-            if (window["WebSocket"] && forceFallback !== true) {
+            // Choose the socket layer depending on the browser support.
+            // The fallback chain is: websocket, server-sent events, ajax long polling.
+            // Failed websocket connections fall back to server-sent events.
+            if (window["WebSocket"] && !useFallback && forceFallback !== true) {
                 socket = Bulldozer.WebSocket;
+            } else if (Bulldozer.SSESocket.isSupported()) {
+                socket = Bulldozer.SSESocket;
             } else {
                 socket = Bulldozer.AjaxSocket;
             }
+            socketType = socket.type();
 
 
             // Set the socket events
             socket.onOpen = function() {
-                // Initialize the connection
-                socket.send(prepareSendMsg(""));
+                // Initialize the connection with the access token and the public key.
+                var msg = {};
+                msg[SocketKey.SessionID] = sid;
+                msg[SocketKey.Token] = token;
+                msg[SocketKey.PublicKey] = Bulldozer.socketAuth.reset();
+                msg[SocketKey.Ack] = String(recvSeq);
+
+                socket.send(JSON.stringify(msg));
             };
 
             socket.onClose = function() {
@@ -311,15 +497,17 @@ Bulldozer.fn.socket = new function () {
                     return;
                 }
 
-                // Stop the send queue clear timeout.
-                stopSendQueueTimeout();
+                isReady = true;
 
-                // Send data, which could not be send...
-                var l = sendQueue.length;
-                for (var i = 0; i < l; i++) {
-                    socket.send(prepareSendMsg(sendQueue[i]));
+                // Send the messages, which the server hasn't received.
+                for (var i = 0; i < sentBuffer.length; i++) {
+                    socket.send(prepareSendMsg(sentBuffer[i]));
+                }
+
+                // The server assumes a visible tab for new sessions.
+                if (document.hidden) {
+                    sendVisibility();
                 }
-                sendQueue = [];
 
                 // Trigger the custom bulldozer ready event if this
                 // is the first successfull socket connection.
@@ -340,32 +528,90 @@ Bulldozer.fn.socket = new function () {
 
     // Send the data object to the server. The data object is converted into a string.
     // A boolean is returned, indicating if the data has been send to the server.
-    // if false, it is added to the queue and will be resend as soon as a connection is established.
+    // Messages are kept until the server acknowledges them. If false, the message
+    // will be sent as soon as the connection is resumed.
     this.send = function(type, data) {
-        var str = SocketKey.Task + '=' + String(type) + '&';
+        // All values are sent as strings.
+        var msg = {};
         for (var p in data) {
             if (data.hasOwnProperty(p)) {
-                str += p + '=' + Bulldozer.utils.escapeData(data[p]) + '&';
+                msg[p] = String(data[p]);
             }
         }
+        msg[SocketKey.Task] = String(type);
 
-        // Reconnect the socket session if the connection is lost.
-        if (Bulldozer.connectionLost.connectionLost()) {
-            // Add the data to the queue.
-            sendQueue.push(str);
+        // Buffer the message with the next sequence number.
+        var entry = {
+            seq: ++sendSeq,
+            msg: msg
+        };
+        sentBuffer.push(entry);
+
+        if (sentBuffer.length > maxSentBuffer) {
+            droppedSeq = sentBuffer.shift().seq;
+        }
 
-            // Clear the send queue after a timeout.
-            clearSendQueueOnTimeout();
+        // Resume the suspended socket session.
+        if (isSuspended) {
+            resume();
+            return false;
+        }
 
-            // Reconnect.
+        // Reconnect the socket session if the connection is lost.
+        if (Bulldozer.connectionLost.connectionLost()) {
             Bulldozer.socket.reconnect();
             return false;
         }
-        
-        socket.send(prepareSendMsg(str));
+
+        // The message is sent as soon as the session is resumed.
+        if (!isReady) {
+            return false;
+        }
+
+        socket.send(prepareSendMsg(entry));
         return true;
     };
 
+    // Reply to ping requests of the server.
+    this.onMessage(SocketData.Ping, function() {
+        Bulldozer.socket.send(SocketData.Pong);
+    });
+
+    // The server suspended the socket, because the tab is in the background.
+    // Close the socket silently. It is resumed as soon as the tab is visible.
+    this.onMessage(SocketData.Suspend, function() {
+        // Handle the following received messages of this packet first.
+        setTimeout(function() {
+            stopConnectionLostTimeout();
+            closeSocket();
+            isSuspended = true;
+
+            // The tab might have become visible in the meantime.
+            if (!document.hidden) {
+                resume();
+            }
+        }, 0);
+    });
+
+    // Report the tab visibility changes.
+    document.addEventListener("visibilitychange", function() {
+        if (isSuspended) {
+            if (!document.hidden) {
+                resume();
+            }
+            return;
+        }
+
+        if (isReady) {
+            sendVisibility();
+        }
+    });
+
+    // Release the messages acknowledged by the server.
+    this.onMessage(SocketData.Ack, function(id, seq) {
+        releaseSent(seq || 0);
+    });
+
     this.reconnect = function(forceFallback) {
         $.ajax({
             url: "/bulldozer/reconnect",
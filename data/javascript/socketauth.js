@@ -0,0 +1,186 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+
+/*
+ * Bulldozer Socket Authentication
+ *
+ * The per-socket key is agreed with a Diffie-Hellman key exchange.
+ * Each message is framed as "mac&seq&data". The HMAC-SHA256 covers
+ * the direction, the sequence number and the data. The sequence numbers
+ * are handled by the socket and continue across reconnections.
+ *
+ * The key exchange isn't authenticated. The HMAC only guards the integrity
+ * and the order of the messages over an already authenticated TLS channel.
+ * It doesn't protect against an active man-in-the-middle.
+ */
+
+Bulldozer.fn.socketAuth = new function () {
+    /*
+     * Const
+     */
+
+    // The 2048-bit MODP group of RFC 3526.
+    var Prime = BigInt("0x" +
+        "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+        "29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+        "EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+        "E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+        "EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D" +
+        "C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F" +
+        "83655D23DCA3AD961C62F356208552BB9ED529077096966D" +
+        "670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+        "E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9" +
+        "DE2BCBF6955817183995497CEA956AE515D2261898FA0510" +
+        "15728E5A8AACAA68FFFFFFFFFFFFFFFF");
+
+    var Generator = BigInt(2);
+    var PrimeHexLength = 512;
+    var PrivateKeyLength = 32;
+
+    var Direction = {
+        Client: "c",
+        Server: "s"
+    };
+
+
+
+    /*
+     * Private Variables
+     */
+
+    var privateKey = false,
+        key = false;
+
+
+
+    /*
+     * Private Methods
+     */
+
+    var modPow = function (base, exp, mod) {
+        var result = BigInt(1);
+        var zero = BigInt(0), one = BigInt(1), two = BigInt(2);
+
+        base = base % mod;
+        while (exp > zero) {
+            if (exp % two === one) {
+                result = (result * base) % mod;
+            }
+            exp = exp / two;
+            base = (base * base) % mod;
+        }
+
+        return result;
+    };
+
+    var randomHex = function (n) {
+        var b = new Uint8Array(n);
+        window.crypto.getRandomValues(b);
+
+        var hex = "";
+        for (var i = 0; i < b.length; i++) {
+            hex += ("0" + b[i].toString(16)).slice(-2);
+        }
+
+        return hex;
+    };
+
+    var bytesToWordArray = function (bytes) {
+        var words = [];
+        for (var i = 0; i < bytes.length; i++) {
+            words[i >>> 2] |= bytes[i] << (24 - (i % 4) * 8);
+        }
+
+        return CryptoJS.lib.WordArray.create(words, bytes.length);
+    };
+
+    var asciiDecode = function (bytes) {
+        return String.fromCharCode.apply(null, bytes);
+    };
+
+    // hmac returns the hex encoded HMAC-SHA256 of the word array.
+    var hmac = function (msg) {
+        var ipad = [], opad = [];
+        for (var i = 0; i < 16; i++) {
+            var w = key.words[i] || 0;
+            ipad.push(w ^ 0x36363636);
+            opad.push(w ^ 0x5c5c5c5c);
+        }
+
+        var inner = CryptoJS.SHA256(CryptoJS.lib.WordArray.create(ipad, 64)
+            .concat(msg));
+
+        return CryptoJS.SHA256(CryptoJS.lib.WordArray.create(opad, 64)
+            .concat(inner)).toString();
+    };
+
+
+
+    /*
+     * Public Methods
+     */
+
+    // reset creates a new private key and returns
+    // the hex encoded public key for the server.
+    this.reset = function () {
+        privateKey = BigInt("0x" + randomHex(PrivateKeyLength));
+        key = false;
+
+        return modPow(Generator, privateKey, Prime).toString(16);
+    };
+
+    // exchange derives the key from the hex encoded public key of the server.
+    this.exchange = function (serverPublicKey) {
+        var y = BigInt("0x" + serverPublicKey);
+        if (y <= BigInt(1) || y >= Prime - BigInt(1)) {
+            return false;
+        }
+
+        // The key is the hash of the padded shared secret.
+        var secret = modPow(y, privateKey, Prime).toString(16);
+        while (secret.length < PrimeHexLength) {
+            secret = "0" + secret;
+        }
+
+        key = CryptoJS.SHA256(CryptoJS.enc.Hex.parse(secret));
+        privateKey = false;
+
+        return true;
+    };
+
+    // sign frames the data with the sequence number
+    // and the message authentication code.
+    this.sign = function (seq, data) {
+        var prefix = Direction.Client + seq + "&";
+        return hmac(CryptoJS.enc.Utf8.parse(prefix + data)) + "&" + seq + "&" + data;
+    };
+
+    // verify checks the message authentication code of the binary server packet.
+    // The sequence number and the message data as Uint8Array are returned.
+    // False is returned if the packet is invalid.
+    this.verify = function (packet) {
+        var Delimiter = 38;
+        var i = packet.indexOf(Delimiter);
+        var j = packet.indexOf(Delimiter, i + 1);
+        if (!key || i < 0 || j < 0) {
+            return false;
+        }
+
+        var mac = asciiDecode(packet.subarray(0, i)),
+            seq = asciiDecode(packet.subarray(i + 1, j)),
+            data = packet.subarray(j + 1);
+
+        var msg = CryptoJS.enc.Latin1.parse(Direction.Server + seq + "&").concat(bytesToWordArray(data));
+        if (mac !== hmac(msg)) {
+            return false;
+        }
+
+        return {
+            seq: Number(seq),
+            data: data
+        };
+    };
+};
@@ -0,0 +1,127 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+Bulldozer.fn.SSESocket = new function () {
+    /*
+     * Private Variables
+     */
+
+     var uid;
+     var sendTimeout = 7000;
+
+     var es = false;
+     var sendXhr = false;
+     var sendQueue = [];
+
+     var Event = {
+        Init: "init"
+     };
+
+    /*
+     * Private Methods
+     */
+
+    var stopRequests = function() {
+        // Clear the send queue
+        sendQueue = [];
+
+        // Close the event stream and kill the ajax request
+        if (es) {
+            es.close();
+            es = false;
+        }
+        if (sendXhr) {
+            sendXhr.abort();
+        }
+    };
+
+    var triggerError = function() {
+        // Stop the requests.
+        stopRequests();
+
+        // Trigger the event
+        Bulldozer.SSESocket.onError();
+    };
+
+    // flushSendQueue sends the queued messages one after another.
+    // This keeps the order of the message sequence numbers.
+    var flushSendQueue = function () {
+        if (sendXhr || sendQueue.length === 0) {
+            return;
+        }
+
+        sendXhr = $.ajax({
+            url: "/bulldozer/sse/send",
+            success: function () {
+                sendXhr = false;
+                flushSendQueue();
+            },
+            error: function () {
+                sendXhr = false;
+                triggerError();
+            },
+            type: "POST",
+            data: sendQueue.shift(),
+            dataType: "text",
+            timeout: sendTimeout
+        });
+    };
+
+
+
+    /*
+     * Public Methods
+     */
+
+    this.onOpen;
+    this.onClose;
+    this.onMessage;
+    this.onError;
+
+    this.type = function () {
+        return "ssesocket";
+    };
+
+    this.isSupported = function () {
+        return !!window["EventSource"];
+    };
+
+    this.open = function () {
+        try {
+            es = new EventSource("/bulldozer/sse");
+
+            // The server sends the unique id as first event.
+            es.addEventListener(Event.Init, function (event) {
+                uid = event.data;
+
+                // Trigger the event
+                Bulldozer.SSESocket.onOpen();
+            });
+
+            es.onmessage = function (event) {
+                Bulldozer.SSESocket.onMessage(event.data.toString());
+            };
+
+            // Don't let the browser reconnect the event stream.
+            // A new stream requires a new socket session.
+            es.onerror = function () {
+                triggerError();
+            };
+        } catch (e) {
+            triggerError();
+        }
+    };
+
+    this.send = function (data) {
+        // Always prepend the uid to the data
+        sendQueue.push(uid + "&" + data);
+        flushSendQueue();
+    };
+
+    this.reset = function() {
+        // Stop the requests.
+        stopRequests();
+    };
+};
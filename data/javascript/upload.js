@@ -0,0 +1,142 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+
+
+/*
+ * Bulldozer upload Methods
+ */
+
+Bulldozer.fn.upload = new function () {
+    /*
+     * Private Variables
+     */
+
+    var uploadUrl = "/bulldozer/upload",
+        idCount = 0,
+        uploads = {},
+        listeners = {};
+
+
+
+    /*
+     * Private Methods
+     */
+
+    var trigger = function (event, id) {
+        var l = listeners[event],
+            file = uploads[id];
+
+        if (!l || !file) {
+            return;
+        }
+
+        var args = [id, file].concat(Array.prototype.slice.call(arguments, 2));
+
+        for (var i = 0; i < l.length; i++) {
+            try {
+                l[i].apply(null, args);
+            }
+            catch (err) {
+                console.log("Bulldozer.upload: listener error: " + err.message);
+            }
+        }
+    };
+
+
+
+    /*
+     * Public Methods
+     */
+
+    // Add a listener for the upload events:
+    // start, progress, done and error.
+    // The listener is called with the upload ID, the file
+    // and additional event specific arguments.
+    this.on = function (event, f) {
+        if (!listeners[event]) {
+            listeners[event] = [];
+        }
+
+        listeners[event].push(f);
+    };
+
+    // Upload the file and pass it to the server event function.
+    // Additional arguments are passed to the event function.
+    // The upload ID is returned.
+    this.send = function (domID, key, file) {
+        if (!file) {
+            console.log("Bulldozer.upload: no file passed!");
+            return;
+        }
+
+        var id = String(++idCount);
+        uploads[id] = file;
+
+        // Construct the data object be send
+        var data = {
+            did: domID,
+            key: key,
+            id: id,
+            name: file.name || "",
+            size: String(file.size),
+            type: file.type || ""
+        };
+
+        // Append the arguments to the data string
+        for (var i = 3; i < arguments.length; i++) {
+            data['arg' + (i-2)] = arguments[i];
+        }
+
+        // Request a one-time upload token
+        Bulldozer.socket.send('upload', data);
+
+        return id;
+    };
+
+    // Start is called as soon as the server issued the upload token.
+    this.start = function (id, token) {
+        var file = uploads[id];
+        if (!file) {
+            return;
+        }
+
+        trigger('start', id);
+
+        var xhr = new XMLHttpRequest();
+        xhr.open("POST", uploadUrl + "?token=" + encodeURIComponent(token), true);
+        xhr.setRequestHeader("Content-Type", "application/octet-stream");
+
+        // The server reports most errors also over the socket.
+        // Errors are only triggered once per upload.
+        xhr.onload = function () {
+            if (xhr.status !== 200) {
+                Bulldozer.upload.error(id, "upload failed");
+            }
+        };
+        xhr.onerror = function () {
+            Bulldozer.upload.error(id, "connection error");
+        };
+
+        xhr.send(file);
+    };
+
+    // Progress is called by the upload.progress server message handler.
+    this.progress = function (id, loaded, total) {
+        trigger('progress', id, loaded, total);
+    };
+
+    // Done is called as soon as the server event function returned.
+    this.done = function (id) {
+        trigger('done', id);
+        delete uploads[id];
+    };
+
+    // Error is called if the upload failed.
+    this.error = function (id, msg) {
+        trigger('error', id, msg);
+        delete uploads[id];
+    };
+};
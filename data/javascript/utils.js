@@ -9,12 +9,6 @@
  */
  
 Bulldozer.utils = {
-	// Replace all '\' to '\\' and '&' with '\&'
-    escapeData : function(data) {
-        return data.toString().replace(/\\|&/g, '\\$&');
-    },
-
-
     showErrorMessageBox : function(title, text, error) {
         // First escape the strings
         title = Kepler.utils.escapeHTML(title);
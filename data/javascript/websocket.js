@@ -33,12 +33,14 @@ Bulldozer.fn.WebSocket = new function () {
             }
             url += window.location.host + "/bulldozer/ws";
 
-            // Open the websocket connection
+            // Open the websocket connection.
+            // Binary messages are received as array buffer.
             ws = new WebSocket(url);
+            ws.binaryType = "arraybuffer";
 
             // Set the callback handlers
             ws.onmessage = function(event) {
-                Bulldozer.WebSocket.onMessage(event.data.toString());
+                Bulldozer.WebSocket.onMessage(event.data);
             };
 
             ws.onerror = function() {
@@ -1 +1 @@
-var Bulldozer=new function(){this.fn=Object.getPrototypeOf(this),this.utils,this.init=function(e,o){Bulldozer.loadingIndicator.show(),Bulldozer.socket.init(e,o),Kepler.init()}};Bulldozer.utils={escapeData:function(e){return e.toString().replace(/\\|&/g,"\\$&")},showErrorMessageBox:function(e,o,n){e=Kepler.utils.escapeHTML(e),o=Kepler.utils.escapeHTML(o);var t='<div class="topbar alert"><div class="icon"></div><div class="title"><h3>'+e+'</h3></div></div><div class="kepler grid"><div class="large-12 column"><p>'+o+"</p>";n&&(t+="<br><code>"+Kepler.utils.escapeHTML(n)+"</code>"),t+='</div><div class="large-12 column"><hr></hr></div><div class="large-12 column"><a class="kepler button expand close-modal">OK</a></div></div>',this.addAndShowTmpModal(t,{closable:!1,zIndex:10001})},addAndShowTmpModal:function(e,o){var n=$.extend({domId:!1,closable:!0,"class":"radius shadow",zIndex:"auto"},o);if(!e)return void console.log("error: addAndShowTmpModal: body is invalid!");var t=$('<div class="kepler modal"></div>');if(n.class&&t.addClass(n.class.toString()),n.domId&&t.attr("id",n.domId.toString()),t.append(e),n.closable){var i='<a class="close-modal">&#215;</a>',r=t.find(".topbar:first");r.length>0?r.append(i):t.prepend(i)}t.appendTo($("body")),Kepler.modal.open(t,{closeOnBackdropClick:n.closable,removeOnClose:!0,zIndex:n.zIndex}),Kepler.init()}},Bulldozer.fn.loadingIndicator=new function(){var e=!1,o=!1,n=function(){e!==!1&&(clearTimeout(e),e=!1)};this.show=function(){var t=$("#bud-loading-indicator");o||(o=!0,n(),t.removeClass("none-pointer-events"),t.css("opacity","0").show(),e=setTimeout(function(){e=!1,t.css("opacity","1").addClass("show"),e=setTimeout(function(){e=!1,Bulldozer.loadingIndicator.hide(),Bulldozer.utils.showErrorMessageBox("Error","Failed to perform the request. Timeout reached. Please try again...")},25e3)},1e3))},this.hide=function(){var t=$("#bud-loading-indicator");o&&(o=!1,n(),t.removeClass("show").addClass("none-pointer-events"),e=setTimeout(function(){e=!1,t.hide()},2e3))}},Bulldozer.fn.connectionLost=new function(){var e=!1,o=!1,n=!1,t=function(){e!==!1&&(clearTimeout(e),e=!1)},i=function(){o!==!1&&clearTimeout(o),o=setTimeout(function(){o=!1,$("#bud-connection-lost .click-to-reconnect").removeClass("connecting fail success")},1500)};this.show=function(){var o=$("#bud-connection-lost");n||(n=!0,t(),e=setTimeout(function(){e=!1,o.show().addClass("show")},700))},this.hide=function(){var o=$("#bud-connection-lost");n&&(n=!1,t(),o.removeClass("show"),e=setTimeout(function(){e=!1,o.hide()},3e3))},this.connectionLost=function(){return n},this.reconnectFailed=function(){var e=$("#bud-connection-lost .click-to-reconnect");e.hasClass("connecting")&&!e.hasClass("fail")&&(e.addClass("fail"),i())},this.reconnectSuccess=function(){var e=$("#bud-connection-lost .click-to-reconnect");e.hasClass("success")||(e.addClass("success"),i())},$(function(){$("#bud-connection-lost .click-to-reconnect").click(function(){var e=$(this);e.hasClass("connecting")||(e.addClass("connecting"),Bulldozer.socket.reconnect(),i())})})},Bulldozer.fn.WebSocket=new function(){var e;this.onOpen,this.onClose,this.onMessage,this.onError,this.type=function(){return"websocket"},this.open=function(){try{var o="ws://";"https:"===window.location.protocol&&(o="wss://"),o+=window.location.host+"/bulldozer/ws",e=new WebSocket(o),e.onmessage=function(e){Bulldozer.WebSocket.onMessage(e.data.toString())},e.onerror=function(){Bulldozer.WebSocket.onError&&Bulldozer.WebSocket.onError()},e.onclose=function(){Bulldozer.WebSocket.onClose&&Bulldozer.WebSocket.onClose()},e.onopen=function(){Bulldozer.WebSocket.onOpen()}}catch(n){Bulldozer.WebSocket.onError&&Bulldozer.WebSocket.onError()}},this.send=function(o){e.send(o)},this.reset=function(){e&&e.close(),e=void 0}},Bulldozer.fn.AjaxSocket=new function(){var e,o,n=7e3,t=45e3,i=!1,r=!1,s={Init:"init"},l=function(){i&&i.abort(),r&&r.abort()},a=function(){l(),Bulldozer.AjaxSocket.onError()},c=function(){i=$.ajax({url:"/bulldozer/ajax/poll",success:function(e){i=!1;var n=e.indexOf("&");return 0>n?(console.log("ajaxsocket: failed to split poll token from data! '&' not found! data: "+e),void a()):(o=e.substring(0,n),e=e.substr(n+1),c(),void Bulldozer.AjaxSocket.onMessage(e))},error:function(){i=!1,a()},type:"POST",data:e+"&"+o,dataType:"text",timeout:t})},d=function(e,o){r=$.ajax({url:"/bulldozer/ajax",success:function(e){r=!1,o&&o(e)},error:function(){r=!1,a()},type:"POST",data:e,dataType:"text",timeout:n})};this.onOpen,this.onClose,this.onMessage,this.onError,this.type=function(){return"ajaxsocket"},this.open=function(){d(s.Init,function(n){var t=n.indexOf("&");return 0>t?(console.log("ajaxsocket: failed to split uid and poll token from data! '&' not found! data: "+n),void a()):(e=n.substring(0,t),o=n.substr(t+1),c(),void Bulldozer.AjaxSocket.onOpen())})},this.send=function(o){d(e+"&"+o)},this.reset=function(){l()}},Bulldozer.fn.socket=new function(){var e,o,n,t=3,i={Task:"tsk"},r={InvalidRequest:"invalid_request",RefreshRequest:"req_refresh",Ping:"ping",Pong:"pong"},s=!1,l=!1,a=!1,c=0,d=[],u=!1,f=function(o){return"sid="+e+"&tok="+n+"&"+o},h=function(){a!==!1&&(clearTimeout(a),a=!1)},v=function(){u!==!1&&(clearTimeout(u),u=!1)},g=function(){v(),u=setTimeout(function(){u=!1,d=[]},6e3)},p=function(){a!==!1&&clearTimeout(a),Bulldozer.connectionLost.hide(),a=setTimeout(function(){a=!1,Bulldozer.connectionLost.show()},6e4)},m=function(e){if(p(),e){if(e===r.InvalidRequest)return void console.log("The server replied with an invalid request notification! The previous request was invalid!");var o=e.indexOf("&");if(0>o)return void Bulldozer.utils.showErrorMessageBox("Error","Warning! Invalid data received from server! Please reload this webpage and notify the site administrator!","Error data: '"+e+"'");if(n=e.substring(0,o),e=e.substr(o+1),e===r.Ping)return void l.send(f(i.Task+"="+r.Pong+"&"));if(e)try{jQuery.globalEval(e)}catch(t){console.log("failed to execute request: "+t.message)}}},b=function(e){if(!e)return console.log("Failed to initialize socket session! Received emtpy data from server!"),!1;if(e===r.InvalidRequest)return console.log("The server replied with an invalid request notification! The previous request was invalid!"),!1;var t=e.split("&");return t.length<2?(console.log("Failed to initialize socket session! Received list length is invalid: '"+e+"'"),!1):(o=t[0],n=t[1],c=0,p(),Bulldozer.connectionLost.reconnectSuccess(),Bulldozer.connectionLost.hide(),!0)},z=function(){Bulldozer.connectionLost.show(),Bulldozer.connectionLost.reconnectFailed(),h();var e=!1;c+=1,t>=c?setTimeout(function(){Bulldozer.socket.reconnect(e)},1500):(console.log("giving up..."),Bulldozer.connectionLost.show())};this.hasSocket=function(){return!(l===!1)},this.sessionID=function(){return e},this.init=function(o,t,i){if(!o||!t)return void console.log("empty session ID or socket access token!");h(),e=o,n=t;var r=0,a=function(){l&&(l.onOpen=void 0,l.onClose=void 0,l.onMessage=void 0,l.onError=void 0,l.reset(),l=!1,r=300)};a(),setTimeout(function(){l=window.WebSocket&&i!==!0?Bulldozer.WebSocket:Bulldozer.AjaxSocket,l.onOpen=function(){l.send(f(""))},l.onClose=function(){z()},l.onError=function(){console.log(l.type()+": a connection error occurred!"),z()},l.onMessage=function(e){if(!b(e))return a(),void z();v();for(var o=d.length,n=0;o>n;n++)l.send(f(d[n]));d=[],s||(s=!0,$(document).triggerHandler("bulldozer.ready")),l.onMessage=m},l.open()},r)},this.send=function(e,o){var n=i.Task+"="+String(e)+"&";for(var t in o)o.hasOwnProperty(t)&&(n+=t+"="+Bulldozer.utils.escapeData(o[t])+"&");return Bulldozer.connectionLost.connectionLost()?(d.push(n),g(),Bulldozer.socket.reconnect(),!1):(l.send(f(n)),!0)},this.reconnect=function(e){$.ajax({url:"/bulldozer/reconnect",type:"POST",data:{id:o},dataType:"text",timeout:7e3,success:function(o){if(o===r.RefreshRequest)return void window.location.reload();var n=o.split("&");return n.length<2?(console.log("Failed to reconnect socket session! Received list length is invalid: '"+o+"'"),void Bulldozer.utils.showErrorMessageBox("Error","Failed to reconnect to server! Please reload this webpage and try again...")):void Bulldozer.socket.init(n[0],n[1],e)},error:function(){console.log("failed to reconnect to server!"),z()}})}},Bulldozer.fn.core=new function(){var e,o=!1,n=[],t=[],i={};$(document).on("bulldozer.ready",function(){o=!0,Bulldozer.core.execJsLoad()}),$(document).on("click","a",function(e){var o=String($(this).attr("href"));return"mailto:"===o.slice(0,7)?(e.preventDefault(),window.open(o,"_blank"),!1):Bulldozer.socket.hasSocket()&&this.host===window.location.host&&"#"!==o.slice(0,1)&&"public/"!==o.slice(0,7)&&"/public/"!==o.slice(0,8)?(e.preventDefault(),o&&Bulldozer.core.navigate(o),!1):void 0}),this.navigateToDefault=function(){this.navigate("/")},this.navigate=function(e){Bulldozer.loadingIndicator.show();var o={path:e};Bulldozer.socket.send("route",o)},this.emit=function(){if(arguments.length<2)return void console.log("Bulldozer.emit: Invalid arguments passed! The emit function requires a DOM ID and key parameter!");for(var e={did:arguments[0],key:arguments[1]},o=2;o<arguments.length;o++)e["arg"+(o-1)]=arguments[o];Bulldozer.socket.send("emit",e)},this.loadStyleSheet=function(e){$('<link rel="stylesheet" type="text/css" href="'+e+'">').appendTo("head")},this.loadScript=function(e,o){var t={url:e,callback:o};n.push(t);var i=function(e,o){var t={dataType:"script",cache:!0,url:e},r=function(){n.length>0?i(n[0].url,n[0].callback):Bulldozer.core.execJsLoad()};jQuery.ajax(t).done(function(){n.shift(),o&&o(),r()}).fail(function(o,t,i){n.shift(),Bulldozer.utils.showErrorMessageBox("Error","Failed to load script '"+e+"'. Please contact the site administrator!","Error message: "+String(i)),r()})};n.length<=1&&($.isReady?i(e,o):$(document).ready(function(){i(e,o)}))},$(window).on("beforeunload",function(){return e?e:void 0}),this.setExitMessage=function(o){e=String(o)},this.resetExitMessage=function(){e=""},this.execJsLoad=function(e){setTimeout(function(){n.length>0||!o?e&&t.push(e.toString()):($.each(t,function(e,o){$("#"+o).triggerHandler("bulldozer.execJsLoad")}),t=[],e&&$("#"+e).triggerHandler("bulldozer.execJsLoad"),setTimeout(function(){Bulldozer.loadingIndicator.hide()},50))},10)},this.onJsLoad=function(e,o){$("#"+e).one("bulldozer.execJsLoad",function(){try{o()}catch(e){console.log("execute js load function error: "+e.message)}})},this.execJsUnload=function(e){$("#"+e).triggerHandler("bulldozer.execJsUnload")},this.onJsUnload=function(e,o){var n=function(){try{o()}catch(e){console.log("execute js unload function error: "+e.message)}};$("#"+e).one("bulldozer.execJsUnload",n),$(document).one("bulldozer.execJsUnload",n)},this.addServerEvent=function(e,o,n){var t=$("#"+e);if(t.length<=0)return void console.log("addServerEvent: element with id '"+e+"' does not exists!");var i=t.data("bulldozerserverevents");i||(i={}),i[o]=n,t.data("bulldozerserverevents",i)},this.emitServerEvent=function(e,o){var n=$("#"+e);if(n.length<=0)return void console.log("emitServerEvent: element with id '"+e+"' does not exists!");var t=n.data("bulldozerserverevents");if(!t)return void console.log("emitServerEvent: event with key '"+o+"' does not exists!");if(func=t[o],!func)return void console.log("emitServerEvent: event with key '"+o+"' does not exists!");try{var i=Array.prototype.slice.call(arguments,2);func.apply(n,i)}catch(r){console.log("execute server event error: "+r.message)}},this.addGlobalServerEvent=function(e,o){i[e]=o},this.clearGlobalServerEvents=function(){i={}},this.emitGlobalServerEvent=function(e){var o=i[e];if(!o)return void console.log("emitGlobalServerEvent: event with key '"+e+"' does not exists!");try{var n=Array.prototype.slice.call(arguments,1);o.apply(document,n)}catch(t){console.log("execute global server event error: "+t.message)}}},Bulldozer.fn.auth=new function(){this.hashPassword=function(e,o){return CryptoJS.SHA256(CryptoJS.SHA256(e)+Bulldozer.socket.sessionID()+o).toString()}},Bulldozer.fn.render=new function(){var e,o=!1;$(window).on("statechange",function(){if(!o){var e=History.getState().hash;e||(e="/"),Bulldozer.core.navigate(e)}}),this.updateTemplate=function(e,o){var n=$("#"+e);return n.length<=0?void Bulldozer.utils.showErrorMessageBox("Error","Failed to update template: '"+e+"'. Try to reload the page and please contact the site administrator!"):(Bulldozer.core.execJsUnload(e),n.removeData().replaceWith(o),void Kepler.init())},this.page=function(n,t,i){$(document).triggerHandler("bulldozer.execJsUnload"),Bulldozer.core.clearGlobalServerEvents();var r=$("#bud-body");r&&r.length>0&&(r.off(),r.find("*").off());var s,l;$("body").children().each(function(){s=$(this),l=s.attr("id"),"bud-loading-indicator"===l||"bud-body"===l||"bud-connection-lost"===l||s.is("noscript")&&s.has("#bud-noscript")||$(this).remove()}),$("html, body").removeAttr("style"),Bulldozer.topbar.space();var a=$('<div id="bud-body"></div>');a.append(n),r.replaceWith(a),window.scrollTo(0,0),i&&e!==i&&(e=i,o=!0,History.pushState(null,null,i),o=!1),document.title=t,Kepler.init()}},Bulldozer.fn.data=new function(){var e={};this.set=function(o,n){e[o]=n},this.delete=function(o){delete e[o]},this.get=function(o){return e[o]},this.getAndReply=function(o,n){var t=e[o];t||(t="");var i={key:o,rand:n,data:t};Bulldozer.socket.send("clientData",i)}},Bulldozer.fn.topbar=new function(){var e="0";this.space=function(o){o===!0?e="45px":o===!1&&(e="0"),$("body").css("margin-top",e),$(".bud-topbar-auto-move").css("margin-top",e)}};
\ No newline at end of file
+var Bulldozer=new function(){this.fn=Object.getPrototypeOf(this);this.utils;this.init=function(a,b){Bulldozer.loadingIndicator.show();Bulldozer.socket.init(a,b);Kepler.init()}}();Bulldozer.utils={showErrorMessageBox:function(a,b,d){a=Kepler.utils.escapeHTML(a);b=Kepler.utils.escapeHTML(b);var c='<div class="topbar alert"><div class="icon"></div><div class="title"><h3>'+a+'</h3></div></div><div class="kepler grid"><div class="large-12 column"><p>'+b+'</p>';if(d)c+="<br><code>"+Kepler.utils.escapeHTML(d)+"</code>";c+='</div><div class="large-12 column"><hr></hr></div><div class="large-12 column"><a class="kepler button expand close-modal">OK</a></div></div>';this.addAndShowTmpModal(c,{closable:!1,zIndex:10001})},addAndShowTmpModal:function(c,f){var a=$.extend({domId:!1,closable:!0,class:"radius shadow",zIndex:'auto'},f);if(!c){console.log("error: addAndShowTmpModal: body is invalid!");return}var b=$('<div class="kepler modal"></div>');if(a.class)b.addClass(a.class.toString());if(a.domId)b.attr("id",a.domId.toString());b.append(c);if(a.closable){var d='<a class="close-modal">&#215;</a>';var e=b.find(".topbar:first");if(e.length>0)e.append(d);else b.prepend(d)}b.appendTo($('body'));Kepler.modal.open(b,{closeOnBackdropClick:a.closable,removeOnClose:!0,zIndex:a.zIndex});Kepler.init()}};Bulldozer.fn.loadingIndicator=new function(){var a=!1;var b=!1;var c=function(){if(a!==!1){clearTimeout(a);a=!1}};this.show=function(){var d=$("#bud-loading-indicator");if(b)return;b=!0;c();d.removeClass('none-pointer-events');d.css('opacity','0').show();a=setTimeout(function(){a=!1;d.css('opacity','1').addClass('show');a=setTimeout(function(){a=!1;Bulldozer.loadingIndicator.hide();Bulldozer.utils.showErrorMessageBox("Error","Failed to perform the request. Timeout reached. Please try again...")},25000)},1000)};this.hide=function(){var d=$("#bud-loading-indicator");if(!b)return;b=!1;c();d.removeClass('show').addClass('none-pointer-events');a=setTimeout(function(){a=!1;d.hide()},2000)}}();Bulldozer.fn.connectionLost=new function(){var a=!1;var c=!1;var b=!1;var e=function(){if(a!==!1){clearTimeout(a);a=!1}};var d=function(){if(c!==!1)clearTimeout(c);c=setTimeout(function(){c=!1;$("#bud-connection-lost .click-to-reconnect").removeClass("connecting fail success")},1500)};this.show=function(){var c=$("#bud-connection-lost");if(b)return;b=!0;e();a=setTimeout(function(){a=!1;c.show().addClass('show')},700)};this.hide=function(){var c=$("#bud-connection-lost");if(!b)return;b=!1;e();c.removeClass('show');a=setTimeout(function(){a=!1;c.hide()},3000)};this.connectionLost=function(){return b};this.reconnectFailed=function(){var a=$("#bud-connection-lost .click-to-reconnect");if(!a.hasClass("connecting")||a.hasClass("fail"))return;a.addClass("fail");d()};this.reconnectSuccess=function(){var a=$("#bud-connection-lost .click-to-reconnect");if(a.hasClass("success"))return;a.addClass("success");d()};$(function(){$("#bud-connection-lost .click-to-reconnect").click(function(){var a=$(this);if(a.hasClass("connecting"))return;a.addClass("connecting");Bulldozer.socket.reconnect();d()})})}();Bulldozer.fn.WebSocket=new function(){var a;this.onOpen;this.onClose;this.onMessage;this.onError;this.type=function(){return"websocket"};this.open=function(){try{var b="ws://";if(window.location.protocol==='https:')b="wss://";b+=window.location.host+"/bulldozer/ws";a=new WebSocket(b);a.binaryType="arraybuffer";a.onmessage=function(a){Bulldozer.WebSocket.onMessage(a.data)};a.onerror=function(){if(Bulldozer.WebSocket.onError)Bulldozer.WebSocket.onError()};a.onclose=function(){if(Bulldozer.WebSocket.onClose)Bulldozer.WebSocket.onClose()};a.onopen=function(){Bulldozer.WebSocket.onOpen()}}catch(a){if(Bulldozer.WebSocket.onError)Bulldozer.WebSocket.onError()}};this.send=function(b){a.send(b)};this.reset=function(){if(a)a.close();a=undefined}}();Bulldozer.fn.AjaxSocket=new function(){var f,g;var l=7000;var m=45000;var b=!1;var a=!1;var c=[];var n="+";var d="";var o={Init:"init"};var h=function(){c=[];d="";if(b)b.abort();if(a)a.abort()};var e=function(){h();Bulldozer.AjaxSocket.onError()};var i=function(){b=$.ajax({url:"/bulldozer/ajax/poll",success:function(a){b=!1;var c=a.indexOf('&');if(c<0){console.log("ajaxsocket: failed to split poll token from data! '&' not found! data: "+a);e();return}g=a.substring(0,c);a=a.substr(c+1);i();if(a.charAt(0)===n){d+=a.substr(1);return}a=d+a;d="";Bulldozer.AjaxSocket.onMessage(a)},error:function(){b=!1;e()},type:"POST",data:f+"&"+g,dataType:"text",timeout:m})};var j=function(c,b){a=$.ajax({url:"/bulldozer/ajax",success:function(c){a=!1;if(b)b(c)},error:function(){a=!1;e()},type:"POST",data:c,dataType:"text",timeout:l})};var k=function(){if(a||c.length===0)return;j(c.shift(),k)};this.onOpen;this.onClose;this.onMessage;this.onError;this.type=function(){return"ajaxsocket"};this.open=function(){j(o.Init,function(a){var b=a.indexOf('&');if(b<0){console.log("ajaxsocket: failed to split uid and poll token from data! '&' not found! data: "+a);e();return}f=a.substring(0,b);g=a.substr(b+1);i();Bulldozer.AjaxSocket.onOpen()})};this.send=function(a){c.push(f+"&"+a);k()};this.reset=function(){h()}}();Bulldozer.fn.SSESocket=new function(){var e;var h=7000;var a=!1;var b=!1;var c=[];var i={Init:"init"};var f=function(){c=[];if(a){a.close();a=!1}if(b)b.abort()};var d=function(){f();Bulldozer.SSESocket.onError()};var g=function(){if(b||c.length===0)return;b=$.ajax({url:"/bulldozer/sse/send",success:function(){b=!1;g()},error:function(){b=!1;d()},type:"POST",data:c.shift(),dataType:"text",timeout:h})};this.onOpen;this.onClose;this.onMessage;this.onError;this.type=function(){return"ssesocket"};this.isSupported=function(){return!!window["EventSource"]};this.open=function(){try{a=new EventSource("/bulldozer/sse");a.addEventListener(i.Init,function(a){e=a.data;Bulldozer.SSESocket.onOpen()});a.onmessage=function(a){Bulldozer.SSESocket.onMessage(a.data.toString())};a.onerror=function(){d()}}catch(a){d()}};this.send=function(a){c.push(e+"&"+a);g()};this.reset=function(){f()}}();Bulldozer.fn.socketAuth=new function(){var c=BigInt("0x"+"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D"+"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F"+"83655D23DCA3AD961C62F356208552BB9ED529077096966D"+"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9"+"DE2BCBF6955817183995497CEA956AE515D2261898FA0510"+"15728E5A8AACAA68FFFFFFFFFFFFFFFF");var h=BigInt(2);var i=512;var j=32;var d={Client:"c",Server:"s"};var a=!1,b=!1;var e=function(a,b,c){var d=BigInt(1);var f=BigInt(0),g=BigInt(1),e=BigInt(2);a=a%c;while(b>f){if(b%e===g)d=(d*a)%c;b=b/e;a=(a*a)%c}return d};var k=function(d){var a=new Uint8Array(d);window.crypto.getRandomValues(a);var c="";for(var b=0;b<a.length;b++){c+=("0"+a[b].toString(16)).slice(-2)}return c};var l=function(b){var c=[];for(var a=0;a<b.length;a++){c[a>>>2]|=b[a]<<(24-(a%4)*8)}return CryptoJS.lib.WordArray.create(c,b.length)};var f=function(a){return String.fromCharCode.apply(null,a)};var g=function(f){var c=[],d=[];for(var a=0;a<16;a++){var e=b.words[a]||0;c.push(e^0x36363636);d.push(e^0x5c5c5c5c)}var g=CryptoJS.SHA256(CryptoJS.lib.WordArray.create(c,64).concat(f));return CryptoJS.SHA256(CryptoJS.lib.WordArray.create(d,64).concat(g)).toString()};this.reset=function(){a=BigInt("0x"+k(j));b=!1;return e(h,a,c).toString(16)};this.exchange=function(g){var f=BigInt("0x"+g);if(f<=BigInt(1)||f>=c-BigInt(1))return!1;var d=e(f,a,c).toString(16);while(d.length<i)d="0"+d;b=CryptoJS.SHA256(CryptoJS.enc.Hex.parse(d));a=!1;return!0};this.sign=function(a,b){var c=d.Client+a+"&";return g(CryptoJS.enc.Utf8 .parse(c+b))+"&"+a+"&"+b};this.verify=function(a){var h=38;var c=a.indexOf(h);var e=a.indexOf(h,c+1);if(!b||c<0||e<0)return!1;var k=f(a.subarray(0,c)),i=f(a.subarray(c+1,e)),j=a.subarray(e+1);var m=CryptoJS.enc.Latin1 .parse(d.Server+i+"&").concat(l(j));if(k!==g(m))return!1;return{seq:Number(i),data:j}}}();Bulldozer.fn.socket=new function(){var C=3;var D=2;var E=500;var F=1000;var d={SessionID:"sid",Token:"tok",PublicKey:"dh",Ack:"ack",Task:"tsk"};var b={InvalidRequest:"invalid_request",RefreshRequest:"req_refresh",Ping:"ping",Pong:"pong",Ack:"ack",Visibility:"visibility",Suspend:"socket.suspend"};var G="#";var H=58;var p=!1,a=!1,q=!1,l=!1,f=!1,h=!1,j,r,s,e=!1,i=0,I=0,g=0,t=0,m=!1,c=[],u=0,v={};var w=function(b){var a=b.msg;a[d.SessionID]=j;a[d.Ack]=String(g);t=g;return Bulldozer.socketAuth.sign(b.seq,JSON.stringify(a))};var x=function(b){var a=0;while(a<c.length&&c[a].seq<=b)a++;c.splice(0,a)};var J=function(){if(m!==!1)return;m=setTimeout(function(){m=!1;if(f&&g>t)Bulldozer.socket.send(b.Ack)},F)};var K=function(a){if(a instanceof ArrayBuffer)return new Uint8Array(a);a=String(a);if(a.charAt(0)!==G)return a;var c=window.atob(a.substr(1));var d=new Uint8Array(c.length);for(var b=0;b<c.length;b++){d[b]=c.charCodeAt(b)}return d};var y=function(d,b){var c=0,a;for(;b<d.length;b++){a=d[b];if(a===H)return{pos:b+1,n:c};if(a<48||a>57)break;c=c*10+(a-48)}throw new Error("invalid frame length")};var z=function(){f=!1;if(!a)return!1;a.onOpen=undefined;a.onClose=undefined;a.onMessage=undefined;a.onError=undefined;a.reset();a=!1;return!0};var A=function(){Bulldozer.socket.send(b.Visibility,{visible:!document.hidden})};var n=function(){if(!h)return;h=!1;Bulldozer.socket.reconnect()};var o=function(){if(e!==!1){clearTimeout(e);e=!1}};var B=function(){if(e!==!1)clearTimeout(e);Bulldozer.connectionLost.hide();e=setTimeout(function(){e=!1;Bulldozer.connectionLost.show()},60000)};var L=function(a){B();if(a){a=K(a);if(a===b.InvalidRequest){console.log("The server replied with an invalid request notification! The previous request was invalid!");return}var c=a;a=(c instanceof Uint8Array)?Bulldozer.socketAuth.verify(c):!1;if(a===!1||a.seq!==g+1){Bulldozer.utils.showErrorMessageBox("Error","Warning! Invalid data received from server! Please reload this webpage and notify the site administrator!","Error data length: "+c.length);return}g=a.seq;J();M(a.data)}};var M=function(c){var d=0,a,b,f,e;while(d<c.length){try{a=y(c,d);b=JSON.parse(Bulldozer.socket.decodeText(c.subarray(a.pos,a.pos+a.n)));a=y(c,a.pos+a.n);f=(a.n>0)?c.subarray(a.pos,a.pos+a.n):undefined;d=a.pos+a.n}catch(a){console.log("failed to parse messages: "+a.message);return}e=v[b.t];if(!e){console.log("no handler for message type '"+b.t+"' registered!");continue}try{e(b.id,b.d,f)}catch(a){console.log("failed to handle message '"+b.t+"': "+a.message)}}};var N=function(a){if(!a){console.log("Failed to initialize socket session! Received emtpy data from server!");return!1}if(a===b.InvalidRequest){console.log("The server replied with an invalid request notification! The previous request was invalid!");return!1}if(a===b.RefreshRequest){window.location.reload();return!1}var c=a.split('&');if(c.length<3){console.log("Failed to initialize socket session! Received list length is invalid: '"+a+"'");return!1}r=c[0];if(!Bulldozer.socketAuth.exchange(c[1])){console.log("Failed to initialize socket session! Key exchange failed!");return!1}var d=Number(c[2]);if(u>d){console.log("Failed to resume socket session! Sent messages were lost!");window.location.reload();return!1}x(d);i=0;B();Bulldozer.connectionLost.reconnectSuccess();Bulldozer.connectionLost.hide();return!0};var k=function(){f=!1;Bulldozer.connectionLost.show();Bulldozer.connectionLost.reconnectFailed();o();i+=1;if(!l&&i>=D&&q===Bulldozer.WebSocket.type()&&Bulldozer.SSESocket.isSupported()){console.log("falling back to server-sent events...");l=!0;i=0}if(i<=C)setTimeout(function(){Bulldozer.socket.reconnect()},1500);else{console.log("giving up...");Bulldozer.connectionLost.show()}};this.onMessage=function(a,b){v[a]=b};this.decodeText=function(a){if(!a)return"";return new TextDecoder("utf-8").decode(a)};this.hasSocket=function(){return!(a===!1)};this.sessionID=function(){return j};this.init=function(b,e,m){if(!b||!e){console.log("empty session ID or socket access token!");return}o();j=b;s=e;var h=0;var i=function(){if(z())h=300};i();setTimeout(function(){if(window["WebSocket"]&&!l&&m!==!0)a=Bulldozer.WebSocket;else if(Bulldozer.SSESocket.isSupported())a=Bulldozer.SSESocket;else a=Bulldozer.AjaxSocket;q=a.type();a.onOpen=function(){var b={};b[d.SessionID]=j;b[d.Token]=s;b[d.PublicKey]=Bulldozer.socketAuth.reset();b[d.Ack]=String(g);a.send(JSON.stringify(b))};a.onClose=function(){k()};a.onError=function(){console.log(a.type()+": a connection error occurred!");k()};a.onMessage=function(d){if(!N(d)){i();k();return}f=!0;for(var b=0;b<c.length;b++){a.send(w(c[b]))}if(document.hidden)A();if(!p){p=!0;$(document).triggerHandler('bulldozer.ready')}a.onMessage=L};a.open()},h)};this.send=function(j,b){var e={};for(var g in b){if(b.hasOwnProperty(g))e[g]=String(b[g])}e[d.Task]=String(j);var i={seq:++I,msg:e};c.push(i);if(c.length>E)u=c.shift().seq;if(h){n();return!1}if(Bulldozer.connectionLost.connectionLost()){Bulldozer.socket.reconnect();return!1}if(!f)return!1;a.send(w(i));return!0};this.onMessage(b.Ping,function(){Bulldozer.socket.send(b.Pong)});this.onMessage(b.Suspend,function(){setTimeout(function(){o();z();h=!0;if(!document.hidden)n()},0)});document.addEventListener("visibilitychange",function(){if(h){if(!document.hidden)n();return}if(f)A()});this.onMessage(b.Ack,function(b,a){x(a||0)});this.reconnect=function(a){$.ajax({url:"/bulldozer/reconnect",type:"POST",data:{id:r},dataType:"text",timeout:7000,success:function(c){if(c===b.RefreshRequest){window.location.reload();return}var d=c.split('&');if(d.length<2){console.log("Failed to reconnect socket session! Received list length is invalid: '"+c+"'");Bulldozer.utils.showErrorMessageBox("Error","Failed to reconnect to server! Please reload this webpage and try again...");return}Bulldozer.socket.init(d[0],d[1],a)},error:function(){console.log("failed to reconnect to server!");k()}})}}();Bulldozer.fn.core=new function(){var e=!1;var a=[];var c=[];var d={};var b;$(document).on('bulldozer.ready',function(){e=!0;Bulldozer.core.execJsLoad()});$(document).on('click','a',function(b){var a=String($(this).attr('href'));if(a.slice(0,7)==="mailto:"){b.preventDefault();window.open(a,'_blank');return!1}else if(Bulldozer.socket.hasSocket()&&this.host===window.location.host&&a.slice(0,1)!=="#"&&a.slice(0,7)!=="public/"&&a.slice(0,8)!=="/public/"){b.preventDefault();if(a)Bulldozer.core.navigate(a);return!1}});this.navigateToDefault=function(){this.navigate("/")};this.navigate=function(a){Bulldozer.loadingIndicator.show();var b={path:a};Bulldozer.socket.send('route',b)};this.emit=function(){if(arguments.length<2){console.log("Bulldozer.emit: Invalid arguments passed! The emit function requires a DOM ID and key parameter!");return}var b={did:arguments[0],key:arguments[1]};for(var a=2;a<arguments.length;a++){b['arg'+(a-1)]=arguments[a]}Bulldozer.socket.send('emit',b)};this.loadStyleSheet=function(a){$('<link rel="stylesheet" type="text/css" href="'+a+'">').appendTo("head")};this.loadScript=function(b,c){var e={url:b,callback:c};a.push(e);var d=function(b,c){var f={dataType:"script",cache:!0,url:b};var e=function(){if(a.length>0)d(a[0].url,a[0].callback);else Bulldozer.core.execJsLoad()};jQuery.ajax(f).done(function(b,d){a.shift();if(c)c();e()}).fail(function(d,f,c){a.shift();Bulldozer.utils.showErrorMessageBox("Error","Failed to load script '"+b+"'. Please contact the site administrator!","Error message: "+String(c));e()})};if(a.length<=1){if(!($.isReady))$(document).ready(function(){d(b,c)});else d(b,c)}};$(document).on('bulldozer.ready',function(){var a="";try{a=Intl.DateTimeFormat().resolvedOptions().timeZone||""}catch(a){}Bulldozer.socket.send('timeZone',{name:a,offset:-(new Date()).getTimezoneOffset()})});$(window).on('beforeunload',function(){if(b)return b});this.setExitMessage=function(a){b=String(a)};this.resetExitMessage=function(){b=""};this.renewSessionCookie=function(a){jQuery.ajax({url:"/bulldozer/cookie",type:"POST",data:{token:a},error:function(){console.log("Bulldozer: failed to renew the session cookie!")}})};this.execJsLoad=function(b){setTimeout(function(){if(a.length>0||!e){if(b)c.push(b.toString())}else{$.each(c,function(b,a){$("#"+a).triggerHandler('bulldozer.execJsLoad')});c=[];if(b)$("#"+b).triggerHandler('bulldozer.execJsLoad');setTimeout(function(){Bulldozer.loadingIndicator.hide()},50)}},10)};this.onJsLoad=function(a,b){$("#"+a).one("bulldozer.execJsLoad",function(){try{b()}catch(a){console.log("execute js load function error: "+a.message)}})};this.execJsUnload=function(a){$("#"+a).triggerHandler('bulldozer.execJsUnload')};this.onJsUnload=function(b,c){var a=function(){try{c()}catch(a){console.log("execute js unload function error: "+a.message)}};$("#"+b).one("bulldozer.execJsUnload",a);$(document).one("bulldozer.execJsUnload",a)};this.addServerEvent=function(c,d,e){var b=$("#"+c);if(b.length<=0){console.log("addServerEvent: element with id '"+c+"' does not exists!");return}var a=b.data("bulldozerserverevents");if(!a)a={};a[d]=e;b.data("bulldozerserverevents",a)};this.emitServerEvent=function(c,a){var b=$("#"+c);if(b.length<=0){console.log("emitServerEvent: element with id '"+c+"' does not exists!");return}var d=b.data("bulldozerserverevents");if(!d){console.log("emitServerEvent: event with key '"+a+"' does not exists!");return}func=d[a];if(!func){console.log("emitServerEvent: event with key '"+a+"' does not exists!");return}try{var e=Array.prototype.slice.call(arguments,2);func.apply(b,e)}catch(a){console.log("execute server event error: "+a.message)}};this.addGlobalServerEvent=function(a,b){d[a]=b};this.clearGlobalServerEvents=function(){d={}};this.emitGlobalServerEvent=function(a){var b=d[a];if(!b){console.log("emitGlobalServerEvent: event with key '"+a+"' does not exists!");return}try{var c=Array.prototype.slice.call(arguments,1);b.apply(document,c)}catch(a){console.log("execute global server event error: "+a.message)}}}();Bulldozer.fn.auth=new function(){this.hashPassword=function(a,b){return CryptoJS.SHA256(CryptoJS.SHA256(a)+Bulldozer.socket.sessionID()+b).toString()}}();Bulldozer.fn.render=new function(){var b;var c=!1;var a={};var l="bud-body";$(window).on('statechange',function(){if(c)return;var a=History.getState().hash;if(!a)a="/";Bulldozer.core.navigate(a)});var d=function(c){var a=0x811c9dc5;for(var b=0;b<c.length;b++){a^=c.charCodeAt(b);a+=(a<<1)+(a<<4)+(a<<7)+(a<<8)+(a<<24)}return a>>>0};var m=function(f,e){var b=[],c=0,a;for(var d=0;d<e.length;d++){a=e[d];if(typeof a==="string")b.push(a);else if(a>=0){b.push(f.substr(c,a));c+=a}else c-=a}return b.join("")};var e=function(b,e,f){if(!f){a[b]=e;return e}var g=a[b];var c;try{if(g!==undefined&&d(g)===f.base)c=m(g,JSON.parse(e))}catch(a){console.log("failed to apply render patch: "+a.message)}if(c===undefined||d(c)!==f.hash){delete a[b];Bulldozer.socket.send('render.resync',{did:b});return!1}a[b]=c;return c};var f=function(c){var a=document.createElement("div");a.innerHTML=c;var b=[];$(a).find("script").each(function(){b.push({src:this.src?$(this).attr("src"):!1,text:this.text||this.textContent||this.innerHTML||""});this.parentNode.removeChild(this)});return{container:a,scripts:b}};var g=function(a){$.each(a,function(b,a){if(a.src)Bulldozer.core.loadScript(a.src);else jQuery.globalEval(a.text)})};var h=function(a,b){if(a.nodeType!==b.nodeType||a.nodeName!==b.nodeName)return!1;return a.nodeType!==1||(a.id||"")===(b.id||"")};var n=function(c,d){var a,b;for(a=c.attributes.length-1;a>=0;a--){b=c.attributes[a];if(!d.hasAttribute(b.name))c.removeAttribute(b.name)}for(a=0;a<d.attributes.length;a++){b=d.attributes[a];if(c.getAttribute(b.name)!==b.value)c.setAttribute(b.name,b.value)}};var i=function(c,k){var i={},g=[],e,a,f,b,d;for(a=c.firstChild;a;a=a.nextSibling){if(a.nodeType===1&&a.id)i[a.id]=a}for(b=k.firstChild;b;b=b.nextSibling){g.push(b)}a=c.firstChild;for(e=0;e<g.length;e++){b=g[e];d=(b.nodeType===1&&b.id)?i[b.id]:undefined;if(d&&d!==a&&d.parentNode===c){c.insertBefore(d,a);a=d}if(a&&h(a,b)){f=a.nextSibling;j(a,b);a=f}else c.insertBefore(b,a)}while(a){f=a.nextSibling;c.removeChild(a);a=f}};var j=function(a,b){if(a.nodeType!==1){if(a.nodeValue!==b.nodeValue)a.nodeValue=b.nodeValue;return}n(a,b);i(a,b)};var k=function(a){a.find("*").addBack().off().removeData()};this.updateTemplate=function(c,a,l){var b=$("#"+c);if(b.length<=0){Bulldozer.utils.showErrorMessageBox("Error","Failed to update template: '"+c+"'. Try to reload the page and please contact the site administrator!");return}a=e(c,a,l);if(a===!1)return;Bulldozer.core.execJsUnload(c);var d=f(a);var i=d.container.firstChild;if(!i||d.container.childNodes.length!==1||!h(b[0],i)){b.removeData().replaceWith(a);Kepler.init();return}k(b);j(b[0],i);g(d.scripts);Kepler.init()};this.page=function(h,q,j,r){h=e(l,h,r);if(h===!1)return;a={"bud-body":h};var s=(j&&b===j);$(document).triggerHandler('bulldozer.execJsUnload');Bulldozer.core.clearGlobalServerEvents();var d=$("#bud-body");if(d&&d.length>0){d.off();d.find("*").off()}var m,n;$('body').children().each(function(){m=$(this);n=m.attr('id');if(n==="bud-loading-indicator"||n==="bud-body"||n==="bud-connection-lost"||(m.is('noscript')&&m.has('#bud-noscript')))return;$(this).remove()});$('html, body').removeAttr('style');Bulldozer.topbar.space();if(s&&d&&d.length>0){k(d);var o=f(h);i(d[0],o.container);g(o.scripts)}else{var p=$('<div id="bud-body"></div>');p.append(h);d.replaceWith(p);window.scrollTo(0,0)}if(j&&b!==j){b=j;c=!0;History.pushState(null,null,j);c=!1}document.title=q;Kepler.init()}}();Bulldozer.fn.data=new function(){var b={},a="bud.";var c=function(a){try{if(a==="local")return window.localStorage;return window.sessionStorage}catch(a){console.log("Bulldozer.data: web storage not available: "+a.message);return null}};var f=function(f){var c=encodeURIComponent(a+f)+"=",d=document.cookie.split(";");for(var b=0;b<d.length;b++){var e=d[b].replace(/^\s+/,"");if(e.indexOf(c)===0)return decodeURIComponent(e.substring(c.length))}return undefined};var d=function(d,e,c){var b=encodeURIComponent(a+d)+"="+encodeURIComponent(e)+"; path=/";if(c!==undefined)b+="; max-age="+c;if(window.location.protocol==="https:")b+="; secure";document.cookie=b};var g=function(e,d){if(!d||d==="memory")return b[e];else if(d==="cookie")return f(e);var g=c(d);if(!g)return undefined;var h=g.getItem(a+e);if(h===null)return undefined;return h};var h=function(e,g,f){if(!f||f==="memory"){b[e]=g;return}else if(f==="cookie"){d(e,g);return}var h=c(f);if(!h)return;try{h.setItem(a+e,g)}catch(a){console.log("Bulldozer.data: failed to set value '"+e+"': "+a.message)}};var e=function(f,e){if(!e||e==="memory"){delete b[f];return}else if(e==="cookie"){d(f,"",0);return}var g=c(e);if(g)g.removeItem(a+f)};this.set=function(a,b,c){h(a,JSON.stringify(b),c)};this.setMulti=function(a,c){for(var b in a){if(a.hasOwnProperty(b))this.set(b,a[b],c)}};this.delete=function(a,b){e(a,b)};this.deleteMulti=function(b,c){for(var a=0;a<b.length;a++){e(b[a],c)}};this.get=function(a,c){var b=g(a,c);if(b===undefined)return undefined;try{return JSON.parse(b)}catch(b){console.log("Bulldozer.data: failed to decode value '"+a+"': "+b.message);return undefined}};this.getAndReply=function(b,e,f){var c={};for(var a=0;a<b.length;a++){var d=this.get(b[a],e);if(d!==undefined)c[b[a]]=d}var g={rand:f,data:JSON.stringify(c)};Bulldozer.socket.send('clientData',g)}}();Bulldozer.fn.broadcast=new function(){var a={};this.on=function(b,c){if(!a[b])a[b]=[];a[b].push(c)};this.off=function(d,e){if(!e){delete a[d];return}var b=a[d];if(!b)return;for(var c=0;c<b.length;c++){if(b[c]===e){b.splice(c,1);break}}};this.subscribe=function(a){Bulldozer.socket.send('subscribe',{channel:a})};this.unsubscribe=function(a){Bulldozer.socket.send('unsubscribe',{channel:a})};this.trigger=function(d,e){var b=a[d];if(!b)return;b=b.slice();for(var c=0;c<b.length;c++){try{b[c](e)}catch(a){console.log("Bulldozer: broadcast listener error: "+a.message)}}}}();Bulldozer.fn.upload=new function(){var d="/bulldozer/upload",e=0,a={},b={};var c=function(g,e){var c=b[g],f=a[e];if(!c||!f)return;var h=[e,f].concat(Array.prototype.slice.call(arguments,2));for(var d=0;d<c.length;d++){try{c[d].apply(null,h)}catch(a){console.log("Bulldozer.upload: listener error: "+a.message)}}};this.on=function(a,c){if(!b[a])b[a]=[];b[a].push(c)};this.send=function(g,h,b){if(!b){console.log("Bulldozer.upload: no file passed!");return}var d=String(++e);a[d]=b;var f={did:g,key:h,id:d,name:b.name||"",size:String(b.size),type:b.type||""};for(var c=3;c<arguments.length;c++){f['arg'+(c-2)]=arguments[c]}Bulldozer.socket.send('upload',f);return d};this.start=function(e,g){var f=a[e];if(!f)return;c('start',e);var b=new XMLHttpRequest();b.open("POST",d+"?token="+encodeURIComponent(g),!0);b.setRequestHeader("Content-Type","application/octet-stream");b.onload=function(){if(b.status!==200)Bulldozer.upload.error(e,"upload failed")};b.onerror=function(){Bulldozer.upload.error(e,"connection error")};b.send(f)};this.progress=function(a,b,d){c('progress',a,b,d)};this.done=function(b){c('done',b);delete a[b]};this.error=function(b,d){c('error',b,d);delete a[b]}}();Bulldozer.fn.topbar=new function(){var a="0";this.space=function(b){if(b===!0)a="45px";else if(b===!1)a="0";$("body").css("margin-top",a);$(".bud-topbar-auto-move").css("margin-top",a)}}();(function(){var a=Bulldozer.socket.onMessage;a('cmd',function(b,a){jQuery.globalEval(a)});a('loading.show',function(){Bulldozer.loadingIndicator.show()});a('loading.hide',function(){Bulldozer.loadingIndicator.hide()});a('exitMessage.set',function(b,a){Bulldozer.core.setExitMessage(a)});a('exitMessage.reset',function(){Bulldozer.core.resetExitMessage()});a('loadScript',function(c,a){var b;if(a.cmd)b=function(){jQuery.globalEval(a.cmd)};Bulldozer.core.loadScript(a.url,b)});a('loadStyleSheet',function(b,a){Bulldozer.core.loadStyleSheet(a)});a('render.page',function(c,a,b){Bulldozer.render.page(Bulldozer.socket.decodeText(b),a.title,a.path,a.patch)});a('render.template',function(a,b,c){Bulldozer.render.updateTemplate(a,Bulldozer.socket.decodeText(c),b)});a('event.emit',function(b,a){Bulldozer.core.emitServerEvent.apply(Bulldozer.core,[b,a.event].concat(a.args||[]))});a('event.emitGlobal',function(b,a){Bulldozer.core.emitGlobalServerEvent.apply(Bulldozer.core,[a.event].concat(a.args||[]))});a('data.get',function(b,a){Bulldozer.data.getAndReply(a.keys||[],a.st,a.rand)});a('data.set',function(b,a){Bulldozer.data.setMulti(a.values||{},a.st)});a('data.delete',function(b,a){Bulldozer.data.deleteMulti(a.keys||[],a.st)});a('broadcast',function(b,a){Bulldozer.broadcast.trigger(a.event,a.payload)});a('upload.start',function(b,a){Bulldozer.upload.start(a.id,a.token)});a('upload.progress',function(b,a){Bulldozer.upload.progress(a.id,a.loaded,a.total)});a('upload.done',function(b,a){Bulldozer.upload.done(a.id)});a('upload.error',function(b,a){Bulldozer.upload.error(a.id,a.error)});a('session.cookie',function(b,a){Bulldozer.core.renewSessionCookie(a)});a('dialog.show',function(b,a){Bulldozer.utils.addAndShowTmpModal(a.body,{domId:b,closable:a.closable,class:a.class})});a('dialog.close',function(b){var a=$('#'+b);a.data('serverClosedDialog',!0);Kepler.modal.close(a)});a('topbar.space',function(b,a){Bulldozer.topbar.space(a)})})();
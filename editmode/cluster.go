@@ -0,0 +1,183 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package editmode
+
+import (
+	"github.com/desertbit/bulldozer/cluster"
+	"github.com/desertbit/bulldozer/log"
+	"sync"
+	"time"
+)
+
+const (
+	clusterChannel = "editmode.sessions"
+
+	// Each node publishes its active edit mode sessions
+	// with this interval. Nodes without any sign of life
+	// are removed after the expire timeout.
+	clusterSyncInterval  = 15 * time.Second
+	clusterExpireTimeout = 3 * clusterSyncInterval
+)
+
+var (
+	// Key: node ID
+	remoteNodes      map[string]*remoteNode = make(map[string]*remoteNode)
+	remoteNodesMutex sync.Mutex
+
+	stopClusterSyncLoop chan struct{} = make(chan struct{})
+)
+
+func init() {
+	cluster.Subscribe(clusterChannel, onClusterMessage)
+}
+
+//#####################//
+//### Private types ###//
+//#####################//
+
+type clusterMessage struct {
+	Node        string
+	InstanceIDs []string
+}
+
+type remoteNode struct {
+	instanceIDs map[string]struct{}
+	lastSeen    time.Time
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// Init initializes the edit mode package.
+// This is handled by the main bulldozer package.
+func Init() {
+	// Start the sync loop in a new goroutine.
+	go clusterSyncLoop()
+}
+
+// Release releases the edit mode package.
+// This is handled by the main bulldozer package.
+func Release() {
+	// Stop the loop by triggering the quit trigger
+	close(stopClusterSyncLoop)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// hasActiveRemoteSessions returns a boolean whenever
+// other nodes have active edit mode sessions.
+func hasActiveRemoteSessions() bool {
+	// Lock the mutex.
+	remoteNodesMutex.Lock()
+	defer remoteNodesMutex.Unlock()
+
+	for _, n := range remoteNodes {
+		if len(n.instanceIDs) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isActiveRemoteInstance returns a boolean whenever the
+// instance is in edit mode on another node.
+func isActiveRemoteInstance(instanceID string) bool {
+	// Lock the mutex.
+	remoteNodesMutex.Lock()
+	defer remoteNodesMutex.Unlock()
+
+	for _, n := range remoteNodes {
+		if _, ok := n.instanceIDs[instanceID]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// publishSessions sends the active edit mode
+// sessions of this node to all other nodes.
+func publishSessions() {
+	if !cluster.IsEnabled() {
+		return
+	}
+
+	// Get the instance IDs of all active sessions.
+	activeSessions := GetSessions()
+	ids := make([]string, len(activeSessions))
+	for i, s := range activeSessions {
+		ids[i] = s.InstanceID()
+	}
+
+	err := cluster.Publish(clusterChannel, &clusterMessage{
+		Node:        cluster.NodeID(),
+		InstanceIDs: ids,
+	})
+	if err != nil {
+		log.L.Error("editmode: %v", err)
+	}
+}
+
+func onClusterMessage(data []byte) {
+	var m clusterMessage
+	if err := cluster.Decode(data, &m); err != nil {
+		log.L.Error("editmode: failed to decode cluster message: %v", err)
+		return
+	}
+
+	// Create the instance IDs set.
+	n := &remoteNode{
+		instanceIDs: make(map[string]struct{}),
+		lastSeen:    time.Now(),
+	}
+	for _, id := range m.InstanceIDs {
+		n.instanceIDs[id] = struct{}{}
+	}
+
+	// Lock the mutex.
+	remoteNodesMutex.Lock()
+	defer remoteNodesMutex.Unlock()
+
+	// Replace the previous node state.
+	remoteNodes[m.Node] = n
+}
+
+func clusterSyncLoop() {
+	// Create a new ticker
+	ticker := time.NewTicker(clusterSyncInterval)
+
+	defer func() {
+		// Stop the ticker
+		ticker.Stop()
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			publishSessions()
+			removeExpiredRemoteNodes()
+		case <-stopClusterSyncLoop:
+			// Just exit the loop
+			return
+		}
+	}
+}
+
+func removeExpiredRemoteNodes() {
+	// Lock the mutex.
+	remoteNodesMutex.Lock()
+	defer remoteNodesMutex.Unlock()
+
+	for id, n := range remoteNodes {
+		if time.Since(n.lastSeen) > clusterExpireTimeout {
+			delete(remoteNodes, id)
+		}
+	}
+}
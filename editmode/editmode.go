@@ -42,7 +42,7 @@ func Start(s *sessions.Session) {
 	template.EnableSessionContextStore(s)
 
 	// Confirm on exit.
-	s.SetExitMessage(tr.S("bud.core.exitMessage"))
+	s.SetExitMessage(tr.For(s).S("bud.core.exitMessage"))
 
 	// Add the session to the active sessions.
 	addSession(s, true)
@@ -87,9 +87,22 @@ func IsActive(s *sessions.Session) bool {
 }
 
 // HasActiveSessions returns a boolean if there are
-// active sessions in the edit mode.
+// active sessions in the edit mode on any cluster node.
 func HasActiveSessions() bool {
-	return len(activeSessions) > 0
+	return len(activeSessions) > 0 || hasActiveRemoteSessions()
+}
+
+// IsActiveInstance returns a boolean whenever the session instance
+// with the given instance ID is in the edit mode on any cluster node.
+func IsActiveInstance(instanceID string) bool {
+	// Check the local sessions first.
+	for _, s := range GetSessions() {
+		if s.InstanceID() == instanceID {
+			return true
+		}
+	}
+
+	return isActiveRemoteInstance(instanceID)
 }
 
 // GetSessions returns a slice of all active edit mode sessions.
@@ -125,6 +138,9 @@ func addSession(s *sessions.Session, triggerEvent bool) {
 	// Add the session to the map.
 	activeSessions[s.SessionID()] = s
 
+	// Notify the other cluster nodes.
+	go publishSessions()
+
 	if triggerEvent {
 		// Trigger the event.
 		triggerOnNewSession(s)
@@ -142,6 +158,9 @@ func removeSession(s *sessions.Session) {
 	// Remove the session from the map.
 	delete(activeSessions, s.SessionID())
 
+	// Notify the other cluster nodes.
+	go publishSessions()
+
 	// Trigger the event.
 	triggerOnRemoveSession(s)
 }
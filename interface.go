@@ -8,9 +8,12 @@ package bulldozer
 import (
 	"github.com/desertbit/bulldozer/mux"
 	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/template"
 	"github.com/desertbit/bulldozer/templates"
-	"github.com/desertbit/bulldozer/topbar"
-	"github.com/desertbit/bulldozer/utils"
+)
+
+const (
+	messageTypeRenderPage = "render.page"
 )
 
 var (
@@ -23,6 +26,12 @@ var (
 
 type backendInterface struct{}
 
+type renderPageData struct {
+	Title string          `json:"title"`
+	Path  string          `json:"path"`
+	Patch *template.Patch `json:"patch,omitempty"`
+}
+
 // This navigates the session to the given route path.
 func (i *backendInterface) NavigateFunc(s *sessions.Session, path string) {
 	// Execute the route.
@@ -48,21 +57,19 @@ func (i *backendInterface) ShowNotFoundPage(s *sessions.Session) {
 	renderPage(s, title, body, s.CurrentPath())
 }
 
-func (i *backendInterface) ExecTopBar(ti interface{}) (string, error) {
-	return topbar.ExecTopBar(ti)
-}
-
 //###############//
 //### Private ###//
 //###############//
 
 func renderPage(s *sessions.Session, title string, body string, path string) {
-	// Create the client command.
-	cmd := `Bulldozer.render.page('` +
-		utils.EscapeJS(body) + `','` +
-		utils.EscapeJS(title) + `','` +
-		utils.EscapeJS(path) + `');`
+	// Only send the changes if the client knows the previous page body.
+	b, patch := template.DiffOutput(s, template.PageDomID, []byte(body))
 
 	// Send the new render request to the client.
-	s.SendCommand(cmd)
+	// The page body is sent as unescaped binary message body.
+	s.SendBinaryMessage(messageTypeRenderPage, "", &renderPageData{
+		Title: title,
+		Path:  path,
+		Patch: patch,
+	}, b)
 }
@@ -12,19 +12,27 @@ import (
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/router"
 	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/settings"
 	"github.com/desertbit/bulldozer/template"
 	"github.com/desertbit/bulldozer/templates"
+	tr "github.com/desertbit/bulldozer/translate"
 	"github.com/desertbit/bulldozer/utils"
 	"github.com/desertbit/bulldozer/webcrawler"
 )
 
 var (
-	backendI      Interface
 	mainRouter    *router.Router = router.New()
 	notFoundError                = errors.New("Not Found")
+
+	// The layout of the routes, which don't define a layout.
+	defaultLayout = DefaultLayout
 )
 
 const (
+	// DefaultLayout is the name of the bulldozer default layout template.
+	// It renders the topbar above the page content.
+	DefaultLayout = "bud/layouts/default"
+
 	requestTypeRoute = "route"
 	keyRoutePath     = "path"
 )
@@ -37,14 +45,6 @@ func init() {
 	}
 }
 
-//#################//
-//### Interface ###//
-//#################//
-
-type Interface interface {
-	ExecTopBar(i interface{}) (string, error)
-}
-
 //#############//
 //### Types ###//
 //#############//
@@ -55,6 +55,7 @@ type pageRoute struct {
 	ID           string
 	TemplateName string
 	Title        string
+	Layout       string
 }
 
 //####################//
@@ -66,6 +67,11 @@ type Request struct {
 	Title     string
 	Body      string
 
+	// The layout template, which renders the body.
+	// The default layout is used if empty.
+	// Set this to template.NoLayout to render the body without a layout.
+	Layout string
+
 	err error
 }
 
@@ -83,10 +89,12 @@ func (r *Request) Error(err error) {
 //### Public ###//
 //##############//
 
-// Init initializes this packages.
-// This is handled by the bulldozer package.
-func Init(i Interface) {
-	backendI = i
+// SetDefaultLayout sets the layout template of all routes, which don't
+// define a layout. Pass template.NoLayout to disable the default layout.
+// Templates extending a layout with {{extends}} keep their own layout.
+// This call is not thread-safe! Set the default layout during program initialization.
+func SetDefaultLayout(name string) {
+	defaultLayout = name
 }
 
 // Route the given path.
@@ -102,10 +110,18 @@ func Route(path string, f RouteFunc) {
 // The path is automatically added to the webcrawler sitemap paths.
 // If you don't want to have this added, then remove the path from the webcrawler's sitemap again.
 func RoutePage(path string, title string, templateName string, vars ...string) {
+	RoutePageLayout(path, title, templateName, "", vars...)
+}
+
+// RoutePageLayout does the same as RoutePage, but renders the page template into
+// the given layout template. The default layout is used if the layout is empty.
+// Pass template.NoLayout to render the page without a layout.
+func RoutePageLayout(path string, title string, templateName string, layout string, vars ...string) {
 	// Create a new page route value.
 	p := &pageRoute{
 		TemplateName: templateName,
 		Title:        title,
+		Layout:       layout,
 	}
 
 	// Set the ID if present.
@@ -142,8 +158,20 @@ func ExecRoute(s *sessions.Session, requestedPath string) (statusCode int, body
 	// Set the current session path.
 	s.SetCurrentPath(path)
 
+	// Set the session locale if the path is prefixed with a locale.
+	// The route is matched without the prefix.
+	routePath := path
+	if settings.Settings.LocaleURLPrefix {
+		if locale, rest, ok := tr.SplitLocalePrefix(path); ok {
+			if err := tr.SetSessionLocale(s, locale); err != nil {
+				log.L.Error("mux: %v", err)
+			}
+			routePath = rest
+		}
+	}
+
 	// Execute the route.
-	data := mainRouter.Match(path)
+	data := mainRouter.Match(routePath)
 	if data == nil {
 		// Execute the not found template.
 		statusCode, body, title = templates.ExecNotFound(s)
@@ -151,9 +179,9 @@ func ExecRoute(s *sessions.Session, requestedPath string) (statusCode int, body
 	}
 
 	// Show the error page if a parse template error occurred.
-	if templates.ParseError != nil {
+	if err := templates.LastParseError(); err != nil {
 		// Execute the error template.
-		statusCode, body, title = templates.ExecError(s, templates.ParseError.Error(), false)
+		statusCode, body, title = templates.ExecError(s, err.Error(), false)
 		return
 	}
 
@@ -178,19 +206,25 @@ func ExecRoute(s *sessions.Session, requestedPath string) (statusCode int, body
 			return
 		}
 
-		// Execute the topbar.
-		topBarO, err := backendI.ExecTopBar(s)
-		if err != nil {
-			// Execute the error template.
-			statusCode, body, title = templates.ExecError(s, fmt.Sprintf("failed to execute the topbar template: %v", err))
-			return
-		}
-
 		// Set the title and the body from the request value.
 		title = r.Title
+		body = r.Body
+
+		// Render the body into the layout if present.
+		layout := r.Layout
+		if len(layout) == 0 {
+			layout = defaultLayout
+		}
 
-		// The body is composed of the topbar and the page body.
-		body = topBarO + r.Body
+		if len(layout) > 0 && layout != template.NoLayout {
+			var err error
+			body, err = templates.Templates.ExecuteLayoutToString(s, layout, body)
+			if err != nil {
+				// Execute the error template.
+				statusCode, body, title = templates.ExecError(s, fmt.Sprintf("failed to execute the layout template: %v", err))
+				return
+			}
+		}
 
 		return
 	case *pageRoute:
@@ -198,10 +232,18 @@ func ExecRoute(s *sessions.Session, requestedPath string) (statusCode int, body
 		opts := template.ExecOpts{
 			ID:           v.ID,
 			StyleClasses: []string{"bud-page"},
+			Layout:       v.Layout,
+		}
+
+		// Use the default layout if the template doesn't extend a layout.
+		if len(opts.Layout) == 0 {
+			if t := templates.Templates.Lookup(v.TemplateName); t != nil && len(t.Layout()) == 0 {
+				opts.Layout = defaultLayout
+			}
 		}
 
 		// Execute the template
-		o, c, found, err := templates.Templates.ExecuteTemplateToString(s, v.TemplateName, opts)
+		o, _, found, err := templates.Templates.ExecuteTemplateToString(s, v.TemplateName, opts)
 
 		if err != nil {
 			if found {
@@ -215,19 +257,9 @@ func ExecRoute(s *sessions.Session, requestedPath string) (statusCode int, body
 			}
 		}
 
-		// Execute the topbar.
-		topBarO, err := backendI.ExecTopBar(c)
-		if err != nil {
-			// Execute the error template.
-			statusCode, body, title = templates.ExecError(s, fmt.Sprintf("failed to execute the topbar template: %v", err))
-			return
-		}
-
-		// Set the title.
+		// Set the title and the body.
 		title = v.Title
-
-		// The body is composed of the topbar and the page body.
-		body = topBarO + o
+		body = o
 
 		return
 	default:
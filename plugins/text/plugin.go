@@ -151,8 +151,8 @@ func (p *Plugin) EventLock(c *template.Context) {
 
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.plugin.text.error.alreadyLockedTitle")).
-			SetText(tr.S("bud.plugin.text.error.alreadyLockedText")).
+			SetTitle(tr.For(c.Session()).S("bud.plugin.text.error.alreadyLockedTitle")).
+			SetText(tr.For(c.Session()).S("bud.plugin.text.error.alreadyLockedText")).
 			SetType(messagebox.TypeWarning).
 			Show(c.Session())
 		return
@@ -177,8 +177,8 @@ func (p *Plugin) EventSetText(c *template.Context, text string) {
 
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.plugin.text.error.saveChangesTitle")).
-			SetText(tr.S("bud.plugin.text.error.saveChangesText")).
+			SetTitle(tr.For(c.Session()).S("bud.plugin.text.error.saveChangesTitle")).
+			SetText(tr.For(c.Session()).S("bud.plugin.text.error.saveChangesText")).
 			SetType(messagebox.TypeWarning).
 			Show(c.Session())
 		return
@@ -201,16 +201,16 @@ func getText(c *template.Context) (text string) {
 	i, ok, err := store.Get(c)
 	if err != nil {
 		log.L.Error("plugin text: failed to get data from database: %v", err)
-		text = tr.S("bud.plugin.text.error.getDataFromDatabase")
+		text = tr.For(c.Session()).S("bud.plugin.text.error.getDataFromDatabase")
 	}
 
 	if !ok {
-		text = tr.S("bud.plugin.text.placeholder")
+		text = tr.For(c.Session()).S("bud.plugin.text.placeholder")
 	} else {
 		text, ok = i.(string)
 		if !ok {
 			log.L.Error("plugin text: failed to cast database data to string!")
-			text = tr.S("bud.plugin.text.placeholder")
+			text = tr.For(c.Session()).S("bud.plugin.text.placeholder")
 		}
 	}
 
@@ -0,0 +1,319 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/desertbit/bulldozer/cluster"
+	"github.com/desertbit/bulldozer/log"
+	"sync"
+)
+
+const (
+	clusterChannelBroadcast = "sessions.broadcast"
+
+	// Instance keys
+	instanceKeyChannels = "budChannels"
+
+	// Client requests
+	requestTypeSubscribe   = "subscribe"
+	requestTypeUnsubscribe = "unsubscribe"
+	requestKeyChannel      = "channel"
+)
+
+var (
+	userMatcher UserMatcherFunc
+
+	// Channels which clients are allowed to subscribe to.
+	channels      map[string]ChannelAccessFunc = make(map[string]ChannelAccessFunc)
+	channelsMutex sync.Mutex
+)
+
+func init() {
+	// Register the client subscription requests.
+	err := Request(requestTypeSubscribe, onSubscribeRequest)
+	if err != nil {
+		log.L.Fatalf("failed to register session subscribe request: %v", err)
+	}
+	err = Request(requestTypeUnsubscribe, onUnsubscribeRequest)
+	if err != nil {
+		log.L.Fatalf("failed to register session unsubscribe request: %v", err)
+	}
+
+	// Deliver broadcasts of other cluster nodes.
+	cluster.Subscribe(clusterChannelBroadcast, onClusterBroadcast)
+}
+
+//#############//
+//### Types ###//
+//#############//
+
+// A Filter selects the sessions of a broadcast.
+// All non-empty fields have to match.
+// An empty filter matches all sessions.
+type Filter struct {
+	// Matches the sessions of the authenticated user with this ID.
+	UserID string
+
+	// Matches the sessions of authenticated users in this group.
+	Group string
+
+	// Matches the sessions with this current route path.
+	Path string
+
+	// Matches the sessions subscribed to this channel.
+	Channel string
+}
+
+// UserMatcherFunc returns a function which matches sessions
+// of the user with the ID and of users in the group.
+// Empty arguments are ignored.
+type UserMatcherFunc func(userID, group string) func(s *Session) bool
+
+// ChannelAccessFunc returns a boolean whenever the
+// session is allowed to subscribe to the channel.
+type ChannelAccessFunc func(s *Session) bool
+
+type clusterBroadcast struct {
+	Filter  Filter
+	Event   string
+	Payload []byte
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// SetUserMatcher sets the function used to match the user ID and
+// group filter fields. This is handled by the auth package.
+func SetUserMatcher(f UserMatcherFunc) {
+	userMatcher = f
+}
+
+// RegisterChannel allows clients to subscribe to the channel.
+// The optional access function decides if a session is allowed
+// to subscribe. Server-side subscriptions with Session.Subscribe
+// don't require a registered channel.
+func RegisterChannel(channel string, access ...ChannelAccessFunc) {
+	var f ChannelAccessFunc
+	if len(access) > 0 {
+		f = access[0]
+	}
+
+	// Lock the mutex
+	channelsMutex.Lock()
+	defer channelsMutex.Unlock()
+
+	channels[channel] = f
+}
+
+// Broadcast triggers the named client event with the JSON encoded
+// payload on all sessions matching the filter.
+// Sessions on other cluster nodes are also reached.
+// Listen for the event on the client side with Bulldozer.broadcast.on(event, func).
+func Broadcast(filter Filter, event string, payload interface{}) error {
+	// Encode the payload.
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("broadcast: failed to encode payload of event '%s': %v", event, err)
+	}
+
+	// Notify the other cluster nodes in a new goroutine.
+	// Unreachable nodes must not block the caller.
+	go publishBroadcast(filter, event, data)
+
+	broadcast(filter, event, data)
+
+	return nil
+}
+
+// SendEvent triggers the named client event with the
+// JSON encoded payload on the session.
+func (s *Session) SendEvent(event string, payload interface{}) error {
+	// Encode the payload.
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("send event: failed to encode payload of event '%s': %v", event, err)
+	}
+
+	s.sendEvent(event, data)
+
+	return nil
+}
+
+// Subscribe adds the session to the channel.
+func (s *Session) Subscribe(channel string) {
+	// Lock the mutex
+	s.sessionInstance.mutex.Lock()
+	defer s.sessionInstance.mutex.Unlock()
+
+	l, _ := s.sessionInstance.Values[instanceKeyChannels].([]string)
+	for _, c := range l {
+		if c == channel {
+			return
+		}
+	}
+
+	s.sessionInstance.Values[instanceKeyChannels] = append(l, channel)
+
+	// Mark the session values as dirty.
+	s.Dirty()
+}
+
+// Unsubscribe removes the session from the channel.
+func (s *Session) Unsubscribe(channel string) {
+	// Lock the mutex
+	s.sessionInstance.mutex.Lock()
+	defer s.sessionInstance.mutex.Unlock()
+
+	l, _ := s.sessionInstance.Values[instanceKeyChannels].([]string)
+	for i, c := range l {
+		if c == channel {
+			s.sessionInstance.Values[instanceKeyChannels] = append(l[:i:i], l[i+1:]...)
+
+			// Mark the session values as dirty.
+			s.Dirty()
+			return
+		}
+	}
+}
+
+// IsSubscribed returns a boolean whenever the session is subscribed to the channel.
+func (s *Session) IsSubscribed(channel string) bool {
+	for _, c := range s.Channels() {
+		if c == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Channels returns all channels the session is subscribed to.
+func (s *Session) Channels() []string {
+	// Lock the mutex
+	s.sessionInstance.mutex.Lock()
+	defer s.sessionInstance.mutex.Unlock()
+
+	l, _ := s.sessionInstance.Values[instanceKeyChannels].([]string)
+
+	// Return a copy.
+	return append([]string(nil), l...)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func (s *Session) sendEvent(event string, data []byte) {
+	s.SendMessage(messageTypeBroadcast, "", map[string]interface{}{
+		"event":   event,
+		"payload": json.RawMessage(data),
+	})
+}
+
+// publishBroadcast sends the broadcast to the other cluster nodes.
+func publishBroadcast(filter Filter, event string, data []byte) {
+	if !cluster.IsEnabled() {
+		return
+	}
+
+	err := cluster.Publish(clusterChannelBroadcast, &clusterBroadcast{
+		Filter:  filter,
+		Event:   event,
+		Payload: data,
+	})
+	if err != nil {
+		log.L.Error("broadcast: %v", err)
+	}
+}
+
+// broadcast sends the event to all matching sessions of this node.
+func broadcast(filter Filter, event string, data []byte) {
+	// Collect the matching sessions first. Don't hold
+	// the sessions mutex during the user matching.
+	var l []*Session
+	GetSessions(func(sessions Sessions) {
+		for _, s := range sessions {
+			if !s.IsWebCrawler() {
+				l = append(l, s)
+			}
+		}
+	})
+
+	// Create the user matcher if required.
+	var matchUser func(s *Session) bool
+	if len(filter.UserID) > 0 || len(filter.Group) > 0 {
+		if userMatcher == nil {
+			log.L.Error("broadcast: no user matcher set: can't filter by user ID or group!")
+			return
+		}
+
+		matchUser = userMatcher(filter.UserID, filter.Group)
+	}
+
+	for _, s := range l {
+		if len(filter.Path) > 0 && s.CurrentPath() != filter.Path {
+			continue
+		}
+
+		if len(filter.Channel) > 0 && !s.IsSubscribed(filter.Channel) {
+			continue
+		}
+
+		if matchUser != nil && !matchUser(s) {
+			continue
+		}
+
+		s.sendEvent(event, data)
+	}
+}
+
+func onClusterBroadcast(data []byte) {
+	var m clusterBroadcast
+	if err := cluster.Decode(data, &m); err != nil {
+		log.L.Error("broadcast: failed to decode cluster message: %v", err)
+		return
+	}
+
+	broadcast(m.Filter, m.Event, m.Payload)
+}
+
+func onSubscribeRequest(s *Session, data map[string]string) error {
+	// Get the channel.
+	channel, ok := data[requestKeyChannel]
+	if !ok || len(channel) == 0 {
+		return fmt.Errorf("subscribe request: missing channel!")
+	}
+
+	// Check if the channel is registered.
+	channelsMutex.Lock()
+	access, ok := channels[channel]
+	channelsMutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("subscribe request: channel '%s' is not registered!", channel)
+	} else if access != nil && !access(s) {
+		return fmt.Errorf("subscribe request: access to channel '%s' denied!", channel)
+	}
+
+	s.Subscribe(channel)
+
+	return nil
+}
+
+func onUnsubscribeRequest(s *Session, data map[string]string) error {
+	// Get the channel.
+	channel, ok := data[requestKeyChannel]
+	if !ok || len(channel) == 0 {
+		return fmt.Errorf("unsubscribe request: missing channel!")
+	}
+
+	s.Unsubscribe(channel)
+
+	return nil
+}
@@ -0,0 +1,311 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/settings"
+	"github.com/desertbit/bulldozer/utils"
+	"sync"
+	"time"
+)
+
+// Values are stored JSON encoded on the client side. One request gets or
+// sets multiple values at once. Get requests are answered by the client with
+// the random request key. The pending promise is kept in the session cache
+// until the client responds or the timeout is reached.
+
+const (
+	cacheValueKeyClientPromisePrefix = "budClientPromise_"
+
+	requestTypeClientData   = "clientData"
+	requestKeyDataRandomKey = "rand"
+	requestKeyData          = "data"
+
+	clientRequestRandomKeyLength = 15
+)
+
+const (
+	// ClientMemory stores the values in the memory of the browser tab.
+	// They are lost on page reloads.
+	ClientMemory ClientStorage = iota
+	// ClientSessionStorage stores the values in the sessionStorage of the browser tab.
+	ClientSessionStorage
+	// ClientLocalStorage stores the values in the localStorage of the browser.
+	ClientLocalStorage
+	// ClientCookie stores the values in session cookies.
+	// They are also sent with each HTTP request, so keep them small.
+	ClientCookie
+)
+
+func init() {
+	// Register the client data response request.
+	err := Request(requestTypeClientData, onClientDataResponse)
+	if err != nil {
+		log.L.Fatalf("failed to register session client data response request: %v", err)
+	}
+}
+
+//############################//
+//### Client Storage Types ###//
+//############################//
+
+// ClientStorage defines the client-side storage of the values.
+type ClientStorage int
+
+func (c ClientStorage) String() string {
+	switch c {
+	case ClientSessionStorage:
+		return "session"
+	case ClientLocalStorage:
+		return "local"
+	case ClientCookie:
+		return "cookie"
+	default:
+		return "memory"
+	}
+}
+
+// ClientValues holds the JSON encoded values obtained from the client.
+// Keys which are not set on the client side are missing.
+type ClientValues map[string]json.RawMessage
+
+// Has returns a boolean whenever the value is set on the client side.
+func (v ClientValues) Has(key string) bool {
+	_, ok := v[key]
+	return ok
+}
+
+// Decode decodes the value with the key into the passed pointer.
+// False is returned if the value is not set on the client side.
+func (v ClientValues) Decode(key string, value interface{}) (bool, error) {
+	data, ok := v[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return true, fmt.Errorf("client data: failed to decode value '%s': %v", key, err)
+	}
+
+	return true, nil
+}
+
+//############################//
+//### Client Promise Types ###//
+//############################//
+
+// ClientPromise is the pending response of a client get request.
+type ClientPromise struct {
+	done   chan struct{}
+	values ClientValues
+	err    error
+
+	timer *time.Timer
+	once  sync.Once
+}
+
+func newClientPromise() *ClientPromise {
+	return &ClientPromise{
+		done: make(chan struct{}),
+	}
+}
+
+// Done returns a channel which is closed as soon as the promise is resolved.
+func (p *ClientPromise) Done() <-chan struct{} {
+	return p.done
+}
+
+// Result blocks until the promise is resolved and returns the values.
+func (p *ClientPromise) Result() (ClientValues, error) {
+	<-p.done
+	return p.values, p.err
+}
+
+// Wait blocks until the promise is resolved or the context is done.
+func (p *ClientPromise) Wait(ctx context.Context) (ClientValues, error) {
+	select {
+	case <-p.done:
+		return p.values, p.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("client data: %v", ctx.Err())
+	}
+}
+
+// Then calls the function in a new goroutine as soon as the promise is resolved.
+func (p *ClientPromise) Then(f func(values ClientValues, err error)) {
+	go func() {
+		f(p.Result())
+	}()
+}
+
+// resolve resolves the promise. Only the first call has an effect.
+func (p *ClientPromise) resolve(values ClientValues, err error) {
+	p.once.Do(func() {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+
+		p.values = values
+		p.err = err
+		close(p.done)
+	})
+}
+
+//###########################//
+//### Client Store Struct ###//
+//###########################//
+
+// ClientStore accesses the values of one client-side storage.
+type ClientStore struct {
+	s       *Session
+	storage ClientStorage
+}
+
+// ClientStore returns the client-side value store with the storage type.
+func (s *Session) ClientStore(storage ClientStorage) *ClientStore {
+	return &ClientStore{
+		s:       s,
+		storage: storage,
+	}
+}
+
+// GetAsync requests the values from the client and returns the pending promise.
+// The promise fails if the client doesn't respond within the client storage timeout.
+func (c *ClientStore) GetAsync(keys ...string) *ClientPromise {
+	return c.getAsync(time.Duration(settings.Settings.ClientStorageTimeout)*time.Second, keys)
+}
+
+// Get obtains the value from the client and decodes it into the passed pointer.
+// False is returned if the value is not set on the client side.
+// This method blocks until the client responds or the context is done.
+// If the context has no deadline, then the client storage timeout is used.
+func (c *ClientStore) Get(ctx context.Context, key string, value interface{}) (bool, error) {
+	values, err := c.GetMulti(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	return values.Decode(key, value)
+}
+
+// GetMulti obtains multiple values from the client in one request.
+// This method blocks until the client responds or the context is done.
+// If the context has no deadline, then the client storage timeout is used.
+func (c *ClientStore) GetMulti(ctx context.Context, keys ...string) (ClientValues, error) {
+	timeout := time.Duration(settings.Settings.ClientStorageTimeout) * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = deadline.Sub(time.Now())
+	}
+
+	return c.getAsync(timeout, keys).Wait(ctx)
+}
+
+// Set encodes the value to JSON and stores it on the client side.
+func (c *ClientStore) Set(key string, value interface{}) error {
+	return c.SetMulti(map[string]interface{}{key: value})
+}
+
+// SetMulti encodes the values to JSON and stores them on the client side in one request.
+func (c *ClientStore) SetMulti(values map[string]interface{}) error {
+	encoded := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("client data: failed to encode value '%s': %v", key, err)
+		}
+
+		encoded[key] = data
+	}
+
+	return c.s.SendMessage(messageTypeDataSet, "", map[string]interface{}{
+		"st":     c.storage.String(),
+		"values": encoded,
+	})
+}
+
+// Delete removes the values from the client side.
+func (c *ClientStore) Delete(keys ...string) error {
+	return c.s.SendMessage(messageTypeDataDelete, "", map[string]interface{}{
+		"st":   c.storage.String(),
+		"keys": keys,
+	})
+}
+
+// getAsync requests the values from the client.
+// The promise fails after the timeout.
+func (c *ClientStore) getAsync(timeout time.Duration, keys []string) *ClientPromise {
+	p := newClientPromise()
+
+	// Create a random key and add the promise to the cache.
+	randomKey := utils.RandomString(clientRequestRandomKeyLength)
+	cacheKey := cacheValueKeyClientPromisePrefix + randomKey
+	c.s.CacheSet(cacheKey, p)
+
+	// Fail the promise after the timeout.
+	p.timer = time.AfterFunc(timeout, func() {
+		c.s.CacheDelete(cacheKey)
+		p.resolve(nil, fmt.Errorf("client data: client did not respond!"))
+	})
+
+	// Send the request to the client.
+	err := c.s.SendMessage(messageTypeDataGet, "", map[string]interface{}{
+		"st":   c.storage.String(),
+		"keys": keys,
+		"rand": randomKey,
+	})
+	if err != nil {
+		c.s.CacheDelete(cacheKey)
+		p.resolve(nil, fmt.Errorf("client data: %v", err))
+	}
+
+	return p
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func onClientDataResponse(s *Session, data map[string]string) error {
+	// Try to obtain the random key.
+	randomKey, ok := data[requestKeyDataRandomKey]
+	if !ok {
+		return fmt.Errorf("client data: missing random key in request!")
+	}
+
+	// Try to obtain the data value.
+	d, ok := data[requestKeyData]
+	if !ok {
+		return fmt.Errorf("client data: missing data value in request!")
+	}
+
+	// Try to get the pending promise.
+	key := cacheValueKeyClientPromisePrefix + randomKey
+	i, ok := s.CachePull(key)
+	if !ok {
+		return fmt.Errorf("client data: no pending request found for key '%s'", key)
+	}
+
+	// Assertion.
+	p, ok := i.(*ClientPromise)
+	if !ok {
+		return fmt.Errorf("client data: failed to assert promise with key '%s'", key)
+	}
+
+	// Decode the values.
+	var values ClientValues
+	if err := json.Unmarshal([]byte(d), &values); err != nil {
+		p.resolve(nil, fmt.Errorf("client data: invalid response: %v", err))
+		return fmt.Errorf("client data: invalid response: %v", err)
+	}
+
+	p.resolve(values, nil)
+
+	return nil
+}
@@ -7,11 +7,13 @@ package sessions
 
 import (
 	"encoding/gob"
+	"fmt"
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/sessions/store"
 	"github.com/desertbit/bulldozer/settings"
 	"github.com/desertbit/bulldozer/utils"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -20,11 +22,63 @@ const (
 	cookieTokenLength = 15
 
 	cookieValueTimeout = 20 * time.Second
+
+	// The client has to renew the cookie within this timeout.
+	cookieRenewalTimeout     = 30 * time.Second
+	cookieRenewalTokenLength = 30
+
+	urlCookieRenewal         = "/bulldozer/cookie"
+	postKeyCookieRenewal     = "token"
+	messageTypeCookieRenewal = "session.cookie"
+)
+
+var (
+	// Key: one-time renewal token
+	cookieRenewals      map[string]*store.Session = make(map[string]*store.Session)
+	cookieRenewalsMutex sync.Mutex
 )
 
 func init() {
 	// Register the custom struct to gob
 	gob.Register(&sessionCookie{})
+
+	// Register the cookie renewal handler.
+	http.HandleFunc(urlCookieRenewal, handleCookieRenewal)
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// RenewID assigns a new ID to the store session and invalidates the old one.
+// Call this on privilege changes as logins to prevent session fixation.
+// The client obtains the new session cookie with a one-time token.
+func (s *Session) RenewID() error {
+	if s.isWebCrawler {
+		return fmt.Errorf("renew session ID: not supported for web crawler sessions!")
+	}
+
+	err := store.AssignNewSessionID(s.storeSession)
+	if err != nil {
+		return fmt.Errorf("renew session ID: %v", err)
+	}
+
+	// Create a one-time token to renew the cookie.
+	token := utils.RandomString(cookieRenewalTokenLength)
+
+	cookieRenewalsMutex.Lock()
+	cookieRenewals[token] = s.storeSession
+	cookieRenewalsMutex.Unlock()
+
+	// Release the token after the timeout.
+	time.AfterFunc(cookieRenewalTimeout, func() {
+		cookieRenewalsMutex.Lock()
+		delete(cookieRenewals, token)
+		cookieRenewalsMutex.Unlock()
+	})
+
+	// Tell the client to renew the cookie.
+	return s.SendMessage(messageTypeCookieRenewal, "", token)
 }
 
 //#######################//
@@ -145,36 +199,78 @@ func getStoreSession(rw http.ResponseWriter, req *http.Request) (*store.Session,
 		// Add the cookie value to the cached session values
 		storeSession.CacheSet(cacheKeyCookieToken, cValue)
 
-		// Set a new random cookie token. This is a security improvement.
-		sCookie.Token = utils.RandomString(cookieTokenLength)
-
-		// Encode the session cookie
-		encoded, err := secureCookie.Encode(cookieName, sCookie)
-		if err != nil {
-			// Return the encoding error
+		// Set a new session cookie with a new random token.
+		if err = setSessionCookie(rw, storeSession); err != nil {
 			return nil, false, err
 		}
+	}
 
-		// TODO: Set cookie max age to settings.Settings.SessionMaxAge if authenticated and if remeber login is set
+	return storeSession, newStoreSessionCreated, nil
+}
 
-		// Create a new session cookie
-		cookie = &http.Cookie{
-			Name:     cookieName,
-			Value:    encoded,
-			Path:     "/",
-			MaxAge:   0,
-			HttpOnly: true,                                // Don't allow scripts to manipulate the cookie
-			Secure:   settings.Settings.SecureHttpsAccess, // Only send this cookie over a secure https connection if provided
-		}
+// setSessionCookie sets a new session cookie with the store
+// session ID and a new random cookie token.
+func setSessionCookie(rw http.ResponseWriter, storeSession *store.Session) error {
+	// Set a new random cookie token. This is a security improvement.
+	sCookie := sessionCookie{
+		ID:    storeSession.ID(),
+		Token: utils.RandomString(cookieTokenLength),
+	}
+
+	// Encode the session cookie
+	encoded, err := secureCookie.Encode(cookieName, sCookie)
+	if err != nil {
+		// Return the encoding error
+		return err
+	}
 
-		// Set the new session cookie
-		http.SetCookie(rw, cookie)
+	// TODO: Set cookie max age to settings.Settings.SessionMaxAge if authenticated and if remeber login is set
 
-		// Set the cookie token to the session store
-		storeSession.Set(keyCookieToken, sCookie.Token)
+	// Create a new session cookie
+	cookie := &http.Cookie{
+		Name:     cookieName,
+		Value:    encoded,
+		Path:     "/",
+		MaxAge:   0,
+		HttpOnly: true,                                // Don't allow scripts to manipulate the cookie
+		Secure:   settings.Settings.SecureHttpsAccess, // Only send this cookie over a secure https connection if provided
 	}
 
-	return storeSession, newStoreSessionCreated, nil
+	// Set the new session cookie
+	http.SetCookie(rw, cookie)
+
+	// Set the cookie token to the session store
+	storeSession.Set(keyCookieToken, sCookie.Token)
+
+	return nil
+}
+
+// handleCookieRenewal sets the session cookie with the renewed store session ID.
+func handleCookieRenewal(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(rw, "Bad Request", 400)
+		return
+	}
+
+	// Obtain and remove the store session. Tokens are only valid once.
+	token := req.PostFormValue(postKeyCookieRenewal)
+
+	cookieRenewalsMutex.Lock()
+	storeSession, ok := cookieRenewals[token]
+	delete(cookieRenewals, token)
+	cookieRenewalsMutex.Unlock()
+
+	if !ok {
+		log.L.Warning("client requested a cookie renewal with an invalid or expired token")
+		http.Error(rw, "Bad Request", 400)
+		return
+	}
+
+	if err := setSessionCookie(rw, storeSession); err != nil {
+		log.L.Error("failed to renew session cookie: %v", err)
+		http.Error(rw, "Internal Server Error", 500)
+		return
+	}
 }
 
 func getCachedCookieValue(storeSession *store.Session) (value *cookieValue) {
@@ -0,0 +1,203 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"github.com/desertbit/bulldozer/sessions/socket"
+	"github.com/desertbit/bulldozer/sessions/store"
+	"github.com/desertbit/bulldozer/sessions/stream"
+	"github.com/desertbit/bulldozer/settings"
+	"github.com/desertbit/bulldozer/utils"
+
+	"github.com/chuckpreslar/emission"
+
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+// DummyOpts defines the options of a dummy session.
+type DummyOpts struct {
+	RemoteAddr     string
+	UserAgent      string
+	AcceptLanguage string
+
+	// The initial session path.
+	Path string
+
+	// The interface handles the navigation requests and the error pages.
+	// The interface passed to Init is used if nil.
+	Interface Interface
+}
+
+// A SentMessage is a message which was sent to the client.
+type SentMessage struct {
+	Type   string
+	Target string
+
+	// The JSON encoded message payload.
+	Data json.RawMessage
+
+	// The optional binary body.
+	Body []byte
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// NewDummy creates a session which is not connected to any client.
+// The session is not added to the active sessions and the session
+// values are never saved. All messages sent to the client are buffered.
+// Obtain them with ReadMessages. Use this to test templates and events
+// without a browser.
+func NewDummy(opts DummyOpts) *Session {
+	if len(opts.Path) == 0 {
+		opts.Path = "/"
+	}
+
+	// Create a new temporary store session and lock it.
+	storeSession := store.NewTemporary()
+	storeSession.Lock()
+
+	s := &Session{
+		sessionID:                     utils.RandomString(sessionIDLength),
+		instanceID:                    newUniqueInstanceID(),
+		path:                          utils.ToPath(opts.Path),
+		domEncryptionKey:              utils.RandomString(domEncryptionKeyLength),
+		acceptLanguage:                opts.AcceptLanguage,
+		stream:                        stream.New(settings.Settings.SocketMaxBufferSize),
+		storeSession:                  storeSession,
+		socket:                        socket.NewSocketDummy(opts.RemoteAddr, opts.UserAgent),
+		stopExpireAccessSocketTimeout: make(chan struct{}),
+		lastActivity:                  time.Now(),
+		isDummy:                       true,
+		iface:                         opts.Interface,
+	}
+
+	// Create a new emitter and set the recover function
+	s.emitter = emission.NewEmitter().
+		RecoverWith(recoverEmitter)
+
+	// Get the instance pointer. This will create a new instance.
+	s.sessionInstance = getInstance(s)
+
+	// Add a custom event function to cleanup the session on close.
+	s.socket.OnClose(func() {
+		removeSession(s)
+		s.stream.Close()
+	})
+
+	return s
+}
+
+// ReadMessages returns and removes all buffered messages sent to the client.
+// This is only supported by dummy sessions, because the messages of
+// connected sessions are read by the socket.
+func (s *Session) ReadMessages() ([]*SentMessage, error) {
+	if !s.isDummy {
+		return nil, fmt.Errorf("session: read messages: not a dummy session!")
+	}
+
+	var messages []*SentMessage
+	for _, frame := range s.stream.Read() {
+		m, err := decodeMessages(frame)
+		if err != nil {
+			return nil, fmt.Errorf("session: read messages: %v", err)
+		}
+
+		messages = append(messages, m...)
+	}
+
+	return messages, nil
+}
+
+// CallRequest calls the registered request function of the type
+// with the data as if the client sent the request.
+func CallRequest(s *Session, typeStr string, data map[string]string) error {
+	request, ok := requests[typeStr]
+	if !ok {
+		return fmt.Errorf("session request for task type '%s' not found!", typeStr)
+	}
+
+	s.updateLastActivity()
+
+	return request(s, data)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// backend returns the interface which handles the session navigation.
+func (s *Session) backend() Interface {
+	if s.iface != nil {
+		return s.iface
+	}
+
+	return backendI
+}
+
+// decodeMessages decodes all length-prefixed message frames.
+func decodeMessages(frame []byte) ([]*SentMessage, error) {
+	var messages []*SentMessage
+
+	for len(frame) > 0 {
+		header, rest, err := decodeFramePart(frame)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message header: %v", err)
+		}
+
+		body, rest, err := decodeFramePart(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message body: %v", err)
+		}
+
+		var m struct {
+			Type   string          `json:"t"`
+			Target string          `json:"id"`
+			Data   json.RawMessage `json:"d"`
+		}
+		if err = json.Unmarshal(header, &m); err != nil {
+			return nil, fmt.Errorf("invalid message header: %v", err)
+		}
+
+		sm := &SentMessage{
+			Type:   m.Type,
+			Target: m.Target,
+			Data:   m.Data,
+		}
+		if len(body) > 0 {
+			sm.Body = body
+		}
+
+		messages = append(messages, sm)
+		frame = rest
+	}
+
+	return messages, nil
+}
+
+// decodeFramePart decodes one "<length>:<data>" part of a frame.
+func decodeFramePart(b []byte) (data []byte, rest []byte, err error) {
+	i := bytes.IndexByte(b, ':')
+	if i < 0 {
+		return nil, nil, fmt.Errorf("missing length delimiter")
+	}
+
+	n, err := strconv.Atoi(string(b[:i]))
+	if err != nil || n < 0 || i+1+n > len(b) {
+		return nil, nil, fmt.Errorf("invalid length '%s'", b[:i])
+	}
+
+	return b[i+1 : i+1+n], b[i+1+n:], nil
+}
@@ -0,0 +1,129 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions/stream"
+	"strconv"
+)
+
+// All server to client traffic is sent as JSON message envelopes.
+// The client dispatches each message by its type to the handler registered
+// with Bulldozer.socket.onMessage(type, function(target, data, body)).
+// Raw javascript commands are only sent with the command message type.
+//
+// Each message is encoded as length-prefixed frame with an optional
+// binary body: "<header length>:<JSON header><body length>:<body>".
+// Lengths are decimal byte counts. Binary bodies are sent without
+// any escaping. Multiple frames are sent in one socket message.
+
+const (
+	// MessageTypeCommand executes the javascript data string on the client.
+	// Use Session.SendCommand.
+	MessageTypeCommand = "cmd"
+
+	messageTypePing = "ping"
+	messageTypeAck  = "ack"
+
+	// Session message types
+	messageTypeLoadingShow      = "loading.show"
+	messageTypeLoadingHide      = "loading.hide"
+	messageTypeExitMessageSet   = "exitMessage.set"
+	messageTypeExitMessageReset = "exitMessage.reset"
+	messageTypeLoadScript       = "loadScript"
+	messageTypeLoadStyleSheet   = "loadStyleSheet"
+	messageTypeDataGet          = "data.get"
+	messageTypeDataSet          = "data.set"
+	messageTypeDataDelete       = "data.delete"
+	messageTypeBroadcast        = "broadcast"
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+// A Message is a structured message envelope sent to the client.
+type Message struct {
+	// Type selects the client message handler.
+	Type string `json:"t"`
+
+	// Target is the optional DOM ID of the target element.
+	Target string `json:"id,omitempty"`
+
+	// Data is the JSON encoded message payload.
+	Data interface{} `json:"d,omitempty"`
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// SendMessage sends a structured message to the client.
+// The target is the optional DOM ID of the target element.
+// The data value is JSON encoded. Errors are logged.
+// This call blocks shortly if the session socket buffer is full.
+// If the client falls behind or if no socket reads the buffer,
+// then the session is closed.
+func (s *Session) SendMessage(msgType string, target string, data interface{}) error {
+	return s.SendBinaryMessage(msgType, target, data, nil)
+}
+
+// SendBinaryMessage sends a structured message with an additional
+// binary body to the client. The body is passed unescaped to the
+// client message handler as Uint8Array. Use this for large payloads.
+func (s *Session) SendBinaryMessage(msgType string, target string, data interface{}, body []byte) error {
+	frame, err := encodeMessage(&Message{
+		Type:   msgType,
+		Target: target,
+		Data:   data,
+	}, body)
+	if err != nil {
+		log.L.Error("session: failed to encode client message of type '%s': %v", msgType, err)
+		return err
+	}
+
+	err = s.stream.Write(frame)
+	if err == stream.ErrFull {
+		log.L.Warning("session %s: closing socket with remote address '%s': %v", s.sessionID, s.RemoteAddr(), err)
+		s.Close()
+	}
+
+	return err
+}
+
+// SendCommand sends a javascript command to the client.
+// Prefer SendMessage with a registered client message handler.
+// This is only an escape hatch for custom javascript.
+func (s *Session) SendCommand(cmd string) error {
+	return s.SendMessage(MessageTypeCommand, "", cmd)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// encodeMessage encodes the message and the body to a length-prefixed frame.
+func encodeMessage(m *Message, body []byte) ([]byte, error) {
+	header, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Grow(len(header) + len(body) + 24)
+
+	b.WriteString(strconv.Itoa(len(header)))
+	b.WriteByte(':')
+	b.Write(header)
+	b.WriteString(strconv.Itoa(len(body)))
+	b.WriteByte(':')
+	b.Write(body)
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,325 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"fmt"
+	"github.com/desertbit/bulldozer/settings"
+	"sync"
+	"time"
+)
+
+// The message sequence numbers belong to the session instance and
+// continue across socket reconnections. Both sides buffer their sent
+// messages until the peer acknowledges them. On reconnection the client
+// passes its last received sequence number and the server replies with
+// its own. Both sides replay the missing messages signed with the new
+// socket key. If the missing messages aren't buffered anymore, then the
+// client has to refresh the page.
+
+const (
+	// Release the replay buffer if no socket resumed the instance in time.
+	// Suspended background tabs resume the instance later. Their timeout
+	// is defined by the settings.
+	replayBufferTimeout = 10 * time.Minute
+)
+
+var (
+	// Key: instance ID
+	replayBuffers      map[string]*replayBuffer = make(map[string]*replayBuffer)
+	replayBuffersMutex sync.Mutex
+
+	// The detached replay buffers of suspended sockets ordered by their
+	// detach time and their total size. Be sure to lock the replayBuffersMutex.
+	suspendedReplayBuffers []*replayBuffer
+	suspendedReplaySize    int
+)
+
+//###########################//
+//### Replay buffer types ###//
+//###########################//
+
+type replayPacket struct {
+	seq  uint64
+	data []byte
+}
+
+type replayBuffer struct {
+	instanceID string
+
+	// The last sent and the last received sequence number.
+	sendSeq uint64
+	recvSeq uint64
+
+	// Unacknowledged packets ordered by their sequence numbers.
+	packets []replayPacket
+	size    int
+	maxSize int
+
+	// The last sequence number dropped before it was acknowledged.
+	droppedSeq uint64
+
+	// The socket session which currently sends with this buffer.
+	owner      *socketSession
+	detachedAt time.Time
+
+	// Whenever the socket was suspended, because the tab is in the background.
+	suspended bool
+
+	// The size counted to the total suspended size.
+	// Be sure to lock the replayBuffersMutex.
+	suspendedSize int
+
+	mutex sync.Mutex
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// resumeReplayBuffer attaches the socket session to the replay buffer
+// of the instance and returns the packets the client hasn't received.
+// The previous socket session of the instance is closed.
+func resumeReplayBuffer(instanceID string, ss *socketSession, clientAck uint64) (*replayBuffer, []replayPacket, error) {
+	// Lock the mutex
+	replayBuffersMutex.Lock()
+	r, ok := replayBuffers[instanceID]
+	if !ok {
+		// The client expects a replay, but the buffer is gone.
+		if clientAck > 0 {
+			replayBuffersMutex.Unlock()
+			return nil, nil, fmt.Errorf("no replay buffer for the instance present")
+		}
+
+		r = &replayBuffer{
+			instanceID: instanceID,
+			maxSize:    settings.Settings.SocketMaxReplaySize,
+		}
+		replayBuffers[instanceID] = r
+	}
+	replayBuffersMutex.Unlock()
+
+	// Lock the mutex
+	r.mutex.Lock()
+
+	if clientAck > r.sendSeq {
+		r.mutex.Unlock()
+		return nil, nil, fmt.Errorf("client acknowledged sequence number %v, but only %v were sent", clientAck, r.sendSeq)
+	} else if clientAck < r.droppedSeq {
+		r.mutex.Unlock()
+		return nil, nil, fmt.Errorf("missing messages were dropped from the replay buffer")
+	}
+
+	r.ackLocked(clientAck)
+
+	// Copy the remaining packets.
+	packets := append([]replayPacket(nil), r.packets...)
+
+	// Set the new owner.
+	prevOwner := r.owner
+	r.owner = ss
+	r.suspended = false
+
+	r.mutex.Unlock()
+
+	// The buffer doesn't count to the suspended buffers anymore.
+	replayBuffersMutex.Lock()
+	untrackSuspendedReplayBuffer(r)
+	replayBuffersMutex.Unlock()
+
+	// Close the previous socket. It must not send with this buffer anymore.
+	if prevOwner != nil && prevOwner != ss {
+		prevOwner.socketConn.Close()
+	}
+
+	return r, packets, nil
+}
+
+// suspendReplayBuffer keeps the replay buffer of the instance longer
+// after the socket is closed, because the client resumes it later.
+func suspendReplayBuffer(instanceID string) {
+	// Lock the mutex
+	replayBuffersMutex.Lock()
+	r, ok := replayBuffers[instanceID]
+	replayBuffersMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// Lock the mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.suspended = true
+}
+
+// detach releases the buffer from the socket session.
+// The buffer is removed if no socket session resumes it in time.
+func (r *replayBuffer) detach(ss *socketSession) {
+	// Lock the mutex
+	r.mutex.Lock()
+
+	if r.owner != ss {
+		r.mutex.Unlock()
+		return
+	}
+
+	r.owner = nil
+	r.detachedAt = time.Now()
+	detachedAt := r.detachedAt
+	suspended := r.suspended
+
+	r.mutex.Unlock()
+
+	timeout := replayBufferTimeout
+	if suspended {
+		timeout = time.Duration(settings.Settings.SocketSuspendedReplayTimeout) * time.Second
+
+		// Keep the total size of the suspended buffers bounded.
+		trackSuspendedReplayBuffer(r, detachedAt)
+	}
+
+	time.AfterFunc(timeout, func() {
+		// Lock the mutexes
+		replayBuffersMutex.Lock()
+		defer replayBuffersMutex.Unlock()
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		// Only remove the buffer if it wasn't resumed in the meantime.
+		if r.owner == nil && r.detachedAt == detachedAt && replayBuffers[r.instanceID] == r {
+			delete(replayBuffers, r.instanceID)
+			untrackSuspendedReplayBuffer(r)
+		}
+	})
+}
+
+// add buffers the packet data and returns its sequence number.
+// False is returned if the socket session isn't the owner anymore.
+func (r *replayBuffer) add(ss *socketSession, data []byte) (uint64, bool) {
+	// Lock the mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.owner != ss {
+		return 0, false
+	}
+
+	r.sendSeq++
+	r.packets = append(r.packets, replayPacket{seq: r.sendSeq, data: data})
+	r.size += len(data)
+
+	// Drop the oldest packets if the buffer is full. Keep at least the new one.
+	for r.size > r.maxSize && len(r.packets) > 1 {
+		r.droppedSeq = r.packets[0].seq
+		r.size -= len(r.packets[0].data)
+		r.packets[0].data = nil
+		r.packets = r.packets[1:]
+	}
+
+	return r.sendSeq, true
+}
+
+// ack releases all packets acknowledged by the client.
+func (r *replayBuffer) ack(seq uint64) {
+	// Lock the mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.ackLocked(seq)
+}
+
+// ackLocked releases all packets acknowledged by the client.
+// Be sure to lock the mutex.
+func (r *replayBuffer) ackLocked(seq uint64) {
+	i := 0
+	for ; i < len(r.packets) && r.packets[i].seq <= seq; i++ {
+		r.size -= len(r.packets[i].data)
+		r.packets[i].data = nil
+	}
+
+	r.packets = r.packets[i:]
+}
+
+// receive checks that the client message sequence number is the next one.
+func (r *replayBuffer) receive(seq uint64) error {
+	// Lock the mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if seq != r.recvSeq+1 {
+		return fmt.Errorf("replayed or reordered message: expected sequence number %v but got %v", r.recvSeq+1, seq)
+	}
+
+	r.recvSeq = seq
+
+	return nil
+}
+
+// received returns the last received client message sequence number.
+func (r *replayBuffer) received() uint64 {
+	// Lock the mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.recvSeq
+}
+
+// trackSuspendedReplayBuffer adds the detached buffer to the suspended
+// buffers. The oldest suspended buffers are released if the maximum
+// total size is exceeded. Their clients have to refresh the page.
+func trackSuspendedReplayBuffer(r *replayBuffer, detachedAt time.Time) {
+	// Lock the mutex
+	replayBuffersMutex.Lock()
+	defer replayBuffersMutex.Unlock()
+
+	// Skip if the buffer was resumed or removed in the meantime.
+	r.mutex.Lock()
+	detached := r.owner == nil && r.detachedAt == detachedAt
+	size := r.size
+	r.mutex.Unlock()
+
+	if !detached || replayBuffers[r.instanceID] != r {
+		return
+	}
+
+	untrackSuspendedReplayBuffer(r)
+
+	r.suspendedSize = size
+	suspendedReplayBuffers = append(suspendedReplayBuffers, r)
+	suspendedReplaySize += size
+
+	for suspendedReplaySize > settings.Settings.SocketMaxSuspendedReplaySize && len(suspendedReplayBuffers) > 0 {
+		old := suspendedReplayBuffers[0]
+		untrackSuspendedReplayBuffer(old)
+
+		// Don't remove the buffer if it is resumed right now.
+		old.mutex.Lock()
+		if old.owner == nil && replayBuffers[old.instanceID] == old {
+			delete(replayBuffers, old.instanceID)
+		}
+		old.mutex.Unlock()
+	}
+}
+
+// untrackSuspendedReplayBuffer removes the buffer from the suspended buffers.
+// Be sure to lock the replayBuffersMutex.
+func untrackSuspendedReplayBuffer(r *replayBuffer) {
+	for i, b := range suspendedReplayBuffers {
+		if b != r {
+			continue
+		}
+
+		last := len(suspendedReplayBuffers) - 1
+		copy(suspendedReplayBuffers[i:], suspendedReplayBuffers[i+1:])
+		suspendedReplayBuffers[last] = nil
+		suspendedReplayBuffers = suspendedReplayBuffers[:last]
+		suspendedReplaySize -= r.suspendedSize
+		r.suspendedSize = 0
+		return
+	}
+}
@@ -93,6 +93,7 @@ type Session struct {
 
 	domEncryptionKey string
 	isWebCrawler     bool
+	acceptLanguage   string
 
 	sessionInstance *instance
 
@@ -114,6 +115,29 @@ type Session struct {
 	loadedStyleSheetsMutex sync.Mutex
 
 	navigateMutex sync.Mutex
+
+	// The client tab visibility and the socket idle timer.
+	isHidden        bool
+	idleTimer       *time.Timer
+	visibilityMutex sync.Mutex
+
+	// The time of the last client request.
+	lastActivity      time.Time
+	lastActivityMutex sync.Mutex
+
+	// Dummy sessions aren't connected to any client.
+	isDummy bool
+	iface   Interface
+}
+
+// LastActivity returns the time of the last client request.
+// Pings, acknowledgements and visibility changes don't count as activity.
+func (s *Session) LastActivity() time.Time {
+	// Lock the mutex
+	s.lastActivityMutex.Lock()
+	defer s.lastActivityMutex.Unlock()
+
+	return s.lastActivity
 }
 
 // SessionID returns the session ID.
@@ -142,11 +166,6 @@ func (s *Session) DomEncryptionKey() string {
 	return s.domEncryptionKey
 }
 
-// SendCommand sends a javascript command to the client
-func (s *Session) SendCommand(cmd string) {
-	s.stream.Write(cmd)
-}
-
 // IsClosed returns a boolean indicating if the session socket connection is closed.
 func (s *Session) IsClosed() bool {
 	return s.isClosed
@@ -169,28 +188,38 @@ func (s *Session) RemoteAddr() string {
 	return s.socket.RemoteAddr()
 }
 
+// UserAgent returns the client user agent
+func (s *Session) UserAgent() string {
+	return s.socket.UserAgent()
+}
+
+// AcceptLanguage returns the Accept-Language header of the initial client request.
+func (s *Session) AcceptLanguage() string {
+	return s.acceptLanguage
+}
+
 // IsWebCrawler returns a boolean whenever the client is a web crawler.
 func (s *Session) IsWebCrawler() bool {
 	return s.isWebCrawler
 }
 
 func (s *Session) ShowLoadingIndicator() {
-	s.SendCommand("Bulldozer.loadingIndicator.show();")
+	s.SendMessage(messageTypeLoadingShow, "", nil)
 }
 
 func (s *Session) HideLoadingIndicator() {
-	s.SendCommand("Bulldozer.loadingIndicator.hide();")
+	s.SendMessage(messageTypeLoadingHide, "", nil)
 }
 
 // SetExitMessage sets the exit message which is shown during the page unload.
 func (s *Session) SetExitMessage(msg string) {
-	s.SendCommand("Bulldozer.core.setExitMessage('" + utils.EscapeJS(msg) + "');")
+	s.SendMessage(messageTypeExitMessageSet, "", msg)
 }
 
 // ResetExitMessage resets the exit message.
 // This won't show any message on unload.
 func (s *Session) ResetExitMessage() {
-	s.SendCommand("Bulldozer.core.resetExitMessage();")
+	s.SendMessage(messageTypeExitMessageReset, "", nil)
 }
 
 // IsJavaScriptLoaded returns a boolean if a javascript library is already loaded.
@@ -237,19 +266,12 @@ func (s *Session) LoadJavaScript(url string, vars ...string) {
 		cmd = strings.TrimSpace(strings.Replace(vars[0], "\n", "", -1))
 	}
 
-	// Prepare the final command to send to the client.
-	o := "Bulldozer.core.loadScript('" + utils.EscapeJS(url) + "'"
-
-	// Only add the extra command if set.
-	if len(cmd) > 0 {
-		o += ",function(){" + cmd + "}"
-	}
-
-	// Ending
-	o += ");"
-
-	// Send the command
-	s.SendCommand(o)
+	// Send the message. The optional command
+	// is executed by the client after the load.
+	s.SendMessage(messageTypeLoadScript, "", map[string]string{
+		"url": url,
+		"cmd": cmd,
+	})
 }
 
 // JavaScripts returns a slice of all current loaded session javascripts.
@@ -297,7 +319,7 @@ func (s *Session) LoadStyleSheet(url string) {
 	s.loadedStyleSheetsMutex.Unlock()
 
 	// Send the command to load the stylesheet
-	s.SendCommand("Bulldozer.core.loadStyleSheet('" + utils.EscapeJS(url) + "');")
+	s.SendMessage(messageTypeLoadStyleSheet, "", url)
 }
 
 // StyleSheets returns a slice of all current loaded session stylesheets.
@@ -351,7 +373,7 @@ func (s *Session) Navigate(path string) {
 		defer s.navigateMutex.Unlock()
 
 		// Call the navigate interface function.
-		backendI.NavigateFunc(s, path)
+		s.backend().NavigateFunc(s, path)
 	}()
 }
 
@@ -365,13 +387,13 @@ func (s *Session) NavigateHome() {
 // user is authenticated. The error message will be also logged.
 // One optional boolean can be set. If set to false, the error message won't be logged.
 func (s *Session) ShowErrorPage(errorMessage string, vars ...bool) {
-	backendI.ShowErrorPage(s, errorMessage, vars...)
+	s.backend().ShowErrorPage(s, errorMessage, vars...)
 
 }
 
 // ShowNotFoundPage show the not found page.
 func (s *Session) ShowNotFoundPage() {
-	backendI.ShowNotFoundPage(s)
+	s.backend().ShowNotFoundPage(s)
 }
 
 //######################//
@@ -469,6 +491,11 @@ func Init(i Interface) {
 	// Set the max age in seconds
 	secureCookie.MaxAge(settings.Settings.SessionMaxAge)
 
+	// The socket message authentication relies on an authenticated channel.
+	if !settings.Settings.SecureHttpsAccess || !strings.HasPrefix(settings.Settings.SiteUrl, "https://") {
+		log.L.Warning("[WARNING] sessions: sockets run without TLS! The socket message authentication doesn't protect against man-in-the-middle attacks without TLS!")
+	}
+
 	// Initialize the store package
 	store.Init()
 }
@@ -529,11 +556,12 @@ func New(rw http.ResponseWriter, req *http.Request, isWebCrawler bool, vars ...s
 	// Create a new session with a random socket token
 	s := &Session{
 		path:                          utils.ToPath(req.URL.Path),
-		stream:                        stream.New(),
+		stream:                        stream.New(settings.Settings.SocketMaxBufferSize),
 		storeSession:                  storeSession,
 		stopExpireAccessSocketTimeout: make(chan struct{}),
 		isClosed:                      false,
 		isWebCrawler:                  isWebCrawler,
+		lastActivity:                  time.Now(),
 
 		// Add the static scripts and stylesheets.
 		// They will be loaded always on session initialization.
@@ -573,6 +601,9 @@ func New(rw http.ResponseWriter, req *http.Request, isWebCrawler bool, vars ...s
 	remoteAddr, _ := utils.RemoteAddress(req)
 	userAgent := req.Header.Get("User-Agent")
 
+	// Save the preferred languages of the client.
+	s.acceptLanguage = req.Header.Get("Accept-Language")
+
 	// Set the session socket to a dummy socket.
 	s.socket = socket.NewSocketDummy(remoteAddr, userAgent)
 
@@ -702,3 +733,12 @@ func removeSession(s *Session) {
 		delete(sessions, s.sessionID)
 	}()
 }
+
+// updateLastActivity sets the last activity time to now.
+func (s *Session) updateLastActivity() {
+	// Lock the mutex
+	s.lastActivityMutex.Lock()
+	defer s.lastActivityMutex.Unlock()
+
+	s.lastActivity = time.Now()
+}
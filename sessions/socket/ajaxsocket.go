@@ -19,6 +19,16 @@ import (
 const (
 	ajaxPollTimeout = 35 * time.Second
 
+	// Close the socket if the client doesn't poll the messages in time.
+	ajaxWriteTimeout = ajaxPollTimeout + 10*time.Second
+
+	// Large messages are split into chunks of this size.
+	// Each chunk is sent with a separate poll request.
+	ajaxChunkSize = 256 * 1024
+
+	// This prefix marks a chunk which is followed by further chunks.
+	ajaxChunkPrefix = "+"
+
 	ajaxSocketDataInit = "init"
 )
 
@@ -111,13 +121,49 @@ func (a *AjaxSocket) IsClosed() bool {
 }
 
 func (a *AjaxSocket) Write(data string) {
-	a.writeChannel <- data
+	// Split large messages into chunks.
+	for len(data) > ajaxChunkSize {
+		if !a.write(ajaxChunkPrefix + data[:ajaxChunkSize]) {
+			return
+		}
+
+		data = data[ajaxChunkSize:]
+	}
+
+	a.write(data)
+}
+
+func (a *AjaxSocket) WriteBinary(data []byte) {
+	a.Write(encodeBinary(data))
 }
 
 func (a *AjaxSocket) OnRead(f func(string)) {
 	a.onRead = f
 }
 
+//###############//
+//### Private ###//
+//###############//
+
+// write passes the data to the next poll request.
+// If the client doesn't poll in time, the socket is closed
+// and false is returned.
+func (a *AjaxSocket) write(data string) bool {
+	timeout := time.NewTimer(ajaxWriteTimeout)
+	defer timeout.Stop()
+
+	select {
+	case a.writeChannel <- data:
+		return true
+	case <-a.isClosing:
+		return false
+	case <-timeout.C:
+		log.L.Warning("ajax socket with remote address %s: write timeout: the client falls behind", a.remoteAddr)
+		a.Close()
+		return false
+	}
+}
+
 //####################//
 //### HTTP Handler ###//
 //####################//
@@ -76,4 +76,6 @@ func (s *DummySocket) OnClose(f func()) {
 
 func (s *DummySocket) Write(string) {}
 
+func (s *DummySocket) WriteBinary([]byte) {}
+
 func (s *DummySocket) OnRead(func(string)) {}
@@ -6,6 +6,7 @@
 package socket
 
 import (
+	"encoding/base64"
 	"net/http"
 )
 
@@ -14,6 +15,7 @@ const (
 	TypeDummySocket SocketType = 1 << iota
 	TypeAjaxSocket  SocketType = 1 << iota
 	TypeWebSocket   SocketType = 1 << iota
+	TypeSSESocket   SocketType = 1 << iota
 
 	// Socket keys and sessions
 	keySessionID        = "sid"
@@ -21,6 +23,10 @@ const (
 	valueInvalidSession = "invalid_session"
 	valueInvalidRequest = "invalid_request"
 	keyToken            = "tok"
+
+	// Binary data is base64 encoded and prefixed
+	// with this character on text based sockets.
+	binaryPrefix = "#"
 )
 
 var (
@@ -40,6 +46,10 @@ func InitHttpHandlers() {
 
 	// Create the websocket handler
 	http.HandleFunc("/bulldozer/ws", handleWebSocket)
+
+	// Create the server-sent events handlers
+	http.HandleFunc("/bulldozer/sse", handleSSESocket)
+	http.HandleFunc("/bulldozer/sse/send", handleSSESocketSend)
 }
 
 func OnNewSocketConnection(f func(Socket)) {
@@ -50,6 +60,11 @@ func OnNewSocketConnection(f func(Socket)) {
 //### Private ###//
 //###############//
 
+// encodeBinary encodes the binary data for text based sockets.
+func encodeBinary(data []byte) string {
+	return binaryPrefix + base64.StdEncoding.EncodeToString(data)
+}
+
 func triggerOnNewSocketConnection(s Socket) {
 	if onNewSocketConnectionFunc != nil {
 		onNewSocketConnectionFunc(s)
@@ -69,6 +84,11 @@ type Socket interface {
 	IsClosed() bool
 	OnClose(func())
 
+	// Write sends a text message.
 	Write(data string)
+
+	// WriteBinary sends a binary message.
+	WriteBinary(data []byte)
+
 	OnRead(func(data string))
 }
@@ -0,0 +1,294 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package socket
+
+import (
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/utils"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Send a comment line with this period to keep proxies from closing the stream.
+	sseKeepAlivePeriod = 15 * time.Second
+
+	// The size of the write buffer channel.
+	sseWriteBufferSize = 32
+
+	// Close the socket if the client doesn't read the stream in time.
+	sseWriteTimeout = 30 * time.Second
+
+	sseEventInit = "init"
+)
+
+var (
+	sseSockets map[string]*SSESocket = make(map[string]*SSESocket)
+	sseMutex   sync.Mutex
+)
+
+//######################//
+//### SSESocket Layer ###//
+//######################//
+
+// SSESocket streams the messages to the client with server-sent events.
+// The client sends its messages with POST requests.
+type SSESocket struct {
+	uid string
+
+	isClosed  bool
+	isClosing chan struct{}
+	mutex     sync.Mutex
+
+	writeChannel chan string
+
+	onClose func()
+	onRead  func(string)
+
+	userAgent  string
+	remoteAddr string
+}
+
+func NewSSESocket() *SSESocket {
+	// Create a new sse socket struct
+	return &SSESocket{
+		onRead:       nil,
+		isClosed:     false,
+		onClose:      nil,
+		isClosing:    make(chan struct{}),
+		writeChannel: make(chan string, sseWriteBufferSize),
+	}
+}
+
+func (a *SSESocket) Type() SocketType {
+	return TypeSSESocket
+}
+
+func (a *SSESocket) RemoteAddr() string {
+	return a.remoteAddr
+}
+
+func (a *SSESocket) UserAgent() string {
+	return a.userAgent
+}
+
+func (a *SSESocket) Close() {
+	// Lock the mutex
+	a.mutex.Lock()
+
+	// Just return if the socket is already closed
+	if a.isClosed {
+		// Unlock the mutex again
+		a.mutex.Unlock()
+		return
+	}
+
+	// Update the flag
+	a.isClosed = true
+
+	// Unlock the mutex again
+	a.mutex.Unlock()
+
+	// Stop the streaming goroutine
+	close(a.isClosing)
+
+	// Remove the sse socket from the map
+	sseMutex.Lock()
+	delete(sseSockets, a.uid)
+	sseMutex.Unlock()
+
+	// Trigger the onClose function if defined
+	if a.onClose != nil {
+		a.onClose()
+	}
+}
+
+func (a *SSESocket) OnClose(f func()) {
+	a.onClose = f
+}
+
+func (a *SSESocket) IsClosed() bool {
+	return a.isClosed
+}
+
+func (a *SSESocket) Write(data string) {
+	timeout := time.NewTimer(sseWriteTimeout)
+	defer timeout.Stop()
+
+	// Don't block if the socket is closed.
+	select {
+	case a.writeChannel <- data:
+	case <-a.isClosing:
+	case <-timeout.C:
+		log.L.Warning("sse socket with remote address %s: write timeout: the client falls behind", a.remoteAddr)
+		a.Close()
+	}
+}
+
+func (a *SSESocket) WriteBinary(data []byte) {
+	a.Write(encodeBinary(data))
+}
+
+func (a *SSESocket) OnRead(f func(string)) {
+	a.onRead = f
+}
+
+//####################//
+//### HTTP Handler ###//
+//####################//
+
+// handleSSESocket opens the event stream.
+func handleSSESocket(w http.ResponseWriter, req *http.Request) {
+	// Check for bad requests
+	if req.Method != "GET" {
+		log.L.Warning("client tried to access the sse interface with an invalid http method: %s", req.Method)
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.L.Error("sse socket: the http response writer does not support flushing!")
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	// The request context is canceled as soon as the client disconnects.
+	closeNotify := req.Context().Done()
+
+	// Create a new sse socket struct
+	a := NewSSESocket()
+	a.remoteAddr, _ = utils.RemoteAddress(req)
+	a.userAgent = req.Header.Get("User-Agent")
+
+	// Lock the mutex
+	sseMutex.Lock()
+
+	// Obtain a new unique Id
+	for {
+		// Get a new Id
+		a.uid = utils.RandomString(15)
+
+		// Check if the new Id is already used.
+		// This is very unlikely, but we have to check this!
+		if _, ok := sseSockets[a.uid]; !ok {
+			break
+		}
+	}
+
+	// Add the new sse socket to the map
+	sseSockets[a.uid] = a
+
+	// Unlock the mutex again
+	sseMutex.Unlock()
+
+	// Close the socket as soon as the stream ends.
+	defer a.Close()
+
+	// Set the event stream headers.
+	// Disable the response buffering of proxies.
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+
+	// Tell the client the unique Id
+	writeSSEEvent(w, sseEventInit, a.uid)
+	flusher.Flush()
+
+	// Trigger the new socket connection function
+	triggerOnNewSocketConnection(a)
+
+	keepAlive := time.NewTicker(sseKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-a.writeChannel:
+			writeSSEEvent(w, "", data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			// Send a comment line
+			fmt.Fprint(w, ":\n\n")
+			flusher.Flush()
+		case <-closeNotify:
+			return
+		case <-a.isClosing:
+			return
+		}
+	}
+}
+
+// handleSSESocketSend handles the client messages.
+func handleSSESocketSend(w http.ResponseWriter, req *http.Request) {
+	// Get the body data
+	body, err := ioutil.ReadAll(req.Body)
+
+	// Check for bad requests
+	if err != nil || req.Method != "POST" {
+		log.L.Warning("client tried to access the sse interface with an invalid http method: %s", req.Method)
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	data := string(body)
+
+	// Get the uid from the data string
+	i := strings.Index(data, "&")
+	if i <= 0 {
+		log.L.Warning("client didn't send the sse uid: data: %s", data)
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	uid := data[:i]
+
+	// Remove the uid from the data string
+	data = data[i+1:]
+	if len(data) == 0 {
+		log.L.Warning("client send empty data")
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Obtain the sse socket with the uid
+	sseMutex.Lock()
+	a, ok := sseSockets[uid]
+	sseMutex.Unlock()
+
+	if !ok {
+		log.L.Warning("client requested an invalid sse socket: uid is invalid")
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Trigger the onRead function if defined
+	if a.onRead != nil {
+		a.onRead(data)
+	}
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// writeSSEEvent writes the data as server-sent event.
+// Each line is sent as separate data field.
+func writeSSEEvent(w http.ResponseWriter, event string, data string) {
+	if len(event) > 0 {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+
+	fmt.Fprint(w, "\n")
+}
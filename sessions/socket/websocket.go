@@ -122,6 +122,17 @@ func (w *WebSocket) Write(data string) {
 	}
 }
 
+// WriteBinary sends the binary data to the client
+func (w *WebSocket) WriteBinary(data []byte) {
+	err := w.write(websocket.BinaryMessage, data)
+	if err != nil {
+		log.L.Warning("failed to write to websocket with remote address %s: %s", w.RemoteAddr(), err.Error())
+
+		// Close the websocket on error
+		w.Close()
+	}
+}
+
 func (w *WebSocket) OnRead(f func(string)) {
 	w.onRead = f
 }
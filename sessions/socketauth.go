@@ -0,0 +1,189 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Socket messages are authenticated with a per-socket key.
+// The key is agreed with a Diffie-Hellman key exchange during the
+// socket session initialization. Each message is framed as
+// "mac&seq&data". The HMAC-SHA256 covers the direction, the sequence
+// number and the data. Sequence numbers start at 1 for each direction
+// and have to increase strictly by one. They are handled by the session
+// instance replay buffer and continue across reconnections.
+// Replayed, reordered or forged messages are rejected.
+//
+// The key exchange itself isn't authenticated. An active man-in-the-middle
+// is able to agree separate keys with both sides. The HMAC only guards the
+// integrity and the order of the messages over an already authenticated
+// channel. Always serve the sockets over TLS. A warning is logged on startup
+// if the secure https access isn't enabled.
+
+const (
+	socketAuthPrivateKeyLength = 32
+
+	socketAuthDirectionClient = "c"
+	socketAuthDirectionServer = "s"
+
+	socketAuthDelimiter = "&"
+)
+
+var (
+	// The 2048-bit MODP group of RFC 3526.
+	socketAuthPrime, _ = new(big.Int).SetString(
+		"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
+			"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
+			"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+
+			"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+			"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D"+
+			"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F"+
+			"83655D23DCA3AD961C62F356208552BB9ED529077096966D"+
+			"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+			"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9"+
+			"DE2BCBF6955817183995497CEA956AE515D2261898FA0510"+
+			"15728E5A8AACAA68FFFFFFFFFFFFFFFF", 16)
+
+	socketAuthGenerator = big.NewInt(2)
+
+	// The byte length of the prime.
+	socketAuthPrimeLength = (socketAuthPrime.BitLen() + 7) / 8
+)
+
+//##########################//
+//### Socket Auth struct ###//
+//##########################//
+
+type socketAuth struct {
+	key   []byte
+	mutex sync.Mutex
+}
+
+func newSocketAuth() *socketAuth {
+	return &socketAuth{}
+}
+
+// isReady returns a boolean whenever the key was agreed.
+func (a *socketAuth) isReady() bool {
+	// Lock the mutex
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.key != nil
+}
+
+// exchange derives the key from the hex encoded public value of
+// the client and returns the hex encoded public value of the server.
+func (a *socketAuth) exchange(clientPublic string) (string, error) {
+	// Parse and check the public value of the client.
+	y, ok := new(big.Int).SetString(clientPublic, 16)
+	if !ok {
+		return "", fmt.Errorf("invalid public key")
+	}
+
+	max := new(big.Int).Sub(socketAuthPrime, big.NewInt(1))
+	if y.Cmp(big.NewInt(1)) <= 0 || y.Cmp(max) >= 0 {
+		return "", fmt.Errorf("public key out of range")
+	}
+
+	// Create a random private value.
+	b := make([]byte, socketAuthPrivateKeyLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	x := new(big.Int).SetBytes(b)
+
+	// Calculate the own public value and the shared secret.
+	public := new(big.Int).Exp(socketAuthGenerator, x, socketAuthPrime)
+	shared := new(big.Int).Exp(y, x, socketAuthPrime)
+
+	// The key is the hash of the padded shared secret.
+	secret := make([]byte, socketAuthPrimeLength)
+	sb := shared.Bytes()
+	copy(secret[len(secret)-len(sb):], sb)
+	key := sha256.Sum256(secret)
+
+	// Lock the mutex
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.key = key[:]
+
+	return public.Text(16), nil
+}
+
+// sign frames the data with the sequence number and the message
+// authentication code. False is returned if no key was agreed yet.
+func (a *socketAuth) sign(seqNum uint64, data []byte) ([]byte, bool) {
+	// Lock the mutex
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.key == nil {
+		return nil, false
+	}
+
+	seq := strconv.FormatUint(seqNum, 10)
+	mac := a.mac(socketAuthDirectionServer, seq, data)
+
+	frame := make([]byte, 0, len(mac)+len(seq)+len(data)+2)
+	frame = append(frame, mac...)
+	frame = append(frame, socketAuthDelimiter...)
+	frame = append(frame, seq...)
+	frame = append(frame, socketAuthDelimiter...)
+	frame = append(frame, data...)
+
+	return frame, true
+}
+
+// verify checks the message authentication code of the client message
+// and returns the sequence number and the message data.
+// The caller has to check the sequence number.
+func (a *socketAuth) verify(frame string) (uint64, string, error) {
+	// Split the frame.
+	parts := strings.SplitN(frame, socketAuthDelimiter, 3)
+	if len(parts) != 3 {
+		return 0, "", fmt.Errorf("invalid message frame")
+	}
+
+	// Lock the mutex
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.key == nil {
+		return 0, "", fmt.Errorf("no key agreed")
+	}
+
+	// Check the message authentication code first.
+	if !hmac.Equal([]byte(parts[0]), []byte(a.mac(socketAuthDirectionClient, parts[1], []byte(parts[2])))) {
+		return 0, "", fmt.Errorf("invalid message authentication code")
+	}
+
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid sequence number: %v", err)
+	}
+
+	return seq, parts[2], nil
+}
+
+// mac returns the hex encoded message authentication code.
+// Be sure to lock the mutex.
+func (a *socketAuth) mac(direction, seq string, data []byte) string {
+	h := hmac.New(sha256.New, a.key)
+	h.Write([]byte(direction + seq + socketAuthDelimiter))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
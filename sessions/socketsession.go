@@ -6,21 +6,29 @@
 package sessions
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/sessions/socket"
 	"github.com/desertbit/bulldozer/sessions/stream"
+	"github.com/desertbit/bulldozer/settings"
+	"strconv"
+	"sync"
 	"time"
 )
 
 const (
-	socketKeyPing = "ping"
 	socketKeyPong = "pong"
+	socketKeyAck  = "ack"
 
 	socketKeySessionID = "sid"
 	socketKeyToken     = "tok"
 	socketKeyTask      = "tsk"
+	socketKeyPublicKey = "dh"
 
 	socketValueInvalidRequest = "invalid_request"
+	socketValueRefreshRequest = "req_refresh"
 
 	initTokenDelimiter = "&"
 
@@ -28,6 +36,22 @@ const (
 	pingPeriod = 30 * time.Second
 )
 
+var (
+	pingFrame []byte
+
+	// Serializes the socket type checks of new socket sessions.
+	socketTypeMutex sync.Mutex
+)
+
+func init() {
+	// Encode the ping message frame once.
+	var err error
+	pingFrame, err = encodeMessage(&Message{Type: messageTypePing}, nil)
+	if err != nil {
+		log.L.Fatalf("failed to encode the socket ping message: %v", err)
+	}
+}
+
 //#############################//
 //### Socket Session Struct ###//
 //#############################//
@@ -35,9 +59,19 @@ const (
 type socketSession struct {
 	socketConn socket.Socket
 	session    *Session
-	token      *randomToken
+	auth       *socketAuth
 	stream     *stream.Stream
 
+	// The replay buffer of the session instance.
+	// It is set as soon as the socket session is initialized.
+	replay *replayBuffer
+
+	// The last acknowledged client message sequence number.
+	ackSent uint64
+
+	// Serializes the packets sent to the socket connection.
+	writeMutex sync.Mutex
+
 	pingCount int
 	pingTimer *time.Timer
 
@@ -60,8 +94,8 @@ func onNewSocketConnection(s socket.Socket) {
 	ss := &socketSession{
 		socketConn: s,
 		session:    nil,
-		token:      newRandomToken(),
-		stream:     stream.New(),
+		auth:       newSocketAuth(),
+		stream:     stream.New(settings.Settings.SocketMaxBufferSize),
 
 		pingCount: 0,
 		pingTimer: time.NewTimer(pingPeriod),
@@ -73,6 +107,9 @@ func onNewSocketConnection(s socket.Socket) {
 	s.OnClose(ss.onClose)
 	s.OnRead(ss.onRead)
 
+	// The write loop reads the stream.
+	ss.stream.Attach()
+
 	// Start the goroutine for writing messages to the client
 	go ss.writeLoop()
 }
@@ -95,17 +132,18 @@ func (ss *socketSession) writeLoop() {
 	for {
 		select {
 		case <-ss.stream.HasData:
-			// Create a new token
-			t, ok := ss.token.new()
-			if !ok {
-				log.L.Warning("Closing session %s with remote address '%s' due to flooding attack!", ss.session.SessionID(), ss.socketConn.RemoteAddr())
-				// Immediately close the session. The client tries to flood the server...
+			// Get the buffered message frames
+			frames := ss.stream.Read()
+			if len(frames) == 0 {
+				continue
+			}
+
+			// Sign and send the messages
+			if !ss.sendPacket(bytes.Join(frames, nil)) {
+				log.L.Error("socket session: failed to send messages: the socket session is not initialized or was resumed by another socket!")
 				ss.socketConn.Close()
 				return
 			}
-
-			// Send the new token and the message
-			ss.socketConn.Write(t + "&" + ss.stream.Read())
 		case <-ss.pingTimer.C:
 			// Check if the client didn't respond since the last ping request.
 			if ss.pingCount >= 1 {
@@ -117,18 +155,13 @@ func (ss *socketSession) writeLoop() {
 			// Increment the ping count
 			ss.pingCount += 1
 
-			// Create a new token
-			t, ok := ss.token.new()
-			if !ok {
-				log.L.Warning("Closing session %s with remote address '%s' due to flooding attack!", ss.session.SessionID(), ss.socketConn.RemoteAddr())
-				// Immediately close the session. The client tries to flood the server...
+			// Send the ping request. Close the socket if the
+			// client didn't initialize the session in time.
+			if !ss.sendPacket(pingFrame) {
 				ss.socketConn.Close()
 				return
 			}
 
-			// Send the new token and a ping request
-			ss.socketConn.Write(t + "&" + socketKeyPing)
-
 			// Reset the timer again
 			ss.pingTimer.Reset(pingPeriod)
 		case <-ss.stopWriteLoop:
@@ -138,6 +171,47 @@ func (ss *socketSession) writeLoop() {
 	}
 }
 
+// sendPacket buffers the packet data for replays, signs it with the
+// next sequence number and sends it to the client. Acknowledgements of
+// received client messages are prepended. False is returned if the
+// socket session is not initialized or was resumed by another socket.
+func (ss *socketSession) sendPacket(data []byte) bool {
+	// Lock the mutex
+	ss.writeMutex.Lock()
+	defer ss.writeMutex.Unlock()
+
+	r := ss.replay
+	if r == nil {
+		return false
+	}
+
+	// Acknowledge the received client messages.
+	if recvSeq := r.received(); recvSeq != ss.ackSent {
+		frame, err := encodeMessage(&Message{Type: messageTypeAck, Data: recvSeq}, nil)
+		if err != nil {
+			log.L.Error("socket session: failed to encode acknowledgement: %v", err)
+			return false
+		}
+
+		data = append(frame, data...)
+		ss.ackSent = recvSeq
+	}
+
+	seq, ok := r.add(ss, data)
+	if !ok {
+		return false
+	}
+
+	packet, ok := ss.auth.sign(seq, data)
+	if !ok {
+		return false
+	}
+
+	ss.socketConn.WriteBinary(packet)
+
+	return true
+}
+
 // Send an invalid request to the client and close the socket connection
 func (ss *socketSession) receivedInvalidRequest(closeConn bool) {
 	ss.socketConn.Write(socketValueInvalidRequest)
@@ -151,6 +225,18 @@ func (ss *socketSession) onClose() {
 	// Stop the write messages loop by triggering the quit trigger
 	close(ss.stopWriteLoop)
 
+	// Release all blocked writers
+	ss.stream.Close()
+
+	// Keep the unacknowledged messages for a reconnection.
+	ss.writeMutex.Lock()
+	r := ss.replay
+	ss.writeMutex.Unlock()
+
+	if r != nil {
+		r.detach(ss)
+	}
+
 	// Remove the session if defined
 	if ss.session != nil {
 		removeSession(ss.session)
@@ -165,38 +251,58 @@ func (ss *socketSession) onRead(data string) {
 		}
 	}()
 
-	// Create a data map from the received message
-	m := getDataMap(data)
+	// If no session is set, then try to initialize it
+	if ss.session == nil {
+		m, err := getDataMap(data)
+		if err != nil {
+			log.L.Warning("socket session: invalid initialization request: %v", err)
+			ss.receivedInvalidRequest(true)
+			return
+		}
 
-	// Try to obtain the session Id
-	sid, ok := m[socketKeySessionID]
-	if !ok {
-		log.L.Warning("received an invalid session ID from the client")
-		ss.receivedInvalidRequest(true)
+		ss.initSocketSession(m)
 		return
 	}
 
-	// Try to obtain the temporary token
-	token, ok := m[socketKeyToken]
-	if !ok {
-		log.L.Warning("missing temporary token in client request")
+	// Verify the message authentication code and the sequence number
+	seq, data, err := ss.auth.verify(data)
+	if err == nil {
+		err = ss.replay.receive(seq)
+	}
+	if err != nil {
+		log.L.Warning("socket session: invalid message from remote address '%s': %v", ss.socketConn.RemoteAddr(), err)
 		ss.receivedInvalidRequest(true)
 		return
 	}
 
-	// If no session is set, then try to initialize it
-	if ss.session == nil {
-		ss.initSocketSession(m, sid, token)
+	// Create a data map from the received message
+	m, err := getDataMap(data)
+	if err != nil {
+		log.L.Warning("socket session: invalid request: %v", err)
+		ss.receivedInvalidRequest(true)
 		return
 	}
 
-	// Check if the session matches and if the token is valid
-	if ss.session.sessionID != sid || !ss.token.isTokenValid(token) {
-		log.L.Warning("socket session: the session ID or session token is invalid!")
+	// Check if the session matches
+	sid, ok := m[socketKeySessionID]
+	if !ok || ss.session.sessionID != sid {
+		log.L.Warning("socket session: the session ID is invalid!")
 		ss.receivedInvalidRequest(true)
 		return
 	}
 
+	// Release the server messages acknowledged by the client.
+	if ack, ok := m[socketKeyAck]; ok {
+		n, err := strconv.ParseUint(ack, 10, 64)
+		if err != nil {
+			log.L.Warning("socket session: invalid acknowledgement: %v", err)
+			ss.receivedInvalidRequest(true)
+			return
+		}
+
+		ss.replay.ack(n)
+	}
+
 	// Reset the ping timer
 	ss.resetPingTimer()
 
@@ -208,9 +314,9 @@ func (ss *socketSession) onRead(data string) {
 		return
 	}
 
-	// If this is a pong answer, then just return.
+	// If this is a pong answer or an acknowledgement, then just return.
 	// The timeout timer is already reset
-	if task == socketKeyPong {
+	if task == socketKeyPong || task == socketKeyAck {
 		return
 	}
 
@@ -222,8 +328,13 @@ func (ss *socketSession) onRead(data string) {
 		return
 	}
 
+	// Visibility changes aren't user activity.
+	if task != requestTypeVisibility {
+		ss.session.updateLastActivity()
+	}
+
 	// Call the request function
-	err := request(ss.session, m)
+	err = request(ss.session, m)
 	if err != nil {
 		log.L.Warning("session request '%s': error: %v", task, err)
 		ss.receivedInvalidRequest(false)
@@ -231,7 +342,9 @@ func (ss *socketSession) onRead(data string) {
 	}
 }
 
-func (ss *socketSession) initSocketSession(m map[string]string, sid string, accessToken string) {
+func (ss *socketSession) initSocketSession(m map[string]string) {
+	sid := m[socketKeySessionID]
+	accessToken := m[socketKeyToken]
 	if sid == "" || accessToken == "" {
 		log.L.Warning("invalid session ID '%s' or access token '%s' in client request!", sid, accessToken)
 		ss.receivedInvalidRequest(true)
@@ -262,16 +375,30 @@ func (ss *socketSession) initSocketSession(m map[string]string, sid string, acce
 
 	// Check if the new socket connection has the same socket type
 	// than other active socket connections in the same store session.
-	socketTypeI, _ := s.storeSession.CacheGet(cacheKeySocketType, func() interface{} {
-		return ss.socketConn.Type()
-	})
-	socketType, ok := socketTypeI.(socket.SocketType)
-	if !ok || socketType != ss.socketConn.Type() {
+	if !ss.checkSocketType(s) {
 		log.L.Error("session socket connected with a different socket type than the other active socket sessions: remote address: %s", ss.socketConn.RemoteAddr())
 		ss.receivedInvalidRequest(true)
 		return
 	}
 
+	// Agree on the message authentication key.
+	publicKey, err := ss.auth.exchange(m[socketKeyPublicKey])
+	if err != nil {
+		log.L.Warning("socket session: key exchange failed: %v", err)
+		ss.receivedInvalidRequest(true)
+		return
+	}
+
+	// Resume the session instance messages. The client has to refresh
+	// the page if messages were lost.
+	err = ss.resume(s, publicKey, m[socketKeyAck])
+	if err != nil {
+		log.L.Warning("socket session: failed to resume instance messages: %v", err)
+		ss.socketConn.Write(socketValueRefreshRequest)
+		ss.socketConn.Close()
+		return
+	}
+
 	// Set the session pointer
 	ss.session = s
 
@@ -281,66 +408,105 @@ func (ss *socketSession) initSocketSession(m map[string]string, sid string, acce
 	// Set the new socket stream to the session
 	pStream := s.stream
 	s.stream = ss.stream
+	defer pStream.Close()
 
 	// Set the socket to the session
 	s.socket = ss.socketConn
 
-	// Tell the client the instance ID and the new token
-	ss.socketConn.Write(s.instanceID + initTokenDelimiter + ss.token.get())
-
 	// Write all previous buffered stream data to the new stream
-	data := pStream.Read()
-	if len(data) > 0 {
-		s.stream.Write(data)
+	if err := s.stream.Write(pStream.Read()...); err != nil {
+		log.L.Warning("socket session: failed to pass buffered messages: %v", err)
 	}
 }
 
-// getDataMap creates a data map out of a string.
-// The input string format should be: "key1=data1&key2=data2&"
-// Escaped '\\' and '\&' are replaced with '\' and '&'
-func getDataMap(s string) map[string]string {
-	m := make(map[string]string)
-	var key, data []rune
-	var pp rune
-	isKey := true
-
-	for _, p := range s {
-		if isKey {
-			if p == '=' {
-				isKey = false
-			} else {
-				key = append(key, p)
-			}
-		} else {
-			if p == '\\' && pp == '\\' {
-				// Do nothing here, because '\\' should be tranformed in '\'.
-				// Change the p rune to any character, so that pp won't hold '\'.
-				// This is important for the '&'...
-				p = ' '
-			} else if p == '&' {
-				// Skip escaped '&' characters
-				if pp == '\\' {
-					// Remove the last '\' and replace it by the '&' character
-					data[len(data)-1] = '&'
-				} else {
-					// Return an emtpy map if the key is empty
-					if len(key) == 0 {
-						return make(map[string]string)
-					}
-
-					m[string(key)] = string(data)
-					key = key[:0]
-					data = data[:0]
-					isKey = true
-				}
-			} else {
-				data = append(data, p)
+// checkSocketType checks if the socket type matches the type of the other
+// active socket connections in the same store session. The client falls back
+// to another socket type if its connections fail. This change is accepted,
+// as long as no other socket of the store session is active.
+func (ss *socketSession) checkSocketType(s *Session) bool {
+	// Lock the mutex
+	socketTypeMutex.Lock()
+	defer socketTypeMutex.Unlock()
+
+	socketTypeI, _ := s.storeSession.CacheGet(cacheKeySocketType, func() interface{} {
+		return ss.socketConn.Type()
+	})
+	socketType, ok := socketTypeI.(socket.SocketType)
+	if ok && socketType == ss.socketConn.Type() {
+		return true
+	}
+
+	if hasActiveSocket(s) {
+		return false
+	}
+
+	s.storeSession.CacheSet(cacheKeySocketType, ss.socketConn.Type())
+	return true
+}
+
+// hasActiveSocket returns a boolean indicating if any other session of the
+// same store session is connected with a client socket.
+func hasActiveSocket(s *Session) (active bool) {
+	GetSessions(func(sessions Sessions) {
+		for _, o := range sessions {
+			if o != s && !o.isDummy && !o.IsClosed() &&
+				o.storeSession.ID() == s.storeSession.ID() &&
+				o.SocketType() != socket.TypeDummySocket {
+				active = true
+				return
 			}
 		}
+	})
 
-		// Save the current part to the previous part rune
-		pp = p
+	return
+}
+
+// resume attaches the socket session to the instance replay buffer.
+// The client is told the instance ID, the public key and the last received
+// client message sequence number. Afterwards all messages, which the client
+// hasn't acknowledged, are sent again.
+func (ss *socketSession) resume(s *Session, publicKey string, clientAck string) error {
+	var ack uint64
+	if len(clientAck) > 0 {
+		var err error
+		ack, err = strconv.ParseUint(clientAck, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid acknowledgement: %v", err)
+		}
 	}
 
-	return m
+	// Lock the mutex. Packets must not be sent before the replay.
+	ss.writeMutex.Lock()
+	defer ss.writeMutex.Unlock()
+
+	r, packets, err := resumeReplayBuffer(s.instanceID, ss, ack)
+	if err != nil {
+		return err
+	}
+
+	ss.replay = r
+	ss.ackSent = r.received()
+
+	// Tell the client the instance ID, the public key and the last received message
+	ss.socketConn.Write(s.instanceID + initTokenDelimiter + publicKey +
+		initTokenDelimiter + strconv.FormatUint(ss.ackSent, 10))
+
+	// Replay the messages with the new key
+	for _, p := range packets {
+		packet, ok := ss.auth.sign(p.seq, p.data)
+		if !ok {
+			return fmt.Errorf("no key agreed")
+		}
+
+		ss.socketConn.WriteBinary(packet)
+	}
+
+	return nil
+}
+
+// getDataMap creates a data map out of the JSON object string.
+// All values have to be strings.
+func getDataMap(s string) (m map[string]string, err error) {
+	err = json.Unmarshal([]byte(s), &m)
+	return
 }
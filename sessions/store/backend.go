@@ -0,0 +1,99 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"fmt"
+	"github.com/desertbit/bulldozer/settings"
+)
+
+const (
+	// The available backend names for the settings.
+	BackendBolt   = "bolt"
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+var (
+	backend Backend
+)
+
+//#################//
+//### Interface ###//
+//#################//
+
+// A Backend persists the encoded session values.
+// All methods have to be thread-safe.
+type Backend interface {
+	// Open is called once during the store initialization.
+	Open() error
+
+	// Close is called once during the store release.
+	Close() error
+
+	// Shared returns a boolean whenever the backend
+	// is shared between multiple cluster nodes.
+	Shared() bool
+
+	// Get returns the session data. ErrNotFound has to be
+	// returned if the session does not exists or if it is expired.
+	Get(id string) ([]byte, error)
+
+	// Exists returns a boolean whenever the session ID is used.
+	// Expired sessions which are not removed yet, might be reported as existing.
+	Exists(id string) (bool, error)
+
+	// Save inserts or updates the sessions.
+	Save(sessions []*BackendSession) error
+
+	// Remove deletes the sessions with the given IDs.
+	Remove(ids []string) error
+
+	// Cleanup removes expired sessions. This is called periodically.
+	Cleanup() error
+}
+
+// A BackendSession holds the encoded session values.
+type BackendSession struct {
+	ID   string
+	Data []byte
+
+	// Unix timestamp when the session expires.
+	ExpiresAt int64
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// SetBackend sets a custom session store backend.
+// This has to be called before the bulldozer initialization.
+// Otherwise the backend defined by the settings is used.
+func SetBackend(b Backend) {
+	backend = b
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// newBackendFromSettings creates the backend defined by the settings.
+func newBackendFromSettings() (Backend, error) {
+	switch settings.Settings.SessionsBackend {
+	case BackendBolt, "":
+		return newBoltBackend(settings.Settings.SessionsDatabasePath), nil
+	case BackendMemory:
+		return newMemoryBackend(), nil
+	case BackendRedis:
+		return newRedisBackend(
+			settings.Settings.SessionsRedisAddr,
+			settings.Settings.SessionsRedisPassword,
+			settings.Settings.SessionsRedisDB,
+		), nil
+	default:
+		return nil, fmt.Errorf("invalid sessions backend '%s'", settings.Settings.SessionsBackend)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// testBackendContract checks the behavior every backend has to provide.
+func testBackendContract(t *testing.T, b Backend) {
+	if err := b.Open(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() {
+		if err := b.Close(); err != nil {
+			t.Errorf("close: %v", err)
+		}
+	}()
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	// Not existing sessions.
+	if _, err := b.Get("missing"); err != ErrNotFound {
+		t.Fatalf("get missing session: expected ErrNotFound, got %v", err)
+	}
+	if ok, err := b.Exists("missing"); err != nil || ok {
+		t.Fatalf("exists missing session: got (%v, %v)", ok, err)
+	}
+
+	// Insert.
+	err := b.Save([]*BackendSession{
+		{ID: "a", Data: []byte("data a"), ExpiresAt: expiresAt},
+		{ID: "b", Data: []byte("data b"), ExpiresAt: expiresAt},
+		{ID: "c", Data: []byte("data c"), ExpiresAt: expiresAt},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		data, err := b.Get(id)
+		if err != nil {
+			t.Fatalf("get session '%s': %v", id, err)
+		} else if !bytes.Equal(data, []byte("data "+id)) {
+			t.Fatalf("get session '%s': invalid data '%s'", id, data)
+		}
+
+		if ok, err := b.Exists(id); err != nil || !ok {
+			t.Fatalf("exists session '%s': got (%v, %v)", id, ok, err)
+		}
+	}
+
+	// Update.
+	err = b.Save([]*BackendSession{{ID: "a", Data: []byte("updated"), ExpiresAt: expiresAt}})
+	if err != nil {
+		t.Fatalf("save update: %v", err)
+	}
+	if data, err := b.Get("a"); err != nil || !bytes.Equal(data, []byte("updated")) {
+		t.Fatalf("get updated session: got ('%s', %v)", data, err)
+	}
+
+	// Expired sessions are not returned.
+	err = b.Save([]*BackendSession{{ID: "b", Data: []byte("expired"), ExpiresAt: time.Now().Unix() - 1}})
+	if err != nil {
+		t.Fatalf("save expired: %v", err)
+	}
+	if _, err := b.Get("b"); err != ErrNotFound {
+		t.Fatalf("get expired session: expected ErrNotFound, got %v", err)
+	}
+
+	// Remove.
+	if err = b.Remove([]string{"a", "missing"}); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err = b.Remove(nil); err != nil {
+		t.Fatalf("remove nothing: %v", err)
+	}
+	if _, err := b.Get("a"); err != ErrNotFound {
+		t.Fatalf("get removed session: expected ErrNotFound, got %v", err)
+	}
+	if ok, err := b.Exists("a"); err != nil || ok {
+		t.Fatalf("exists removed session: got (%v, %v)", ok, err)
+	}
+
+	// Cleanup must keep valid sessions.
+	if err = b.Cleanup(); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if _, err := b.Get("b"); err != ErrNotFound {
+		t.Fatalf("get expired session after cleanup: expected ErrNotFound, got %v", err)
+	}
+	if data, err := b.Get("c"); err != nil || !bytes.Equal(data, []byte("data c")) {
+		t.Fatalf("get session after cleanup: got ('%s', %v)", data, err)
+	}
+}
+
+func TestMemoryBackendContract(t *testing.T) {
+	testBackendContract(t, newMemoryBackend())
+}
+
+func TestMemoryBackendExpiry(t *testing.T) {
+	b := newMemoryBackend()
+	now := time.Now().Unix()
+
+	err := b.Save([]*BackendSession{
+		{ID: "expired", Data: []byte("expired"), ExpiresAt: now - 1},
+		{ID: "now", Data: []byte("now"), ExpiresAt: now},
+		{ID: "valid", Data: []byte("valid"), ExpiresAt: now + 3600},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id            string
+		get           bool
+		existsBefore  bool
+		existsAfterGC bool
+	}{
+		{id: "expired", get: false, existsBefore: true, existsAfterGC: false},
+		{id: "now", get: false, existsBefore: true, existsAfterGC: false},
+		{id: "valid", get: true, existsBefore: true, existsAfterGC: true},
+	}
+
+	// Expired sessions are reported as existing until they are removed.
+	for _, test := range tests {
+		_, err := b.Get(test.id)
+		if (err == nil) != test.get {
+			t.Errorf("get '%s': unexpected error: %v", test.id, err)
+		}
+
+		if ok, _ := b.Exists(test.id); ok != test.existsBefore {
+			t.Errorf("exists '%s' before cleanup: got %v", test.id, ok)
+		}
+	}
+
+	if err = b.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		if ok, _ := b.Exists(test.id); ok != test.existsAfterGC {
+			t.Errorf("exists '%s' after cleanup: got %v", test.id, ok)
+		}
+	}
+}
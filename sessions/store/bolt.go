@@ -0,0 +1,290 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"code.google.com/p/gogoprotobuf/proto"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions/store/protobuf"
+	"time"
+)
+
+const (
+	boltOpenTimeout = 5 * time.Second
+
+	boltCleanupBatchSize = 100
+
+	boltBucketName = "s"
+)
+
+var (
+	boltBucketNameBytes = []byte(boltBucketName)
+)
+
+//####################//
+//### Bolt Backend ###//
+//####################//
+
+// boltBackend is the default backend. It stores the sessions
+// wrapped in protobuf values to a local bolt database file.
+type boltBackend struct {
+	path string
+	db   *bolt.DB
+
+	// The previous database iteration key for scanning for expired sessions
+	prevExpiredScanKey []byte
+}
+
+func newBoltBackend(path string) *boltBackend {
+	return &boltBackend{
+		path: path,
+	}
+}
+
+func (b *boltBackend) Open() (err error) {
+	// The bolt database options.
+	opts := &bolt.Options{
+		Timeout: boltOpenTimeout,
+	}
+
+	// Open the sessions database file.
+	// It will be created if it doesn't exist.
+	b.db, err = bolt.Open(b.path, 0600, opts)
+	if err != nil {
+		return fmt.Errorf("failed to open sessions database '%s': %v", b.path, err)
+	}
+
+	// Create the bucket if not already exists
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucketNameBytes)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the sessions database bucket: %v", err)
+	}
+
+	return nil
+}
+
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
+
+func (b *boltBackend) Shared() bool {
+	return false
+}
+
+func (b *boltBackend) Get(id string) (data []byte, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		// Get the bucket
+		bucket := tx.Bucket(boltBucketNameBytes)
+		if bucket == nil {
+			return fmt.Errorf("no bucket '%s' found!", boltBucketName)
+		}
+
+		// Obtain the session data
+		v := bucket.Get([]byte(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		// Get the proto session value from the session data
+		protoSession, err := getProtoSession(v)
+		if err != nil {
+			return err
+		}
+
+		// Check if the session is expired
+		if protoSessionExpired(protoSession) {
+			// This session is expired. Just return a not found error.
+			// The cleanup loop will handle deletion of it.
+			return ErrNotFound
+		}
+
+		// Copy the values, because this data is
+		// not safe outside of this transaction.
+		values := protoSession.GetValues()
+		data = make([]byte, len(values))
+		copy(data, values)
+
+		return nil
+	})
+
+	return
+}
+
+func (b *boltBackend) Exists(id string) (exists bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		// Get the bucket
+		bucket := tx.Bucket(boltBucketNameBytes)
+		if bucket == nil {
+			return fmt.Errorf("no bucket '%s' found!", boltBucketName)
+		}
+
+		// Try to obtain the session data
+		exists = bucket.Get([]byte(id)) != nil
+
+		return nil
+	})
+
+	return
+}
+
+func (b *boltBackend) Save(sessions []*BackendSession) error {
+	// Wrap the session data into proto sessions.
+	values := make([][]byte, len(sessions))
+	for i, s := range sessions {
+		expiresAt := s.ExpiresAt
+
+		// Create a new proto session
+		protoSession := &protobuf.Session{
+			Values:    s.Data,
+			ExpiresAt: &expiresAt,
+		}
+
+		// Marshal the proto session to a bytes slice
+		data, err := proto.Marshal(protoSession)
+		if err != nil {
+			return err
+		}
+
+		values[i] = data
+	}
+
+	// Now save everything to the database
+	return b.db.Update(func(tx *bolt.Tx) error {
+		// Get the bucket
+		bucket := tx.Bucket(boltBucketNameBytes)
+		if bucket == nil {
+			return fmt.Errorf("no bucket '%s' found!", boltBucketName)
+		}
+
+		// Save all the sessions data
+		for i, s := range sessions {
+			err := bucket.Put([]byte(s.ID), values[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *boltBackend) Remove(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		// Get the bucket
+		bucket := tx.Bucket(boltBucketNameBytes)
+		if bucket == nil {
+			return fmt.Errorf("no bucket '%s' found!", boltBucketName)
+		}
+
+		// Remove all sessions in the slice
+		for _, id := range ids {
+			err := bucket.Delete([]byte(id))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Cleanup scans a batch of sessions for expired sessions and removes them.
+// The next call continues the scan with the next batch.
+func (b *boltBackend) Cleanup() error {
+	var expiredSessionIDs []string
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		// Get the bucket
+		bucket := tx.Bucket(boltBucketNameBytes)
+		if bucket == nil {
+			return fmt.Errorf("no bucket '%s' found!", boltBucketName)
+		}
+
+		c := bucket.Cursor()
+		i := 0
+		var isExpired bool
+
+		for k, v := c.Seek(b.prevExpiredScanKey); ; k, v = c.Next() {
+			// If we hit the end of our sessions then
+			// exit and start over next time.
+			if k == nil {
+				b.prevExpiredScanKey = nil
+				return nil
+			}
+
+			// Increment the counter
+			i++
+
+			// The flag if the session is expired
+			isExpired = false
+
+			// Get the proto session value from the session data
+			// and check if the session is expired.
+			protoSession, err := getProtoSession(v)
+			if err != nil {
+				// Just remove the session with the invalid session data.
+				// Log the error first.
+				log.L.Error("session store: removing session from database with invalid value: %v", err)
+				isExpired = true
+			} else if protoSessionExpired(protoSession) {
+				isExpired = true
+			}
+
+			if isExpired {
+				// Add it to the expired sessios IDs slice.
+				// The string conversion copies the key.
+				expiredSessionIDs = append(expiredSessionIDs, string(k))
+			}
+
+			if i >= boltCleanupBatchSize {
+				// Store the current key to the previous key.
+				// Copy the byte slice key, because this data is
+				// not safe outside of this transaction.
+				b.prevExpiredScanKey = make([]byte, len(k))
+				copy(b.prevExpiredScanKey, k)
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("obtain expired sessions error: %v", err)
+	}
+
+	// Remove the expired sessions from the database
+	return b.Remove(expiredSessionIDs)
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// getProtoSession converts the byte slice to the proto session struct
+func getProtoSession(data []byte) (s *protobuf.Session, err error) {
+	s = &protobuf.Session{}
+	err = proto.Unmarshal(data, s)
+	return
+}
+
+// protoSessionExpired checks if the session is expired.
+func protoSessionExpired(s *protobuf.Session) bool {
+	expiresAt := s.GetExpiresAt()
+
+	// The session is expired if the value is invalid
+	if expiresAt <= 0 {
+		return true
+	}
+
+	return expiresAt <= time.Now().Unix()
+}
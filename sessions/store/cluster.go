@@ -0,0 +1,143 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"github.com/desertbit/bulldozer/cluster"
+	"github.com/desertbit/bulldozer/log"
+)
+
+// Sessions are replicated to all cluster nodes. This way a reconnect
+// can land on any node. Saved sessions are pushed to the other nodes,
+// which store them in their own backend and release any unlocked
+// cached copy. Shared backends skip the replication and only release
+// the cached copies. If the same session is changed on multiple nodes,
+// the last saved change wins.
+
+const (
+	clusterChannel = "sessions.store"
+)
+
+func init() {
+	cluster.Subscribe(clusterChannel, onClusterMessage)
+}
+
+//#####################//
+//### Private types ###//
+//#####################//
+
+type clusterMessage struct {
+	Saved   []*BackendSession
+	Removed []string
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// publishSavedSessions sends the saved sessions to all other cluster nodes.
+func publishSavedSessions(saved []*BackendSession) {
+	if len(saved) == 0 || !cluster.IsEnabled() {
+		return
+	}
+
+	m := &clusterMessage{
+		Saved: saved,
+	}
+
+	if err := cluster.Publish(clusterChannel, m); err != nil {
+		log.L.Error("session store: %v", err)
+	}
+}
+
+// publishRemovedSession tells all other cluster nodes to remove the session.
+func publishRemovedSession(id string) {
+	if !cluster.IsEnabled() {
+		return
+	}
+
+	m := &clusterMessage{
+		Removed: []string{id},
+	}
+
+	if err := cluster.Publish(clusterChannel, m); err != nil {
+		log.L.Error("session store: %v", err)
+	}
+}
+
+func onClusterMessage(data []byte) {
+	// Skip if the backend is not initialized.
+	if backend == nil {
+		return
+	}
+
+	var m clusterMessage
+	if err := cluster.Decode(data, &m); err != nil {
+		log.L.Error("session store: failed to decode cluster message: %v", err)
+		return
+	}
+
+	if len(m.Saved) > 0 {
+		// Save the replicated sessions to the backend.
+		// Shared backends already contain the sessions.
+		if !backend.Shared() {
+			if err := backend.Save(m.Saved); err != nil {
+				log.L.Error("session store: failed to save replicated sessions: %v", err)
+				return
+			}
+		}
+
+		// Release the outdated cached sessions.
+		// They will be reloaded from the database on the next access.
+		for _, s := range m.Saved {
+			releaseCachedSession(s.ID, false)
+		}
+	}
+
+	for _, id := range m.Removed {
+		// Invalidate the cached session and remove it from the backend.
+		releaseCachedSession(id, true)
+		removeSessionFromDB(id)
+	}
+}
+
+// releaseCachedSession removes the session from the cache if not locked.
+// If invalidate is true, then the session is always removed from the
+// cache and flagged as invalid. It won't be saved anymore.
+func releaseCachedSession(id string, invalidate bool) {
+	// Get the cached session.
+	mutex.Lock()
+	s, ok := sessions[id]
+	mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// Lock the session mutex to access the lock count variable
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if invalidate {
+		s.valid = false
+	} else if s.lockCount != 0 {
+		// The session is in use by this node.
+		return
+	}
+
+	// Set the lockCount to -1, which indicates, that
+	// this session is released from cache.
+	s.lockCount = -1
+
+	// Lock the main mutex for the sessions map
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Delete the session from the map if not replaced in the meantime.
+	if cur, ok := sessions[id]; ok && cur == s {
+		delete(sessions, id)
+	}
+}
@@ -7,26 +7,18 @@ package store
 
 import (
 	"bytes"
-	"code.google.com/p/gogoprotobuf/proto"
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"github.com/boltdb/bolt"
 	"github.com/desertbit/bulldozer/log"
-	"github.com/desertbit/bulldozer/sessions/store/protobuf"
 	"github.com/desertbit/bulldozer/settings"
 	"sync"
 	"time"
 )
 
 const (
-	openTimeout           = 5 * time.Second
 	saveLoopTimeout       = 1 * time.Minute
 	cleanupExpiredTimeout = 2 * time.Minute
-
-	cleanupExpiredSessionsBatchSize = 100
-
-	bucketName = "s"
 )
 
 var (
@@ -34,15 +26,9 @@ var (
 	ErrNotFound = errors.New("store: the session with the corresponding ID does not exists")
 
 	// Private
-	db              *bolt.DB
-	bucketNameBytes = []byte(bucketName)
-
 	stopSaveLoop      chan struct{} = make(chan struct{})
 	stopCleanupDBLoop chan struct{} = make(chan struct{})
 
-	// The previous database iteration key for scanning for expired sessions
-	prevExpiredScanKey []byte
-
 	changedSessions      map[string]*Session = make(map[string]*Session)
 	changedSessionsMutex sync.Mutex
 
@@ -57,54 +43,30 @@ var (
 // Init initializes this store package.
 // This is handled by the main bulldozer package.
 func Init() {
-	// The bolt database options.
-	opts := &bolt.Options{
-		Timeout: openTimeout,
-	}
-
-	// Open the sessions database file.
-	// It will be created if it doesn't exist.
-	var err error
-	db, err = bolt.Open(settings.Settings.SessionsDatabasePath, 0600, opts)
-	if err != nil {
-		log.L.Fatalf("failed to open sessions database '%s': %v", settings.Settings.SessionsDatabasePath, err)
+	// Create the backend defined by the settings
+	// if no custom backend is set.
+	if backend == nil {
+		var err error
+		backend, err = newBackendFromSettings()
+		if err != nil {
+			log.L.Fatalf("session store: %v", err)
+		}
 	}
 
-	// Create the bucket if not already exists
-	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucketNameBytes)
-		return err
-	})
-	if err != nil {
-		log.L.Fatalf("failed to create the sessions database bucket: %v", err)
+	// Open the backend.
+	if err := backend.Open(); err != nil {
+		log.L.Fatalf("session store: %v", err)
 	}
 
 	// Start the loops in a new goroutine
 	go saveLoop()
 	go cleanupDBLoop()
-
-	/* Hint: For debugging purpose
-	go func() {
-		for {
-			db.View(func(tx *bolt.Tx) error {
-				b := tx.Bucket(bucketNameBytes)
-				b.ForEach(func(k, v []byte) error {
-					fmt.Printf("key=%s, value size=%v\n", k, len(v))
-					return nil
-				})
-				return nil
-			})
-			fmt.Println("=====================================")
-			time.Sleep(5 * time.Second)
-		}
-	}()
-	*/
 }
 
 // Release releases this store package.
 // This is handled by the main bulldozer package.
 func Release() {
-	if db == nil {
+	if backend == nil {
 		return
 	}
 
@@ -118,23 +80,20 @@ func Release() {
 	// Remove all the manual removed sessions without scanning for expired sessions
 	cleanupDBSessions(true)
 
-	// Close the database on exit
-	db.Close()
+	// Close the backend on exit
+	if err := backend.Close(); err != nil {
+		log.L.Error("session store: failed to close backend: %v", err)
+	}
 }
 
 //###############//
 //### Private ###//
 //###############//
 
-type dbSessionBuffer struct {
-	id    []byte
-	value []byte
-}
-
 // registerChangedSession notifies the daemon to save the sessions' changes
 func registerChangedSession(s *Session) {
-	// Check if aready registered as dirty.
-	if s.dirty {
+	// Check if aready registered as dirty or if this is a temporary session.
+	if s.dirty || s.temporary {
 		return
 	}
 
@@ -179,6 +138,9 @@ func saveUnsavedSessions() {
 		return
 	}
 
+	// The successfully saved sessions.
+	var saved []*BackendSession
+
 	err := func() (err error) {
 		// Lock the mutex
 		changedSessionsMutex.Lock()
@@ -193,13 +155,13 @@ func saveUnsavedSessions() {
 			return nil
 		}
 
-		// Create a temporary database buffer for the batched write procedure
-		var dbBuffer []dbSessionBuffer
+		// Create a temporary buffer for the batched write procedure
+		var buffer []*BackendSession
 
 		// Create the expire timestamp
 		expiresAt := time.Now().Unix() + int64(settings.Settings.SessionMaxAge)
 
-		// Iterate over all changed session and save them to the database
+		// Iterate over all changed session and save them to the backend
 		for _, s := range changedSessions {
 			// Skip if this session is flagged as invalid
 			if !s.valid {
@@ -227,44 +189,28 @@ func saveUnsavedSessions() {
 				return
 			}
 
-			// Create a new proto session
-			protoSession := &protobuf.Session{
-				Values:    buf.Bytes(),
-				ExpiresAt: &expiresAt,
-			}
-
-			// Marshal the proto session to a bytes slice
-			var data []byte
-			data, err = proto.Marshal(protoSession)
-			if err != nil {
-				return
-			}
-
-			// Add the data to the temporary database buffer
-			dbBuffer = append(dbBuffer, dbSessionBuffer{[]byte(s.id), data})
+			// Add the data to the temporary buffer
+			buffer = append(buffer, &BackendSession{
+				ID:        s.id,
+				Data:      buf.Bytes(),
+				ExpiresAt: expiresAt,
+			})
 		}
 
 		// Clear the changed sessions map
 		changedSessions = make(map[string]*Session)
 
-		// Now save everything to the database
-		err = db.Update(func(tx *bolt.Tx) (err error) {
-			// Get the bucket
-			b := tx.Bucket(bucketNameBytes)
-			if b == nil {
-				return fmt.Errorf("no bucket '%s' found!", bucketName)
-			}
+		// Now save everything to the backend
+		if len(buffer) == 0 {
+			return nil
+		}
 
-			// Save all the buffered sessions data
-			for _, buf := range dbBuffer {
-				err = b.Put(buf.id, buf.value)
-				if err != nil {
-					return err
-				}
-			}
+		err = backend.Save(buffer)
+		if err != nil {
+			return
+		}
 
-			return nil
-		})
+		saved = buffer
 
 		return
 	}()
@@ -272,6 +218,9 @@ func saveUnsavedSessions() {
 	if err != nil {
 		log.L.Error("sessions database save error: %v", err)
 	}
+
+	// Replicate the saved sessions to the other cluster nodes.
+	publishSavedSessions(saved)
 }
 
 func cleanupDBLoop() {
@@ -296,118 +245,31 @@ func cleanupDBLoop() {
 }
 
 func cleanupDBSessions(skipExpiredSessions bool) {
-	var err error
-	expiredSessionIDs := make([][]byte, 0)
-
 	if !skipExpiredSessions {
-		// Cleanup all expired database sessions
-		err = db.View(func(tx *bolt.Tx) error {
-			// Get the bucket
-			b := tx.Bucket(bucketNameBytes)
-			if b == nil {
-				return fmt.Errorf("no bucket '%s' found!", bucketName)
-			}
-
-			c := b.Cursor()
-			i := 0
-			var isExpired bool
-
-			for k, v := c.Seek(prevExpiredScanKey); ; k, v = c.Next() {
-				// If we hit the end of our sessions then
-				// exit and start over next time.
-				if k == nil {
-					prevExpiredScanKey = nil
-					return nil
-				}
-
-				// Increment the counter
-				i++
-
-				// The flag if the session is expired
-				isExpired = false
-
-				// Get the proto session value from the session data
-				// and check if the session is expired.
-				protoSession, err := getProtoSession(v)
-				if err != nil {
-					// Just remove the session with the invalid session data.
-					// Log the error first.
-					log.L.Error("session store: removing session from database with invalid value: %v", err)
-					isExpired = true
-				} else if protoSessionExpired(protoSession) {
-					isExpired = true
-				}
-
-				if isExpired {
-					// Copy the byte slice key, because this data is
-					// not safe outside of this transaction.
-					temp := make([]byte, len(k))
-					copy(temp, k)
-
-					// Add it to the expired sessios IDs slice
-					expiredSessionIDs = append(expiredSessionIDs, temp)
-				}
-
-				if i >= cleanupExpiredSessionsBatchSize {
-					// Store the current key to the previous key.
-					// Copy the byte slice key, because this data is
-					// not safe outside of this transaction.
-					prevExpiredScanKey = make([]byte, len(k))
-					copy(prevExpiredScanKey, k)
-					return nil
-				}
-			}
-
-			return nil
-		})
-
-		if err != nil {
-			log.L.Error("sessions database: obtain expired sessions error: %v", err)
+		// Cleanup all expired backend sessions
+		if err := backend.Cleanup(); err != nil {
+			log.L.Error("sessions database: cleanup expired sessions error: %v", err)
 		}
 	}
 
-	// Add all session IDs to the expired map,
-	// which should be removed from the database.
-	if len(removeSessionIDs) > 0 {
-		// Lock the mutex
-		removeSessionIDsMutex.Lock()
-		// Unlock it first after the database transaction,
-		// to be really sure, that no parallel getSessionFromDB()
-		// call is retrieving a deleted session.
-		defer removeSessionIDsMutex.Unlock()
-
-		for _, id := range removeSessionIDs {
-			expiredSessionIDs = append(expiredSessionIDs, []byte(id))
-		}
+	// Lock the mutex
+	removeSessionIDsMutex.Lock()
+	// Unlock it first after the backend operation,
+	// to be really sure, that no parallel getSessionFromDB()
+	// call is retrieving a deleted session.
+	defer removeSessionIDsMutex.Unlock()
 
-		// Clear the slice again
-		removeSessionIDs = nil
+	if len(removeSessionIDs) == 0 {
+		return
 	}
 
-	if len(expiredSessionIDs) > 0 {
-		// Remove the expired sessions from the database
-		err = db.Update(func(tx *bolt.Tx) error {
-			// Get the bucket
-			b := tx.Bucket(bucketNameBytes)
-			if b == nil {
-				return fmt.Errorf("no bucket '%s' found!", bucketName)
-			}
-
-			// Remove all expired sessions in the slice
-			for _, id := range expiredSessionIDs {
-				err = b.Delete(id)
-				if err != nil {
-					return err
-				}
-			}
-
-			return nil
-		})
-
-		if err != nil {
-			log.L.Error("sessions database: remove expired sessions error: %v", err)
-		}
+	// Remove all manual removed sessions from the backend
+	if err := backend.Remove(removeSessionIDs); err != nil {
+		log.L.Error("sessions database: remove sessions error: %v", err)
 	}
+
+	// Clear the slice again
+	removeSessionIDs = nil
 }
 
 func getSessionFromDB(id string) (*Session, error) {
@@ -433,51 +295,20 @@ func getSessionFromDB(id string) (*Session, error) {
 		return s, nil
 	}
 
-	var values map[interface{}]interface{}
-
-	// Try to obtain the session from the database
-	err := db.View(func(tx *bolt.Tx) error {
-		idb := []byte(id)
-
-		// Get the bucket
-		b := tx.Bucket(bucketNameBytes)
-		if b == nil {
-			return fmt.Errorf("no bucket '%s' found!", bucketName)
-		}
-
-		// Obtain the session data
-		data := b.Get(idb)
-		if data == nil {
-			return ErrNotFound
-		}
-
-		// Get the proto session value from the session data
-		protoSession, err := getProtoSession(data)
-		if err != nil {
-			return err
-		}
-
-		// Check if the session is expired
-		if protoSessionExpired(protoSession) {
-			// This session is expired. Just return a not found error.
-			// The cleanupExpiredLoop will handle deletion of it.
-			return ErrNotFound
-		}
-
-		// Decode the session data and set the values map
-		dec := gob.NewDecoder(bytes.NewBuffer(protoSession.GetValues()))
-		err = dec.Decode(&values)
-		if err != nil {
-			return fmt.Errorf("failed to gob decode session database values: %v", err)
-		}
-
-		return nil
-	})
-
+	// Try to obtain the session from the backend
+	data, err := backend.Get(id)
 	if err != nil {
 		return nil, err
 	}
 
+	// Decode the session data and set the values map
+	var values map[interface{}]interface{}
+	dec := gob.NewDecoder(bytes.NewBuffer(data))
+	err = dec.Decode(&values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gob decode session database values: %v", err)
+	}
+
 	// Create a new session and set the values map
 	s := &Session{
 		id:          id,
@@ -532,44 +363,6 @@ func getChangedSession(id string) (s *Session, ok bool) {
 	return
 }
 
-func sessionIDExistsInDB(id string) (exists bool, err error) {
-	exists = true
-
-	// Check if the ID exists in the database
-	err = db.View(func(tx *bolt.Tx) error {
-		// Get the bucket
-		b := tx.Bucket(bucketNameBytes)
-		if b == nil {
-			return fmt.Errorf("no bucket '%s' found!", bucketName)
-		}
-
-		// Try to obtain the session data
-		data := b.Get([]byte(id))
-		if data == nil {
-			exists = false
-		}
-
-		return nil
-	})
-
-	return
-}
-
-// getProtoSession converts the byte slice to the proto session struct
-func getProtoSession(data []byte) (s *protobuf.Session, err error) {
-	s = &protobuf.Session{}
-	err = proto.Unmarshal(data, s)
-	return
-}
-
-// protoSessionExpired checks if the session is expired.
-func protoSessionExpired(s *protobuf.Session) bool {
-	expiresAt := s.GetExpiresAt()
-
-	// The session is expired if the value is invalid
-	if expiresAt <= 0 {
-		return true
-	}
-
-	return expiresAt <= time.Now().Unix()
+func sessionIDExistsInDB(id string) (bool, error) {
+	return backend.Exists(id)
 }
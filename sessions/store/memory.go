@@ -0,0 +1,102 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+//######################//
+//### Memory Backend ###//
+//######################//
+
+// memoryBackend keeps all sessions in memory.
+// The sessions are lost on exit. This is useful for tests.
+type memoryBackend struct {
+	sessions map[string]*BackendSession
+	mutex    sync.Mutex
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		sessions: make(map[string]*BackendSession),
+	}
+}
+
+func (b *memoryBackend) Open() error {
+	return nil
+}
+
+func (b *memoryBackend) Close() error {
+	return nil
+}
+
+func (b *memoryBackend) Shared() bool {
+	return false
+}
+
+func (b *memoryBackend) Get(id string) ([]byte, error) {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s, ok := b.sessions[id]
+	if !ok || s.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrNotFound
+	}
+
+	return s.Data, nil
+}
+
+func (b *memoryBackend) Exists(id string) (bool, error) {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_, ok := b.sessions[id]
+	return ok, nil
+}
+
+func (b *memoryBackend) Save(sessions []*BackendSession) error {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, s := range sessions {
+		b.sessions[s.ID] = s
+	}
+
+	return nil
+}
+
+func (b *memoryBackend) Remove(ids []string) error {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, id := range ids {
+		delete(b.sessions, id)
+	}
+
+	return nil
+}
+
+func (b *memoryBackend) Cleanup() error {
+	now := time.Now().Unix()
+
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for id, s := range b.sessions {
+		if s.ExpiresAt <= now {
+			delete(b.sessions, id)
+		}
+	}
+
+	return nil
+}
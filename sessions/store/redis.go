@@ -0,0 +1,306 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	redisKeyPrefix   = "bud:session:"
+	redisDialTimeout = 5 * time.Second
+	redisIOTimeout   = 10 * time.Second
+)
+
+//#####################//
+//### Redis Backend ###//
+//#####################//
+
+// redisBackend stores the sessions on a server speaking the redis protocol.
+// The server is shared between all cluster nodes. Expired sessions
+// are removed by the server itself.
+type redisBackend struct {
+	addr     string
+	password string
+	db       int
+
+	conn   net.Conn
+	reader *bufio.Reader
+	mutex  sync.Mutex
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func newRedisBackend(addr, password string, db int) *redisBackend {
+	return &redisBackend{
+		addr:     addr,
+		password: password,
+		db:       db,
+	}
+}
+
+func (b *redisBackend) Open() error {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Connect to check if the server is reachable.
+	if err := b.connect(); err != nil {
+		return fmt.Errorf("failed to connect to the sessions redis server '%s': %v", b.addr, err)
+	}
+
+	return nil
+}
+
+func (b *redisBackend) Close() error {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.disconnect()
+	return nil
+}
+
+func (b *redisBackend) Shared() bool {
+	return true
+}
+
+func (b *redisBackend) Get(id string) ([]byte, error) {
+	r, err := b.do("GET", redisKeyPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A nil reply is returned for not existing or expired keys.
+	data, ok := r.([]byte)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (b *redisBackend) Exists(id string) (bool, error) {
+	r, err := b.do("EXISTS", redisKeyPrefix+id)
+	if err != nil {
+		return true, err
+	}
+
+	n, _ := r.(int64)
+	return n > 0, nil
+}
+
+func (b *redisBackend) Save(sessions []*BackendSession) error {
+	now := time.Now().Unix()
+
+	for _, s := range sessions {
+		var err error
+
+		// Let the server handle the expiration.
+		ttl := s.ExpiresAt - now
+		if ttl <= 0 {
+			_, err = b.do("DEL", redisKeyPrefix+s.ID)
+		} else {
+			_, err = b.do("SET", redisKeyPrefix+s.ID, s.Data, "EX", strconv.FormatInt(ttl, 10))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *redisBackend) Remove(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(ids)+1)
+	args[0] = "DEL"
+	for i, id := range ids {
+		args[i+1] = redisKeyPrefix + id
+	}
+
+	_, err := b.do(args...)
+	return err
+}
+
+func (b *redisBackend) Cleanup() error {
+	// Expired keys are removed by the server.
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// do sends the command and returns the reply.
+// Arguments have to be strings or byte slices.
+// A broken connection is reconnected once.
+func (b *redisBackend) do(args ...interface{}) (interface{}, error) {
+	// Lock the mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	r, err := b.doUnlocked(args...)
+	if err == nil {
+		return r, nil
+	} else if _, ok := err.(redisError); ok {
+		return nil, err
+	}
+
+	// Retry once with a new connection.
+	b.disconnect()
+	return b.doUnlocked(args...)
+}
+
+func (b *redisBackend) doUnlocked(args ...interface{}) (interface{}, error) {
+	if b.conn == nil {
+		if err := b.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.roundTrip(args...)
+}
+
+// connect dials the server and handles the authentication
+// and database selection. Be sure to lock the mutex.
+func (b *redisBackend) connect() (err error) {
+	b.conn, err = net.DialTimeout("tcp", b.addr, redisDialTimeout)
+	if err != nil {
+		b.conn = nil
+		return err
+	}
+
+	b.reader = bufio.NewReader(b.conn)
+
+	if len(b.password) > 0 {
+		if _, err = b.roundTrip("AUTH", b.password); err != nil {
+			b.disconnect()
+			return err
+		}
+	}
+
+	if b.db != 0 {
+		if _, err = b.roundTrip("SELECT", strconv.Itoa(b.db)); err != nil {
+			b.disconnect()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// disconnect closes the connection. Be sure to lock the mutex.
+func (b *redisBackend) disconnect() {
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+		b.reader = nil
+	}
+}
+
+// roundTrip writes the command and reads the reply.
+// Be sure to lock the mutex.
+func (b *redisBackend) roundTrip(args ...interface{}) (interface{}, error) {
+	b.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+
+	// Write the command as array of bulk strings.
+	w := bufio.NewWriter(b.conn)
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		var data []byte
+		switch v := a.(type) {
+		case string:
+			data = []byte(v)
+		case []byte:
+			data = v
+		default:
+			return nil, fmt.Errorf("redis: invalid argument type: %T", a)
+		}
+
+		fmt.Fprintf(w, "$%d\r\n", len(data))
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return readRedisReply(b.reader)
+}
+
+// readRedisReply parses one reply of the redis serialization protocol.
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: invalid reply line: '%s'", line)
+	}
+
+	// Remove the line ending.
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		} else if n < 0 {
+			return nil, nil
+		}
+
+		// Read the data with the trailing line ending.
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		} else if n < 0 {
+			return nil, nil
+		}
+
+		l := make([]interface{}, n)
+		for i := range l {
+			if l[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return l, nil
+	default:
+		return nil, fmt.Errorf("redis: invalid reply type: '%s'", line)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadRedisReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		reply    interface{}
+		redisErr bool
+		err      bool
+	}{
+		{name: "simple string", input: "+OK\r\n", reply: "OK"},
+		{name: "error", input: "-ERR unknown command\r\n", redisErr: true},
+		{name: "integer", input: ":42\r\n", reply: int64(42)},
+		{name: "negative integer", input: ":-1\r\n", reply: int64(-1)},
+		{name: "invalid integer", input: ":abc\r\n", err: true},
+		{name: "bulk", input: "$5\r\nhello\r\n", reply: []byte("hello")},
+		{name: "bulk with line endings", input: "$7\r\na\r\nb\r\nc\r\n", reply: []byte("a\r\nb\r\nc")},
+		{name: "empty bulk", input: "$0\r\n\r\n", reply: []byte{}},
+		{name: "nil bulk", input: "$-1\r\n", reply: nil},
+		{name: "truncated bulk", input: "$10\r\nhello\r\n", err: true},
+		{name: "array", input: "*3\r\n$1\r\na\r\n:1\r\n$-1\r\n", reply: []interface{}{[]byte("a"), int64(1), nil}},
+		{name: "nil array", input: "*-1\r\n", reply: nil},
+		{name: "missing carriage return", input: "+OK\n", err: true},
+		{name: "invalid type", input: "?OK\r\n", err: true},
+		{name: "eof", input: "", err: true},
+	}
+
+	for _, test := range tests {
+		r, err := readRedisReply(bufio.NewReader(strings.NewReader(test.input)))
+
+		_, isRedisErr := err.(redisError)
+		if test.redisErr != isRedisErr {
+			t.Errorf("%s: expected redis error: %v, got %v", test.name, test.redisErr, err)
+			continue
+		} else if test.err != (err != nil && !isRedisErr) {
+			t.Errorf("%s: expected error: %v, got %v", test.name, test.err, err)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(r, test.reply) {
+			t.Errorf("%s: got %#v, want %#v", test.name, r, test.reply)
+		}
+	}
+}
+
+func TestRedisBackendContract(t *testing.T) {
+	s := newFakeRedis(t, "secret")
+	defer s.Close()
+
+	b := newRedisBackend(s.Addr(), "secret", 2)
+	testBackendContract(t, b)
+
+	if db := s.selectedDB(); db != "2" {
+		t.Errorf("expected database 2 to be selected, got '%s'", db)
+	}
+}
+
+func TestRedisBackendAuth(t *testing.T) {
+	s := newFakeRedis(t, "secret")
+	defer s.Close()
+
+	b := newRedisBackend(s.Addr(), "invalid", 0)
+	if err := b.Open(); err == nil {
+		b.Close()
+		t.Fatal("expected an authentication error")
+	}
+}
+
+func TestRedisBackendReconnect(t *testing.T) {
+	s := newFakeRedis(t, "")
+	defer s.Close()
+
+	b := newRedisBackend(s.Addr(), "", 0)
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	// Drop the connection. The next command reconnects.
+	s.closeConns()
+
+	err := b.Save([]*BackendSession{{ID: "a", Data: []byte("a"), ExpiresAt: time.Now().Unix() + 60}})
+	if err != nil {
+		t.Fatalf("save after dropped connection: %v", err)
+	}
+	if data, err := b.Get("a"); err != nil || string(data) != "a" {
+		t.Fatalf("get after reconnect: got ('%s', %v)", data, err)
+	}
+}
+
+//##################//
+//### Fake Redis ###//
+//##################//
+
+// fakeRedis is a local stand-in for a redis server.
+// It handles the commands used by the redis backend.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	// Key: key
+	values  map[string][]byte
+	expires map[string]time.Time
+	db      string
+	conns   map[net.Conn]struct{}
+	mutex   sync.Mutex
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeRedis{
+		listener: l,
+		password: password,
+		values:   make(map[string][]byte),
+		expires:  make(map[string]time.Time),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	go s.acceptLoop()
+
+	return s
+}
+
+func (s *fakeRedis) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) Close() {
+	s.listener.Close()
+	s.closeConns()
+}
+
+func (s *fakeRedis) closeConns() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for c := range s.conns {
+		c.Close()
+		delete(s.conns, c)
+	}
+}
+
+func (s *fakeRedis) selectedDB() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.db
+}
+
+func (s *fakeRedis) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *fakeRedis) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authenticated := len(s.password) == 0
+
+	for {
+		// Commands are sent as arrays of bulk strings.
+		req, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+
+		items, ok := req.([]interface{})
+		if !ok || len(items) == 0 {
+			fmt.Fprint(conn, "-ERR invalid request\r\n")
+			continue
+		}
+
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+
+		cmd := strings.ToUpper(args[0])
+		if cmd == "AUTH" {
+			if len(args) == 2 && args[1] == s.password {
+				authenticated = true
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-ERR invalid password\r\n")
+			}
+			continue
+		} else if !authenticated {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+
+		fmt.Fprint(conn, s.exec(cmd, args[1:]))
+	}
+}
+
+// exec executes the command and returns the encoded reply.
+func (s *fakeRedis) exec(cmd string, args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Remove the expired keys.
+	now := time.Now()
+	for key, t := range s.expires {
+		if !now.Before(t) {
+			delete(s.values, key)
+			delete(s.expires, key)
+		}
+	}
+
+	switch {
+	case cmd == "SELECT" && len(args) == 1:
+		s.db = args[0]
+		return "+OK\r\n"
+	case cmd == "GET" && len(args) == 1:
+		v, ok := s.values[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case cmd == "SET" && len(args) == 4 && strings.ToUpper(args[2]) == "EX":
+		ttl, err := strconv.Atoi(args[3])
+		if err != nil || ttl <= 0 {
+			return "-ERR invalid expire time\r\n"
+		}
+		s.values[args[0]] = []byte(args[1])
+		s.expires[args[0]] = now.Add(time.Duration(ttl) * time.Second)
+		return "+OK\r\n"
+	case cmd == "EXISTS" && len(args) == 1:
+		if _, ok := s.values[args[0]]; ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case cmd == "DEL" && len(args) > 0:
+		n := 0
+		for _, key := range args {
+			if _, ok := s.values[key]; ok {
+				delete(s.values, key)
+				delete(s.expires, key)
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	default:
+		return "-ERR unknown command '" + cmd + "'\r\n"
+	}
+}
@@ -6,6 +6,7 @@
 package store
 
 import (
+	"github.com/desertbit/bulldozer/cluster"
 	"sync"
 	"time"
 )
@@ -24,6 +25,9 @@ type Session struct {
 	dirty     bool
 	lockCount int
 
+	// Temporary sessions are never saved.
+	temporary bool
+
 	// Main value implementation. Values are stored to the database.
 	values map[interface{}]interface{}
 	mutex  sync.Mutex
@@ -79,6 +83,12 @@ func (s *Session) Unlock() {
 		// Be sure the lock count is 0
 		s.lockCount = 0
 
+		// Save the changes immediately if running in a cluster.
+		// This way a reconnect on another node gets the current values.
+		if s.dirty && cluster.IsEnabled() {
+			go saveUnsavedSessions()
+		}
+
 		// Remove the session from the cache if not locked after the timeout
 		removeSessionFromCacheAfterTimeout(s)
 	}
@@ -68,6 +68,18 @@ func New() (*Session, error) {
 	return s, nil
 }
 
+// NewTemporary creates and returns a new session, which is not added
+// to the cache and which is never saved to the backend.
+func NewTemporary() *Session {
+	return &Session{
+		id:          utils.RandomString(sessionIDLength),
+		valid:       true,
+		temporary:   true,
+		values:      make(map[interface{}]interface{}),
+		cacheValues: make(map[interface{}]interface{}),
+	}
+}
+
 // Get will return a session fitting to the session ID.
 // This operation is thread-safe.
 func Get(id string) (*Session, error) {
@@ -117,6 +129,9 @@ func Remove(id string) {
 
 	// Remove the session also from the database
 	removeSessionFromDB(id)
+
+	// Remove the session also on the other cluster nodes.
+	go publishRemovedSession(id)
 }
 
 // AssignNewSessionID invalidates the old ID and creates a new ID for the session.
@@ -140,7 +155,9 @@ func AssignNewSessionID(s *Session) error {
 	delete(sessions, s.id)
 
 	// Remove the session also from the database
+	// and on the other cluster nodes.
 	removeSessionFromDB(s.id)
+	go publishRemovedSession(s.id)
 
 	// Set the new session ID
 	s.id = id
@@ -6,48 +6,136 @@
 package stream
 
 import (
+	"errors"
 	"sync"
 	"time"
 )
 
+const (
+	// Writes block at most this duration if the buffer is full
+	// and a reader is attached.
+	writeTimeout = time.Second
+)
+
+var (
+	ErrClosed = errors.New("stream closed")
+	ErrFull   = errors.New("stream buffer full: the client falls behind")
+)
+
 //######################//
 //### Stream struct ###//
 //######################//
 
 // This stream struct is used as buffer to send streams to the the socket
-// implementation (Ajax socket or Websocket)
+// implementation (Ajax socket, SSE socket or Websocket).
+// The stream buffers single encoded client message frames.
+// The buffered size is bounded. If the buffer is full, then writes wait
+// shortly for the attached reader. Without reader they fail immediately.
 type Stream struct {
 	HasData chan bool
 
-	data  string
+	data     [][]byte
+	size     int
+	maxSize  int
+	closed   bool
+	attached bool
+
+	// This channel is closed and replaced as soon as buffer space is released.
+	hasSpace chan struct{}
+
 	mutex sync.Mutex
 }
 
-func New() *Stream {
+// New creates a new stream with the maximum buffered size in bytes.
+func New(maxSize int) *Stream {
 	return &Stream{
-		HasData: make(chan bool, 1),
+		HasData:  make(chan bool, 1),
+		maxSize:  maxSize,
+		hasSpace: make(chan struct{}),
 	}
 }
 
-// Write appends the data string to the data stream buffer
-func (m *Stream) Write(data string) {
+// Attach marks the stream as read by a reader. Writes to a full
+// buffer only wait for free buffer space if a reader is attached.
+func (m *Stream) Attach() {
 	// Lock mutex
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	// Append data to the data string
-	m.data += data
+	m.attached = true
+}
+
+// Write appends the data frames to the data stream buffer.
+// If the buffer is full and a reader is attached, then this call blocks
+// shortly until the buffer is read. ErrFull is returned if the buffer is
+// not read in time or if no reader is attached. Frames larger than the
+// maximum size are only buffered if the buffer is empty.
+func (m *Stream) Write(data ...[]byte) error {
+	if len(data) == 0 {
+		return nil
+	}
 
-	// Only trigger the channel if not already.
-	// This way, this channel will never block.
-	select {
-	case m.HasData <- true:
-	default:
+	// Get the total size of the frames.
+	var n int
+	for _, d := range data {
+		n += len(d)
+	}
+
+	var timeout <-chan time.Time
+
+	for {
+		// Lock mutex
+		m.mutex.Lock()
+
+		if m.closed {
+			m.mutex.Unlock()
+			return ErrClosed
+		}
+
+		if m.size == 0 || m.size+n <= m.maxSize {
+			// Append data to the data buffer
+			m.data = append(m.data, data...)
+			m.size += n
+
+			m.mutex.Unlock()
+
+			// Only trigger the channel if not already.
+			// This way, this channel will never block.
+			select {
+			case m.HasData <- true:
+			default:
+			}
+
+			return nil
+		}
+
+		hasSpace := m.hasSpace
+		attached := m.attached
+
+		// Unlock mutex
+		m.mutex.Unlock()
+
+		// Nobody reads the buffer. Don't block the caller.
+		if !attached {
+			return ErrFull
+		}
+
+		// Start the timeout on the first wait.
+		if timeout == nil {
+			timeout = time.After(writeTimeout)
+		}
+
+		// Wait for free buffer space.
+		select {
+		case <-hasSpace:
+		case <-timeout:
+			return ErrFull
+		}
 	}
 }
 
-// Read gets the data in the stream buffer
-func (m *Stream) Read() (data string) {
+// Read gets the data frames in the stream buffer
+func (m *Stream) Read() (data [][]byte) {
 	// Wait for 1 millisecond, so that other data might
 	// be added to the stream data. This way, two calls to stream.write
 	// don't send two messages to the server.
@@ -62,7 +150,38 @@ func (m *Stream) Read() (data string) {
 	data = m.data
 
 	// Clear the original data
-	m.data = ""
+	m.data = nil
+	m.size = 0
+
+	// Wake up all blocked writers
+	m.releaseSpace()
 
 	return
 }
+
+// Close closes the stream. Blocked and following writes return ErrClosed.
+func (m *Stream) Close() {
+	// Lock mutex
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return
+	}
+
+	m.closed = true
+
+	// Wake up all blocked writers
+	m.releaseSpace()
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// releaseSpace wakes up all blocked writers.
+// Be sure to lock the mutex.
+func (m *Stream) releaseSpace() {
+	close(m.hasSpace)
+	m.hasSpace = make(chan struct{})
+}
@@ -0,0 +1,83 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteWithoutReader(t *testing.T) {
+	m := New(4)
+
+	// Frames larger than the maximum size are buffered if the buffer is empty.
+	if err := m.Write([]byte("12345")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Full buffers fail immediately without reader.
+	start := time.Now()
+	if err := m.Write([]byte("6")); err != ErrFull {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("write without reader blocked for %v", d)
+	}
+
+	if data := m.Read(); len(data) != 1 || string(data[0]) != "12345" {
+		t.Fatalf("invalid data: %q", data)
+	}
+}
+
+func TestWriteWithReader(t *testing.T) {
+	m := New(4)
+	m.Attach()
+
+	if err := m.Write([]byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The blocked write continues as soon as the buffer is read.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.Read()
+	}()
+
+	if err := m.Write([]byte("5")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The write times out if the reader falls behind.
+	start := time.Now()
+	if err := m.Write([]byte("6789")); err != ErrFull {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+	if d := time.Since(start); d < writeTimeout || d > 2*writeTimeout {
+		t.Fatalf("unexpected write timeout: %v", d)
+	}
+}
+
+func TestWriteClosed(t *testing.T) {
+	m := New(4)
+	m.Attach()
+
+	if err := m.Write([]byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing releases the blocked writers.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.Close()
+	}()
+
+	if err := m.Write([]byte("5")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if err := m.Write([]byte("5")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
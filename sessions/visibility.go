@@ -0,0 +1,124 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package sessions
+
+import (
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/settings"
+	"strconv"
+	"time"
+)
+
+// The client reports the visibility of the browser tab. If the tab stays in
+// the background longer than the socket idle timeout, then the socket is
+// suspended: the client is told to close its socket and the session is closed.
+// The session instance and its unacknowledged messages are kept, so the client
+// resumes the instance with a cheap reconnect as soon as the tab is visible again.
+
+const (
+	requestTypeVisibility = "visibility"
+	requestKeyVisible     = "visible"
+
+	messageTypeSocketSuspend = "socket.suspend"
+
+	// Close the socket if the client doesn't close it after the suspend message.
+	suspendCloseTimeout = 10 * time.Second
+)
+
+func init() {
+	// Register the client visibility request.
+	err := Request(requestTypeVisibility, onVisibilityRequest)
+	if err != nil {
+		log.L.Fatalf("failed to register session visibility request: %v", err)
+	}
+
+	// Stop the idle timer of closed sessions.
+	OnCloseSession(func(s *Session) {
+		s.stopIdleTimer()
+	})
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// IsVisible returns a boolean whenever the browser tab of the session is visible.
+func (s *Session) IsVisible() bool {
+	// Lock the mutex
+	s.visibilityMutex.Lock()
+	defer s.visibilityMutex.Unlock()
+
+	return !s.isHidden
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func onVisibilityRequest(s *Session, data map[string]string) error {
+	visible, err := strconv.ParseBool(data[requestKeyVisible])
+	if err != nil {
+		return fmt.Errorf("visibility request: invalid visible value: %v", err)
+	}
+
+	s.setVisible(visible)
+
+	return nil
+}
+
+// setVisible sets the tab visibility and starts the idle timer if hidden.
+func (s *Session) setVisible(visible bool) {
+	// Lock the mutex
+	s.visibilityMutex.Lock()
+	defer s.visibilityMutex.Unlock()
+
+	s.isHidden = !visible
+
+	// Stop the previous idle timer.
+	s.stopIdleTimerLocked()
+
+	if visible || settings.Settings.SocketIdleTimeout <= 0 {
+		return
+	}
+
+	s.idleTimer = time.AfterFunc(time.Duration(settings.Settings.SocketIdleTimeout)*time.Second, s.suspend)
+}
+
+// stopIdleTimer stops the socket idle timer if running.
+func (s *Session) stopIdleTimer() {
+	// Lock the mutex
+	s.visibilityMutex.Lock()
+	defer s.visibilityMutex.Unlock()
+
+	s.stopIdleTimerLocked()
+}
+
+// stopIdleTimerLocked stops the socket idle timer if running.
+// Be sure to lock the mutex.
+func (s *Session) stopIdleTimerLocked() {
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+}
+
+// suspend closes the socket of the background tab.
+// The session instance stays resumable.
+func (s *Session) suspend() {
+	if s.IsClosed() || s.IsVisible() {
+		return
+	}
+
+	// Keep the unacknowledged messages until the client resumes.
+	suspendReplayBuffer(s.instanceID)
+
+	// Tell the client to close the socket without reconnecting.
+	s.SendMessage(messageTypeSocketSuspend, "", nil)
+
+	// Close the socket if the client doesn't.
+	time.AfterFunc(suspendCloseTimeout, s.Close)
+}
@@ -38,6 +38,14 @@ const (
 	UrlPublic             = "/public/"
 	UrlBulldozerResources = "/bulldozer/res/"
 
+	// The auth session limit policies
+	AuthSessionLimitEvict = "evict"
+	AuthSessionLimitDeny  = "deny"
+
+	// The application environments
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
 	/*
 	 *  Private
 	 */
@@ -45,6 +53,7 @@ const (
 	bulldozerGoPath      = "src/github.com/desertbit/bulldozer/"
 	tmpDirName           = "bulldozer"
 	sessionsDatabaseName = "sessions.db"
+	uploadDirName        = "uploads"
 
 	// Default cookie keys
 	defaultCookieHashKey  = "R7DqYdgWlztQ06diRM4z7ByuDwfiAvehLxTwAEDHFvgjkA4CcPrWBhZk6FJIBuDs"
@@ -72,6 +81,7 @@ func init() {
 		SocketType:        TypeTcpSocket,
 		ListenAddress:     ":9000",
 		ServeFiles:        true,
+		Environment:       EnvDevelopment,
 
 		DatabaseAddr:    "localhost",
 		DatabasePort:    "28015",
@@ -84,14 +94,41 @@ func init() {
 		CookieBlockKey: defaultCookieBlockKey,
 		SessionMaxAge:  60 * 60 * 24 * 14, // 14 Days
 
+		SessionsBackend:   "bolt",
+		SessionsRedisAddr: "localhost:6379",
+
+		SocketMaxBufferSize: 4 << 20, // 4 MB
+		SocketMaxReplaySize: 4 << 20, // 4 MB
+		SocketIdleTimeout:   60 * 5,  // 5 minutes
+
+		SocketSuspendedReplayTimeout: 60 * 15,  // 15 minutes
+		SocketMaxSuspendedReplaySize: 64 << 20, // 64 MB
+
+		RenderCacheMaxSize: 2 << 20, // 2 MB
+
+		ClientStorageTimeout: 10, // 10 seconds
+
+		DefaultLocale:   "en",
+		LocaleURLPrefix: false,
+
+		UploadMaxSize: 32 << 20, // 32 MB
+
 		FirewallMaxRequestsPerMinute: 100,
 		FirewallReleaseBlockAfter:    60 * 5, // 5 minutes
 
+		TrustedProxies: []string{"127.0.0.1/8", "::1/128"},
+
+		ClusterNetwork: "tcp",
+
 		ScssCmd: "scss",
 
 		RegistrationDisabled:           true,
 		PasswordEncryptionKey:          defaultPasswordEncryptionKey,
 		RemoveNotConfirmedUsersTimeout: 60 * 60 * 24 * 20, // 20 Days
+		AuthSessionIdleTimeout:         0,
+		AuthSessionMaxLifetime:         0,
+		AuthMaxSessionsPerUser:         0,
+		AuthSessionLimitPolicy:         AuthSessionLimitEvict,
 
 		MailSMTPPort:              587,
 		MailSkipCertificateVerify: false,
@@ -119,6 +156,7 @@ func init() {
 
 	// Set the paths
 	Settings.SessionsDatabasePath = Settings.TmpPath + sessionsDatabaseName
+	Settings.UploadPath = Settings.TmpPath + uploadDirName
 
 	Settings.PublicPath = Settings.WorkingPath + "public"
 	Settings.TemplatesPath = Settings.WorkingPath + "templates"
@@ -167,6 +205,8 @@ func Prepare() error {
 	Settings.DatabaseAddr = getEnv("BULLDOZER_DB_ADDR", Settings.DatabaseAddr)
 	Settings.DatabasePort = getEnv("BULLDOZER_DB_PORT", Settings.DatabasePort)
 	Settings.SessionsDatabasePath = getEnv("BULLDOZER_SESSIONS_DB_PATH", Settings.SessionsDatabasePath)
+	Settings.SessionsRedisAddr = getEnv("BULLDOZER_SESSIONS_REDIS_ADDR", Settings.SessionsRedisAddr)
+	Settings.Environment = getEnv("BULLDOZER_ENV", Settings.Environment)
 
 	// Get environment variable values if the environment prefix is set on struct field strings.
 	s := reflect.ValueOf(&Settings).Elem()
@@ -232,6 +272,72 @@ func Prepare() error {
 		log.L.Warning("[WARNING] settings: the default password encryption key is set! You should replace this with a secret key!")
 	}
 
+	// Set the trusted proxy networks used to obtain the client remote addresses.
+	if err := utils.SetTrustedProxies(Settings.TrustedProxies); err != nil {
+		return fmt.Errorf("settings: %v", err)
+	}
+
+	if Settings.Environment != EnvDevelopment && Settings.Environment != EnvProduction {
+		return fmt.Errorf("settings: invalid environment '%s': valid values are '%s' and '%s'",
+			Settings.Environment, EnvDevelopment, EnvProduction)
+	}
+
+	if Settings.SocketMaxBufferSize <= 0 {
+		return fmt.Errorf("settings: invalid socket max buffer size: %v", Settings.SocketMaxBufferSize)
+	} else if Settings.SocketMaxReplaySize <= 0 {
+		return fmt.Errorf("settings: invalid socket max replay size: %v", Settings.SocketMaxReplaySize)
+	} else if Settings.SocketIdleTimeout < 0 {
+		return fmt.Errorf("settings: invalid socket idle timeout: %v", Settings.SocketIdleTimeout)
+	} else if Settings.SocketSuspendedReplayTimeout <= 0 {
+		return fmt.Errorf("settings: invalid socket suspended replay timeout: %v", Settings.SocketSuspendedReplayTimeout)
+	} else if Settings.SocketMaxSuspendedReplaySize < 0 {
+		return fmt.Errorf("settings: invalid socket max suspended replay size: %v", Settings.SocketMaxSuspendedReplaySize)
+	}
+
+	if Settings.RenderCacheMaxSize < 0 {
+		return fmt.Errorf("settings: invalid render cache max size: %v", Settings.RenderCacheMaxSize)
+	}
+
+	if len(Settings.DefaultLocale) == 0 {
+		return fmt.Errorf("settings: the default locale is not set!")
+	}
+
+	if Settings.ClientStorageTimeout <= 0 {
+		return fmt.Errorf("settings: invalid client storage timeout: %v", Settings.ClientStorageTimeout)
+	}
+
+	if Settings.AuthSessionIdleTimeout < 0 {
+		return fmt.Errorf("settings: invalid auth session idle timeout: %v", Settings.AuthSessionIdleTimeout)
+	} else if Settings.AuthSessionMaxLifetime < 0 {
+		return fmt.Errorf("settings: invalid auth session max lifetime: %v", Settings.AuthSessionMaxLifetime)
+	} else if Settings.AuthMaxSessionsPerUser < 0 {
+		return fmt.Errorf("settings: invalid auth max sessions per user: %v", Settings.AuthMaxSessionsPerUser)
+	} else if Settings.AuthSessionLimitPolicy != AuthSessionLimitEvict && Settings.AuthSessionLimitPolicy != AuthSessionLimitDeny {
+		return fmt.Errorf("settings: invalid auth session limit policy '%s': valid policies are '%s' and '%s'",
+			Settings.AuthSessionLimitPolicy, AuthSessionLimitEvict, AuthSessionLimitDeny)
+	}
+
+	if Settings.UploadMaxSize <= 0 {
+		return fmt.Errorf("settings: invalid upload max size: %v", Settings.UploadMaxSize)
+	}
+
+	// Check the cluster settings.
+	if len(Settings.ClusterListenAddress) > 0 {
+		if Settings.ClusterNetwork != "tcp" && Settings.ClusterNetwork != "unix" {
+			return fmt.Errorf("settings: invalid cluster network '%s': valid networks are 'tcp' and 'unix'", Settings.ClusterNetwork)
+		}
+
+		if len(Settings.ClusterKey) == 0 {
+			log.L.Warning("[WARNING] settings: the cluster key is empty! You should set a secret key to protect the cluster bus!")
+		}
+
+		if (len(Settings.ClusterTLSCertFile) == 0) != (len(Settings.ClusterTLSKeyFile) == 0) {
+			return fmt.Errorf("settings: the cluster TLS certificate and key files have to be set both!")
+		} else if len(Settings.ClusterTLSCAFile) > 0 && len(Settings.ClusterTLSCertFile) == 0 {
+			return fmt.Errorf("settings: the cluster TLS CA file requires a certificate and key file!")
+		}
+	}
+
 	// Warn the user about possible forgotten root slashes.
 	for _, url := range Settings.StaticJavaScripts {
 		if !strings.HasPrefix(url, "/") {
@@ -291,8 +397,18 @@ type settings struct {
 	ListenAddress string
 	ServeFiles    bool
 
+	// The application environment: "development" or "production".
+	// It can be also set with the BULLDOZER_ENV environment variable.
+	Environment string
+
+	// Minify the HTML of the templates during parsing.
+	// This is always enabled in the production environment.
 	MinifyTemplates bool
 
+	// Reload the project templates on file changes.
+	// This is meant for development and is disabled by default.
+	WatchTemplates bool
+
 	DatabaseAddr string
 	DatabasePort string
 	DatabaseName string
@@ -313,6 +429,7 @@ type settings struct {
 	WorkingPath          string
 	TmpPath              string
 	SessionsDatabasePath string
+	UploadPath           string
 
 	PublicPath      string
 	PagesPath       string
@@ -344,11 +461,89 @@ type settings struct {
 	// The maximum session age in seconds
 	SessionMaxAge int
 
+	// The sessions store backend: "bolt", "memory" or "redis".
+	// The bolt backend uses the SessionsDatabasePath file.
+	// The redis backend connects to any server speaking the redis protocol
+	// and is shared between all cluster nodes.
+	SessionsBackend       string
+	SessionsRedisAddr     string
+	SessionsRedisPassword string
+	SessionsRedisDB       int
+
+	// The maximum size in bytes of pending messages per session socket.
+	// Sending blocks shortly if the buffer is full. If the client falls behind
+	// or if no socket is connected, then the session is closed.
+	SocketMaxBufferSize int
+	// The maximum size in bytes of sent messages per session instance,
+	// which are kept until the client acknowledges them. They are replayed
+	// if the client reconnects. If messages are dropped before they are
+	// acknowledged, then a reconnecting client has to refresh the page.
+	SocketMaxReplaySize int
+	// Suspend the socket connection after x seconds, if the browser tab
+	// is in the background. The session is resumed as soon as the tab is
+	// visible again. Set to 0 to keep background sockets connected.
+	SocketIdleTimeout int
+	// Keep the replay buffers of suspended sockets for x seconds.
+	// The client has to refresh the page if it resumes later.
+	SocketSuspendedReplayTimeout int
+	// The maximum total size in bytes of all replay buffers of suspended
+	// sockets. The oldest buffers are released if the size is exceeded.
+	SocketMaxSuspendedReplaySize int
+
+	// The maximum size in bytes of the rendered outputs per session instance,
+	// which are kept to send only the changes on the next render of a page or
+	// template. Set to 0 to always send the complete output.
+	RenderCacheMaxSize int
+
+	// The default timeout in seconds for client storage get requests.
+	ClientStorageTimeout int
+
+	// The locale used if no translation locale is resolved for a session.
+	// Sessions use the user preference, the Accept-Language header or
+	// the URL prefix if enabled.
+	DefaultLocale string
+	// Resolve the session locale from the first URL path element: /de/page.
+	// The prefix is only handled if translations for the locale are loaded.
+	LocaleURLPrefix bool
+	// Custom fallback locales which are tried if a message is missing.
+	// Example: "pt-br": {"pt", "es"}
+	LocaleFallbacks map[string][]string
+
+	// The maximum size in bytes of a single file upload.
+	UploadMaxSize int64
+	// The allowed MIME types of uploaded files. Wildcards like "image/*"
+	// are supported. All types are allowed if the slice is empty.
+	// The content type detected by the first bytes of the file is checked too.
+	// Types which can't be detected, like text/csv, have to be listed without wildcards.
+	UploadContentTypes []string
+
 	// The maximum allowed requests per minute before the IP is blocked
 	FirewallMaxRequestsPerMinute int
 	// Release the blocked remote address after x seconds
 	FirewallReleaseBlockAfter int
 
+	// The proxy networks in CIDR notation, which are trusted to pass
+	// the client address with the Forwarded, X-Forwarded-For or X-Real-Ip headers.
+	// Requests from all other addresses are never allowed to set the client address.
+	TrustedProxies []string
+
+	// Cluster bus. If the listen address is empty, then this instance runs
+	// standalone. Otherwise all other nodes have to be listed as peers.
+	// The network is either "tcp" or "unix". Only nodes with the same key
+	// are allowed to connect. The key is never sent and all messages are
+	// authenticated, but they are sent unencrypted. Without TLS, the tcp
+	// network has to be trusted, because anyone on it can read the messages.
+	ClusterNetwork       string
+	ClusterListenAddress string
+	ClusterPeers         []string
+	ClusterKey           string
+	// Enable TLS for the cluster bus by setting the certificate and key files.
+	// The certificate is used by the listener and for the peer connections.
+	// Peers are verified with the CA file, or with the system roots if empty.
+	ClusterTLSCertFile string
+	ClusterTLSKeyFile  string
+	ClusterTLSCAFile   string
+
 	// This are the static stylesheets and javascripts which
 	// will be always loaded.
 	// Don't manipulate this slices after Bulldozer initialization!
@@ -359,6 +554,18 @@ type settings struct {
 	RegistrationDisabled           bool
 	PasswordEncryptionKey          string
 	RemoveNotConfirmedUsersTimeout int
+	// Log out authenticated sessions without any activity for x seconds.
+	// Set to 0 to disable the idle timeout. Disabled by default.
+	AuthSessionIdleTimeout int
+	// Log out authenticated sessions x seconds after the login,
+	// regardless of any activity. Set to 0 to disable. Disabled by default.
+	AuthSessionMaxLifetime int
+	// The maximum number of concurrent authenticated sessions per user.
+	// Set to 0 for an unlimited number of sessions.
+	AuthMaxSessionsPerUser int
+	// Either "evict" to log out the oldest session of the user if the
+	// limit is reached, or "deny" to reject the new login.
+	AuthSessionLimitPolicy string
 
 	// Mail
 	MailFrom                  string
@@ -376,3 +583,8 @@ func (s *settings) CookieHashKeyBytes() []byte {
 func (s *settings) CookieBlockKeyBytes() []byte {
 	return []byte(s.CookieBlockKey)
 }
+
+// IsProduction returns a boolean whenever the application runs in the production environment.
+func (s *settings) IsProduction() bool {
+	return s.Environment == EnvProduction
+}
@@ -9,6 +9,7 @@ import (
 	tr "github.com/desertbit/bulldozer/translate"
 
 	"fmt"
+	"github.com/desertbit/bulldozer/cluster"
 	"github.com/desertbit/bulldozer/editmode"
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/sessions"
@@ -28,6 +29,9 @@ const (
 	contextValueKeyStorePrefix = "budStore_"
 
 	saveTemporaryChangesCallback = "budSaveTmpChanges"
+
+	// Cluster channels.
+	clusterChannelChangedContext = "store.changedContext"
 )
 
 var (
@@ -50,6 +54,15 @@ func newStore(data *dbStore) *store {
 	}
 }
 
+//############################//
+//### Cluster message type ###//
+//############################//
+
+type clusterChangedContext struct {
+	ContextID string
+	Timestamp int64
+}
+
 //##############//
 //### Public ###//
 //##############//
@@ -62,6 +75,9 @@ func Init() {
 	// Register the messagebox callback.
 	messagebox.RegisterCallback(saveTemporaryChangesCallback, saveTemporaryChanges)
 
+	// Update the edit mode sessions on context changes of other cluster nodes.
+	cluster.Subscribe(clusterChannelChangedContext, onClusterChangedContext)
+
 	// Start the cleanup loop in a new goroutine.
 	go cleanupLocksLoop()
 }
@@ -318,16 +334,16 @@ func SaveTemporaryChanges(s *sessions.Session) {
 	if err != nil {
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.core.saveFailedTitle")).
-			SetText(tr.S("bud.core.saveFailedText")).
+			SetTitle(tr.For(s).S("bud.core.saveFailedTitle")).
+			SetText(tr.For(s).S("bud.core.saveFailedText")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
 	} else if !hasChanges {
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.core.nothingToSaveTitle")).
-			SetText(tr.S("bud.core.nothingToSaveText")).
+			SetTitle(tr.For(s).S("bud.core.nothingToSaveTitle")).
+			SetText(tr.For(s).S("bud.core.nothingToSaveText")).
 			SetType(messagebox.TypeInfo).
 			Show(s)
 		return
@@ -335,8 +351,8 @@ func SaveTemporaryChanges(s *sessions.Session) {
 
 	// Show a messagebox to continue.
 	messagebox.New().
-		SetTitle(tr.S("bud.core.saveChangesTitle")).
-		SetText(tr.S("bud.core.saveChangesText")).
+		SetTitle(tr.For(s).S("bud.core.saveChangesTitle")).
+		SetText(tr.For(s).S("bud.core.saveChangesText")).
 		SetType(messagebox.TypeQuestion).
 		SetButtons(messagebox.ButtonYes | messagebox.ButtonNo).
 		SetCallback(saveTemporaryChangesCallback).
@@ -359,16 +375,16 @@ func saveTemporaryChanges(s *sessions.Session, b messagebox.Button) {
 
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.core.saveFailedTitle")).
-			SetText(tr.S("bud.core.saveFailedText")).
+			SetTitle(tr.For(s).S("bud.core.saveFailedTitle")).
+			SetText(tr.For(s).S("bud.core.saveFailedText")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
 	} else if len(locks) > 0 {
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.core.objectsLockedTitle")).
-			SetText(tr.S("bud.core.objectsLockedText")).
+			SetTitle(tr.For(s).S("bud.core.objectsLockedTitle")).
+			SetText(tr.For(s).S("bud.core.objectsLockedText")).
 			SetType(messagebox.TypeWarning).
 			Show(s)
 		return
@@ -381,8 +397,8 @@ func saveTemporaryChanges(s *sessions.Session, b messagebox.Button) {
 
 		// Show a messagebox.
 		messagebox.New().
-			SetTitle(tr.S("bud.core.saveFailedTitle")).
-			SetText(tr.S("bud.core.saveFailedText")).
+			SetTitle(tr.For(s).S("bud.core.saveFailedTitle")).
+			SetText(tr.For(s).S("bud.core.saveFailedText")).
 			SetType(messagebox.TypeAlert).
 			Show(s)
 		return
@@ -390,8 +406,8 @@ func saveTemporaryChanges(s *sessions.Session, b messagebox.Button) {
 
 	// Show a success messagebox.
 	messagebox.New().
-		SetTitle(tr.S("bud.core.successSaveTitle")).
-		SetText(tr.S("bud.core.successSaveText")).
+		SetTitle(tr.For(s).S("bud.core.successSaveTitle")).
+		SetText(tr.For(s).S("bud.core.successSaveText")).
 		SetType(messagebox.TypeSuccess).
 		Show(s)
 }
@@ -468,50 +484,81 @@ func broadcastChangedContext(contextID string, currentSession ...*sessions.Sessi
 	}
 
 	// Start this in a new go-routine. Don't block any calling mutexes...
-	go func() {
-		// Get all sessions which are in the edit mode.
-		activeSessions := editmode.GetSessions()
-
-		for _, s := range activeSessions {
-			// Update the store state in the client values.
-			// This way, we can detect, if a session is out-of-sync on reconnect.
-			s.ClientSet(clientKeyStoreState, strconv.FormatInt(timestamp, 10))
-
-			// Skip if this is the current session.
-			if s.SessionID() == curSid {
-				continue
-			}
+	go updateEditModeSessions(contextID, timestamp, curSid)
 
-			// Recover panics and log the error message.
-			defer func() {
-				if e := recover(); e != nil {
-					log.L.Warning("update session context with ID '%s': panic: %v", contextID, e)
-				}
-			}()
-
-			// Get the context store of the session.
-			store := template.GetSessionContextStore(s)
-			if store == nil {
-				log.L.Warning("failed to update session context with ID '%s': failed to get context store!", contextID)
-				sessionOutOfSync(s)
-				return
-			}
+	// Notify the other cluster nodes. Unreachable nodes might block.
+	go publishChangedContext(contextID, timestamp)
+}
 
-			cc, ok := store.Get(contextID)
-			if !ok {
-				log.L.Warning("failed to update session context with ID '%s': failed to get context!", contextID)
-				sessionOutOfSync(s)
-				return
-			}
+func publishChangedContext(contextID string, timestamp int64) {
+	if !cluster.IsEnabled() {
+		return
+	}
 
-			err = cc.Update()
-			if err != nil {
-				log.L.Warning("failed to update session context with ID '%s': %v", contextID, err)
-				sessionOutOfSync(s)
-				return
+	err := cluster.Publish(clusterChannelChangedContext, &clusterChangedContext{
+		ContextID: contextID,
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		log.L.Error("store: %v", err)
+	}
+}
+
+// updateEditModeSessions updates the changed context of all edit mode sessions
+// of this node. The session with the current session ID is skipped.
+func updateEditModeSessions(contextID string, timestamp int64, curSid string) {
+	// Get all sessions which are in the edit mode.
+	activeSessions := editmode.GetSessions()
+
+	for _, s := range activeSessions {
+		// Update the store state in the client values.
+		// This way, we can detect, if a session is out-of-sync on reconnect.
+		s.ClientSet(clientKeyStoreState, strconv.FormatInt(timestamp, 10))
+
+		// Skip if this is the current session.
+		if s.SessionID() == curSid {
+			continue
+		}
+
+		// Recover panics and log the error message.
+		defer func() {
+			if e := recover(); e != nil {
+				log.L.Warning("update session context with ID '%s': panic: %v", contextID, e)
 			}
+		}()
+
+		// Get the context store of the session.
+		store := template.GetSessionContextStore(s)
+		if store == nil {
+			log.L.Warning("failed to update session context with ID '%s': failed to get context store!", contextID)
+			sessionOutOfSync(s)
+			return
 		}
-	}()
+
+		cc, ok := store.Get(contextID)
+		if !ok {
+			log.L.Warning("failed to update session context with ID '%s': failed to get context!", contextID)
+			sessionOutOfSync(s)
+			return
+		}
+
+		err := cc.Update()
+		if err != nil {
+			log.L.Warning("failed to update session context with ID '%s': %v", contextID, err)
+			sessionOutOfSync(s)
+			return
+		}
+	}
+}
+
+func onClusterChangedContext(data []byte) {
+	var m clusterChangedContext
+	if err := cluster.Decode(data, &m); err != nil {
+		log.L.Error("store: failed to decode cluster message: %v", err)
+		return
+	}
+
+	updateEditModeSessions(m.ContextID, m.Timestamp, "")
 }
 
 func onNewEditModeSession(s *sessions.Session) {
@@ -556,8 +603,8 @@ func sessionOutOfSync(s *sessions.Session) {
 
 	// Show a messagebox
 	messagebox.New().
-		SetTitle(tr.S("bud.core.sessionOutOfSyncTitle")).
-		SetText(tr.S("bud.core.sessionOutOfSyncText")).
+		SetTitle(tr.For(s).S("bud.core.sessionOutOfSyncTitle")).
+		SetText(tr.For(s).S("bud.core.sessionOutOfSyncText")).
 		SetType(messagebox.TypeWarning).
 		Show(s)
 }
@@ -607,24 +654,11 @@ func cleanupLocks() {
 		return
 	}
 
-	// Get all sessions which are in the edit mode
-	// and create a session map sorted with instance IDs.
-	getActiveInstanceIDs := func() map[string]*sessions.Session {
-		activeSessions := editmode.GetSessions()
-		m := make(map[string]*sessions.Session)
-		for _, s := range activeSessions {
-			m[s.InstanceID()] = s
-		}
-		return m
-	}
-	activeInstanceIDs := getActiveInstanceIDs()
-
 	// Find locks which are not locked by an active session.
+	// Sessions of all cluster nodes are considered.
 	var expiredLocks []*dbLockData
-	var found bool
 	for _, lock := range locks {
-		_, found = activeInstanceIDs[lock.Value]
-		if !found {
+		if !editmode.IsActiveInstance(lock.Value) {
 			expiredLocks = append(expiredLocks, lock)
 		}
 	}
@@ -636,12 +670,8 @@ func cleanupLocks() {
 	// Give the sessions a chance to reconnect.
 	time.Sleep(removeExpiredLocksAfterTimeout)
 
-	// Update the active sessions.
-	activeInstanceIDs = getActiveInstanceIDs()
-
 	for _, lock := range expiredLocks {
-		_, found = activeInstanceIDs[lock.Value]
-		if found {
+		if editmode.IsActiveInstance(lock.Value) {
 			continue
 		}
 
@@ -12,12 +12,13 @@ import (
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/sessions"
 	"github.com/desertbit/bulldozer/utils"
-	"strconv"
 	"sync"
 )
 
 const (
 	GlobalID = "global"
+
+	messageTypeRenderTemplate = "render.template"
 )
 
 func init() {
@@ -310,38 +311,37 @@ func (c *Context) Update(vars ...interface{}) error {
 		return err
 	}
 
+	// Only send the changes if the client knows the previous output.
+	body, patch := DiffOutput(c.ns.s, c.data.DomID, b.Bytes())
+
 	// Update the current div wrapper of this template.
-	c.ns.s.SendCommand(`Bulldozer.render.updateTemplate("` + c.data.DomID + `",'` + utils.EscapeJS(b.String()) + `');`)
+	// The client morphs the DOM to keep the focus and the input states.
+	if patch != nil {
+		c.ns.s.SendBinaryMessage(messageTypeRenderTemplate, c.data.DomID, patch, body)
+	} else {
+		c.ns.s.SendBinaryMessage(messageTypeRenderTemplate, c.data.DomID, nil, body)
+	}
 
 	return nil
 }
 
 // TriggerEvent triggers the event on the client side defined with the template event syntax.
 func (c *Context) TriggerEvent(eventName string, params ...interface{}) {
-	cmd := `Bulldozer.core.emitServerEvent("` + c.data.DomID + `",'` + utils.EscapeJS(eventName) + `'`
-
-	// Append all the parameters
+	// Check the parameter types.
 	for i, param := range params {
-		// type assertion
-		switch v := param.(type) {
-		case int:
-			cmd += "," + strconv.Itoa(v)
-		case int64:
-			cmd += "," + strconv.FormatInt(v, 10)
-		case bool:
-			cmd += "," + strconv.FormatBool(v)
-		case string:
-			cmd += ",'" + utils.EscapeJS(v) + "'"
+		switch param.(type) {
+		case int, int64, bool, string:
 		default:
 			log.L.Error("context: trigger event: invalid type of function event parameter: %v : parameters: %v", i+1, params)
 			return
 		}
 	}
 
-	cmd += ");"
-
-	// Send the command to the client
-	c.ns.s.SendCommand(cmd)
+	// Send the event to the client
+	c.ns.s.SendMessage(messageTypeEmitEvent, c.data.DomID, &eventMessage{
+		Event: eventName,
+		Args:  params,
+	})
 }
 
 //############################//
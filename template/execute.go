@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/desertbit/bulldozer/sessions"
+	ht "html/template"
 	"io"
 )
 
@@ -29,6 +30,9 @@ type renderData struct {
 	Context *Context
 	Pkg     map[string]interface{}
 	Data    interface{}
+
+	// The blocks of the extending templates if executed as layout.
+	layout *layoutData
 }
 
 //##########################//
@@ -40,6 +44,7 @@ type ExecOpts struct {
 	ID           string      // This is added to the unique context ID.
 	DomID        string      // Set this, to set a custom DOM ID.
 	StyleClasses []string    // Additional style classes.
+	Layout       string      // Overrides the layout of the template. Set to NoLayout to disable the layout.
 }
 
 //###############################//
@@ -64,15 +69,20 @@ func (t *Template) Execute(s *sessions.Session, wr io.Writer, optArgs ...ExecOpt
 	// Create a new context.
 	c = newContext(s, t, optArgs...)
 
-	// Obtain the data from the execute options if present
+	// Obtain the data and the layout from the execute options if present
 	var data interface{}
+	layout := t.Layout()
 
 	if len(optArgs) > 0 {
 		data = optArgs[0].Data
+
+		if len(optArgs[0].Layout) > 0 {
+			layout = optArgs[0].Layout
+		}
 	}
 
-	// Execute the context.
-	err = ExecuteContext(c, wr, data)
+	// Execute the context and render it into the layout if present.
+	err = executeWithLayout(c, wr, data, layout)
 	if err != nil {
 		return nil, err
 	}
@@ -104,15 +114,20 @@ func (t *Template) ExecuteTemplate(s *sessions.Session, wr io.Writer, name strin
 	// Create a new context.
 	c = newContext(s, tt, optArgs...)
 
-	// Obtain the data from the execute options if present
+	// Obtain the data and the layout from the execute options if present
 	var data interface{}
+	layout := tt.Layout()
 
 	if len(optArgs) > 0 {
 		data = optArgs[0].Data
+
+		if len(optArgs[0].Layout) > 0 {
+			layout = optArgs[0].Layout
+		}
 	}
 
-	// Execute the context.
-	err = ExecuteContext(c, wr, data)
+	// Execute the context and render it into the layout if present.
+	err = executeWithLayout(c, wr, data, layout)
 	if err != nil {
 		return nil, true, err
 	}
@@ -142,12 +157,58 @@ func (t *Template) ExecuteTemplateToString(s *sessions.Session, name string, opt
 	return b.String(), c, found, err
 }
 
+// ExecuteLayout executes the layout template with the given name, which is
+// associated with t. The content is rendered into the content block of the layout.
+// Use this to render content into a layout, which is not the output of a template.
+func (t *Template) ExecuteLayout(s *sessions.Session, wr io.Writer, name string, content string) (err error) {
+	// Recover panics and return the error message.
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("bulldozer execute layout panic: %v", e)
+		}
+	}()
+
+	lt := t.Lookup(name)
+	if lt == nil {
+		return fmt.Errorf("failed to execute layout: template not found with name '%s'", name)
+	}
+
+	l := &layoutData{
+		content: ht.HTML(content),
+	}
+
+	return executeLayout(newContext(s, lt), wr, l)
+}
+
+// ExecuteLayoutToString does the same as ExecuteLayout, but instead writes the output to a string.
+func (t *Template) ExecuteLayoutToString(s *sessions.Session, name string, content string) (string, error) {
+	var b bytes.Buffer
+	err := t.ExecuteLayout(s, &b, name, content)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
 //##############//
 //### Public ###//
 //##############//
 
 // ExecuteContext executes the template context.
+// The layout of the template is not rendered.
 func ExecuteContext(c *Context, wr io.Writer, data interface{}) error {
+	_, err := executeContext(c, wr, data, nil)
+	return err
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// executeContext executes the template context and returns the used render data.
+// The layout data is passed to the blocks of the template.
+func executeContext(c *Context, wr io.Writer, data interface{}, l *layoutData) (*renderData, error) {
 	// Get the template pointer.
 	t := c.t
 
@@ -161,13 +222,13 @@ func ExecuteContext(c *Context, wr io.Writer, data interface{}) error {
 		if action.action == actionError {
 			// Show the error page.
 			c.ns.s.ShowErrorPage(action.data, false)
-			return ExecTemplateAbort
+			return nil, ExecTemplateAbort
 		} else if action.action == actionRedirect {
 			// Navigate to the path.
 			c.ns.s.Navigate(action.data)
-			return ExecTemplateAbort
+			return nil, ExecTemplateAbort
 		} else {
-			return fmt.Errorf("invalid template action type: %v", action.action)
+			return nil, fmt.Errorf("invalid template action type: %v", action.action)
 		}
 	}
 
@@ -178,7 +239,7 @@ func ExecuteContext(c *Context, wr io.Writer, data interface{}) error {
 		// If an error is returned, then abort the execution.
 		switch data.(type) {
 		case error:
-			return fmt.Errorf("failed to get template data from getData function: %v", data.(error))
+			return nil, fmt.Errorf("failed to get template data from getData function: %v", data.(error))
 		}
 	}
 
@@ -189,11 +250,22 @@ func ExecuteContext(c *Context, wr io.Writer, data interface{}) error {
 	defer t.triggerOnTemplateExecutionFinished(c, data)
 
 	// Create the render data
-	d := renderData{
+	d := &renderData{
 		Context: c,
 		Data:    data,
 		Pkg:     packages,
+		layout:  l,
+	}
+
+	// Get the parsed html template.
+	// It might be replaced during a reparse.
+	t.parsedMutex.RLock()
+	tmpl := t.template
+	t.parsedMutex.RUnlock()
+
+	if err := tmpl.Execute(wr, d); err != nil {
+		return nil, err
 	}
 
-	return t.template.Execute(wr, &d)
+	return d, nil
 }
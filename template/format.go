@@ -0,0 +1,178 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	tr "github.com/desertbit/bulldozer/translate"
+
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// The format functions use the locale and time zone of the context's session:
+//
+//	{{fmtDate .Created}}  {{fmtTime .Created}}  {{fmtDateTime .Created}}
+//	{{fmtRelTime .LastLogin}}
+//	{{fmtNumber .Value}}  {{fmtNumber .Value 2}}
+//	{{fmtCurrency .Price "EUR"}}
+//	{{fmtBytes .Size}}
+//
+// Times are passed as time.Time or as Unix timestamps in seconds.
+// Nested calls are written as (trFormat $.Context "fmtDate" .Created).
+
+type formatFunc func(t *tr.Translator, args []interface{}) (string, error)
+
+var (
+	formatFuncs = map[string]formatFunc{
+		"fmtDate": func(t *tr.Translator, args []interface{}) (string, error) {
+			tm, err := formatArgTime(args)
+			if err != nil {
+				return "", err
+			}
+			return t.Date(tm), nil
+		},
+		"fmtTime": func(t *tr.Translator, args []interface{}) (string, error) {
+			tm, err := formatArgTime(args)
+			if err != nil {
+				return "", err
+			}
+			return t.Time(tm), nil
+		},
+		"fmtDateTime": func(t *tr.Translator, args []interface{}) (string, error) {
+			tm, err := formatArgTime(args)
+			if err != nil {
+				return "", err
+			}
+			return t.DateTime(tm), nil
+		},
+		"fmtRelTime": func(t *tr.Translator, args []interface{}) (string, error) {
+			tm, err := formatArgTime(args)
+			if err != nil {
+				return "", err
+			}
+			return t.RelativeTime(tm), nil
+		},
+		"fmtNumber": func(t *tr.Translator, args []interface{}) (string, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return "", fmt.Errorf("expected a number and optional decimals")
+			}
+			f, ok := toFloat64(args[0])
+			if !ok {
+				return "", fmt.Errorf("invalid number: %v", args[0])
+			}
+			decimals := -1
+			if len(args) == 2 {
+				d, ok := toFloat64(args[1])
+				if !ok {
+					return "", fmt.Errorf("invalid decimals: %v", args[1])
+				}
+				decimals = int(d)
+			}
+			return t.Number(f, decimals), nil
+		},
+		"fmtCurrency": func(t *tr.Translator, args []interface{}) (string, error) {
+			if len(args) != 2 {
+				return "", fmt.Errorf("expected an amount and a currency code")
+			}
+			f, ok := toFloat64(args[0])
+			if !ok {
+				return "", fmt.Errorf("invalid amount: %v", args[0])
+			}
+			code, ok := args[1].(string)
+			if !ok {
+				return "", fmt.Errorf("invalid currency code: %v", args[1])
+			}
+			return t.Currency(f, code), nil
+		},
+		"fmtBytes": func(t *tr.Translator, args []interface{}) (string, error) {
+			if len(args) != 1 {
+				return "", fmt.Errorf("expected a byte size")
+			}
+			f, ok := toFloat64(args[0])
+			if !ok {
+				return "", fmt.Errorf("invalid byte size: %v", args[0])
+			}
+			return t.ByteSize(int64(f)), nil
+		},
+	}
+)
+
+func init() {
+	// Register the format parse functions.
+	for name := range formatFuncs {
+		registerParseFunc(name, parseFormat)
+	}
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func parseFormat(typeStr string, token string, d *parseData) error {
+	// Check if the value is set.
+	if len(token) == 0 {
+		return fmt.Errorf("no value set!\nSyntax: {{%s $VALUE}}", typeStr)
+	}
+
+	*d.final += `{{trFormat $.Context "` + typeStr + `" ` + token + `}}`
+
+	return nil
+}
+
+// formatContext formats the arguments with the format function
+// in the locale and time zone of the context's session.
+func formatContext(c *Context, name string, args ...interface{}) (string, error) {
+	f, ok := formatFuncs[name]
+	if !ok {
+		return "", fmt.Errorf("format: no format function with name '%s'", name)
+	}
+
+	s, err := f(tr.For(c.ns.s), args)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+
+	return s, nil
+}
+
+func formatArgTime(args []interface{}) (time.Time, error) {
+	if len(args) != 1 {
+		return time.Time{}, fmt.Errorf("expected one time value")
+	}
+
+	switch v := args[0].(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, fmt.Errorf("time value is nil")
+		}
+		return *v, nil
+	}
+
+	// Unix timestamps in seconds.
+	f, ok := toFloat64(args[0])
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid time value: %v", args[0])
+	}
+
+	return time.Unix(int64(f), 0), nil
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(r.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), true
+	default:
+		return 0, false
+	}
+}
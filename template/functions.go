@@ -16,6 +16,9 @@ import (
 var (
 	bulldozerFuncMap FuncMap = FuncMap{
 		"tr":          tr.S,
+		"trCtx":       translateContext,
+		"trArgs":      translateArgs,
+		"trFormat":    formatContext,
 		"plugin":      renderPlugin,
 		"callFunc":    callTemplateFunc,
 		"eventKeyVar": createEventAccessKeyFromVar,
@@ -23,6 +26,7 @@ var (
 		"tmplR":       renderTemplate,
 		"loadJS":      loadJavaScript,
 		"loadStyle":   loadStyleSheet,
+		"layoutBlock": layoutBlock,
 	}
 )
 
@@ -82,8 +86,9 @@ func renderTemplate(templateName string, id string, r *renderData, values ...int
 	}
 
 	// Execute the sub template context.
+	// Pass the layout blocks to the sub template. It might define regions.
 	var b bytes.Buffer
-	err := ExecuteContext(c, &b, data)
+	_, err := executeContext(c, &b, data, r.layout)
 	if err != nil {
 		return ht.HTML(""), fmt.Errorf("failed to render sub template '%s': %v", templateName, err)
 	}
@@ -104,3 +109,27 @@ func loadStyleSheet(c *Context, url string) string {
 
 	return ""
 }
+
+// translateArgs creates the named translation arguments from key value pairs:
+// {{tr "id" (trArgs "count" .Count)}}
+func translateArgs(values ...interface{}) (tr.Args, error) {
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("invalid translation arguments: values must have a key")
+	}
+
+	args := make(tr.Args, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid translation arguments: keys must be of type string")
+		}
+		args[key] = values[i+1]
+	}
+
+	return args, nil
+}
+
+func translateContext(c *Context, id string, args ...interface{}) string {
+	// Translate in the locale of the session
+	return tr.For(c.ns.s).S(id, args...)
+}
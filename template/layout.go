@@ -0,0 +1,293 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	ht "html/template"
+	"io"
+	"strings"
+)
+
+// Layouts:
+// A template extends a layout template with {{extends "layout"}}.
+// The layout defines named regions with {{block "name"}} ... {{end block}}.
+// The content between the block tags is the default content of the region.
+// The extending template overrides regions by defining top level blocks
+// with the same name. The output of the extending template itself is
+// rendered into the content block. Layouts may extend other layouts.
+// Their content block is filled with the output of the extending template.
+//
+//		layout:	<header>{{block "header"}}Default Header{{end block}}</header>
+//				<main>{{block "content"}}{{end block}}</main>
+//
+//		page:	{{extends "layout"}}
+//				{{block "header"}}Page Header{{end block}}
+//				<p>Page content</p>
+//
+// The block keyword replaces the native {{block "name" pipeline}} action of
+// Go templates. Bulldozer blocks take no pipeline and are closed with
+// {{end block}} instead of {{end}}. They are rendered with the data of the
+// executed template. Use {{define}} with {{template}} for the native behavior.
+
+const (
+	// ContentBlock is the name of the block which contains
+	// the output of the extending template.
+	ContentBlock = "content"
+
+	// NoLayout disables the layout of a template if passed as execute option.
+	NoLayout = "-"
+
+	// The block template names are composed of the template
+	// name, this separator and the block name.
+	blockNameSeparator = "#block#"
+
+	// The maximum layout inheritance depth. This prevents endless loops.
+	maxLayoutDepth = 32
+)
+
+func init() {
+	// Register the layout parse functions
+	registerParseFunc("extends", parseExtends)
+	registerParseFunc("block", parseBlock)
+}
+
+//#############//
+//### Types ###//
+//#############//
+
+// layoutData holds the blocks of the extending templates during a layout execution.
+type layoutData struct {
+	// The output of the directly extending template.
+	content ht.HTML
+
+	// The render data of the extending templates.
+	// The most derived template is the first.
+	providers []*renderData
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// Layout returns the name of the layout template, which is extended by
+// this template. An empty string is returned if no layout is extended.
+func (t *Template) Layout() string {
+	t.parsedMutex.RLock()
+	defer t.parsedMutex.RUnlock()
+
+	return t.layout
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// parseExtends sets the layout of the template.
+// Syntax: {{extends "layout"}}
+func parseExtends(typeStr string, token string, d *parseData) error {
+	// Remove leadiing and following quotes.
+	name := strings.TrimPrefix(strings.TrimSuffix(token, "\""), "\"")
+
+	if len(name) == 0 {
+		return fmt.Errorf("no layout name specified!\nSyntax: {{extends \"$LAYOUT\"}}")
+	} else if len(d.t.layout) > 0 {
+		return fmt.Errorf("the template extends already the layout '%s'!", d.t.layout)
+	} else if d.blocksParsed {
+		return fmt.Errorf("the layout has to be extended before any block is defined!")
+	}
+
+	// Set the template layout.
+	d.t.layout = name
+
+	// Don't add anything to the template text...
+	return nil
+}
+
+// parseBlock defines a block. Top level blocks of extending templates
+// override the blocks of the layout. Otherwise the block defines a region,
+// which is replaced by the override of an extending template if present.
+// The content block is always a region.
+// This shadows the native block action of Go templates.
+// Syntax: {{block "name"}} ... {{end block}}
+func parseBlock(typeStr string, token string, d *parseData) error {
+	// The native block action passes a pipeline after the name.
+	token = strings.TrimSpace(token)
+	if strings.HasPrefix(token, "\"") {
+		if pos := strings.Index(token[1:], "\""); pos != -1 && len(strings.TrimSpace(token[pos+2:])) > 0 {
+			return fmt.Errorf("the native block action {{block \"name\" pipeline}} is not supported: bulldozer blocks take no pipeline!" +
+				"\nSyntax: {{block \"$NAME\"}} ... {{end block}}\nUse {{define}} and {{template}} instead.")
+		}
+	}
+
+	// Remove leadiing and following quotes.
+	name := strings.TrimPrefix(strings.TrimSuffix(token, "\""), "\"")
+
+	if len(name) == 0 {
+		return fmt.Errorf("no block name specified!\nSyntax: {{block \"$NAME\"}} ... {{end block}}")
+	} else if strings.ContainsAny(name, "\" ") {
+		return fmt.Errorf("invalid block name '%s'!", name)
+	}
+
+	// Check if the end tag for the block section is present.
+	// Create a copy of the data string.
+	// Otherwise the following method would remove the section...
+	src := *d.src
+	if _, err := getSection("block", &src, d); err != nil {
+		return fmt.Errorf("invalid block syntax! Missing end tag {{end block}}")
+	}
+
+	// The content block is defined by the output of the extending
+	// template and can't be overridden. It is always a region.
+	if len(d.t.layout) > 0 && d.blockDepth == 0 && name != ContentBlock {
+		// Define the block override as separate template.
+		*d.final += d.leftDelim + `define "` + blockTemplateName(d.t.Name(), name) + `"` + d.rightDelim
+	} else {
+		// Render the override if present. Otherwise the default content.
+		*d.final += d.leftDelim + `with layoutBlock $ "` + name + `"` + d.rightDelim +
+			d.leftDelim + `.` + d.rightDelim +
+			d.leftDelim + `else` + d.rightDelim
+	}
+
+	d.blockDepth++
+	d.blocksParsed = true
+
+	return nil
+}
+
+// parseEndBlock closes the block section.
+func parseEndBlock(d *parseData) error {
+	if d.blockDepth == 0 {
+		return fmt.Errorf("invalid block syntax! Missing start tag {{block}}")
+	}
+
+	d.blockDepth--
+
+	*d.final += d.leftDelim + "end" + d.rightDelim
+
+	return nil
+}
+
+func blockTemplateName(templateName string, blockName string) string {
+	return templateName + blockNameSeparator + blockName
+}
+
+// layoutBlock renders the block override of the extending templates.
+// The override of the most derived template is used.
+// Nil is returned if no override exists.
+func layoutBlock(r *renderData, name string) (interface{}, error) {
+	l := r.layout
+	if l == nil {
+		return nil, nil
+	}
+
+	if name == ContentBlock {
+		return l.content, nil
+	}
+
+	for _, p := range l.providers {
+		// Get the parsed html template.
+		// It might be replaced during a reparse.
+		t := p.Context.t
+		t.parsedMutex.RLock()
+		tmpl := t.template.Lookup(blockTemplateName(t.template.Name(), name))
+		t.parsedMutex.RUnlock()
+
+		if tmpl == nil {
+			continue
+		}
+
+		// Execute the block with the render data of the extending template.
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, p); err != nil {
+			return nil, err
+		}
+
+		return ht.HTML(b.String()), nil
+	}
+
+	return nil, nil
+}
+
+// executeWithLayout executes the context and renders
+// the output into the layout with the given name.
+func executeWithLayout(c *Context, wr io.Writer, data interface{}, layout string) error {
+	if len(layout) == 0 || layout == NoLayout {
+		return ExecuteContext(c, wr, data)
+	}
+
+	// Execute the template itself.
+	var b bytes.Buffer
+	r, err := executeContext(c, &b, data, nil)
+	if err != nil {
+		return err
+	}
+
+	// Create the layout context.
+	lc, err := newLayoutContext(c, layout)
+	if err != nil {
+		return err
+	}
+
+	l := &layoutData{
+		content:   ht.HTML(b.String()),
+		providers: []*renderData{r},
+	}
+
+	return executeLayout(lc, wr, l)
+}
+
+// newLayoutContext creates a sub context for the layout with the given name.
+func newLayoutContext(c *Context, name string) (*Context, error) {
+	t := c.t.Lookup(name)
+	if t == nil {
+		return nil, fmt.Errorf("failed to execute layout: no template found with name: '%s'", name)
+	}
+
+	return c.New(t, c.data.ID+"^"+name), nil
+}
+
+// executeLayout executes the layout context and all parent layouts.
+func executeLayout(c *Context, wr io.Writer, l *layoutData) error {
+	for depth := 1; ; depth++ {
+		// Get the parent layout.
+		parent := c.t.Layout()
+
+		// This is the root layout. Write the output.
+		if len(parent) == 0 {
+			_, err := executeContext(c, wr, nil, l)
+			return err
+		}
+
+		if depth >= maxLayoutDepth {
+			return fmt.Errorf("failed to execute layout '%s': maximum layout depth exceeded: layouts extend each other in a loop?", c.t.Name())
+		}
+
+		// Execute the layout. Its output is the content of the parent layout.
+		var b bytes.Buffer
+		r, err := executeContext(c, &b, nil, l)
+		if err != nil {
+			return err
+		}
+
+		// Create a new providers slice. The render data of the
+		// executed layout references the current slice.
+		providers := make([]*renderData, len(l.providers), len(l.providers)+1)
+		copy(providers, l.providers)
+
+		l = &layoutData{
+			content:   ht.HTML(b.String()),
+			providers: append(providers, r),
+		}
+
+		// Create the parent layout context.
+		c, err = newLayoutContext(c, parent)
+		if err != nil {
+			return err
+		}
+	}
+}
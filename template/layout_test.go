@@ -0,0 +1,152 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"github.com/desertbit/bulldozer/sessions"
+
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{name: "block", src: `{{block "header"}}Header{{end block}}`},
+		{name: "nested blocks", src: `{{block "a"}}{{block "b"}}b{{end block}}{{end block}}`},
+		{name: "native block action", src: `{{block "header" .}}Header{{end}}`, err: "native block action"},
+		{name: "missing name", src: `{{block}}{{end block}}`, err: "no block name"},
+		{name: "invalid name", src: `{{block "a b"}}{{end block}}`, err: "invalid block name"},
+		{name: "missing end tag", src: `{{block "header"}}Header{{end}}`, err: "missing end tag"},
+	}
+
+	for i, test := range tests {
+		_, err := New("layoutTestUID", fmt.Sprintf("test%d", i)).Parse(test.src)
+		if len(test.err) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		} else if err == nil || !strings.Contains(strings.ToLower(err.Error()), test.err) {
+			t.Errorf("%s: expected error containing '%s', got %v", test.name, test.err, err)
+		}
+	}
+}
+
+// The context wrappers and load scripts added to the template output.
+var layoutMarkupRegexp = regexp.MustCompile(`<div id="[^"]*">|</div>|<script>[^<]*</script>`)
+
+// executeLayoutTest parses the templates given as name and source pairs
+// and executes the template with the given name.
+func executeLayoutTest(uid string, templates [][2]string, name string) (string, error) {
+	s := sessions.NewDummy(sessions.DummyOpts{RemoteAddr: "127.0.0.1", UserAgent: "layouttest"})
+	defer s.Close()
+
+	root := New(uid, "root")
+	for _, tt := range templates {
+		if _, err := root.New(tt[0]).Parse(tt[1]); err != nil {
+			return "", err
+		}
+	}
+
+	out, _, err := root.Lookup(name).ExecuteToString(s)
+	if err != nil {
+		return "", err
+	}
+
+	return layoutMarkupRegexp.ReplaceAllString(out, ""), nil
+}
+
+func TestExecuteLayout(t *testing.T) {
+	const layout = `<header>{{block "header"}}Default Header{{end block}}</header>` +
+		`<main>{{block "content"}}{{end block}}</main>`
+
+	tests := []struct {
+		name      string
+		templates [][2]string
+		out       string
+	}{
+		{
+			name: "extends",
+			templates: [][2]string{
+				{"layout", layout},
+				{"page", `{{extends "layout"}}<p>Page content</p>`},
+			},
+			out: `<header>Default Header</header><main><p>Page content</p></main>`,
+		},
+		{
+			name: "block override",
+			templates: [][2]string{
+				{"layout", layout},
+				{"page", `{{extends "layout"}}{{block "header"}}Page Header{{end block}}<p>Page content</p>`},
+			},
+			out: `<header>Page Header</header><main><p>Page content</p></main>`,
+		},
+		{
+			name: "nested block regions",
+			templates: [][2]string{
+				{"layout", `{{block "a"}}A{{block "b"}}B{{end block}}{{end block}}|{{block "content"}}{{end block}}`},
+				{"page", `{{extends "layout"}}{{block "b"}}Page B{{end block}}Page`},
+			},
+			out: `APage B|Page`,
+		},
+		{
+			name: "nested layouts",
+			templates: [][2]string{
+				{"base", `<title>{{block "title"}}Base{{end block}}</title>{{block "content"}}{{end block}}`},
+				{"layout", `{{extends "base"}}{{block "title"}}Layout{{end block}}<main>{{block "content"}}{{end block}}</main>`},
+				{"page", `{{extends "layout"}}<p>Page content</p>`},
+			},
+			out: `<title>Layout</title><main><p>Page content</p></main>`,
+		},
+		{
+			name: "nested layouts with most derived override",
+			templates: [][2]string{
+				{"base", `<title>{{block "title"}}Base{{end block}}</title>{{block "content"}}{{end block}}`},
+				{"layout", `{{extends "base"}}{{block "title"}}Layout{{end block}}<main>{{block "content"}}{{end block}}</main>`},
+				{"page", `{{extends "layout"}}{{block "title"}}Page{{end block}}<p>Page content</p>`},
+			},
+			out: `<title>Page</title><main><p>Page content</p></main>`,
+		},
+	}
+
+	for i, test := range tests {
+		out, err := executeLayoutTest(fmt.Sprintf("layoutExecTestUID%d", i), test.templates, "page")
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if out != test.out {
+			t.Errorf("%s:\ngot:  %q\nwant: %q", test.name, out, test.out)
+		}
+	}
+}
+
+func TestExecuteLayoutLoop(t *testing.T) {
+	templates := [][2]string{
+		{"a", `{{extends "b"}}A{{block "content"}}{{end block}}`},
+		{"b", `{{extends "a"}}B{{block "content"}}{{end block}}`},
+		{"page", `{{extends "a"}}Page`},
+	}
+
+	_, err := executeLayoutTest("layoutLoopTestUID", templates, "page")
+	if err == nil || !strings.Contains(err.Error(), "maximum layout depth exceeded") {
+		t.Fatalf("expected a maximum layout depth error, got %v", err)
+	}
+}
+
+func TestExecuteLayoutNotFound(t *testing.T) {
+	templates := [][2]string{
+		{"page", `{{extends "missing"}}Page`},
+	}
+
+	_, err := executeLayoutTest("layoutNotFoundTestUID", templates, "page")
+	if err == nil || !strings.Contains(err.Error(), "no template found") {
+		t.Fatalf("expected a missing layout error, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"bytes"
+	"strings"
+)
+
+var (
+	// The content of these elements is kept untouched.
+	minifyRawElements = []string{"pre", "textarea", "script", "style"}
+)
+
+//###############//
+//### Private ###//
+//###############//
+
+// minifyHTML collapses whitespace and removes HTML comments of the parsed
+// template source. Template actions, conditional comments and the content of
+// pre, textarea, script and style elements are kept untouched. This includes
+// the javascript sections, which are script elements after parsing.
+// Quoted attribute values are kept untouched as well.
+// Whitespace runs are collapsed to a single newline if they contain a line
+// break. Otherwise a single space is kept.
+func minifyHTML(src string, leftDelim string, rightDelim string) string {
+	var b bytes.Buffer
+	b.Grow(len(src))
+
+	// Whether the source is within an element tag.
+	inTag := false
+
+	for len(src) > 0 {
+		switch {
+		case strings.HasPrefix(src, leftDelim):
+			// Keep the template action.
+			n := indexAfter(src, rightDelim, len(leftDelim))
+			b.WriteString(src[:n])
+			src = src[n:]
+
+		case strings.HasPrefix(src, "<!--"):
+			n := indexAfter(src, "-->", 4)
+
+			// Keep conditional comments.
+			if strings.HasPrefix(src, "<!--[") {
+				b.WriteString(src[:n])
+			}
+			src = src[n:]
+
+		case src[0] == '<':
+			// Keep the content of raw elements.
+			if tag, ok := minifyRawElementTag(src); ok {
+				n := indexAfterCloseTag(src, tag)
+				b.WriteString(src[:n])
+				src = src[n:]
+				continue
+			}
+
+			// Check if an element tag starts.
+			inTag = len(src) > 1 && (src[1] == '/' || isMinifyLetter(src[1]))
+
+			b.WriteByte('<')
+			src = src[1:]
+
+		case inTag && src[0] == '>':
+			inTag = false
+			b.WriteByte('>')
+			src = src[1:]
+
+		case inTag && (src[0] == '"' || src[0] == '\'') && minifyAfterEquals(b.Bytes()):
+			// Keep the quoted attribute value.
+			n := indexAfterQuote(src, leftDelim, rightDelim)
+			b.WriteString(src[:n])
+			src = src[n:]
+
+		case isMinifySpace(src[0]):
+			// Collapse the whitespace.
+			n, newline := 0, false
+			for n < len(src) && isMinifySpace(src[n]) {
+				if src[n] == '\n' {
+					newline = true
+				}
+				n++
+			}
+
+			// Merge with a previous whitespace in front of a removed comment.
+			if l := b.Len(); l > 0 && isMinifySpace(b.Bytes()[l-1]) {
+				if b.Bytes()[l-1] == '\n' {
+					newline = true
+				}
+				b.Truncate(l - 1)
+			}
+
+			if newline {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+			src = src[n:]
+
+		default:
+			b.WriteByte(src[0])
+			src = src[1:]
+		}
+	}
+
+	return b.String()
+}
+
+// minifyRawElementTag checks if the source starts with an opening raw element tag.
+func minifyRawElementTag(src string) (string, bool) {
+	lower := strings.ToLower(src[1:minInt(len(src), 10)])
+
+	for _, tag := range minifyRawElements {
+		if !strings.HasPrefix(lower, tag) || len(lower) == len(tag) {
+			continue
+		}
+
+		// The tag name has to end.
+		c := lower[len(tag)]
+		if c == '>' || c == '/' || isMinifySpace(c) {
+			return tag, true
+		}
+	}
+
+	return "", false
+}
+
+// indexAfterCloseTag returns the index after the closing tag of the element.
+// The length of the source is returned if the closing tag is missing.
+func indexAfterCloseTag(src string, tag string) int {
+	i := strings.Index(strings.ToLower(src), "</"+tag)
+	if i < 0 {
+		return len(src)
+	}
+
+	return indexAfter(src, ">", i)
+}
+
+// indexAfter returns the index after the first occurrence of sep
+// starting from the offset. The length of the source is returned if missing.
+func indexAfter(src string, sep string, offset int) int {
+	if offset > len(src) {
+		return len(src)
+	}
+
+	i := strings.Index(src[offset:], sep)
+	if i < 0 {
+		return len(src)
+	}
+
+	return offset + i + len(sep)
+}
+
+// indexAfterQuote returns the index after the closing quote of the quoted
+// value at the beginning of the source. Quotes within template actions are
+// skipped. The length of the source is returned if the closing quote is missing.
+func indexAfterQuote(src string, leftDelim string, rightDelim string) int {
+	quote := src[0]
+
+	for i := 1; i < len(src); {
+		if strings.HasPrefix(src[i:], leftDelim) {
+			i = indexAfter(src, rightDelim, i+len(leftDelim))
+		} else if src[i] == quote {
+			return i + 1
+		} else {
+			i++
+		}
+	}
+
+	return len(src)
+}
+
+// minifyAfterEquals checks if the last non-whitespace character is an equal sign.
+func minifyAfterEquals(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		if !isMinifySpace(b[i]) {
+			return b[i] == '='
+		}
+	}
+
+	return false
+}
+
+func isMinifyLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isMinifySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
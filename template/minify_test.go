@@ -0,0 +1,95 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"testing"
+)
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		out  string
+	}{
+		{
+			name: "collapse whitespace",
+			src:  "<div>  a \t b  </div>",
+			out:  "<div> a b </div>",
+		},
+		{
+			name: "collapse line breaks",
+			src:  "<div>\n\t\t<span>a</span>\n\n</div>",
+			out:  "<div>\n<span>a</span>\n</div>",
+		},
+		{
+			name: "remove comments",
+			src:  "<div> <!-- comment --> a</div>",
+			out:  "<div> a</div>",
+		},
+		{
+			name: "keep conditional comments",
+			src:  "<!--[if IE]><p>IE</p><![endif]-->",
+			out:  "<!--[if IE]><p>IE</p><![endif]-->",
+		},
+		{
+			name: "keep template actions",
+			src:  "<p>{{if  .A}}  a  {{end}}</p>",
+			out:  "<p>{{if  .A}} a {{end}}</p>",
+		},
+		{
+			name: "keep raw elements",
+			src:  "<pre>  a\n  b</pre>  <textarea>  c  </textarea>",
+			out:  "<pre>  a\n  b</pre> <textarea>  c  </textarea>",
+		},
+		{
+			name: "keep double quoted attribute values",
+			src:  `<div  class="a  b"   title="x` + "\n\t" + `y">  c</div>`,
+			out:  `<div class="a  b" title="x` + "\n\t" + `y"> c</div>`,
+		},
+		{
+			name: "keep single quoted attribute values",
+			src:  `<input  value='a   b'>`,
+			out:  `<input value='a   b'>`,
+		},
+		{
+			name: "quoted attribute value with spaces around the equal sign",
+			src:  `<a href = "a  b">`,
+			out:  `<a href = "a  b">`,
+		},
+		{
+			name: "quoted attribute value with template action",
+			src:  `<a title="{{tr "a  b"}}  c">  d</a>`,
+			out:  `<a title="{{tr "a  b"}}  c"> d</a>`,
+		},
+		{
+			name: "quoted attribute value with closing bracket",
+			src:  `<a title="a > b"  href="c  d">`,
+			out:  `<a title="a > b" href="c  d">`,
+		},
+		{
+			name: "quotes in text",
+			src:  `<p>"a  b"  'c  d'</p>`,
+			out:  `<p>"a b" 'c d'</p>`,
+		},
+		{
+			name: "less than sign in text",
+			src:  `<p>a < b  "c  d"</p>`,
+			out:  `<p>a < b "c d"</p>`,
+		},
+		{
+			name: "missing closing quote",
+			src:  `<a title="a  b`,
+			out:  `<a title="a  b`,
+		},
+	}
+
+	for _, test := range tests {
+		if out := minifyHTML(test.src, "{{", "}}"); out != test.out {
+			t.Errorf("%s:\ngot:  %q\nwant: %q", test.name, out, test.out)
+		}
+	}
+}
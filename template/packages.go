@@ -124,7 +124,13 @@ func RegisterPackage(name string, i interface{}) {
 //###############//
 
 func (t *Template) callMustFuncs(c *Context) (action *Action) {
-	if t.mustFuncs == nil || len(t.mustFuncs) == 0 {
+	// Get the must functions.
+	// They might be replaced during a reparse.
+	t.parsedMutex.RLock()
+	mustFuncs := t.mustFuncs
+	t.parsedMutex.RUnlock()
+
+	if len(mustFuncs) == 0 {
 		return nil
 	}
 
@@ -139,7 +145,7 @@ func (t *Template) callMustFuncs(c *Context) (action *Action) {
 	in[2] = reflect.ValueOf(c)
 
 	// Iterate through all must functions.
-	for _, f := range t.mustFuncs {
+	for _, f := range mustFuncs {
 		// Set the receiver.
 		in[0] = f.receiver
 
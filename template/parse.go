@@ -6,6 +6,8 @@
 package template
 
 import (
+	"github.com/desertbit/bulldozer/settings"
+
 	"fmt"
 	"strings"
 )
@@ -27,6 +29,10 @@ type parseData struct {
 	src       *string
 	final     *string
 	lineCount *int
+
+	// The depth of the nested blocks and whenever any block was parsed.
+	blockDepth   int
+	blocksParsed bool
 }
 
 type parseFunc func(typeStr string, token string, d *parseData) error
@@ -113,10 +119,22 @@ func parse(t *Template, src string, linesCountOffset int) (final string, err err
 		}
 	}
 
-	// Append the rest of the source data to the final string
-	// and wrap the final source between a div tag with the template ID.
+	// Append the rest of the source data to the final string.
+	final += src
+
+	// Check if all blocks are closed.
+	if parseData.blockDepth > 0 {
+		return "", fmt.Errorf("%d: invalid block syntax! Missing end tag {{end block}}", lineCount)
+	}
+
+	// Minify the HTML if enabled.
+	if settings.Settings.MinifyTemplates || settings.Settings.IsProduction() {
+		final = minifyHTML(final, t.leftDelim, t.rightDelim)
+	}
+
+	// Wrap the final source between a div tag with the template ID.
 	// Also execute the js load event for the current template.
-	final = `<div id="{{$.Context.DomID}}"{{with $.Context.StylesString}} class="{{.}}"{{end}}>` + final + src + `<script>Bulldozer.core.execJsLoad("{{$.Context.DomID}}");</script></div>`
+	final = `<div id="{{$.Context.DomID}}"{{with $.Context.StylesString}} class="{{.}}"{{end}}>` + final + `<script>Bulldozer.core.execJsLoad("{{$.Context.DomID}}");</script></div>`
 
 	return final, nil
 }
@@ -22,6 +22,7 @@ func init() {
 	registerParseFunc("style", parseStyle)
 	registerParseFunc("must", parseMust)
 	registerParseFunc("global", parseGlobal)
+	registerParseFunc("tr", parseTranslate)
 }
 
 //###############//
@@ -130,6 +131,9 @@ func parseAddEndTag(typeStr string, token string, d *parseData) error {
 	} else if token == "event" {
 		// Add the event end section
 		*d.final += "});"
+	} else if token == "block" {
+		// Close the block section
+		return parseEndBlock(d)
 	} else {
 		// Nothing to do. Just add the tag as it is.
 		if len(token) > 0 {
@@ -165,6 +169,19 @@ func parseScript(typeStr string, token string, d *parseData) error {
 	return nil
 }
 
+// parseTranslate translates the message in the locale of the context's session.
+// Nested calls like (tr "id") use the default locale.
+func parseTranslate(typeStr string, token string, d *parseData) error {
+	// Check if the message ID is set.
+	if len(token) == 0 {
+		return fmt.Errorf("no translation ID set!\nSyntax: {{tr \"$ID\"}}")
+	}
+
+	*d.final += `{{trCtx $.Context ` + token + `}}`
+
+	return nil
+}
+
 func parseStyle(typeStr string, token string, d *parseData) error {
 	// Check if the stylesheet url is set.
 	if len(token) == 0 {
@@ -37,8 +37,9 @@ func init() {
 	gob.Register(&sessionEvents{})
 	gob.Register(&sessionEvent{})
 
-	// Register the emit template parse function.
+	// Register the emit and upload template parse functions.
 	registerParseFunc("emit", parseEmit)
+	registerParseFunc("upload", parseUpload)
 
 	// Register the emit server request.
 	err := sessions.Request(requestTypeEmit, sessionRequestEmit)
@@ -124,6 +125,59 @@ func (t *Template) RegisterEvents(i interface{}, vars ...string) *Template {
 	return t
 }
 
+//##############//
+//### Public ###//
+//##############//
+
+// EmitRequest returns the session request type and data of a client emit call.
+// The event is passed as "Name" or "Namespace.Name" and has to be registered
+// for the context's DOM ID during the template execution with the emit syntax.
+// The parameters are passed as strings like the client does.
+// Pass the request to sessions.CallRequest to simulate the client call.
+func EmitRequest(c *Context, event string, params ...string) (string, map[string]string, error) {
+	// Set the namespace to the default global one.
+	namespace := globalEventsNameSpace
+
+	// Find the function namespace if present.
+	funcName := event
+	if pos := strings.Index(funcName, "."); pos != -1 {
+		namespace = funcName[:pos]
+		funcName = funcName[pos+1:]
+	}
+
+	// Find the event access key.
+	key, ok := func() (string, bool) {
+		// Get the session events.
+		sEvents := getSessionEvents(c.ns.s)
+
+		// Lock the mutex.
+		sEvents.mutex.Lock()
+		defer sEvents.mutex.Unlock()
+
+		for key, e := range sEvents.Events[c.data.DomID] {
+			if e.FuncNameSpace == namespace && e.FuncName == funcName {
+				return key, true
+			}
+		}
+
+		return "", false
+	}()
+	if !ok {
+		return "", nil, fmt.Errorf("emit request: event '%s.%s' is not registered for DOM ID '%s'", namespace, funcName, c.data.DomID)
+	}
+
+	// Create the request data.
+	data := map[string]string{
+		keyEmitDomID: c.data.DomID,
+		keyEmitKey:   key,
+	}
+	for i, p := range params {
+		data[keyEmitParam+strconv.Itoa(i+1)] = p
+	}
+
+	return requestTypeEmit, data, nil
+}
+
 //###########################//
 //### Private events type ###//
 //###########################//
@@ -235,6 +289,18 @@ func releaseSessionTemplateEvents(s *sessions.Session, domID string) {
 }
 
 func parseEmit(typeStr string, token string, d *parseData) error {
+	return parseEmitCall("Bulldozer.core.emit", token, d)
+}
+
+// parseUpload creates an emit call, which uploads the file passed as
+// first argument. The event function receives the *Upload handle.
+func parseUpload(typeStr string, token string, d *parseData) error {
+	return parseEmitCall("Bulldozer.upload.send", token, d)
+}
+
+// parseEmitCall creates a call to the javascript function with
+// the DOM ID, the event access key and the function arguments.
+func parseEmitCall(jsFunc string, token string, d *parseData) error {
 	// Try to find the '(' symbol
 	pos := strings.Index(token, "(")
 
@@ -285,7 +351,7 @@ func parseEmit(typeStr string, token string, d *parseData) error {
 
 	// Generate the javascript code to call the function
 	// with the DOM ID and the access key.
-	cmd := `Bulldozer.core.emit('{{$.Context.DomID}}','{{eventKey`
+	cmd := jsFunc + `('{{$.Context.DomID}}','{{eventKey`
 
 	// If the function name is a template variable, then don't add quotes.
 	if isTemplateVar {
@@ -438,8 +504,26 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 		return fmt.Errorf("emit request: event access key is missing in the request: %v", data)
 	}
 
-	// Get all parameters (arg1, arg2, arg3, ...) and add it to a slice
-	var params []string
+	// Get all parameters (arg1, arg2, arg3, ...)
+	params := getEmitParams(data)
+
+	// Get the event and the template context.
+	c, event, err := getSessionEvent(s, domID, key)
+	if err != nil {
+		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': %v", domID, key, params, err)
+	}
+
+	// Call the event function.
+	err = callEvent(c, event, params, nil)
+	if err != nil {
+		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': %v", domID, key, params, err)
+	}
+
+	return nil
+}
+
+// getEmitParams returns all parameters (arg1, arg2, arg3, ...) as slice.
+func getEmitParams(data map[string]string) (params []string) {
 	for i := 1; ; i++ {
 		p, ok := data[keyEmitParam+strconv.Itoa(i)]
 		if !ok {
@@ -449,6 +533,12 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 		params = append(params, p)
 	}
 
+	return
+}
+
+// getSessionEvent obtains the registered session event with the DOM ID and
+// access key and creates the template context of the event.
+func getSessionEvent(s *sessions.Session, domID string, key string) (*Context, *event, error) {
 	// Get the event if present.
 	sEvent, ok := func() (event *sessionEvent, ok bool) {
 		// Get the session events.
@@ -468,7 +558,7 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 		return
 	}()
 	if !ok {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': no session event registered.", domID, key, params)
+		return nil, nil, fmt.Errorf("no session event registered.")
 	}
 
 	// Get the context data.
@@ -477,7 +567,7 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 	// Create the template context.
 	c, err := newContextFromData(s, cData)
 	if err != nil {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': %v", domID, key, params, err)
+		return nil, nil, err
 	}
 
 	// Get the event functions of the given namespace.
@@ -491,15 +581,34 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 		return
 	}()
 	if !ok {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': invalid template event namespace '%s'", domID, key, params, sEvent.FuncNameSpace)
+		return nil, nil, fmt.Errorf("invalid template event namespace '%s'", sEvent.FuncNameSpace)
 	}
 
 	// Get the function reflect value.
 	event, ok := events.Get(sEvent.FuncName)
 	if !ok {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': no event function defined '%s'", domID, key, params, sEvent.FuncName)
+		return nil, nil, fmt.Errorf("no event function defined '%s'", sEvent.FuncName)
+	}
+
+	return c, event, nil
+}
+
+// acceptsUpload returns a boolean whenever the event function
+// has an upload parameter.
+func (e *event) acceptsUpload() bool {
+	t := e.method.Type()
+	for i := 2; i < t.NumIn(); i++ {
+		if t.In(i) == uploadType {
+			return true
+		}
 	}
 
+	return false
+}
+
+// callEvent calls the event function with the context and the parameters.
+// The optional upload is passed to the *Upload function parameter.
+func callEvent(c *Context, event *event, params []string, upload *Upload) error {
 	// Get the type of the function.
 	t := event.method.Type()
 
@@ -508,30 +617,49 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 
 	// Check if the first function parameter is of type *Context
 	if funcNumIn < 2 || reflect.TypeOf(c) != t.In(1) {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': the event function's first parameter has to be a *template.Context pointer!", domID, key, params)
+		return fmt.Errorf("the event function's first parameter has to be a *template.Context pointer!")
 	}
 
 	// The receiver is the first in argument. They have to match!
 	if reflect.TypeOf(event.receiver) != t.In(0) {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': the event function's receiver is invalid!", domID, key, params)
+		return fmt.Errorf("the event function's receiver is invalid!")
 	}
 
 	// Check if the number of parameters are valid.
-	if len(params)+2 != funcNumIn {
-		return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v': event parameters don't match!", domID, key, params)
+	numIn := len(params) + 2
+	if upload != nil {
+		numIn++
+	}
+	if numIn != funcNumIn {
+		return fmt.Errorf("event parameters don't match!")
 	}
 
 	// Create the parameters slice
-	in := make([]reflect.Value, len(params)+2)
+	in := make([]reflect.Value, funcNumIn)
 
 	// Add the template receiver and the context as first function parameters
 	in[0] = reflect.ValueOf(event.receiver)
 	in[1] = reflect.ValueOf(c)
 
 	// Add all other parameters to the slice
-	var funcIndex int
-	for k, param := range params {
-		funcIndex = k + 2
+	for funcIndex := 2; funcIndex < funcNumIn; funcIndex++ {
+		// Pass the upload to the upload parameter.
+		if t.In(funcIndex) == uploadType {
+			if upload == nil {
+				return fmt.Errorf("the event function expects a file upload!")
+			}
+
+			in[funcIndex] = reflect.ValueOf(upload)
+			upload = nil
+			continue
+		}
+
+		if len(params) == 0 {
+			return fmt.Errorf("event parameters don't match!")
+		}
+
+		param := params[0]
+		params = params[1:]
 
 		// Convert the parameter if required and add it to the slice
 		switch t.In(funcIndex).Kind() {
@@ -541,7 +669,7 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 			// Convert the string to an integer
 			i, err := strconv.Atoi(param)
 			if err != nil {
-				return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v'", domID, key, params)
+				return fmt.Errorf("invalid integer parameter '%s'", param)
 			}
 
 			in[funcIndex] = reflect.ValueOf(i)
@@ -549,7 +677,7 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 			// Convert the string to an 64-bit integer
 			i, err := strconv.ParseInt(param, 10, 64)
 			if err != nil {
-				return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v'", domID, key, params)
+				return fmt.Errorf("invalid integer parameter '%s'", param)
 			}
 
 			in[funcIndex] = reflect.ValueOf(i)
@@ -557,16 +685,21 @@ func sessionRequestEmit(s *sessions.Session, data map[string]string) error {
 			// Convert the string to a boolean
 			b, err := strconv.ParseBool(param)
 			if err != nil {
-				return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v'", domID, key, params)
+				return fmt.Errorf("invalid boolean parameter '%s'", param)
 			}
 
 			in[funcIndex] = reflect.ValueOf(b)
 		default:
 			// Can't convert the parameter
-			return fmt.Errorf("invalid emit call from client: domID '%s' key '%s' parameters '%v'", domID, key, params)
+			return fmt.Errorf("unsupported event parameter type '%s'", t.In(funcIndex))
 		}
 	}
 
+	// The upload has to be consumed by the event function.
+	if upload != nil {
+		return fmt.Errorf("the event function does not accept a file upload!")
+	}
+
 	// Call the function
 	event.method.Call(in)
 
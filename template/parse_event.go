@@ -7,7 +7,6 @@ package template
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/desertbit/bulldozer/log"
@@ -15,39 +14,47 @@ import (
 	"github.com/desertbit/bulldozer/utils"
 )
 
+const (
+	messageTypeEmitEvent       = "event.emit"
+	messageTypeEmitGlobalEvent = "event.emitGlobal"
+)
+
 func init() {
 	// Register the template parse function.
 	registerParseFunc("event", parseEvent)
 }
 
+//#####################//
+//### Private types ###//
+//#####################//
+
+// eventMessage is the client message payload of a triggered event.
+type eventMessage struct {
+	Event string        `json:"event"`
+	Args  []interface{} `json:"args"`
+}
+
 //##############//
 //### Public ###//
 //##############//
 
 // TriggerGlobalEvent triggers the global event on the client side defined with the template event syntax.
 func TriggerGlobalEvent(s *sessions.Session, eventName string, params ...interface{}) {
-	cmd := `Bulldozer.core.emitGlobalServerEvent('` + utils.EscapeJS(eventName) + `'`
-
-	// Append all the parameters
+	// Check the parameter types.
 	for i, param := range params {
-		// type assertion
-		switch v := param.(type) {
-		case int:
-			cmd += "," + strconv.Itoa(v)
-		case bool:
-			cmd += "," + strconv.FormatBool(v)
-		case string:
-			cmd += ",'" + utils.EscapeJS(v) + "'"
+		switch param.(type) {
+		case int, bool, string:
 		default:
 			log.L.Error("context: trigger global event: invalid type of function event parameter: %v : parameters: %v", i+1, params)
 			return
 		}
 	}
 
-	cmd += ");"
-
-	// Send the command to the client
-	s.SendCommand(cmd)
+	// Send the event to the client
+	s.SendMessage(messageTypeEmitGlobalEvent, "", &eventMessage{
+		Event: eventName,
+		Args:  params,
+	})
 }
 
 //###############//
@@ -0,0 +1,361 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Patches:
+// A patch transforms the previously sent output to the new output.
+// It is encoded as JSON array of operations, which are applied in order:
+//	positive number: copy the next n characters of the previous output.
+//	negative number: skip the next n characters of the previous output.
+//	string:          insert the string.
+// The lengths are counted in UTF-16 code units like javascript strings.
+
+const (
+	// Diff the changed lines character by character
+	// if the changed sections are smaller than this size.
+	patchCharDiffMaxSize = 256
+
+	// FNV-1a hash values.
+	hashOffset32 = 2166136261
+	hashPrime32  = 16777619
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+type patchOpType int
+
+const (
+	patchEqual patchOpType = iota
+	patchDelete
+	patchInsert
+)
+
+type patchOp struct {
+	t    patchOpType
+	text string
+}
+
+type patchBuilder struct {
+	ops []patchOp
+}
+
+func (b *patchBuilder) add(t patchOpType, text string) {
+	if len(text) == 0 {
+		return
+	}
+
+	// Merge with the previous operation of the same type.
+	if l := len(b.ops); l > 0 && b.ops[l-1].t == t {
+		b.ops[l-1].text += text
+		return
+	}
+
+	b.ops = append(b.ops, patchOp{t: t, text: text})
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// createPatch returns the JSON encoded patch, which transforms a to b.
+func createPatch(a string, b string) ([]byte, error) {
+	pb := &patchBuilder{}
+	diffText(pb, a, b)
+
+	ops := make([]interface{}, len(pb.ops))
+	for i, op := range pb.ops {
+		switch op.t {
+		case patchEqual:
+			ops[i] = utf16Len(op.text)
+		case patchDelete:
+			ops[i] = -utf16Len(op.text)
+		default:
+			ops[i] = op.text
+		}
+	}
+
+	// Don't escape the HTML characters. This would enlarge the patch.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(ops); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// diffText adds the operations to transform a to b. The texts are compared
+// line by line. Small changed sections are compared character by character.
+func diffText(pb *patchBuilder, a string, b string) {
+	// Skip the common prefix and suffix.
+	prefix := commonPrefixLen(a, b)
+	suffix := commonSuffixLen(a[prefix:], b[prefix:])
+
+	pb.add(patchEqual, a[:prefix])
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma)+len(mb) <= patchCharDiffMaxSize {
+		diffChars(pb, ma, mb)
+	} else {
+		diffLines(pb, splitLines(ma), splitLines(mb))
+	}
+
+	pb.add(patchEqual, a[len(a)-suffix:])
+}
+
+// diffLines is a patience diff. Lines which are unique in both texts are used
+// as anchors. The longest increasing sequence of anchors is kept and the
+// sections between them are compared recursively.
+func diffLines(pb *patchBuilder, a []string, b []string) {
+	// Skip the common prefix and suffix lines.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		pb.add(patchEqual, a[prefix])
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	suffixLines := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	anchors := uniqueLineAnchors(a, b)
+	if len(anchors) == 0 {
+		// Replace the complete section. Small changes
+		// are compared character by character.
+		ja, jb := strings.Join(a, ""), strings.Join(b, "")
+		if len(ja)+len(jb) <= patchCharDiffMaxSize {
+			diffChars(pb, ja, jb)
+		} else {
+			pb.add(patchDelete, ja)
+			pb.add(patchInsert, jb)
+		}
+	} else {
+		// Compare the sections between the anchors.
+		ia, ib := 0, 0
+		for _, anchor := range anchors {
+			diffLines(pb, a[ia:anchor[0]], b[ib:anchor[1]])
+			pb.add(patchEqual, a[anchor[0]])
+			ia, ib = anchor[0]+1, anchor[1]+1
+		}
+		diffLines(pb, a[ia:], b[ib:])
+	}
+
+	for _, l := range suffixLines {
+		pb.add(patchEqual, l)
+	}
+}
+
+// diffChars compares the texts character by character with the
+// longest common subsequence. Only use this for small texts.
+func diffChars(pb *patchBuilder, a string, b string) {
+	ra, rb := []rune(a), []rune(b)
+
+	// Calculate the longest common subsequence lengths.
+	lcs := make([][]int, len(ra)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(rb)+1)
+	}
+	for i := len(ra) - 1; i >= 0; i-- {
+		for j := len(rb) - 1; j >= 0; j-- {
+			if ra[i] == rb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if ra[i] == rb[j] {
+			pb.add(patchEqual, string(ra[i]))
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			pb.add(patchDelete, string(ra[i]))
+			i++
+		} else {
+			pb.add(patchInsert, string(rb[j]))
+			j++
+		}
+	}
+
+	pb.add(patchDelete, string(ra[i:]))
+	pb.add(patchInsert, string(rb[j:]))
+}
+
+// uniqueLineAnchors returns the index pairs of the lines, which are unique in
+// both slices and which form the longest sequence in the same order.
+func uniqueLineAnchors(a []string, b []string) [][2]int {
+	type lineCount struct {
+		countA, countB int
+		indexA, indexB int
+	}
+
+	lines := make(map[string]*lineCount)
+	for i, l := range a {
+		c, ok := lines[l]
+		if !ok {
+			c = &lineCount{}
+			lines[l] = c
+		}
+		c.countA++
+		c.indexA = i
+	}
+	for i, l := range b {
+		if c, ok := lines[l]; ok {
+			c.countB++
+			c.indexB = i
+		}
+	}
+
+	// Get the unique line pairs ordered by their position in a.
+	var pairs [][2]int
+	for _, c := range lines {
+		if c.countA == 1 && c.countB == 1 {
+			pairs = append(pairs, [2]int{c.indexA, c.indexB})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0]
+	})
+
+	return longestIncreasingPairs(pairs)
+}
+
+// longestIncreasingPairs returns the longest sequence of pairs
+// with increasing second values with patience sorting.
+func longestIncreasingPairs(pairs [][2]int) [][2]int {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	// The index of the last pair of each pile and the predecessor of each pair.
+	var piles []int
+	prev := make([]int, len(pairs))
+
+	for i, p := range pairs {
+		n := sort.Search(len(piles), func(k int) bool {
+			return pairs[piles[k]][1] >= p[1]
+		})
+
+		if n > 0 {
+			prev[i] = piles[n-1]
+		} else {
+			prev[i] = -1
+		}
+
+		if n == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[n] = i
+		}
+	}
+
+	// Follow the predecessors of the last pile.
+	seq := make([][2]int, len(piles))
+	for i, k := len(piles)-1, piles[len(piles)-1]; i >= 0; i, k = i-1, prev[k] {
+		seq[i] = pairs[k]
+	}
+
+	return seq
+}
+
+// splitLines splits the text after each line break.
+func splitLines(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+
+	return lines
+}
+
+// commonPrefixLen returns the length of the common prefix without splitting runes.
+func commonPrefixLen(a string, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+
+	// Don't split a multi-byte rune.
+	for n > 0 && n < len(a) && !utf8.RuneStart(a[n]) {
+		n--
+	}
+
+	return n
+}
+
+// commonSuffixLen returns the length of the common suffix without splitting runes.
+func commonSuffixLen(a string, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+
+	// Don't split a multi-byte rune.
+	for n > 0 && !utf8.RuneStart(a[len(a)-n]) {
+		n--
+	}
+
+	return n
+}
+
+// utf16Len returns the length of the text in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+
+	return n
+}
+
+// textHash returns the 32-bit FNV-1a hash of the UTF-16 code units of the text.
+// The client calculates the same hash with javascript strings.
+func textHash(s string) uint32 {
+	h := uint32(hashOffset32)
+	for _, r := range s {
+		if r >= 0x10000 {
+			r1, r2 := utf16.EncodeRune(r)
+			h = (h ^ uint32(r1)) * hashPrime32
+			h = (h ^ uint32(r2)) * hashPrime32
+		} else {
+			h = (h ^ uint32(r)) * hashPrime32
+		}
+	}
+
+	return h
+}
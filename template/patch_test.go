@@ -0,0 +1,259 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// applyPatch applies the patch to the previous output like the client does.
+// The lengths are counted in UTF-16 code units.
+func applyPatch(prev string, patch []byte) (string, error) {
+	var ops []interface{}
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return "", err
+	}
+
+	p := utf16.Encode([]rune(prev))
+	var out []uint16
+	pos := 0
+
+	for _, op := range ops {
+		switch op := op.(type) {
+		case string:
+			out = append(out, utf16.Encode([]rune(op))...)
+		case float64:
+			n := int(op)
+			if n < 0 {
+				n = -n
+			}
+			if pos+n > len(p) {
+				return "", fmt.Errorf("operation %v exceeds the previous output", op)
+			}
+			if op >= 0 {
+				out = append(out, p[pos:pos+n]...)
+			}
+			pos += n
+		default:
+			return "", fmt.Errorf("invalid operation: %v", op)
+		}
+	}
+
+	if pos != len(p) {
+		return "", fmt.Errorf("patch ends at %d of %d", pos, len(p))
+	}
+
+	return string(utf16.Decode(out)), nil
+}
+
+func TestCreatePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		patch string
+	}{
+		{
+			name:  "equal",
+			a:     "abc",
+			b:     "abc",
+			patch: `[3]`,
+		},
+		{
+			name:  "insert",
+			a:     "ac",
+			b:     "abc",
+			patch: `[1,"b",1]`,
+		},
+		{
+			name:  "delete",
+			a:     "abc",
+			b:     "ac",
+			patch: `[1,-1,1]`,
+		},
+		{
+			name:  "empty previous output",
+			a:     "",
+			b:     "abc",
+			patch: `["abc"]`,
+		},
+		{
+			name:  "empty new output",
+			a:     "abc",
+			b:     "",
+			patch: `[-3]`,
+		},
+		{
+			name:  "html is not escaped",
+			a:     "<a>",
+			b:     "<b>",
+			patch: `[1,-1,"b",1]`,
+		},
+		{
+			name:  "surrogate pair counts as two",
+			a:     "\U0001F600a",
+			b:     "\U0001F600b",
+			patch: `[2,-1,"b"]`,
+		},
+		{
+			name:  "delete surrogate pair",
+			a:     "a\U0001F600b",
+			b:     "ab",
+			patch: `[1,-2,1]`,
+		},
+		{
+			name:  "multi-byte prefix isn't split",
+			a:     "ä",
+			b:     "ö",
+			patch: `[-1,"ö"]`,
+		},
+		{
+			name:  "multi-byte suffix isn't split",
+			a:     "ä",
+			b:     "Ĥ",
+			patch: `[-1,"Ĥ"]`,
+		},
+		{
+			name:  "surrogate pair prefix isn't split",
+			a:     "\U0001F600",
+			b:     "\U0001F601",
+			patch: `[-2,"` + "\U0001F601" + `"]`,
+		},
+	}
+
+	for _, test := range tests {
+		patch, err := createPatch(test.a, test.b)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if string(patch) != test.patch {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.name, patch, test.patch)
+		}
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("<div id=\"%d\">line %d äöü \U0001F600</div>\n", i, i))
+	}
+	long := strings.Join(lines, "")
+
+	// Move, change, insert and remove lines.
+	changed := append([]string{}, lines...)
+	changed[10], changed[80] = changed[80], changed[10]
+	changed[20] = "<div>changed 日本語</div>\n"
+	changed = append(changed[:50], changed[55:]...)
+	changed = append(changed[:30], append([]string{"<p>new</p>\n", "<p>new</p>\n"}, changed[30:]...)...)
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+	}{
+		{"same", long, long},
+		{"changed lines", long, strings.Join(changed, "")},
+		{"reversed", strings.Join(changed, ""), long},
+		{"no line breaks", strings.Repeat("ab\U0001F600", 200), strings.Repeat("a\U0001F600b", 200)},
+		{"replace all", long, strings.Repeat("x\n", 300)},
+		{"clear", long, ""},
+	}
+
+	for _, test := range tests {
+		testPatchRoundTrip(t, test.name, test.a, test.b)
+	}
+
+	// Random changes with multi-byte characters and surrogate pairs.
+	alphabet := []rune("ab\nä日\U0001F600\U0001F601")
+	random := func(r *rand.Rand, n int) string {
+		s := make([]rune, n)
+		for i := range s {
+			s[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return string(s)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a := []rune(random(r, r.Intn(400)))
+		b := append([]rune{}, a...)
+
+		for j := r.Intn(5); j >= 0; j-- {
+			pos := r.Intn(len(b) + 1)
+			end := pos + r.Intn(len(b)-pos+1)
+			b = append(b[:pos], append([]rune(random(r, r.Intn(20))), b[end:]...)...)
+		}
+
+		testPatchRoundTrip(t, fmt.Sprintf("random %d", i), string(a), string(b))
+	}
+}
+
+func testPatchRoundTrip(t *testing.T, name string, a string, b string) {
+	patch, err := createPatch(a, b)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	out, err := applyPatch(a, patch)
+	if err != nil {
+		t.Fatalf("%s: failed to apply patch %s: %v", name, patch, err)
+	}
+	if out != b {
+		t.Fatalf("%s:\ngot:  %q\nwant: %q", name, out, b)
+	}
+	if textHash(out) != textHash(b) {
+		t.Fatalf("%s: hash mismatch", name)
+	}
+}
+
+func TestUTF16Len(t *testing.T) {
+	tests := []struct {
+		s string
+		n int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"äöü", 3},
+		{"日本語", 3},
+		{"\U0001F600", 2},
+		{"a\U0001F600b", 4},
+	}
+
+	for _, test := range tests {
+		if n := utf16Len(test.s); n != test.n {
+			t.Errorf("%q: got %d, want %d", test.s, n, test.n)
+		}
+		if n := len(utf16.Encode([]rune(test.s))); n != test.n {
+			t.Errorf("%q: invalid test value: %d", test.s, n)
+		}
+	}
+}
+
+func TestTextHash(t *testing.T) {
+	// The values are calculated by the javascript textHash function in render.js.
+	tests := []struct {
+		s    string
+		hash uint32
+	}{
+		{"", 2166136261},
+		{"a", 3826002220},
+		{"foobar", 3214735720},
+		{"<div>äöü</div>", 1927795336},
+		{"emoji \U0001F600!", 1470778961},
+		{"日本語", 1409693518},
+	}
+
+	for _, test := range tests {
+		if h := textHash(test.s); h != test.hash {
+			t.Errorf("%q: got %d, want %d", test.s, h, test.hash)
+		}
+	}
+}
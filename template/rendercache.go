@@ -0,0 +1,193 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/settings"
+)
+
+const (
+	// PageDomID is the DOM ID of the page body.
+	PageDomID = "bud-body"
+
+	requestTypeRenderResync = "render.resync"
+	requestKeyDomID         = "did"
+)
+
+func init() {
+	// Register the render resync session request.
+	err := sessions.Request(requestTypeRenderResync, sessionRequestRenderResync)
+	if err != nil {
+		log.L.Fatalf("failed to register session render resync request: %v", err)
+	}
+}
+
+//#############//
+//### Types ###//
+//#############//
+
+// A Patch is sent with the message data if the message body contains
+// a patch instead of the complete rendered output.
+type Patch struct {
+	// The hash of the previous output, which is patched.
+	Base uint32 `json:"base"`
+
+	// The hash of the new output.
+	Hash uint32 `json:"hash"`
+}
+
+// renderCacheKey is the session cache key of the render cache of a session instance.
+type renderCacheKey struct {
+	instanceID string
+}
+
+// renderCache holds the last rendered outputs sent to the client.
+// The client keeps the same outputs to apply the patches.
+type renderCache struct {
+	// Key: DOM ID
+	outputs map[string]string
+	size    int
+	mutex   sync.Mutex
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// DiffOutput compares the rendered output with the last output sent to the
+// client with the same DOM ID. Pass the PageDomID for page bodies. The output
+// is recorded as the last sent output. Send the returned body to the client.
+// If a patch is returned, then the body is the encoded patch. Otherwise the body
+// is the complete output. Rendering a page removes all template outputs, because
+// the client replaces them.
+func DiffOutput(s *sessions.Session, domID string, out []byte) (body []byte, p *Patch) {
+	// Return the complete output if the cache is disabled.
+	if settings.Settings.RenderCacheMaxSize <= 0 {
+		return out, nil
+	}
+
+	rc := getRenderCache(s)
+	output := string(out)
+
+	// Lock the mutex
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	prev, ok := rc.outputs[domID]
+
+	// Remove all template outputs if a page is rendered.
+	if domID == PageDomID {
+		rc.outputs = make(map[string]string)
+		rc.size = 0
+	}
+
+	// Record the new output.
+	rc.set(domID, output)
+
+	if !ok {
+		return out, nil
+	}
+
+	patch, err := createPatch(prev, output)
+	if err != nil {
+		log.L.Error("template: failed to create render patch for '%s': %v", domID, err)
+		return out, nil
+	}
+
+	// Only send the patch if it is smaller.
+	if len(patch) >= len(out) {
+		return out, nil
+	}
+
+	return patch, &Patch{
+		Base: textHash(prev),
+		Hash: textHash(output),
+	}
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func getRenderCache(s *sessions.Session) *renderCache {
+	key := renderCacheKey{s.InstanceID()}
+	created := false
+
+	i, _ := s.CacheGet(key, func() interface{} {
+		created = true
+		return &renderCache{
+			outputs: make(map[string]string),
+		}
+	})
+
+	// Remove the cache as soon as the session instance is closed.
+	if created {
+		s.OnClose(func(s *sessions.Session) {
+			s.CacheDelete(key)
+		})
+	}
+
+	return i.(*renderCache)
+}
+
+// set records the output. The mutex has to be locked.
+func (rc *renderCache) set(domID string, output string) {
+	if prev, ok := rc.outputs[domID]; ok {
+		rc.size -= len(prev)
+	}
+
+	// Skip the output if it doesn't fit.
+	if len(output) > settings.Settings.RenderCacheMaxSize {
+		delete(rc.outputs, domID)
+		return
+	}
+
+	// Remove the other outputs if the maximum size is exceeded.
+	// They are sent completely on the next render.
+	if rc.size+len(output) > settings.Settings.RenderCacheMaxSize {
+		rc.outputs = make(map[string]string)
+		rc.size = 0
+	}
+
+	rc.outputs[domID] = output
+	rc.size += len(output)
+}
+
+// sessionRequestRenderResync is triggered from the client side,
+// if the client failed to apply a patch. The complete output is sent again.
+// Pages are reloaded.
+func sessionRequestRenderResync(s *sessions.Session, data map[string]string) error {
+	domID, ok := data[requestKeyDomID]
+	if !ok {
+		return fmt.Errorf("failed to resync render output: missing DOM ID!")
+	}
+
+	rc := getRenderCache(s)
+
+	// Lock the mutex
+	rc.mutex.Lock()
+	output, ok := rc.outputs[domID]
+	if ok && domID == PageDomID {
+		// The page is rendered again completely.
+		delete(rc.outputs, domID)
+		rc.size -= len(output)
+	}
+	rc.mutex.Unlock()
+
+	// Reload the page if the output isn't cached anymore.
+	if domID == PageDomID || !ok {
+		s.Reload()
+		return nil
+	}
+
+	// Send the complete output.
+	return s.SendBinaryMessage(messageTypeRenderTemplate, domID, nil, []byte(output))
+}
@@ -0,0 +1,119 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	htmlTemplate "html/template"
+)
+
+//##############//
+//### Public ###//
+//##############//
+
+// ReparseFunc parses the template files into the passed template.
+type ReparseFunc func(t *Template) error
+
+// Reparse parses the templates of the namespace again with the parse function.
+// The parse function is called with a new empty template set, which has the
+// same name, delimiters and custom functions as t. Existing templates are
+// only replaced if no error occurred. Otherwise the previous templates are kept.
+// The template values stay the same. Therefore registered events, functions,
+// data functions, style classes and execution listeners are kept.
+// Templates which don't exist anymore are removed from the namespace.
+func (t *Template) Reparse(f ReparseFunc) error {
+	// Create a new namespace with the same UID.
+	// Don't add it to the global namespaces map.
+	ns := &nameSpace{
+		uid: t.ns.uid,
+		set: make(map[string]*Template),
+	}
+
+	// Create the new root template.
+	nt := &Template{
+		template:   htmlTemplate.New(t.Name()),
+		leftDelim:  t.leftDelim,
+		rightDelim: t.rightDelim,
+		ns:         ns,
+		eventsMap:  make(map[string]*events),
+	}
+	nt.template.Delims(t.leftDelim, t.rightDelim)
+
+	// Initialize the template default values.
+	nt.initDefaults()
+
+	// Set the custom functions of the current namespace.
+	func() {
+		t.ns.funcMapMutex.Lock()
+		defer t.ns.funcMapMutex.Unlock()
+
+		if len(t.ns.funcMap) > 0 {
+			nt.Funcs(t.ns.funcMap)
+		}
+	}()
+
+	// Add the root template to the new namespace.
+	ns.Set(nt)
+
+	// Parse the templates.
+	err := f(nt)
+	if err != nil {
+		return err
+	}
+
+	// Replace the templates of the current namespace.
+	t.ns.mutex.Lock()
+	defer t.ns.mutex.Unlock()
+
+	ns.mutex.Lock()
+	defer ns.mutex.Unlock()
+
+	for name, tt := range ns.set {
+		// Add new templates to the current namespace.
+		old, ok := t.ns.set[name]
+		if !ok {
+			tt.ns = t.ns
+			t.ns.set[name] = tt
+			continue
+		}
+
+		old.replaceParsed(tt)
+	}
+
+	// Remove templates which don't exist anymore.
+	for name := range t.ns.set {
+		if _, ok := ns.set[name]; !ok {
+			delete(t.ns.set, name)
+		}
+	}
+
+	return nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// replaceParsed replaces the parsed values of the template with the values of tt.
+func (t *Template) replaceParsed(tt *Template) {
+	func() {
+		// Lock the mutex
+		t.parsedMutex.Lock()
+		defer t.parsedMutex.Unlock()
+
+		t.template = tt.template
+		t.leftDelim = tt.leftDelim
+		t.rightDelim = tt.rightDelim
+		t.mustFuncs = tt.mustFuncs
+		t.layout = tt.layout
+	}()
+
+	// Lock the mutex
+	t.pluginDataMapMutex.Lock()
+	defer t.pluginDataMapMutex.Unlock()
+
+	t.pluginDataMap = tt.pluginDataMap
+	t.pluginDataMapUID = tt.pluginDataMapUID
+}
@@ -46,6 +46,11 @@ type nameSpace struct {
 	// Templates map
 	set   map[string]*Template
 	mutex sync.Mutex
+
+	// The custom functions of the templates.
+	// They are required to parse the templates again.
+	funcMap      FuncMap
+	funcMapMutex sync.Mutex
 }
 
 func newNameSpace(uid string) *nameSpace {
@@ -80,6 +85,20 @@ func (ns *nameSpace) Set(t *Template) {
 	ns.set[t.Name()] = t
 }
 
+func (ns *nameSpace) addFuncs(funcMap FuncMap) {
+	// Lock the mutex
+	ns.funcMapMutex.Lock()
+	defer ns.funcMapMutex.Unlock()
+
+	if ns.funcMap == nil {
+		ns.funcMap = make(FuncMap)
+	}
+
+	for name, f := range funcMap {
+		ns.funcMap[name] = f
+	}
+}
+
 func (ns *nameSpace) Get(name string) *Template {
 	// Lock the mutex
 	ns.mutex.Lock()
@@ -135,6 +154,13 @@ type Template struct {
 	// Must functions
 	mustFuncs []*mustFunc
 
+	// The name of the extended layout template.
+	layout string
+
+	// Protects the parsed html template, the must functions and the layout,
+	// because they are replaced if the templates are parsed again.
+	parsedMutex sync.RWMutex
+
 	globalContextID string
 }
 
@@ -196,6 +222,9 @@ func (t *Template) LookupMust(name string) (tt *Template) {
 
 // Name returns the name of the template.
 func (t *Template) Name() string {
+	t.parsedMutex.RLock()
+	defer t.parsedMutex.RUnlock()
+
 	return t.template.Name()
 }
 
@@ -253,6 +282,9 @@ type FuncMap map[string]interface{}
 // Functions have to be registered before any template which use these functions are parsed.
 // This method is equivalent of calling the html/template Funcs method.
 func (t *Template) Funcs(funcMap FuncMap) *Template {
+	// Remember the functions for the next reparse.
+	t.ns.addFuncs(funcMap)
+
 	t.template.Funcs(htmlTemplate.FuncMap(funcMap))
 	return t
 }
@@ -289,8 +321,9 @@ func (t *Template) Parse(src string) (tt *Template, err error) {
 		t.pluginDataMap = make(pluginDataMap)
 	}()
 
-	// Reset the must functions slice.
+	// Reset the must functions slice and the layout.
 	t.mustFuncs = nil
+	t.layout = ""
 
 	// Call the custom bulldozer parse method.
 	src, err = parse(t, src, 0)
@@ -445,7 +478,7 @@ func (t *Template) initDefaults() {
 	t.pluginDataMap = make(pluginDataMap)
 
 	// Set the bulldozer template functions.
-	t.Funcs(bulldozerFuncMap)
+	t.template.Funcs(htmlTemplate.FuncMap(bulldozerFuncMap))
 
 	// Create a new emitter and set the recover function
 	t.emitter = emission.NewEmitter().
@@ -0,0 +1,520 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+// Package templatetest provides utilities to test bulldozer templates
+// without a browser. Templates are executed with a dummy session, which
+// records all messages sent to the client, the navigation requests and
+// the shown error pages. Template events are emitted like the client does.
+//
+//	s := templatetest.NewSession()
+//	defer s.Close()
+//
+//	r, err := s.ExecuteTemplate(templates.Templates, "login")
+//	...
+//	err = s.Emit(r.Context, "Login", "user", "password")
+//	...
+//	path, err := s.WaitNavigation(time.Second)
+package templatetest
+
+import (
+	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/template"
+	"github.com/desertbit/bulldozer/ui/messagebox"
+
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Client message types of the template and dialog packages.
+	messageTypeEmitEvent  = "event.emit"
+	messageTypeShowDialog = "dialog.show"
+)
+
+var (
+	// The messagebox types by their style class.
+	messageBoxTypes = map[string]messagebox.MessageBoxType{
+		"":         messagebox.TypeDefault,
+		"info":     messagebox.TypeInfo,
+		"success":  messagebox.TypeSuccess,
+		"warning":  messagebox.TypeWarning,
+		"alert":    messagebox.TypeAlert,
+		"question": messagebox.TypeQuestion,
+	}
+)
+
+//#####################//
+//### Session Types ###//
+//#####################//
+
+// A Session is a dummy session which records the client communication.
+// All methods are thread-safe.
+type Session struct {
+	*sessions.Session
+
+	messages      []*sessions.SentMessage
+	navigations   []string
+	errorPages    []string
+	notFoundPages int
+	mutex         sync.Mutex
+
+	// Triggered on each navigation request.
+	navigated chan string
+}
+
+// A Result is the output of a template execution.
+type Result struct {
+	// The rendered HTML.
+	HTML string
+
+	// The context of the executed template.
+	Context *template.Context
+
+	// The messages sent to the client during the execution.
+	Messages []*sessions.SentMessage
+}
+
+// A TriggeredEvent is a client event triggered with Context.TriggerEvent.
+type TriggeredEvent struct {
+	DomID string
+	Name  string
+	Args  []interface{}
+}
+
+// A MessageBox is a messagebox shown to the client.
+type MessageBox struct {
+	Title   string
+	Text    string
+	Type    messagebox.MessageBoxType
+	Buttons messagebox.Button
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// NewSession creates a new dummy session. Optional session options can be passed.
+// The navigation requests and error pages are recorded by the session.
+func NewSession(opts ...sessions.DummyOpts) *Session {
+	var o sessions.DummyOpts
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if len(o.RemoteAddr) == 0 {
+		o.RemoteAddr = "127.0.0.1"
+	}
+	if len(o.UserAgent) == 0 {
+		o.UserAgent = "templatetest"
+	}
+
+	s := &Session{
+		navigated: make(chan string, 64),
+	}
+
+	// Record the navigation requests and error pages.
+	o.Interface = &recorder{s: s}
+
+	s.Session = sessions.NewDummy(o)
+
+	return s
+}
+
+// Execute executes the template with the optional execute options.
+func (s *Session) Execute(t *template.Template, opts ...template.ExecOpts) (*Result, error) {
+	var b bytes.Buffer
+	c, err := t.Execute(s.Session, &b, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newResult(b.String(), c)
+}
+
+// ExecuteTemplate executes the template with the given name, which is
+// associated with t. Optional execute options can be passed.
+func (s *Session) ExecuteTemplate(t *template.Template, name string, opts ...template.ExecOpts) (*Result, error) {
+	var b bytes.Buffer
+	c, _, err := t.ExecuteTemplate(s.Session, &b, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newResult(b.String(), c)
+}
+
+// Emit calls the template event like the client does. The event is passed
+// as "Name" or "Namespace.Name" and has to be emitted by the template of the
+// context. Arguments are passed as strings, integers or booleans.
+func (s *Session) Emit(c *template.Context, event string, args ...interface{}) error {
+	params := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			params[i] = v
+		case int:
+			params[i] = strconv.Itoa(v)
+		case int64:
+			params[i] = strconv.FormatInt(v, 10)
+		case bool:
+			params[i] = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("emit '%s': invalid argument type %T: valid types are string, int, int64 and bool", event, arg)
+		}
+	}
+
+	typeStr, data, err := template.EmitRequest(c, event, params...)
+	if err != nil {
+		return err
+	}
+
+	return sessions.CallRequest(s.Session, typeStr, data)
+}
+
+// Messages returns all recorded messages sent to the client.
+func (s *Session) Messages() ([]*sessions.SentMessage, error) {
+	if _, err := s.readMessages(); err != nil {
+		return nil, err
+	}
+
+	// Lock the mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	messages := make([]*sessions.SentMessage, len(s.messages))
+	copy(messages, s.messages)
+
+	return messages, nil
+}
+
+// Commands returns all javascript commands sent to the client.
+func (s *Session) Commands() ([]string, error) {
+	messages, err := s.messagesOfType(sessions.MessageTypeCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmds []string
+	for _, m := range messages {
+		var cmd string
+		if err = json.Unmarshal(m.Data, &cmd); err != nil {
+			return nil, fmt.Errorf("invalid command message: %v", err)
+		}
+
+		cmds = append(cmds, cmd)
+	}
+
+	return cmds, nil
+}
+
+// TriggeredEvents returns all client events triggered with Context.TriggerEvent.
+func (s *Session) TriggeredEvents() ([]TriggeredEvent, error) {
+	messages, err := s.messagesOfType(messageTypeEmitEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []TriggeredEvent
+	for _, m := range messages {
+		var data struct {
+			Event string        `json:"event"`
+			Args  []interface{} `json:"args"`
+		}
+		if err = json.Unmarshal(m.Data, &data); err != nil {
+			return nil, fmt.Errorf("invalid event message: %v", err)
+		}
+
+		events = append(events, TriggeredEvent{
+			DomID: m.Target,
+			Name:  data.Event,
+			Args:  data.Args,
+		})
+	}
+
+	return events, nil
+}
+
+// LastTriggeredEvent returns the last triggered client event with the name.
+func (s *Session) LastTriggeredEvent(name string) (e TriggeredEvent, ok bool, err error) {
+	events, err := s.TriggeredEvents()
+	if err != nil {
+		return
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Name == name {
+			return events[i], true, nil
+		}
+	}
+
+	return
+}
+
+// MessageBoxes returns all messageboxes shown to the client.
+func (s *Session) MessageBoxes() ([]MessageBox, error) {
+	messages, err := s.messagesOfType(messageTypeShowDialog)
+	if err != nil {
+		return nil, err
+	}
+
+	var boxes []MessageBox
+	for _, m := range messages {
+		var data struct {
+			Body string `json:"body"`
+		}
+		if err = json.Unmarshal(m.Data, &data); err != nil {
+			return nil, fmt.Errorf("invalid dialog message: %v", err)
+		}
+
+		// Skip dialogs which are not messageboxes.
+		if box, ok := parseMessageBox(data.Body); ok {
+			boxes = append(boxes, box)
+		}
+	}
+
+	return boxes, nil
+}
+
+// Navigations returns all recorded navigation request paths.
+// Navigation requests are handled asynchronously. Use WaitNavigation
+// to wait for a navigation request.
+func (s *Session) Navigations() []string {
+	// Lock the mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	paths := make([]string, len(s.navigations))
+	copy(paths, s.navigations)
+
+	return paths
+}
+
+// WaitNavigation waits for the next navigation request and returns its path.
+func (s *Session) WaitNavigation(timeout time.Duration) (string, error) {
+	select {
+	case path := <-s.navigated:
+		return path, nil
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no navigation request within %v", timeout)
+	}
+}
+
+// ErrorPages returns the messages of all shown error pages.
+func (s *Session) ErrorPages() []string {
+	// Lock the mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	msgs := make([]string, len(s.errorPages))
+	copy(msgs, s.errorPages)
+
+	return msgs
+}
+
+// NotFoundPages returns how often the not found page was shown.
+func (s *Session) NotFoundPages() int {
+	// Lock the mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.notFoundPages
+}
+
+// Reset removes all recorded messages, navigation requests and error pages.
+func (s *Session) Reset() error {
+	if _, err := s.readMessages(); err != nil {
+		return err
+	}
+
+	// Lock the mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.messages = nil
+	s.navigations = nil
+	s.errorPages = nil
+	s.notFoundPages = 0
+
+	// Drain the navigation channel.
+	for {
+		select {
+		case <-s.navigated:
+		default:
+			return nil
+		}
+	}
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func (s *Session) newResult(out string, c *template.Context) (*Result, error) {
+	messages, err := s.readMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		HTML:     out,
+		Context:  c,
+		Messages: messages,
+	}, nil
+}
+
+// readMessages reads the new messages of the session and records them.
+func (s *Session) readMessages() ([]*sessions.SentMessage, error) {
+	messages, err := s.Session.ReadMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.messages = append(s.messages, messages...)
+
+	return messages, nil
+}
+
+func (s *Session) messagesOfType(msgType string) ([]*sessions.SentMessage, error) {
+	messages, err := s.Messages()
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []*sessions.SentMessage
+	for _, m := range messages {
+		if m.Type == msgType {
+			filtered = append(filtered, m)
+		}
+	}
+
+	return filtered, nil
+}
+
+// parseMessageBox extracts the messagebox values of the rendered dialog body.
+func parseMessageBox(body string) (box MessageBox, ok bool) {
+	const topbarPrefix = `<div class="topbar`
+
+	pos := strings.Index(body, topbarPrefix)
+	if pos == -1 {
+		return
+	}
+	body = body[pos+len(topbarPrefix):]
+
+	// Get the type by its style class.
+	class, ok := textBetween(body, "", `"`)
+	if !ok {
+		return
+	}
+	if box.Type, ok = messageBoxTypes[strings.TrimSpace(class)]; !ok {
+		return
+	}
+
+	title, ok := textBetween(body, "<h3>", "</h3>")
+	if !ok {
+		return
+	}
+
+	text, ok := textBetween(body, "<p>", "</p>")
+	if !ok {
+		return
+	}
+
+	box.Title = html.UnescapeString(title)
+	box.Text = html.UnescapeString(text)
+
+	// Each button passes its type to the button clicked event.
+	const buttonPrefix = `var t = "`
+	for {
+		pos = strings.Index(body, buttonPrefix)
+		if pos == -1 {
+			break
+		}
+		body = body[pos+len(buttonPrefix):]
+
+		value, ok := textBetween(body, "", `"`)
+		if !ok {
+			return box, false
+		}
+
+		b, err := strconv.Atoi(value)
+		if err != nil {
+			return box, false
+		}
+		box.Buttons |= messagebox.Button(b)
+	}
+
+	// A messagebox has at least one button.
+	return box, box.Buttons != 0
+}
+
+func textBetween(s string, start string, end string) (string, bool) {
+	pos := strings.Index(s, start)
+	if pos == -1 {
+		return "", false
+	}
+	s = s[pos+len(start):]
+
+	pos = strings.Index(s, end)
+	if pos == -1 {
+		return "", false
+	}
+
+	return s[:pos], true
+}
+
+//#######################//
+//### Recorder Struct ###//
+//#######################//
+
+// recorder implements the sessions interface and records
+// the navigation requests and error pages.
+type recorder struct {
+	s *Session
+}
+
+func (r *recorder) NavigateFunc(s *sessions.Session, path string) {
+	// Set the current path like the router does.
+	s.SetCurrentPath(path)
+
+	func() {
+		// Lock the mutex
+		r.s.mutex.Lock()
+		defer r.s.mutex.Unlock()
+
+		r.s.navigations = append(r.s.navigations, path)
+	}()
+
+	// Notify waiting calls. Don't block if nobody waits.
+	select {
+	case r.s.navigated <- path:
+	default:
+	}
+}
+
+func (r *recorder) ShowErrorPage(s *sessions.Session, errorMessage string, vars ...bool) {
+	// Lock the mutex
+	r.s.mutex.Lock()
+	defer r.s.mutex.Unlock()
+
+	r.s.errorPages = append(r.s.errorPages, errorMessage)
+}
+
+func (r *recorder) ShowNotFoundPage(s *sessions.Session) {
+	// Lock the mutex
+	r.s.mutex.Lock()
+	defer r.s.mutex.Unlock()
+
+	r.s.notFoundPages++
+}
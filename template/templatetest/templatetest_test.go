@@ -0,0 +1,166 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package templatetest
+
+import (
+	"github.com/desertbit/bulldozer/template"
+	"github.com/desertbit/bulldozer/ui/messagebox"
+
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTemplateText = `<div>
+	<span>{{#.Name}}</span>
+	{{js load}}
+		$("#{{id "save"}}").click(function() {
+			{{emit Save("value")}}
+		});
+	{{end js}}
+</div>`
+
+type testReceiver struct{}
+
+func (r *testReceiver) EventSave(c *template.Context, value string) {
+	c.TriggerEvent("saved", value, 1, true)
+
+	messagebox.New().
+		SetTitle("Saved").
+		SetText("The value <" + value + "> was saved.").
+		SetType(messagebox.TypeSuccess).
+		SetButtons(messagebox.ButtonOk | messagebox.ButtonCancel).
+		Show(c.Session())
+
+	c.Session().Navigate("/saved")
+}
+
+func newTestTemplate(t *testing.T) *template.Template {
+	tt, err := template.New("templatetestUID", "test").Parse(testTemplateText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt.RegisterEvents(new(testReceiver))
+
+	return tt
+}
+
+func executeTestTemplate(t *testing.T, s *Session) *Result {
+	r, err := s.Execute(newTestTemplate(t), template.ExecOpts{
+		Data: struct{ Name string }{Name: "bulldozer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestExecute(t *testing.T) {
+	s := NewSession()
+	defer s.Close()
+
+	r := executeTestTemplate(t, s)
+
+	if !strings.Contains(r.HTML, "<span>bulldozer</span>") {
+		t.Fatalf("invalid output: %s", r.HTML)
+	}
+	if r.Context == nil {
+		t.Fatal("context is nil")
+	}
+}
+
+func TestEmit(t *testing.T) {
+	s := NewSession()
+	defer s.Close()
+
+	r := executeTestTemplate(t, s)
+
+	err := s.Emit(r.Context, "Save", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Navigation requests are handled asynchronously.
+	path, err := s.WaitNavigation(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	} else if path != "/saved" {
+		t.Fatalf("invalid navigation path: %s", path)
+	}
+
+	// Triggered events.
+	events, err := s.TriggeredEvents()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 1 {
+		t.Fatalf("expected 1 triggered event, got %d", len(events))
+	}
+
+	e := events[0]
+	if e.Name != "saved" || e.DomID != r.Context.DomID() {
+		t.Fatalf("invalid triggered event: %+v", e)
+	} else if len(e.Args) != 3 || e.Args[0] != "foo" || e.Args[1] != float64(1) || e.Args[2] != true {
+		t.Fatalf("invalid triggered event arguments: %#v", e.Args)
+	}
+
+	if _, ok, err := s.LastTriggeredEvent("saved"); err != nil || !ok {
+		t.Fatalf("last triggered event: got (%v, %v)", ok, err)
+	}
+
+	// Message boxes.
+	boxes, err := s.MessageBoxes()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(boxes) != 1 {
+		t.Fatalf("expected 1 messagebox, got %d", len(boxes))
+	}
+
+	want := MessageBox{
+		Title:   "Saved",
+		Text:    "The value <foo> was saved.",
+		Type:    messagebox.TypeSuccess,
+		Buttons: messagebox.ButtonOk | messagebox.ButtonCancel,
+	}
+	if boxes[0] != want {
+		t.Fatalf("invalid messagebox: got %+v, want %+v", boxes[0], want)
+	}
+
+	// Reset removes the recorded values.
+	if err = s.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ = s.TriggeredEvents(); len(events) != 0 {
+		t.Fatalf("expected no triggered events after reset, got %d", len(events))
+	}
+	if paths := s.Navigations(); len(paths) != 0 {
+		t.Fatalf("expected no navigations after reset, got %v", paths)
+	}
+}
+
+func TestEmitUnknownEvent(t *testing.T) {
+	s := NewSession()
+	defer s.Close()
+
+	r := executeTestTemplate(t, s)
+
+	if err := s.Emit(r.Context, "Unknown"); err == nil {
+		t.Fatal("expected an error for an unknown event")
+	}
+	if err := s.Emit(r.Context, "Save", 1.5); err == nil {
+		t.Fatal("expected an error for an invalid argument type")
+	}
+}
+
+func TestWaitNavigationTimeout(t *testing.T) {
+	s := NewSession()
+	defer s.Close()
+
+	if _, err := s.WaitNavigation(10 * time.Millisecond); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+}
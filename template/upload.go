@@ -0,0 +1,476 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"fmt"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/settings"
+	"github.com/desertbit/bulldozer/utils"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File uploads are bound to an emit event function with an *Upload parameter.
+// The client requests a one-time upload token over the session socket.
+// Afterwards the file is streamed with a POST request to the upload URL.
+// The server pushes the progress over the session socket and calls the
+// event function as soon as the file is stored in the upload path.
+
+const (
+	UrlUpload = "/bulldozer/upload"
+
+	uploadTokenLength = 32
+
+	// A requested upload has to start within this duration.
+	uploadTokenTimeout = time.Minute
+
+	// Don't send progress messages more often than this.
+	uploadProgressInterval = 250 * time.Millisecond
+
+	// The count of bytes used to detect the content type.
+	uploadSniffLength = 512
+
+	requestTypeUpload = "upload"
+	keyUploadID       = "id"
+	keyUploadName     = "name"
+	keyUploadSize     = "size"
+	keyUploadType     = "type"
+	keyUploadToken    = "token"
+
+	// Client message types
+	messageTypeUploadStart    = "upload.start"
+	messageTypeUploadProgress = "upload.progress"
+	messageTypeUploadDone     = "upload.done"
+	messageTypeUploadError    = "upload.error"
+)
+
+var (
+	uploadType = reflect.TypeOf((*Upload)(nil))
+
+	// Key: upload token
+	pendingUploads      map[string]*pendingUpload = make(map[string]*pendingUpload)
+	pendingUploadsMutex sync.Mutex
+)
+
+func init() {
+	// Register the upload token server request.
+	err := sessions.Request(requestTypeUpload, sessionRequestUpload)
+	if err != nil {
+		log.L.Fatalf("failed to register session upload request: %v", err)
+	}
+
+	// Register the upload handler.
+	http.HandleFunc(UrlUpload, handleUpload)
+}
+
+//###################//
+//### Upload type ###//
+//###################//
+
+// An Upload is a file uploaded by the client.
+// The file is stored in the upload path and removed as soon as the
+// event function returns. Use MoveTo to keep the file.
+type Upload struct {
+	name        string
+	size        int64
+	contentType string
+	path        string
+	moved       bool
+}
+
+// Name returns the file name passed by the client.
+// Don't trust this value.
+func (u *Upload) Name() string {
+	return u.name
+}
+
+// Size returns the file size in bytes.
+func (u *Upload) Size() int64 {
+	return u.size
+}
+
+// ContentType returns the MIME type passed by the client.
+func (u *Upload) ContentType() string {
+	return u.contentType
+}
+
+// Path returns the path of the uploaded file.
+// The file is removed after the event function returns.
+func (u *Upload) Path() string {
+	return u.path
+}
+
+// Open opens the uploaded file for reading.
+func (u *Upload) Open() (*os.File, error) {
+	return os.Open(u.path)
+}
+
+// MoveTo moves the uploaded file to the destination path.
+// The file is not removed anymore after the event function returns.
+func (u *Upload) MoveTo(dst string) error {
+	if u.moved {
+		return fmt.Errorf("upload: file '%s' was already moved!", u.name)
+	}
+
+	err := os.Rename(u.path, dst)
+	if err != nil {
+		// The upload path might be on a different device.
+		// Fallback to copy the file.
+		if err = utils.CopyFile(u.path, dst); err != nil {
+			return fmt.Errorf("upload: failed to move file to '%s': %v", dst, err)
+		}
+
+		os.Remove(u.path)
+	}
+
+	u.path = dst
+	u.moved = true
+
+	return nil
+}
+
+//##############################//
+//### Private pending upload ###//
+//##############################//
+
+type pendingUpload struct {
+	s           *sessions.Session
+	id          string
+	domID       string
+	key         string
+	params      []string
+	name        string
+	size        int64
+	contentType string
+	expires     time.Time
+}
+
+// sendError notifies the client about the failed upload.
+func (p *pendingUpload) sendError(msg string) {
+	p.s.SendMessage(messageTypeUploadError, "", map[string]string{
+		"id":    p.id,
+		"error": msg,
+	})
+}
+
+// sniffWriter keeps the first bytes of the upload to detect the content type.
+type sniffWriter struct {
+	head []byte
+}
+
+func (w *sniffWriter) Write(b []byte) (int, error) {
+	if n := uploadSniffLength - len(w.head); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		w.head = append(w.head, b[:n]...)
+	}
+
+	return len(b), nil
+}
+
+// progressWriter pushes the upload progress to the client.
+type progressWriter struct {
+	p        *pendingUpload
+	loaded   int64
+	lastSent time.Time
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.loaded += int64(len(b))
+
+	if time.Since(w.lastSent) >= uploadProgressInterval {
+		w.lastSent = time.Now()
+
+		w.p.s.SendMessage(messageTypeUploadProgress, "", map[string]interface{}{
+			"id":     w.p.id,
+			"loaded": w.loaded,
+			"total":  w.p.size,
+		})
+	}
+
+	return len(b), nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// sessionRequestUpload is triggered from the client side.
+// Check the upload and issue a one-time upload token.
+func sessionRequestUpload(s *sessions.Session, data map[string]string) error {
+	p := &pendingUpload{
+		s:           s,
+		id:          data[keyUploadID],
+		domID:       data[keyEmitDomID],
+		key:         data[keyEmitKey],
+		params:      getEmitParams(data),
+		name:        filepath.Base(data[keyUploadName]),
+		contentType: data[keyUploadType],
+		expires:     time.Now().Add(uploadTokenTimeout),
+	}
+
+	if len(p.id) == 0 || len(p.domID) == 0 || len(p.key) == 0 {
+		return fmt.Errorf("upload request: missing upload ID, DOM ID or event access key: %v", data)
+	}
+
+	// Parse the media type and remove the parameters.
+	if len(p.contentType) > 0 {
+		mediaType, _, err := mime.ParseMediaType(p.contentType)
+		if err != nil {
+			p.sendError("invalid file type")
+			return fmt.Errorf("upload request: invalid content type '%s': %v", p.contentType, err)
+		}
+
+		p.contentType = mediaType
+	}
+
+	// Check the file size.
+	var err error
+	p.size, err = strconv.ParseInt(data[keyUploadSize], 10, 64)
+	if err != nil || p.size < 0 {
+		p.sendError("invalid file size")
+		return fmt.Errorf("upload request: invalid file size: %v", data)
+	} else if p.size > settings.Settings.UploadMaxSize {
+		p.sendError("file too large")
+		return fmt.Errorf("upload request: file '%s' exceeds the maximum upload size: %v bytes", p.name, p.size)
+	}
+
+	// Check the file type.
+	if !isUploadTypeAllowed(p.contentType) {
+		p.sendError("file type not allowed")
+		return fmt.Errorf("upload request: file '%s' has a forbidden content type: '%s'", p.name, p.contentType)
+	}
+
+	// Check if the event function accepts the upload.
+	_, event, err := getSessionEvent(s, p.domID, p.key)
+	if err != nil {
+		p.sendError("invalid upload")
+		return fmt.Errorf("upload request: domID '%s' key '%s': %v", p.domID, p.key, err)
+	} else if !event.acceptsUpload() {
+		p.sendError("invalid upload")
+		return fmt.Errorf("upload request: domID '%s' key '%s': the event function does not accept a file upload!", p.domID, p.key)
+	}
+
+	// Add the pending upload with a new unique token.
+	token := func() string {
+		// Lock the mutex
+		pendingUploadsMutex.Lock()
+		defer pendingUploadsMutex.Unlock()
+
+		// Release expired tokens.
+		now := time.Now()
+		for t, pu := range pendingUploads {
+			if now.After(pu.expires) {
+				delete(pendingUploads, t)
+			}
+		}
+
+		var token string
+		for {
+			token = utils.RandomString(uploadTokenLength)
+			if _, ok := pendingUploads[token]; !ok {
+				break
+			}
+		}
+
+		pendingUploads[token] = p
+
+		return token
+	}()
+
+	// Tell the client to start the upload.
+	s.SendMessage(messageTypeUploadStart, "", map[string]string{
+		"id":    p.id,
+		"token": token,
+	})
+
+	return nil
+}
+
+// handleUpload receives the upload POST requests.
+func handleUpload(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		log.L.Warning("client tried to access the upload interface with an invalid http method: %s", req.Method)
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Obtain and remove the pending upload. Tokens are only valid once.
+	token := req.URL.Query().Get(keyUploadToken)
+
+	pendingUploadsMutex.Lock()
+	p, ok := pendingUploads[token]
+	delete(pendingUploads, token)
+	pendingUploadsMutex.Unlock()
+
+	if !ok || time.Now().After(p.expires) || p.s.IsClosed() {
+		log.L.Warning("client requested an upload with an invalid or expired token")
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Store the file in the upload path.
+	path, head, err := receiveUpload(p, req.Body)
+	if err != nil {
+		log.L.Warning("upload: session %s: file '%s': %v", p.s.SessionID(), p.name, err)
+		p.sendError("upload failed")
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Don't trust the content type passed by the client.
+	// Check the detected content type of the file too.
+	if detected := detectUploadType(head); !isUploadContentAllowed(p.contentType, detected) {
+		os.Remove(path)
+		log.L.Warning("upload: session %s: file '%s' with content type '%s' has a forbidden detected content type: '%s'",
+			p.s.SessionID(), p.name, p.contentType, detected)
+		p.sendError("file type not allowed")
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	u := &Upload{
+		name:        p.name,
+		size:        p.size,
+		contentType: p.contentType,
+		path:        path,
+	}
+
+	// Remove the file if not moved by the event function.
+	defer func() {
+		if !u.moved {
+			os.Remove(path)
+		}
+	}()
+
+	// Call the event function.
+	if err = emitUpload(p, u); err != nil {
+		log.L.Error("upload: %v", err)
+		p.sendError("upload failed")
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	p.s.SendMessage(messageTypeUploadDone, "", map[string]string{
+		"id": p.id,
+	})
+}
+
+// receiveUpload streams the request body to a new file in the upload path.
+// The first bytes of the file are returned to detect the content type.
+// The file is removed on error.
+func receiveUpload(p *pendingUpload, body io.Reader) (path string, head []byte, err error) {
+	f, err := ioutil.TempFile(settings.Settings.UploadPath, "upload-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+
+	path = f.Name()
+
+	defer func() {
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	// Read one byte more than announced to detect oversized bodies.
+	progress := &progressWriter{p: p}
+	sniff := &sniffWriter{}
+	n, err := io.Copy(io.MultiWriter(f, progress, sniff), io.LimitReader(body, p.size+1))
+
+	// Close the file.
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+
+	if err != nil {
+		return "", nil, err
+	} else if n != p.size {
+		return "", nil, fmt.Errorf("received %v bytes, but expected %v bytes", n, p.size)
+	}
+
+	return path, sniff.head, nil
+}
+
+// emitUpload calls the event function with the upload.
+func emitUpload(p *pendingUpload, u *Upload) (err error) {
+	// Recover panics and log the error message.
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("bulldozer template upload emit panic: %v", e)
+		}
+	}()
+
+	// Get the event again. The template might have been rerendered.
+	c, event, err := getSessionEvent(p.s, p.domID, p.key)
+	if err == nil {
+		err = callEvent(c, event, p.params, u)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid upload emit call: domID '%s' key '%s' parameters '%v': %v", p.domID, p.key, p.params, err)
+	}
+
+	return nil
+}
+
+// isUploadTypeAllowed checks the MIME type against the allowed upload types.
+func isUploadTypeAllowed(contentType string) bool {
+	return len(settings.Settings.UploadContentTypes) == 0 || matchUploadType(contentType, true)
+}
+
+// isUploadContentAllowed checks the detected MIME type of the uploaded file
+// against the allowed upload types. Content sniffing can't distinguish many
+// types, like text/csv or office documents. These are detected as generic
+// types. A generic type is only accepted if the MIME type passed by the client
+// is allowed without a wildcard.
+func isUploadContentAllowed(contentType string, detected string) bool {
+	if len(settings.Settings.UploadContentTypes) == 0 || matchUploadType(detected, true) {
+		return true
+	}
+
+	switch detected {
+	case "application/octet-stream", "text/plain", "application/zip":
+		return matchUploadType(contentType, false)
+	}
+
+	return false
+}
+
+// matchUploadType returns a boolean whenever the MIME type is listed in the
+// allowed upload types. Wildcards are only matched if enabled.
+func matchUploadType(contentType string, wildcards bool) bool {
+	for _, a := range settings.Settings.UploadContentTypes {
+		if a == contentType {
+			return true
+		} else if wildcards && strings.HasSuffix(a, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// detectUploadType returns the MIME type detected by the first bytes of a file.
+func detectUploadType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	return mediaType
+}
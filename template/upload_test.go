@@ -0,0 +1,56 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"github.com/desertbit/bulldozer/settings"
+
+	"testing"
+)
+
+func TestIsUploadContentAllowed(t *testing.T) {
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+	pdf := []byte("%PDF-1.4\n")
+	html := []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+	csv := []byte("a,b,c\n1,2,3\n")
+	zip := []byte("PK\x03\x04\x14\x00\x06\x00")
+	exe := []byte("MZ\x90\x00\x03\x00\x00\x00")
+
+	tests := []struct {
+		name        string
+		allowed     []string
+		contentType string
+		head        []byte
+		ok          bool
+	}{
+		{"all types allowed", nil, "text/html", html, true},
+		{"wildcard", []string{"image/*"}, "image/png", png, true},
+		{"exact type", []string{"application/pdf"}, "application/pdf", pdf, true},
+		{"html declared as image", []string{"image/*"}, "image/png", html, false},
+		{"html declared as pdf", []string{"application/pdf"}, "application/pdf", html, false},
+		{"executable declared as image", []string{"image/*"}, "image/png", exe, false},
+		{"undetectable type listed exactly", []string{"text/csv"}, "text/csv", csv, true},
+		{"undetectable type with wildcard", []string{"text/*"}, "text/csv", csv, true},
+		{"undetectable type not listed", []string{"image/*"}, "image/png", csv, false},
+		{"executable declared as listed type", []string{"text/csv"}, "text/csv", exe, true},
+		{"office document", []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document", zip, true},
+		{"zip declared as image", []string{"image/*"}, "image/png", zip, false},
+	}
+
+	defer func(types []string) {
+		settings.Settings.UploadContentTypes = types
+	}(settings.Settings.UploadContentTypes)
+
+	for _, test := range tests {
+		settings.Settings.UploadContentTypes = test.allowed
+
+		detected := detectUploadType(test.head)
+		if ok := isUploadContentAllowed(test.contentType, detected); ok != test.ok {
+			t.Errorf("%s: detected '%s': got %v, want %v", test.name, detected, ok, test.ok)
+		}
+	}
+}
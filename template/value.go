@@ -0,0 +1,290 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package template
+
+import (
+	"sync"
+
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+)
+
+// Values:
+// A value is an observable value, which can be shared between all sessions.
+// Contexts subscribe to a value by reading it with Get during their execution.
+// Template package functions are passed the executing context:
+//	func (p *Pkg) Counter(c *template.Context) interface{} { return counter.Get(c) }
+//	{{%.pkg.Counter}}
+// Setting a new value updates all subscribed contexts of all sessions.
+// Contexts are updated on their own. Sub templates have to obtain the value
+// without their parent's data. The contexts have to read the value again
+// during their update to stay subscribed.
+
+const (
+	// Remove the closed sessions from the subscriptions
+	// as soon as this count of subscribed sessions is reached.
+	valueMinPruneCount = 64
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+// A Value is an observable value. Contexts which read the value with Get
+// are updated automatically as soon as a new value is set.
+// The subscribed contexts are obtained through the session context store.
+// Therefore the context store is enabled for each subscribed session.
+type Value struct {
+	value interface{}
+	mutex sync.Mutex
+
+	// Key: session, Key: context ID
+	subscribers map[*sessions.Session]map[string]*contextData
+	pruneCount  int
+
+	// The pending contexts of the sessions, which are currently updated.
+	// Each session is updated in its own goroutine. This way a slow
+	// session doesn't delay the updates of the other sessions.
+	// Key: session, Key: context ID
+	updating map[*sessions.Session]map[string]*contextData
+}
+
+// NewValue creates a new observable value with the initial value.
+func NewValue(i interface{}) *Value {
+	return &Value{
+		value:       i,
+		subscribers: make(map[*sessions.Session]map[string]*contextData),
+		pruneCount:  valueMinPruneCount,
+		updating:    make(map[*sessions.Session]map[string]*contextData),
+	}
+}
+
+// Get returns the current value and subscribes the context.
+// The context is updated as soon as a new value is set.
+// Call this during the execution of the context's template.
+func (v *Value) Get(c *Context) interface{} {
+	// Add the context to the subscribers.
+	v.subscribe(c)
+
+	// Lock the mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return v.value
+}
+
+// Peek returns the current value without subscribing a context.
+func (v *Value) Peek() interface{} {
+	// Lock the mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return v.value
+}
+
+// Set sets a new value and updates all subscribed contexts.
+// The contexts are updated in new goroutines.
+func (v *Value) Set(i interface{}) {
+	// Lock the mutex
+	v.mutex.Lock()
+	v.value = i
+	v.mutex.Unlock()
+
+	// Update the subscribed contexts.
+	v.updateSubscribers()
+}
+
+// Modify calls the function with the current value and sets the returned
+// value as new value. This operation is atomic. Don't call any other method
+// of the value within the function. The subscribed contexts are updated
+// in new goroutines.
+func (v *Value) Modify(f func(i interface{}) interface{}) {
+	// Lock the mutex
+	v.mutex.Lock()
+	v.value = f(v.value)
+	v.mutex.Unlock()
+
+	// Update the subscribed contexts.
+	v.updateSubscribers()
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func (v *Value) subscribe(c *Context) {
+	s := c.ns.s
+
+	// Enable the session context store. The store is used to
+	// check if the context is still rendered, before it is updated.
+	EnableSessionContextStore(s)
+
+	// Add the context to the store if it isn't present yet.
+	// This is the case if the store was just enabled.
+	store := getContextStore(s)
+	if store == nil {
+		return
+	}
+
+	if d, ok := store.Get(c.data.ID); !ok || !isSameContextData(d, c.data) {
+		store.Set(c.data)
+	}
+
+	// Lock the mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	contexts, ok := v.subscribers[s]
+	if !ok {
+		// Remove the closed sessions if there are too many subscribed sessions.
+		if len(v.subscribers) >= v.pruneCount {
+			v.pruneClosedSessions()
+		}
+
+		contexts = make(map[string]*contextData)
+		v.subscribers[s] = contexts
+	}
+
+	contexts[c.data.ID] = c.data
+}
+
+// pruneClosedSessions removes all closed sessions from the subscribers.
+// The mutex has to be locked.
+func (v *Value) pruneClosedSessions() {
+	for s := range v.subscribers {
+		if s.IsClosed() {
+			delete(v.subscribers, s)
+		}
+	}
+
+	// Prune again as soon as the remaining sessions have doubled.
+	v.pruneCount = 2 * len(v.subscribers)
+	if v.pruneCount < valueMinPruneCount {
+		v.pruneCount = valueMinPruneCount
+	}
+}
+
+// isSubscribed checks if the context of the session is subscribed.
+func (v *Value) isSubscribed(s *sessions.Session, contextID string) bool {
+	// Lock the mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	contexts, ok := v.subscribers[s]
+	if !ok {
+		return false
+	}
+
+	_, ok = contexts[contextID]
+	return ok
+}
+
+// updateSubscribers queues the updates of all subscribed contexts.
+func (v *Value) updateSubscribers() {
+	// Lock the mutex
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	// Take the current subscribers. The contexts subscribe
+	// again during their update if they still read the value.
+	subscribers := v.subscribers
+	v.subscribers = make(map[*sessions.Session]map[string]*contextData)
+
+	for s, contexts := range subscribers {
+		// Skip closed sessions.
+		if s.IsClosed() {
+			continue
+		}
+
+		// Add the contexts to the pending contexts if the session
+		// is currently updated. They are updated by the running goroutine.
+		if pending, ok := v.updating[s]; ok {
+			for contextID, data := range contexts {
+				pending[contextID] = data
+			}
+			continue
+		}
+
+		v.updating[s] = contexts
+		go v.updateSessionLoop(s)
+	}
+}
+
+// updateSessionLoop updates the pending contexts of the session
+// until no contexts are pending anymore.
+func (v *Value) updateSessionLoop(s *sessions.Session) {
+	for {
+		// Lock the mutex
+		v.mutex.Lock()
+		contexts := v.updating[s]
+		if len(contexts) == 0 {
+			delete(v.updating, s)
+			v.mutex.Unlock()
+			return
+		}
+		v.updating[s] = make(map[string]*contextData)
+		v.mutex.Unlock()
+
+		// Each update renders the latest value.
+		v.updateSession(s, contexts)
+	}
+}
+
+// updateSession updates the contexts of the session one after another.
+func (v *Value) updateSession(s *sessions.Session, contexts map[string]*contextData) {
+	// Recover panics and log the error message.
+	defer func() {
+		if e := recover(); e != nil {
+			log.L.Error("template: value: update subscribers panic: %v", e)
+		}
+	}()
+
+	// Skip closed sessions.
+	if s.IsClosed() {
+		return
+	}
+
+	// Get the session context store.
+	// It might be disabled in the meantime.
+	store := getContextStore(s)
+	if store == nil {
+		return
+	}
+
+	for contextID, data := range contexts {
+		// Skip the context if it was already updated with a parent context.
+		if v.isSubscribed(s, contextID) {
+			continue
+		}
+
+		// Skip the context if it isn't rendered anymore.
+		d, ok := store.Get(contextID)
+		if !ok || !isSameContextData(d, data) {
+			continue
+		}
+
+		c, err := newContextFromData(s, d, false)
+		if err != nil {
+			log.L.Warning("template: value: failed to create context with ID '%s': %v", contextID, err)
+			continue
+		}
+
+		// Execute the template again and send the changes to the client.
+		if err = c.Update(); err != nil {
+			log.L.Warning("template: value: failed to update context with ID '%s': %v", contextID, err)
+		}
+	}
+}
+
+// isSameContextData checks if both context data values describe the same
+// rendered context. The pointers might differ, because context data
+// values are recreated by template events.
+func isSameContextData(a *contextData, b *contextData) bool {
+	return a == b || (a.ID == b.ID &&
+		a.DomID == b.DomID &&
+		a.TemplateUID == b.TemplateUID &&
+		a.TemplateName == b.TemplateName)
+}
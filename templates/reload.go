@@ -0,0 +1,175 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package templates
+
+import (
+	"github.com/desertbit/bulldozer/filewatcher"
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+	"github.com/desertbit/bulldozer/settings"
+	"github.com/desertbit/bulldozer/template"
+	"github.com/desertbit/bulldozer/utils"
+
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	reloadDelay = 300 * time.Millisecond
+)
+
+var (
+	// The loaded template directories.
+	loadedDirs      []*loadedDir
+	loadedDirsMutex sync.Mutex
+
+	fileWatcher *filewatcher.FileWatcher
+	reloadMutex sync.Mutex
+
+	// Debounce multiple file events.
+	reloadLazy = utils.Debounce(reloadDelay, reload)
+)
+
+type loadedDir struct {
+	namespace   string
+	dir         string
+	excludeDirs []string
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// Watch watches all loaded template directories and reloads the templates
+// on changes. Connected sessions reload their current page afterwards.
+// Call this after all templates are loaded.
+func Watch() error {
+	// Create the filewatcher.
+	var err error
+	fileWatcher, err = filewatcher.New()
+	if err != nil {
+		return fmt.Errorf("failed to create templates filewatcher: %v", err)
+	}
+
+	// Set the event function.
+	fileWatcher.OnEvent(onFileChange)
+
+	// Lock the mutex
+	loadedDirsMutex.Lock()
+	defer loadedDirsMutex.Unlock()
+
+	// Add the paths which should be watched.
+	for _, d := range loadedDirs {
+		if err = fileWatcher.Add(d.dir); err != nil {
+			return fmt.Errorf("failed to watch templates directory '%s': %v", d.dir, err)
+		}
+	}
+
+	return nil
+}
+
+// Reload parses all loaded template directories again.
+// The templates are only replaced if no parse error occurred.
+// Otherwise the parse error is set and shown by the server.
+func Reload() error {
+	// Lock the mutex
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	// Get a copy of the loaded directories.
+	loadedDirsMutex.Lock()
+	dirs := make([]*loadedDir, len(loadedDirs))
+	copy(dirs, loadedDirs)
+	loadedDirsMutex.Unlock()
+
+	err := Templates.Reparse(func(t *template.Template) error {
+		for _, d := range dirs {
+			// Pass a copy of the exclude directories. They are modified by the parse method.
+			excludeDirs := make([]string, len(d.excludeDirs))
+			copy(excludeDirs, d.excludeDirs)
+
+			_, err := t.ParseRecToNamespace(d.namespace, d.dir, excludeDirs...)
+			if err != nil &&
+				err != template.ErrNoFilesFound &&
+				err != template.ErrPatternMatchesNoFiles {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to reload templates: %v", err)
+		setParseError(err)
+		return err
+	}
+
+	// Reset the parse error.
+	setParseError(nil)
+
+	return nil
+}
+
+// Release stops the templates filewatcher if present.
+func Release() {
+	if fileWatcher != nil {
+		fileWatcher.Close()
+	}
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func addLoadedDir(namespace string, dir string, excludeDirs []string) {
+	// Lock the mutex
+	loadedDirsMutex.Lock()
+	defer loadedDirsMutex.Unlock()
+
+	// Save a copy of the exclude directories.
+	// They are modified by the parse method.
+	d := &loadedDir{
+		namespace:   namespace,
+		dir:         dir,
+		excludeDirs: make([]string, len(excludeDirs)),
+	}
+	copy(d.excludeDirs, excludeDirs)
+
+	loadedDirs = append(loadedDirs, d)
+}
+
+func onFileChange(event *filewatcher.Event) {
+	// Skip if the path is not a directory and is not a template file.
+	if !strings.HasSuffix(event.Path, settings.TemplateExtension) {
+		if fi, err := os.Stat(event.Path); err != nil || !fi.IsDir() {
+			return
+		}
+
+		// New directories are watched by the filewatcher automatically.
+	}
+
+	// Reload the templates.
+	reloadLazy()
+}
+
+func reload() {
+	log.L.Info("templates: reloading templates")
+
+	if err := Reload(); err != nil {
+		log.L.Error(err.Error())
+	}
+
+	// Reload the current page of all connected sessions.
+	// Also show the parse error, if the templates failed to reload.
+	sessions.GetSessions(func(m sessions.Sessions) {
+		for _, s := range m {
+			s.Reload()
+		}
+	})
+}
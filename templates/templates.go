@@ -12,6 +12,7 @@ import (
 	"github.com/desertbit/bulldozer/log"
 	"github.com/desertbit/bulldozer/sessions"
 	"github.com/desertbit/bulldozer/template"
+	"sync"
 )
 
 const (
@@ -25,8 +26,10 @@ const (
 var (
 	Templates *template.Template = template.New(templatesUID, "")
 
-	// ParseError is set if any template parsing error occurred.
-	ParseError error
+	// Set if any template parsing error occurred.
+	// The templates are reloaded by the filewatcher goroutine.
+	parseError      error
+	parseErrorMutex sync.Mutex
 )
 
 //##############//
@@ -35,7 +38,11 @@ var (
 
 // Load all templates recursivly in the directory.
 // The namespace is prepended to the templates names delimited by a slash.
+// The directory is reloaded by Reload.
 func Load(namespace string, dir string, excludeDirs ...string) error {
+	// Remember the directory for reloads.
+	addLoadedDir(namespace, dir, excludeDirs)
+
 	// Load all the template files recursivly.
 	_, err := Templates.ParseRecToNamespace(namespace, dir, excludeDirs...)
 	if err != nil &&
@@ -43,13 +50,24 @@ func Load(namespace string, dir string, excludeDirs ...string) error {
 		err != template.ErrPatternMatchesNoFiles {
 		// Just store the templates parse error.
 		// The application startup should not be interrupted...
-		ParseError = fmt.Errorf("failed to load templates: %v", err)
-		return ParseError
+		err = fmt.Errorf("failed to load templates: %v", err)
+		setParseError(err)
+		return err
 	}
 
 	return nil
 }
 
+// LastParseError returns the error of the last template parsing
+// or nil if no template parsing error occurred.
+func LastParseError() error {
+	// Lock the mutex
+	parseErrorMutex.Lock()
+	defer parseErrorMutex.Unlock()
+
+	return parseError
+}
+
 // ExecNotFound executes the not found template.
 // @return:
 //  1: http status code
@@ -70,7 +88,7 @@ func ExecNotFound(s *sessions.Session) (int, string, string) {
 		return ExecError(s, err.Error())
 	}
 
-	return 404, out, tr.S("bud.page.notFound.pageTitle")
+	return 404, out, tr.For(s).S("bud.page.notFound.pageTitle")
 }
 
 // ExecError executes the error template and shows the error message if the
@@ -106,8 +124,20 @@ func ExecError(s *sessions.Session, errorMessage string, vars ...bool) (int, str
 	out, _, _, err := Templates.ExecuteTemplateToString(s, templateError, opts)
 	if err != nil {
 		log.L.Error("failed to execute error core template: %v", err)
-		return 500, "Internal Server Error", tr.S("bud.page.error.pageTitle")
+		return 500, "Internal Server Error", tr.For(s).S("bud.page.error.pageTitle")
 	}
 
-	return 500, out, tr.S("bud.page.error.pageTitle")
+	return 500, out, tr.For(s).S("bud.page.error.pageTitle")
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func setParseError(err error) {
+	// Lock the mutex
+	parseErrorMutex.Lock()
+	defer parseErrorMutex.Unlock()
+
+	parseError = err
 }
@@ -0,0 +1,37 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package topbar
+
+import (
+	"github.com/desertbit/bulldozer/template"
+
+	ht "html/template"
+)
+
+const (
+	templatePackageName = "topbar"
+)
+
+func init() {
+	// Register the topbar template package.
+	template.RegisterPackage(templatePackageName, new(templatePackage))
+}
+
+//########################//
+//### Template Package ###//
+//########################//
+
+type templatePackage struct{}
+
+// Render executes the topbar. Use this in layout templates: {{%.topbar.Render}}
+func (p *templatePackage) Render(c *template.Context) (ht.HTML, error) {
+	body, err := ExecTopBar(c)
+	if err != nil {
+		return "", err
+	}
+
+	return ht.HTML(body), nil
+}
@@ -20,6 +20,8 @@ const (
 	topbarTemplateName   = "bud/topbar/topbar"
 	topbarEventNamespace = "budTB"
 	topbarTemplateID     = "budTB"
+
+	messageTypeSpace = "topbar.space"
 )
 
 var (
@@ -131,7 +133,7 @@ func ExecTopBar(i interface{}) (string, error) {
 //###############//
 
 func onEndAuthenticatedSession(s *sessions.Session) {
-	s.SendCommand("Bulldozer.topbar.space(false);")
+	s.SendMessage(messageTypeSpace, "", false)
 }
 
 //##############//
@@ -0,0 +1,63 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+// Package catalog reads translation files and extracts the message IDs
+// used by template sources. It has no framework dependencies and is
+// used by the translate package and the bulldoze tool.
+package catalog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// Suffix is the file suffix of translation files.
+	Suffix = ".tr"
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+// Entry is a message of a translation file.
+// Translation files contain one JSON encoded entry per line.
+type Entry struct {
+	ID, Text string
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// ReadFile reads all entries of the translation file in their order.
+// Duplicate IDs are not removed.
+func ReadFile(path string) ([]Entry, error) {
+	// Open the file
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open translation file '%s': %v", path, err)
+	}
+	defer file.Close()
+
+	// Parse the JSON translation file
+	var entries []Entry
+	dec := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var e Entry
+		if err = dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse translation file '%s': %v", path, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
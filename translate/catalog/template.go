@@ -0,0 +1,174 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package catalog
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	leftDelim  = "{{"
+	rightDelim = "}}"
+)
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenSeparator
+)
+
+//#############//
+//### Types ###//
+//#############//
+
+type tokenKind int
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// TemplateIDs returns the message IDs of all translations with a constant
+// ID in the template source: {{tr "id"}}, nested calls like (tr "id") and
+// {{trCtx $.Context "id"}}. Only the template actions are tokenized.
+// Therefore custom template syntax and functions don't break the extraction.
+func TemplateIDs(src string) []string {
+	var ids []string
+	var tokens []token
+	depth := 0
+
+	for i := 0; i < len(src); {
+		// Actions might contain nested template code.
+		if strings.HasPrefix(src[i:], leftDelim) {
+			depth++
+			i += len(leftDelim)
+			continue
+		} else if depth == 0 {
+			i++
+			continue
+		} else if strings.HasPrefix(src[i:], rightDelim) {
+			depth--
+			i += len(rightDelim)
+
+			// The action ends.
+			if depth == 0 {
+				ids = appendTranslationIDs(ids, tokens)
+				tokens = tokens[:0]
+			} else {
+				tokens = append(tokens, token{kind: tokenSeparator})
+			}
+			continue
+		}
+
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			// Skip comments.
+			n := strings.Index(src[i+2:], "*/")
+			if n < 0 {
+				i = len(src)
+			} else {
+				i += n + 4
+			}
+
+		case c == '"' || c == '`' || c == '\'':
+			n := indexAfterQuote(src, i)
+			kind := tokenString
+			if c == '\'' {
+				// Character constants are no message IDs.
+				kind = tokenSeparator
+			}
+			tokens = append(tokens, token{kind: kind, text: src[i:n]})
+			i = n
+
+		case isSpace(c):
+			i++
+
+		case isSeparator(c):
+			tokens = append(tokens, token{kind: tokenSeparator})
+			i++
+
+		default:
+			n := i + 1
+			for n < len(src) && !isSpace(src[n]) && !isSeparator(src[n]) &&
+				!strings.HasPrefix(src[n:], rightDelim) && !strings.HasPrefix(src[n:], leftDelim) &&
+				src[n] != '"' && src[n] != '`' && src[n] != '\'' {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: src[i:n]})
+			i = n
+		}
+	}
+
+	return ids
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// appendTranslationIDs appends the constant IDs of the tr and trCtx calls
+// of the action tokens. The trCtx function takes the context as first argument.
+func appendTranslationIDs(ids []string, tokens []token) []string {
+	for i, t := range tokens {
+		if t.kind != tokenWord {
+			continue
+		}
+
+		var arg int
+		switch t.text {
+		case "tr":
+			arg = i + 1
+		case "trCtx":
+			if i+1 >= len(tokens) || tokens[i+1].kind == tokenSeparator {
+				continue
+			}
+			arg = i + 2
+		default:
+			continue
+		}
+
+		if arg >= len(tokens) || tokens[arg].kind != tokenString {
+			continue
+		}
+
+		if id, err := strconv.Unquote(tokens[arg].text); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// indexAfterQuote returns the index after the closing quote of the
+// quoted value at the position. Escaped quotes are skipped, except for
+// raw strings. The length of the source is returned if the quote is missing.
+func indexAfterQuote(src string, pos int) int {
+	quote := src[pos]
+
+	for i := pos + 1; i < len(src); i++ {
+		if src[i] == '\\' && quote != '`' {
+			i++
+		} else if src[i] == quote {
+			return i + 1
+		}
+	}
+
+	return len(src)
+}
+
+func isSeparator(c byte) bool {
+	return c == '(' || c == ')' || c == '|' || c == ',' || c == '='
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
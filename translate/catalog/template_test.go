@@ -0,0 +1,41 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package catalog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplateIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		ids  []string
+	}{
+		{name: "no actions", src: `<p>tr "a"</p>`},
+		{name: "tr", src: `<p>{{tr "a"}}</p>`, ids: []string{"a"}},
+		{name: "tr with arguments", src: `{{tr "a" (trArgs "n" 1)}}`, ids: []string{"a"}},
+		{name: "nested tr", src: `{{printf "%s: %s" (tr "a") (tr "b")}}`, ids: []string{"a", "b"}},
+		{name: "pipeline", src: `{{if eq .X (tr "a")}}{{tr "b" | html}}{{end}}`, ids: []string{"a", "b"}},
+		{name: "trCtx", src: `{{trCtx $.Context "a"}}`, ids: []string{"a"}},
+		{name: "variable", src: `{{$x := tr "a"}}`, ids: []string{"a"}},
+		{name: "raw string", src: "{{tr `a\\nb`}}", ids: []string{`a\nb`}},
+		{name: "escaped quotes", src: `{{tr "a \"b\" c"}}`, ids: []string{`a "b" c`}},
+		{name: "action in attribute value", src: `<a title="{{tr "a"}}">{{tr "b"}}</a>`, ids: []string{"a", "b"}},
+		{name: "custom syntax", src: `{{#.Name}}{{js load}}$("#{{id "a"}}").text("{{tr "a"}}");{{emit Save('x')}}{{end js}}`, ids: []string{"a"}},
+		{name: "comment", src: `{{/* tr "a" */}}{{tr "b"}}`, ids: []string{"b"}},
+		{name: "no constant id", src: `{{tr .ID}}{{tr}}{{trCtx "a"}}`},
+		{name: "other functions", src: `{{trFormat "a"}}{{.tr "b"}}{{"tr" "c"}}`},
+		{name: "unterminated", src: `{{tr "a"}}{{tr "b`, ids: []string{"a"}},
+	}
+
+	for _, test := range tests {
+		if ids := TemplateIDs(test.src); !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%s: got %q, want %q", test.name, ids, test.ids)
+		}
+	}
+}
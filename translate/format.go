@@ -0,0 +1,255 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package tr
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats are chosen by the locale of the translator. Dates and times
+// are converted to the time zone of the translator. Relative times use the
+// bud.format.relative.* messages.
+
+const (
+	currencySymbolPlaceholder = "¤"
+	currencyAmountPlaceholder = "#"
+)
+
+var (
+	// Default format for unknown locales.
+	defaultLocaleFormat = &localeFormat{
+		decimal:         ".",
+		group:           ",",
+		date:            "2006-01-02",
+		time:            "15:04",
+		currencyPattern: "¤#",
+	}
+
+	localeFormats = map[string]*localeFormat{
+		"en":    {decimal: ".", group: ",", date: "Jan 2, 2006", time: "3:04 PM", currencyPattern: "¤#"},
+		"en-gb": {decimal: ".", group: ",", date: "02/01/2006", time: "15:04", currencyPattern: "¤#"},
+		"de":    {decimal: ",", group: ".", date: "02.01.2006", time: "15:04", currencyPattern: "# ¤"},
+		"de-ch": {decimal: ".", group: "’", date: "02.01.2006", time: "15:04", currencyPattern: "¤ #"},
+		"fr":    {decimal: ",", group: " ", date: "02/01/2006", time: "15:04", currencyPattern: "# ¤"},
+		"es":    {decimal: ",", group: ".", date: "02/01/2006", time: "15:04", currencyPattern: "# ¤"},
+		"it":    {decimal: ",", group: ".", date: "02/01/2006", time: "15:04", currencyPattern: "# ¤"},
+		"pt":    {decimal: ",", group: ".", date: "02/01/2006", time: "15:04", currencyPattern: "¤ #"},
+		"nl":    {decimal: ",", group: ".", date: "02-01-2006", time: "15:04", currencyPattern: "¤ #"},
+		"ru":    {decimal: ",", group: " ", date: "02.01.2006", time: "15:04", currencyPattern: "# ¤"},
+		"pl":    {decimal: ",", group: " ", date: "02.01.2006", time: "15:04", currencyPattern: "# ¤"},
+		"ja":    {decimal: ".", group: ",", date: "2006/01/02", time: "15:04", currencyPattern: "¤#"},
+		"zh":    {decimal: ".", group: ",", date: "2006/01/02", time: "15:04", currencyPattern: "¤#"},
+	}
+
+	currencySymbols = map[string]string{
+		"EUR": "€",
+		"USD": "$",
+		"GBP": "£",
+		"JPY": "¥",
+		"CNY": "¥",
+		"INR": "₹",
+		"RUB": "₽",
+		"BRL": "R$",
+	}
+
+	// Currencies without minor units.
+	currencyNoDecimals = map[string]bool{
+		"JPY": true,
+		"KRW": true,
+	}
+
+	byteSizeUnits = []string{"B", "KB", "MB", "GB", "TB", "PB"}
+)
+
+//##########################//
+//### Locale Format Type ###//
+//##########################//
+
+type localeFormat struct {
+	decimal string
+	group   string
+
+	// Go time layouts.
+	date string
+	time string
+
+	// The currency pattern: ¤ is replaced by the symbol and # by the amount.
+	currencyPattern string
+}
+
+// getLocaleFormat returns the formats of the locale or of its language.
+func getLocaleFormat(locale string) *localeFormat {
+	if f, ok := localeFormats[locale]; ok {
+		return f
+	} else if f, ok := localeFormats[baseLanguage(locale)]; ok {
+		return f
+	}
+
+	return defaultLocaleFormat
+}
+
+//#################################//
+//### Translator Format Methods ###//
+//#################################//
+
+// Location returns the time zone of the translator.
+// The time zone of the session is resolved if no time zone is set.
+func (t *Translator) Location() *time.Location {
+	if t.loc != nil {
+		return t.loc
+	} else if t.s != nil {
+		return SessionTimeZone(t.s)
+	}
+
+	return time.Local
+}
+
+// Date formats the date in the translator's locale and time zone.
+func (t *Translator) Date(tm time.Time) string {
+	return tm.In(t.Location()).Format(getLocaleFormat(t.locale).date)
+}
+
+// Time formats the time of day in the translator's locale and time zone.
+func (t *Translator) Time(tm time.Time) string {
+	return tm.In(t.Location()).Format(getLocaleFormat(t.locale).time)
+}
+
+// DateTime formats the date and time in the translator's locale and time zone.
+func (t *Translator) DateTime(tm time.Time) string {
+	f := getLocaleFormat(t.locale)
+	return tm.In(t.Location()).Format(f.date + " " + f.time)
+}
+
+// RelativeTime formats the time relative to now: "3 minutes ago" or "in 2 days".
+func (t *Translator) RelativeTime(tm time.Time) string {
+	return t.relativeTime(tm, time.Now())
+}
+
+// Number formats the number with the locale's separators.
+// Pass a negative decimals value to use as many decimals as required.
+func (t *Translator) Number(f float64, decimals int) string {
+	return formatLocaleNumber(f, decimals, getLocaleFormat(t.locale))
+}
+
+// Currency formats the amount with the currency symbol of the ISO 4217
+// currency code. The code is used if the symbol is unknown.
+func (t *Translator) Currency(amount float64, currency string) string {
+	currency = strings.ToUpper(currency)
+	lf := getLocaleFormat(t.locale)
+
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		symbol = currency
+	}
+
+	decimals := 2
+	if currencyNoDecimals[currency] {
+		decimals = 0
+	}
+
+	// Keep the sign in front of the symbol.
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	s := strings.Replace(lf.currencyPattern, currencySymbolPlaceholder, symbol, 1)
+	s = strings.Replace(s, currencyAmountPlaceholder, formatLocaleNumber(amount, decimals, lf), 1)
+
+	return sign + s
+}
+
+// ByteSize formats the size in bytes with binary units: 1.5 MB.
+func (t *Translator) ByteSize(size int64) string {
+	if size < 1024 && size > -1024 {
+		return strconv.FormatInt(size, 10) + " " + byteSizeUnits[0]
+	}
+
+	f := float64(size)
+	unit := 0
+	for math.Abs(f) >= 1024 && unit < len(byteSizeUnits)-1 {
+		f /= 1024
+		unit++
+	}
+
+	// Round to one decimal.
+	f = math.Round(f*10) / 10
+
+	return formatLocaleNumber(f, -1, getLocaleFormat(t.locale)) + " " + byteSizeUnits[unit]
+}
+
+// relativeTime formats the time relative to the passed current time.
+func (t *Translator) relativeTime(tm time.Time, now time.Time) string {
+	d := now.Sub(tm)
+
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var unit string
+	var n int64
+
+	switch {
+	case d < 10*time.Second:
+		return t.S("bud.format.relative.now")
+	case d < 45*time.Second:
+		unit, n = "seconds", int64(d/time.Second)
+	case d < 45*time.Minute:
+		unit, n = "minutes", int64(math.Max(1, math.Round(d.Minutes())))
+	case d < 22*time.Hour:
+		unit, n = "hours", int64(math.Max(1, math.Round(d.Hours())))
+	case d < 26*24*time.Hour:
+		unit, n = "days", int64(math.Max(1, math.Round(d.Hours()/24)))
+	case d < 320*24*time.Hour:
+		unit, n = "months", int64(math.Max(1, math.Round(d.Hours()/24/30)))
+	default:
+		unit, n = "years", int64(math.Max(1, math.Round(d.Hours()/24/365)))
+	}
+
+	if future {
+		return t.S("bud.format.relative.in."+unit, Args{"n": n})
+	}
+
+	return t.S("bud.format.relative.ago."+unit, Args{"n": n})
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// formatLocaleNumber formats the number with the decimal and group separators.
+func formatLocaleNumber(f float64, decimals int, lf *localeFormat) string {
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+
+	intPart, fracPart := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	// Group the integer digits by thousands.
+	var b strings.Builder
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(lf.group)
+		}
+		b.WriteRune(c)
+	}
+
+	if len(fracPart) > 0 {
+		b.WriteString(lf.decimal)
+		b.WriteString(fracPart)
+	}
+
+	return b.String()
+}
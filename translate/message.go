@@ -0,0 +1,604 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package tr
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Messages containing a '{' are parsed with a MessageFormat-style syntax:
+//
+//	Hello {name}!
+//	{count, plural, =0 {No files} one {One file} other {# files}}
+//	{gender, select, female {She} male {He} other {They}} liked your post.
+//	{0} and {1}
+//
+// Named placeholders are filled with the Args map. Numbered placeholders
+// are filled with the positional arguments. Curly braces and '#' are
+// quoted with apostrophes: '{' and '#'. Use '' for a literal apostrophe.
+// All other messages are formatted with fmt.Sprintf as before.
+
+var (
+	printfVerbRegex = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z]`)
+)
+
+// Args defines named arguments for MessageFormat messages.
+// Pass them as single argument: S("id", tr.Args{"count": 3})
+type Args map[string]interface{}
+
+//######################//
+//### Message Struct ###//
+//######################//
+
+type message struct {
+	text  string
+	nodes []msgNode // Nil for printf-style messages.
+
+	placeholders []string // Sorted placeholder names.
+	pluralKeys   []string // Used plural keywords.
+}
+
+// compileMessage parses the message text.
+func compileMessage(text string) (*message, error) {
+	m := &message{
+		text: text,
+	}
+
+	// Printf-style message.
+	if !strings.Contains(text, "{") {
+		m.placeholders = printfVerbRegex.FindAllString(strings.Replace(text, "%%", "", -1), -1)
+		return m, nil
+	}
+
+	p := &msgParser{
+		src:          []rune(text),
+		placeholders: make(map[string]struct{}),
+		pluralKeys:   make(map[string]struct{}),
+	}
+
+	nodes, err := p.parseMessage(false, false)
+	if err != nil {
+		return nil, fmt.Errorf("position %d: %v", p.pos, err)
+	}
+
+	m.nodes = nodes
+	m.placeholders = sortedKeys(p.placeholders)
+	m.pluralKeys = sortedKeys(p.pluralKeys)
+
+	return m, nil
+}
+
+// isPrintf returns a boolean whenever the message is formatted with fmt.Sprintf.
+func (m *message) isPrintf() bool {
+	return m.nodes == nil
+}
+
+// format formats the message with the arguments.
+// Plural rules of the locale are used. Missing arguments are
+// rendered as placeholders and the first error is returned.
+func (m *message) format(locale string, args []interface{}) (string, error) {
+	if m.isPrintf() {
+		return fmt.Sprintf(m.text, args...), nil
+	}
+
+	c := &formatContext{
+		rule: getPluralRule(locale),
+		args: newArgsLookup(args),
+	}
+
+	var b bytes.Buffer
+	formatNodes(&b, c, m.nodes)
+
+	return b.String(), c.err
+}
+
+//#####################//
+//### Message Nodes ###//
+//#####################//
+
+type formatContext struct {
+	rule *pluralRule
+	args func(name string) (interface{}, bool)
+	err  error
+
+	// The current plural value for '#'.
+	plural      float64
+	inPlural    bool
+	pluralFound bool
+}
+
+func (c *formatContext) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+type msgNode interface {
+	format(b *bytes.Buffer, c *formatContext)
+}
+
+func formatNodes(b *bytes.Buffer, c *formatContext, nodes []msgNode) {
+	for _, n := range nodes {
+		n.format(b, c)
+	}
+}
+
+type textNode string
+
+func (n textNode) format(b *bytes.Buffer, c *formatContext) {
+	b.WriteString(string(n))
+}
+
+type argNode string
+
+func (n argNode) format(b *bytes.Buffer, c *formatContext) {
+	v, ok := c.args(string(n))
+	if !ok {
+		c.setErr(fmt.Errorf("missing argument '%s'", string(n)))
+		b.WriteString("{" + string(n) + "}")
+		return
+	}
+
+	if f, ok := toFloat(v); ok {
+		b.WriteString(formatNumber(f))
+		return
+	}
+
+	b.WriteString(fmt.Sprint(v))
+}
+
+type hashNode struct{}
+
+func (n hashNode) format(b *bytes.Buffer, c *formatContext) {
+	if !c.pluralFound {
+		b.WriteString("#")
+		return
+	}
+
+	b.WriteString(formatNumber(c.plural))
+}
+
+type pluralNode struct {
+	name   string
+	offset float64
+	exact  map[float64][]msgNode
+	cases  map[string][]msgNode
+}
+
+func (n *pluralNode) format(b *bytes.Buffer, c *formatContext) {
+	v, ok := c.args(n.name)
+	if !ok {
+		c.setErr(fmt.Errorf("missing argument '%s'", n.name))
+		b.WriteString("{" + n.name + "}")
+		return
+	}
+
+	f, ok := toFloat(v)
+	if !ok {
+		c.setErr(fmt.Errorf("plural argument '%s' is not a number: %v", n.name, v))
+		f = 0
+	}
+
+	// Exact matches are checked before the offset is applied.
+	nodes, ok := n.exact[f]
+	if !ok {
+		nodes, ok = n.cases[c.rule.category(f-n.offset)]
+		if !ok {
+			nodes = n.cases[pluralOther]
+		}
+	}
+
+	// Set the plural value for '#' and restore the previous afterwards.
+	prevPlural, prevFound := c.plural, c.pluralFound
+	c.plural, c.pluralFound = f-n.offset, true
+	formatNodes(b, c, nodes)
+	c.plural, c.pluralFound = prevPlural, prevFound
+}
+
+type selectNode struct {
+	name  string
+	cases map[string][]msgNode
+}
+
+func (n *selectNode) format(b *bytes.Buffer, c *formatContext) {
+	v, ok := c.args(n.name)
+	if !ok {
+		c.setErr(fmt.Errorf("missing argument '%s'", n.name))
+		b.WriteString("{" + n.name + "}")
+		return
+	}
+
+	nodes, ok := n.cases[fmt.Sprint(v)]
+	if !ok {
+		nodes = n.cases[pluralOther]
+	}
+
+	formatNodes(b, c, nodes)
+}
+
+//######################//
+//### Message Parser ###//
+//######################//
+
+type msgParser struct {
+	src []rune
+	pos int
+
+	placeholders map[string]struct{}
+	pluralKeys   map[string]struct{}
+}
+
+func (p *msgParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *msgParser) peek(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *msgParser) skipWhitespace() {
+	for !p.eof() && strings.ContainsRune(" \t\r\n", p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *msgParser) expect(r rune) error {
+	p.skipWhitespace()
+	if p.peek(0) != r {
+		return fmt.Errorf("expected '%c'", r)
+	}
+	p.pos++
+	return nil
+}
+
+// readIdentifier reads a placeholder name, type or keyword.
+func (p *msgParser) readIdentifier() string {
+	p.skipWhitespace()
+
+	start := p.pos
+	for !p.eof() {
+		r := p.src[p.pos]
+		if r != '_' && r != '-' && r != '.' &&
+			!(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') {
+			break
+		}
+		p.pos++
+	}
+
+	return string(p.src[start:p.pos])
+}
+
+// parseMessage parses text and placeholders until the end of the
+// source or the closing brace of a nested message.
+func (p *msgParser) parseMessage(inPlural bool, nested bool) ([]msgNode, error) {
+	var nodes []msgNode
+	var text bytes.Buffer
+
+	flushText := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for !p.eof() {
+		r := p.src[p.pos]
+
+		switch {
+		case r == '}':
+			if !nested {
+				return nil, fmt.Errorf("unexpected '}'")
+			}
+			flushText()
+			return nodes, nil
+
+		case r == '{':
+			p.pos++
+			n, err := p.parseArgument(inPlural)
+			if err != nil {
+				return nil, err
+			}
+			flushText()
+			nodes = append(nodes, n)
+
+		case r == '#' && inPlural:
+			p.pos++
+			flushText()
+			nodes = append(nodes, hashNode{})
+
+		case r == '\'':
+			p.parseQuoted(&text, inPlural)
+
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+
+	if nested {
+		return nil, fmt.Errorf("missing closing '}'")
+	}
+
+	flushText()
+	return nodes, nil
+}
+
+// parseQuoted handles apostrophes. Two apostrophes are a literal apostrophe.
+// An apostrophe in front of a special character starts quoted literal text.
+func (p *msgParser) parseQuoted(text *bytes.Buffer, inPlural bool) {
+	next := p.peek(1)
+	if next == '\'' {
+		text.WriteRune('\'')
+		p.pos += 2
+		return
+	} else if next != '{' && next != '}' && !(next == '#' && inPlural) {
+		text.WriteRune('\'')
+		p.pos++
+		return
+	}
+
+	// Skip the opening apostrophe and read until the closing one.
+	p.pos++
+	for !p.eof() {
+		r := p.src[p.pos]
+		if r == '\'' {
+			if p.peek(1) == '\'' {
+				text.WriteRune('\'')
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			return
+		}
+		text.WriteRune(r)
+		p.pos++
+	}
+}
+
+// parseArgument parses the placeholder after the opening brace.
+func (p *msgParser) parseArgument(inPlural bool) (msgNode, error) {
+	name := p.readIdentifier()
+	if len(name) == 0 {
+		return nil, fmt.Errorf("empty placeholder name")
+	}
+	p.placeholders[name] = struct{}{}
+
+	p.skipWhitespace()
+	if p.peek(0) == '}' {
+		p.pos++
+		return argNode(name), nil
+	} else if p.peek(0) != ',' {
+		return nil, fmt.Errorf("invalid placeholder '%s'", name)
+	}
+	p.pos++
+
+	switch t := p.readIdentifier(); t {
+	case "number":
+		if err := p.expect('}'); err != nil {
+			return nil, fmt.Errorf("placeholder '%s': %v", name, err)
+		}
+		return argNode(name), nil
+
+	case "plural":
+		if err := p.expect(','); err != nil {
+			return nil, fmt.Errorf("placeholder '%s': %v", name, err)
+		}
+		return p.parsePlural(name)
+
+	case "select":
+		if err := p.expect(','); err != nil {
+			return nil, fmt.Errorf("placeholder '%s': %v", name, err)
+		}
+		return p.parseSelect(name, inPlural)
+
+	default:
+		return nil, fmt.Errorf("placeholder '%s': unknown type '%s'", name, t)
+	}
+}
+
+func (p *msgParser) parsePlural(name string) (msgNode, error) {
+	n := &pluralNode{
+		name:  name,
+		exact: make(map[float64][]msgNode),
+		cases: make(map[string][]msgNode),
+	}
+
+	// Parse the optional offset.
+	p.skipWhitespace()
+	if strings.HasPrefix(string(p.src[p.pos:]), "offset:") {
+		p.pos += len("offset:")
+		f, err := strconv.ParseFloat(p.readIdentifier(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("plural '%s': invalid offset", name)
+		}
+		n.offset = f
+	}
+
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			return nil, fmt.Errorf("plural '%s': missing closing '}'", name)
+		} else if p.peek(0) == '}' {
+			p.pos++
+			break
+		}
+
+		// Parse the selector: =N or a plural keyword.
+		exact := false
+		if p.peek(0) == '=' {
+			exact = true
+			p.pos++
+		}
+
+		key := p.readIdentifier()
+		if len(key) == 0 {
+			return nil, fmt.Errorf("plural '%s': missing selector", name)
+		}
+
+		if err := p.expect('{'); err != nil {
+			return nil, fmt.Errorf("plural '%s': %v", name, err)
+		}
+
+		nodes, err := p.parseMessage(true, true)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		if exact {
+			f, err := strconv.ParseFloat(key, 64)
+			if err != nil {
+				return nil, fmt.Errorf("plural '%s': invalid selector '=%s'", name, key)
+			} else if _, ok := n.exact[f]; ok {
+				return nil, fmt.Errorf("plural '%s': duplicate selector '=%s'", name, key)
+			}
+			n.exact[f] = nodes
+			continue
+		}
+
+		if !isPluralCategory(key) {
+			return nil, fmt.Errorf("plural '%s': invalid selector '%s'", name, key)
+		} else if _, ok := n.cases[key]; ok {
+			return nil, fmt.Errorf("plural '%s': duplicate selector '%s'", name, key)
+		}
+		n.cases[key] = nodes
+		p.pluralKeys[key] = struct{}{}
+	}
+
+	if _, ok := n.cases[pluralOther]; !ok {
+		return nil, fmt.Errorf("plural '%s': missing 'other' selector", name)
+	}
+
+	return n, nil
+}
+
+func (p *msgParser) parseSelect(name string, inPlural bool) (msgNode, error) {
+	n := &selectNode{
+		name:  name,
+		cases: make(map[string][]msgNode),
+	}
+
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			return nil, fmt.Errorf("select '%s': missing closing '}'", name)
+		} else if p.peek(0) == '}' {
+			p.pos++
+			break
+		}
+
+		key := p.readIdentifier()
+		if len(key) == 0 {
+			return nil, fmt.Errorf("select '%s': missing selector", name)
+		}
+
+		if err := p.expect('{'); err != nil {
+			return nil, fmt.Errorf("select '%s': %v", name, err)
+		}
+
+		nodes, err := p.parseMessage(inPlural, true)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		if _, ok := n.cases[key]; ok {
+			return nil, fmt.Errorf("select '%s': duplicate selector '%s'", name, key)
+		}
+		n.cases[key] = nodes
+	}
+
+	if _, ok := n.cases[pluralOther]; !ok {
+		return nil, fmt.Errorf("select '%s': missing 'other' selector", name)
+	}
+
+	return n, nil
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// newArgsLookup returns a lookup function for named and positional arguments.
+func newArgsLookup(args []interface{}) func(name string) (interface{}, bool) {
+	if len(args) == 1 {
+		var named map[string]interface{}
+		switch v := args[0].(type) {
+		case Args:
+			named = v
+		case map[string]interface{}:
+			named = v
+		}
+
+		if named != nil {
+			return func(name string) (interface{}, bool) {
+				v, ok := named[name]
+				return v, ok
+			}
+		}
+	}
+
+	return func(name string) (interface{}, bool) {
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= len(args) {
+			return nil, false
+		}
+		return args[i], true
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
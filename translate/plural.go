@@ -0,0 +1,291 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package tr
+
+import (
+	"math"
+)
+
+// The plural rules are based on the CLDR cardinal plural rules.
+// Fractions are only distinguished from integers by their value,
+// so 1.0 is handled like 1.
+
+const (
+	pluralZero  = "zero"
+	pluralOne   = "one"
+	pluralTwo   = "two"
+	pluralFew   = "few"
+	pluralMany  = "many"
+	pluralOther = "other"
+)
+
+var (
+	// Rule for languages without plural forms.
+	pluralRuleOther = &pluralRule{
+		categories: []string{pluralOther},
+		f: func(n float64, i int64, isInt bool) string {
+			return pluralOther
+		},
+	}
+
+	// Default rule: english, german, ...
+	pluralRuleOne = &pluralRule{
+		categories: []string{pluralOne, pluralOther},
+		f: func(n float64, i int64, isInt bool) string {
+			if isInt && i == 1 {
+				return pluralOne
+			}
+			return pluralOther
+		},
+	}
+
+	// Rule for french, portuguese, ...: 0 and 1 are singular.
+	pluralRuleZeroOne = &pluralRule{
+		categories: []string{pluralOne, pluralOther},
+		f: func(n float64, i int64, isInt bool) string {
+			if i == 0 || i == 1 {
+				return pluralOne
+			}
+			return pluralOther
+		},
+	}
+
+	// Rule for russian, ukrainian and belarusian.
+	pluralRuleEastSlavic = &pluralRule{
+		categories: []string{pluralOne, pluralFew, pluralMany, pluralOther},
+		f: func(n float64, i int64, isInt bool) string {
+			if !isInt {
+				return pluralOther
+			}
+			i10, i100 := i%10, i%100
+			if i10 == 1 && i100 != 11 {
+				return pluralOne
+			} else if i10 >= 2 && i10 <= 4 && (i100 < 12 || i100 > 14) {
+				return pluralFew
+			}
+			return pluralMany
+		},
+	}
+
+	// Rule for croatian, serbian and bosnian.
+	pluralRuleSouthSlavic = &pluralRule{
+		categories: []string{pluralOne, pluralFew, pluralOther},
+		f: func(n float64, i int64, isInt bool) string {
+			if !isInt {
+				return pluralOther
+			}
+			i10, i100 := i%10, i%100
+			if i10 == 1 && i100 != 11 {
+				return pluralOne
+			} else if i10 >= 2 && i10 <= 4 && (i100 < 12 || i100 > 14) {
+				return pluralFew
+			}
+			return pluralOther
+		},
+	}
+
+	// Rule for czech and slovak.
+	pluralRuleCzech = &pluralRule{
+		categories: []string{pluralOne, pluralFew, pluralMany, pluralOther},
+		f: func(n float64, i int64, isInt bool) string {
+			if !isInt {
+				return pluralMany
+			} else if i == 1 {
+				return pluralOne
+			} else if i >= 2 && i <= 4 {
+				return pluralFew
+			}
+			return pluralOther
+		},
+	}
+
+	pluralRules = map[string]*pluralRule{
+		"ja": pluralRuleOther,
+		"zh": pluralRuleOther,
+		"ko": pluralRuleOther,
+		"vi": pluralRuleOther,
+		"th": pluralRuleOther,
+		"id": pluralRuleOther,
+		"ms": pluralRuleOther,
+
+		"fr": pluralRuleZeroOne,
+		"pt": pluralRuleZeroOne,
+		"hi": pluralRuleZeroOne,
+		"fa": pluralRuleZeroOne,
+		"bn": pluralRuleZeroOne,
+
+		"ru": pluralRuleEastSlavic,
+		"uk": pluralRuleEastSlavic,
+		"be": pluralRuleEastSlavic,
+
+		"hr": pluralRuleSouthSlavic,
+		"sr": pluralRuleSouthSlavic,
+		"bs": pluralRuleSouthSlavic,
+
+		"pl": {
+			categories: []string{pluralOne, pluralFew, pluralMany, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				if !isInt {
+					return pluralOther
+				}
+				i10, i100 := i%10, i%100
+				if i == 1 {
+					return pluralOne
+				} else if i10 >= 2 && i10 <= 4 && (i100 < 12 || i100 > 14) {
+					return pluralFew
+				}
+				return pluralMany
+			},
+		},
+
+		"cs": pluralRuleCzech,
+		"sk": pluralRuleCzech,
+
+		"lt": {
+			categories: []string{pluralOne, pluralFew, pluralMany, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				if !isInt {
+					return pluralMany
+				}
+				i10, i100 := i%10, i%100
+				if i10 == 1 && (i100 < 11 || i100 > 19) {
+					return pluralOne
+				} else if i10 >= 2 && (i100 < 11 || i100 > 19) {
+					return pluralFew
+				}
+				return pluralOther
+			},
+		},
+
+		"lv": {
+			categories: []string{pluralZero, pluralOne, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				i10, i100 := i%10, i%100
+				if isInt && (i10 == 0 || (i100 >= 11 && i100 <= 19)) {
+					return pluralZero
+				} else if isInt && i10 == 1 && i100 != 11 {
+					return pluralOne
+				}
+				return pluralOther
+			},
+		},
+
+		"ro": {
+			categories: []string{pluralOne, pluralFew, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				i100 := i % 100
+				if isInt && i == 1 {
+					return pluralOne
+				} else if !isInt || i == 0 || (i100 >= 2 && i100 <= 19) {
+					return pluralFew
+				}
+				return pluralOther
+			},
+		},
+
+		"sl": {
+			categories: []string{pluralOne, pluralTwo, pluralFew, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				i100 := i % 100
+				if !isInt {
+					return pluralFew
+				} else if i100 == 1 {
+					return pluralOne
+				} else if i100 == 2 {
+					return pluralTwo
+				} else if i100 == 3 || i100 == 4 {
+					return pluralFew
+				}
+				return pluralOther
+			},
+		},
+
+		"he": {
+			categories: []string{pluralOne, pluralTwo, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				if isInt && i == 1 {
+					return pluralOne
+				} else if isInt && i == 2 {
+					return pluralTwo
+				}
+				return pluralOther
+			},
+		},
+
+		"ar": {
+			categories: []string{pluralZero, pluralOne, pluralTwo, pluralFew, pluralMany, pluralOther},
+			f: func(n float64, i int64, isInt bool) string {
+				if !isInt {
+					return pluralOther
+				}
+				i100 := i % 100
+				switch {
+				case i == 0:
+					return pluralZero
+				case i == 1:
+					return pluralOne
+				case i == 2:
+					return pluralTwo
+				case i100 >= 3 && i100 <= 10:
+					return pluralFew
+				case i100 >= 11:
+					return pluralMany
+				}
+				return pluralOther
+			},
+		},
+	}
+)
+
+//##########################//
+//### Plural Rule Struct ###//
+//##########################//
+
+type pluralRule struct {
+	categories []string
+	f          func(n float64, i int64, isInt bool) string
+}
+
+// category returns the plural category of the number.
+func (r *pluralRule) category(n float64) string {
+	n = math.Abs(n)
+	i := int64(n)
+
+	return r.f(n, i, float64(i) == n)
+}
+
+// hasCategory returns a boolean whenever the category is used by the rule.
+func (r *pluralRule) hasCategory(c string) bool {
+	for _, rc := range r.categories {
+		if rc == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// getPluralRule returns the plural rule of the locale's language.
+func getPluralRule(locale string) *pluralRule {
+	if r, ok := pluralRules[baseLanguage(locale)]; ok {
+		return r
+	}
+
+	return pluralRuleOne
+}
+
+func isPluralCategory(c string) bool {
+	switch c {
+	case pluralZero, pluralOne, pluralTwo, pluralFew, pluralMany, pluralOther:
+		return true
+	}
+
+	return false
+}
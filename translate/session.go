@@ -0,0 +1,326 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package tr
+
+import (
+	"github.com/desertbit/bulldozer/log"
+	"github.com/desertbit/bulldozer/sessions"
+
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionValueKeyLocale             = "budLocale"
+	sessionValueKeyTimeZone           = "budTimeZone"
+	sessionValueKeyClientTimeZone     = "budClientTimeZone"
+	sessionValueKeyClientTimeZoneDiff = "budClientTimeZoneOffset"
+
+	requestTypeTimeZone    = "timeZone"
+	requestKeyTimeZoneName = "name"
+	requestKeyTimeZoneDiff = "offset"
+)
+
+var (
+	// Loaded time zones. Only valid zones are added.
+	// Key: IANA time zone name
+	locations      = make(map[string]*time.Location)
+	locationsMutex sync.Mutex
+)
+
+func init() {
+	// Register the client time zone request.
+	err := sessions.Request(requestTypeTimeZone, onClientTimeZone)
+	if err != nil {
+		log.L.Fatalf("failed to register client time zone request: %v", err)
+	}
+}
+
+//#########################//
+//### Translator Struct ###//
+//#########################//
+
+// Translator translates and formats messages for one locale and time zone.
+type Translator struct {
+	locale string
+	loc    *time.Location
+
+	// The time zone of the session is resolved by the format methods
+	// if no time zone is set.
+	s *sessions.Session
+}
+
+// For returns the translator for the locale of the session.
+// The locale is resolved in the following order:
+// the locale set with SetSessionLocale, the Accept-Language header
+// of the client and finally the default locale.
+func For(s *sessions.Session) *Translator {
+	return &Translator{
+		locale: SessionLocale(s),
+		s:      s,
+	}
+}
+
+// ForLocale returns the translator for the locale with the local time zone.
+// The default locale is used if no translations are loaded for the locale.
+func ForLocale(locale string) *Translator {
+	l, ok := findLocale(locale)
+	if !ok {
+		l = Locale()
+	}
+
+	return &Translator{
+		locale: l,
+		loc:    time.Local,
+	}
+}
+
+// In returns a copy of the translator with the time zone.
+func (t *Translator) In(loc *time.Location) *Translator {
+	return &Translator{
+		locale: t.locale,
+		loc:    loc,
+		s:      t.s,
+	}
+}
+
+// Locale returns the locale of the translator.
+func (t *Translator) Locale() string {
+	return t.locale
+}
+
+// S obtains the translated string for the given ID.
+func (t *Translator) S(id string, args ...interface{}) string {
+	return translate(t.locale, id, args...)
+}
+
+//##############//
+//### Public ###//
+//##############//
+
+// SetSessionLocale sets the translation locale of the session.
+// The locale is saved to the session values.
+// An error is returned if no translations are loaded for the locale.
+func SetSessionLocale(s *sessions.Session, locale string) error {
+	l, ok := findLocale(locale)
+	if !ok {
+		return fmt.Errorf("translate: no translations available for locale '%s'", locale)
+	}
+
+	s.Set(sessionValueKeyLocale, l)
+
+	return nil
+}
+
+// ResetSessionLocale removes the locale set with SetSessionLocale.
+// The locale is resolved from the client request again.
+func ResetSessionLocale(s *sessions.Session) {
+	s.Delete(sessionValueKeyLocale)
+}
+
+// SessionLocale returns the resolved translation locale of the session.
+func SessionLocale(s *sessions.Session) string {
+	if s == nil {
+		return Locale()
+	}
+
+	// Check if a locale is set for the session.
+	if i, ok := s.Get(sessionValueKeyLocale); ok {
+		if l, ok := i.(string); ok {
+			if l, ok = findLocale(l); ok {
+				return l
+			}
+		}
+	}
+
+	// Try to find the best matching locale of the client.
+	for _, l := range parseAcceptLanguage(s.AcceptLanguage()) {
+		if l, ok := findLocale(l); ok {
+			return l
+		}
+	}
+
+	return Locale()
+}
+
+// SetSessionTimeZone sets the time zone of the session by its IANA name,
+// like "Europe/Berlin". It takes precedence over the client time zone.
+func SetSessionTimeZone(s *sessions.Session, name string) error {
+	if _, err := loadLocation(name); err != nil {
+		return fmt.Errorf("translate: invalid time zone '%s': %v", name, err)
+	}
+
+	s.Set(sessionValueKeyTimeZone, name)
+
+	return nil
+}
+
+// SessionTimeZone returns the time zone of the session.
+// The time zone is resolved in the following order: the time zone set
+// with SetSessionTimeZone, the time zone reported by the client and
+// finally the local time zone. The client reports its time zone as soon
+// as the socket is connected, so the first page is rendered without it.
+func SessionTimeZone(s *sessions.Session) *time.Location {
+	if s == nil {
+		return time.Local
+	}
+
+	for _, key := range []string{sessionValueKeyTimeZone, sessionValueKeyClientTimeZone} {
+		if i, ok := s.Get(key); ok {
+			if name, ok := i.(string); ok {
+				if loc, err := loadLocation(name); err == nil {
+					return loc
+				}
+			}
+		}
+	}
+
+	// Use the client's UTC offset if the browser doesn't report the zone name.
+	if i, ok := s.Get(sessionValueKeyClientTimeZoneDiff); ok {
+		if offset, ok := i.(int); ok {
+			sign, a := "+", offset
+			if a < 0 {
+				sign, a = "-", -a
+			}
+			return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", sign, a/60, a%60), offset*60)
+		}
+	}
+
+	return time.Local
+}
+
+// SplitLocalePrefix checks if the first element of the path is
+// a loaded locale. If so, the locale and the remaining path are returned.
+func SplitLocalePrefix(path string) (locale string, rest string, ok bool) {
+	p := strings.TrimPrefix(path, "/")
+
+	prefix := p
+	if i := strings.Index(p, "/"); i >= 0 {
+		prefix = p[:i]
+		rest = p[i:]
+	}
+
+	if len(prefix) == 0 {
+		return "", path, false
+	}
+
+	// Only exact matches are handled.
+	prefix = normalizeLocale(prefix)
+	if l, found := findLocale(prefix); !found || l != prefix {
+		return "", path, false
+	}
+
+	if len(rest) == 0 {
+		rest = "/"
+	}
+
+	return prefix, rest, true
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+// loadLocation returns the time zone with the name.
+// Loaded time zones are cached.
+func loadLocation(name string) (*time.Location, error) {
+	// Lock the mutex
+	locationsMutex.Lock()
+	defer locationsMutex.Unlock()
+
+	if loc, ok := locations[name]; ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locations[name] = loc
+
+	return loc, nil
+}
+
+func onClientTimeZone(s *sessions.Session, data map[string]string) error {
+	// Save the time zone name if valid.
+	name := data[requestKeyTimeZoneName]
+	if len(name) > 0 {
+		if _, err := loadLocation(name); err == nil {
+			s.Set(sessionValueKeyClientTimeZone, name)
+			return nil
+		}
+	}
+
+	// Otherwise save the UTC offset in minutes.
+	offset, err := strconv.Atoi(data[requestKeyTimeZoneDiff])
+	if err != nil {
+		return fmt.Errorf("client time zone: invalid offset: %v", err)
+	}
+
+	s.Set(sessionValueKeyClientTimeZoneDiff, offset)
+
+	return nil
+}
+
+type acceptLanguage struct {
+	locale  string
+	quality float64
+}
+
+// parseAcceptLanguage parses the Accept-Language header value
+// and returns the locales ordered by their quality.
+func parseAcceptLanguage(header string) []string {
+	var langs []acceptLanguage
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		l := acceptLanguage{
+			locale:  part,
+			quality: 1,
+		}
+
+		// Parse the optional quality value: de;q=0.8
+		if i := strings.Index(part, ";"); i >= 0 {
+			l.locale = strings.TrimSpace(part[:i])
+
+			q := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(q, "q=") {
+				f, err := strconv.ParseFloat(q[2:], 64)
+				if err != nil {
+					continue
+				}
+				l.quality = f
+			}
+		}
+
+		if len(l.locale) == 0 || l.locale == "*" || l.quality <= 0 {
+			continue
+		}
+
+		langs = append(langs, l)
+	}
+
+	// Sort by the quality. Keep the order of equal values.
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].quality > langs[j].quality
+	})
+
+	locales := make([]string, len(langs))
+	for i, l := range langs {
+		locales[i] = l.locale
+	}
+
+	return locales
+}
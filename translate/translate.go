@@ -6,32 +6,39 @@
 package tr
 
 import (
-	"bufio"
-	"encoding/json"
-	"fmt"
 	"github.com/desertbit/bulldozer/log"
-	"io"
+	"github.com/desertbit/bulldozer/translate/catalog"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	Suffix = ".tr"
+	Suffix = catalog.Suffix
 
 	defaultLocale      = "en"
 	reloadTimerTimeout = 1 * time.Second
 )
 
 var (
-	currentLocale string            = defaultLocale
-	messages      map[string]string = make(map[string]string)
+	currentLocale string = defaultLocale
 	directories   []string
 	mutex         sync.Mutex
 
+	// All loaded locales with their compiled messages.
+	// Key: normalized locale
+	catalogs map[string]map[string]*message = make(map[string]map[string]*message)
+
+	// Custom fallback locales. Key: normalized locale
+	fallbacks map[string][]string = make(map[string][]string)
+
+	// Missing message IDs which were already logged.
+	missing map[string]struct{} = make(map[string]struct{})
+
 	reloadTimer     *time.Timer
 	reloadTimerStop chan struct{} = make(chan struct{})
 )
@@ -89,31 +96,63 @@ func Release() {
 	close(reloadTimerStop)
 }
 
-// SetLocale sets the current locale.
-// The translations files are all reloaded.
+// SetLocale sets the default locale. It is used if no locale is
+// resolved for a session and for translations without a session.
+// Missing messages always fall back to the built-in english messages.
 func SetLocale(locale string) {
 	// Lock the mutex
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	// Set the new locale
-	currentLocale = locale
+	currentLocale = normalizeLocale(locale)
+}
 
-	// Reload the translation messages
-	reload()
+// Locale returns the default locale.
+func Locale() string {
+	// Lock the mutex
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return currentLocale
 }
 
-// S obtains the translated string for the given ID.
-func S(id string, args ...interface{}) string {
-	// Try to get the translated string for the ID
-	s, ok := getMessage(id)
+// Locales returns all loaded locales.
+func Locales() []string {
+	// Lock the mutex
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	if !ok {
-		log.L.Warning("no translated string found for ID '%s'", id)
-		return "???" + id + "???"
+	locales := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		locales = append(locales, l)
 	}
+	sort.Strings(locales)
 
-	return fmt.Sprintf(s, args...)
+	return locales
+}
+
+// SetFallbacks sets the fallback locales which are tried in the given order
+// if a message is missing for the locale. Afterwards the language without
+// region, the default locale and the built-in english messages are tried.
+func SetFallbacks(locale string, fallbackLocales ...string) {
+	// Lock the mutex
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	locales := make([]string, len(fallbackLocales))
+	for i, l := range fallbackLocales {
+		locales[i] = normalizeLocale(l)
+	}
+
+	fallbacks[normalizeLocale(locale)] = locales
+}
+
+// S obtains the translated string for the given ID in the default locale.
+// Use For(s).S(id) to translate for a session.
+// Named arguments of MessageFormat messages are passed with Args.
+func S(id string, args ...interface{}) string {
+	return translate("", id, args...)
 }
 
 // Add adds a translation directory path
@@ -184,13 +223,125 @@ func Remove(dirPath string) {
 //### Private ###//
 //###############//
 
-func getMessage(id string) (s string, ok bool) {
+// translate obtains the translated string for the given ID in the locale.
+// The default locale is used if the locale is empty.
+// The ID is returned if no message exists in the fallback chain.
+func translate(locale string, id string, args ...interface{}) string {
+	// Try to get the message for the ID
+	m, msgLocale, ok := getMessage(locale, id)
+	if !ok {
+		logMissing(locale, id)
+		return id
+	}
+
+	s, err := m.format(msgLocale, args)
+	if err != nil {
+		log.L.Warning("translation: message '%s' (%s): %v", id, msgLocale, err)
+	}
+
+	return s
+}
+
+// getMessage obtains the message from the catalog of the locale.
+// If missing, the fallback chain of the locale is tried.
+// The locale of the found message is returned.
+func getMessage(locale string, id string) (m *message, msgLocale string, ok bool) {
+	// Lock the mutex
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, l := range fallbackChain(locale) {
+		if m, ok = catalogs[l][id]; ok {
+			return m, l, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// fallbackChain returns the locales which are tried in order to find a message:
+// the locale, its custom fallbacks, the language without region and its
+// custom fallbacks, the default locale and the built-in locale.
+// The mutex has to be locked.
+func fallbackChain(locale string) []string {
+	if len(locale) == 0 {
+		locale = currentLocale
+	}
+
+	base := baseLanguage(locale)
+
+	chain := []string{locale}
+	chain = append(chain, fallbacks[locale]...)
+	chain = append(chain, base)
+	chain = append(chain, fallbacks[base]...)
+	chain = append(chain, currentLocale, defaultLocale)
+
+	// Remove duplicates.
+	unique := chain[:0]
+	seen := make(map[string]struct{}, len(chain))
+	for _, l := range chain {
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		unique = append(unique, l)
+	}
+
+	return unique
+}
+
+// logMissing logs a missing message ID once per locale.
+func logMissing(locale string, id string) {
+	// Lock the mutex
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	key := locale + ":" + id
+	if _, ok := missing[key]; ok {
+		return
+	}
+	missing[key] = struct{}{}
+
+	log.L.Warning("translation: no translated string found for ID '%s' (%s)", id, locale)
+}
+
+// normalizeLocale transforms the locale to lower case and
+// uses dashes as separators: de_AT -> de-at.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(locale), "_", "-", -1))
+}
+
+// baseLanguage returns the language without the region: de-at -> de.
+func baseLanguage(locale string) string {
+	if i := strings.Index(locale, "-"); i > 0 {
+		return locale[:i]
+	}
+
+	return locale
+}
+
+// findLocale returns the loaded locale which matches the locale.
+// The language without region matches also.
+func findLocale(locale string) (string, bool) {
+	locale = normalizeLocale(locale)
+	if len(locale) == 0 {
+		return "", false
+	}
+
 	// Lock the mutex
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	s, ok = messages[id]
-	return
+	if _, ok := catalogs[locale]; ok {
+		return locale, true
+	}
+
+	base := baseLanguage(locale)
+	if _, ok := catalogs[base]; ok {
+		return base, true
+	}
+
+	return "", false
 }
 
 func reload() {
@@ -205,58 +356,80 @@ func _reload() {
 
 	log.L.Info("translation: reloading translation files")
 
-	// Empty the current messages map
-	messages = make(map[string]string)
+	// Empty the current catalogs
+	catalogs = make(map[string]map[string]*message)
+	missing = make(map[string]struct{})
 
-	// Go through all directories
+	// Go through all directories. Each subdirectory contains the files of one locale.
 	for _, d := range directories {
-		// Skip if the base translation directory contains no directories.
 		entries, err := ioutil.ReadDir(d)
 		if err != nil {
 			log.L.Error("failed to obtain entry list of directory '%s': %v", d, err)
 			continue
 		}
-		empty := true
+
 		for _, e := range entries {
-			if e.IsDir() {
-				empty = false
-				break
+			if !e.IsDir() {
+				continue
 			}
-		}
-		if empty {
-			continue
-		}
 
-		dirPath := d + "/" + currentLocale
+			// Get the messages map of the locale.
+			locale := normalizeLocale(e.Name())
+			messages, ok := catalogs[locale]
+			if !ok {
+				messages = make(map[string]*message)
+				catalogs[locale] = messages
+			}
 
-		// Check if the current locale translation folder exists
-		ok, err := dirExists(dirPath)
-		if err != nil {
-			log.L.Error("translate reload error: %v", err)
-			continue
+			err = filepath.Walk(d+"/"+e.Name(), newLoadFileFunc(locale, messages))
+			if err != nil {
+				log.L.Error("translation: filepath walk error: %v", err)
+			}
 		}
+	}
 
-		// If not try to load the default locale
-		if !ok {
-			log.L.Warning("translation: missing translation files '%s' for current locale '%s'", d, currentLocale)
+	// Warn if the default locale is missing.
+	if _, ok := catalogs[currentLocale]; !ok && len(directories) > 0 {
+		log.L.Warning("translation: missing translation files for default locale '%s'", currentLocale)
+	}
 
-			dirPath = d + "/" + defaultLocale
+	// Check the placeholders of the translations.
+	validateCatalogs()
+}
 
-			// Check if the default locale translation folder exists
-			ok, err := dirExists(dirPath)
-			if err != nil {
-				log.L.Error("translate reload error: %v", err)
+// validateCatalogs checks if the translated messages use the same
+// placeholders as the messages of the built-in locale.
+// The mutex has to be locked.
+func validateCatalogs() {
+	reference, ok := catalogs[defaultLocale]
+	if !ok {
+		return
+	}
+
+	for locale, messages := range catalogs {
+		if locale == defaultLocale {
+			continue
+		}
+
+		for id, m := range messages {
+			ref, ok := reference[id]
+			if !ok {
 				continue
 			}
-			if !ok {
-				log.L.Error("translation: missing translation files '%s' for default locale '%s'", d, defaultLocale)
+
+			// Printf-style messages must have the same count of verbs.
+			if m.isPrintf() || ref.isPrintf() {
+				if m.isPrintf() != ref.isPrintf() || len(m.placeholders) != len(ref.placeholders) {
+					log.L.Warning("translation: message '%s' (%s): placeholders %v don't match placeholders %v of locale '%s'",
+						id, locale, m.placeholders, ref.placeholders, defaultLocale)
+				}
 				continue
 			}
-		}
 
-		err = filepath.Walk(dirPath, loadFile)
-		if err != nil {
-			log.L.Error("translation: filepath walk error: %v", err)
+			if strings.Join(m.placeholders, ",") != strings.Join(ref.placeholders, ",") {
+				log.L.Warning("translation: message '%s' (%s): placeholders %v don't match placeholders %v of locale '%s'",
+					id, locale, m.placeholders, ref.placeholders, defaultLocale)
+			}
 		}
 	}
 }
@@ -276,7 +449,15 @@ func dirExists(path string) (bool, error) {
 	return stat.IsDir(), nil
 }
 
-func loadFile(path string, f os.FileInfo, err error) error {
+// newLoadFileFunc returns a filepath walk function which
+// loads the translation files of the locale into the messages map.
+func newLoadFileFunc(locale string, messages map[string]*message) filepath.WalkFunc {
+	return func(path string, f os.FileInfo, err error) error {
+		return loadFile(locale, messages, path, f, err)
+	}
+}
+
+func loadFile(locale string, messages map[string]*message, path string, f os.FileInfo, err error) error {
 	if f == nil {
 		log.L.Error("filepath walk: file info object is nil!")
 		return nil
@@ -287,38 +468,41 @@ func loadFile(path string, f os.FileInfo, err error) error {
 		return nil
 	}
 
-	type Message struct {
-		ID, Text string
-	}
-
-	// Open the file
-	file, err := os.Open(path)
+	// Read the translation file
+	entries, err := catalog.ReadFile(path)
 	if err != nil {
-		log.L.Error("failed to open translation file '%s': %v", path, err)
+		log.L.Error(err.Error())
 		return nil
 	}
-	defer file.Close()
 
-	// Parse the JSON translation file
-	var ok bool
-	dec := json.NewDecoder(bufio.NewReader(file))
-	for {
-		var m Message
-		if err = dec.Decode(&m); err == io.EOF {
-			break
-		} else if err != nil {
-			log.L.Error("failed to parse translation file '%s': %v", path, err)
-			return nil
-		}
+	// Get the plural rule of the locale.
+	rule := getPluralRule(locale)
 
+	var ok bool
+	for _, m := range entries {
 		// Check if overwriting the value
 		_, ok = messages[m.ID]
 		if ok {
 			log.L.Warning("%s: overwriting duplicate translation message with ID '%s'!", path, m.ID)
 		}
 
-		// Add the text to the message map
-		messages[m.ID] = m.Text
+		// Compile the message. Invalid messages are skipped
+		// and the fallback locales are used instead.
+		msg, err := compileMessage(m.Text)
+		if err != nil {
+			log.L.Error("%s: invalid translation message with ID '%s': %v", path, m.ID, err)
+			continue
+		}
+
+		// Check if the plural keywords are used by the locale.
+		for _, k := range msg.pluralKeys {
+			if !rule.hasCategory(k) {
+				log.L.Warning("%s: translation message with ID '%s': plural keyword '%s' is not used by locale '%s'", path, m.ID, k, locale)
+			}
+		}
+
+		// Add the message to the message map
+		messages[m.ID] = msg
 	}
 
 	return nil
@@ -8,10 +8,8 @@ package dialog
 import (
 	"github.com/desertbit/bulldozer/sessions"
 	"github.com/desertbit/bulldozer/template"
-	"github.com/desertbit/bulldozer/utils"
 
 	"fmt"
-	"strconv"
 )
 
 type Size string
@@ -22,6 +20,11 @@ const (
 	SizeLarge  Size = "large"
 )
 
+const (
+	messageTypeShow  = "dialog.show"
+	messageTypeClose = "dialog.close"
+)
+
 //###################//
 //### Dialog type ###//
 //###################//
@@ -97,25 +100,19 @@ func (d *Dialog) Show(s *sessions.Session, data ...interface{}) (*template.Conte
 	// Create the dialog DOM ID.
 	dialogDomID := opts.DomID + "__d"
 
-	// Transform to string
-	closableStr := strconv.FormatBool(d.closable)
-
 	// Transform the additional style classes to a string.
 	var styles string
 	for _, style := range d.StyleClasses {
 		styles += " " + style
 	}
 
-	// Create the command
-	cmd := `Bulldozer.utils.addAndShowTmpModal('` + utils.EscapeJS(o) + `',{
-			domId:'` + dialogDomID + `',
-			closable:` + closableStr + `,
-			class:'radius shadow ` + string(d.size) + styles + `'
-		});`
-
-	// Execute the command on the client side.
+	// Show the dialog on the client side.
 	// The loading indicator is hidden automatically by the Bulldozer.core.execJsLoad() function.
-	s.SendCommand(cmd)
+	s.SendMessage(messageTypeShow, dialogDomID, map[string]interface{}{
+		"body":     o,
+		"closable": d.closable,
+		"class":    "radius shadow " + string(d.size) + styles,
+	})
 
 	return c, nil
 }
@@ -123,11 +120,7 @@ func (d *Dialog) Show(s *sessions.Session, data ...interface{}) (*template.Conte
 // Close the dialog.
 func (d *Dialog) Close(c *template.Context) {
 	// Close the dialog
-	c.Session().SendCommand(`(function(){
-		var e=$('#` + c.DomID() + `__d');
-		e.data('serverClosedDialog', true);
-		Kepler.modal.close(e);
-	})();`)
+	c.Session().SendMessage(messageTypeClose, c.DomID()+"__d", nil)
 
 	// Release the context.
 	c.Release()
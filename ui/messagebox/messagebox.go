@@ -208,7 +208,7 @@ func (m *MessageBox) Show(s *sessions.Session) (err error) {
 	if m.buttons&ButtonOk == ButtonOk {
 		templButtons = append(templButtons, templButton{
 			"ok",
-			tr.S("bud.messagebox.buttonOk"),
+			tr.For(s).S("bud.messagebox.buttonOk"),
 			strconv.Itoa(int(ButtonOk)),
 		})
 		buttonCount++
@@ -216,7 +216,7 @@ func (m *MessageBox) Show(s *sessions.Session) (err error) {
 	if m.buttons&ButtonYes == ButtonYes {
 		templButtons = append(templButtons, templButton{
 			"yes",
-			tr.S("bud.messagebox.buttonYes"),
+			tr.For(s).S("bud.messagebox.buttonYes"),
 			strconv.Itoa(int(ButtonYes)),
 		})
 		buttonCount++
@@ -224,7 +224,7 @@ func (m *MessageBox) Show(s *sessions.Session) (err error) {
 	if m.buttons&ButtonNo == ButtonNo {
 		templButtons = append(templButtons, templButton{
 			"no",
-			tr.S("bud.messagebox.buttonNo"),
+			tr.For(s).S("bud.messagebox.buttonNo"),
 			strconv.Itoa(int(ButtonNo)),
 		})
 		buttonCount++
@@ -232,7 +232,7 @@ func (m *MessageBox) Show(s *sessions.Session) (err error) {
 	if m.buttons&ButtonCancel == ButtonCancel {
 		templButtons = append(templButtons, templButton{
 			"cancel",
-			tr.S("bud.messagebox.buttonCancel"),
+			tr.For(s).S("bud.messagebox.buttonCancel"),
 			strconv.Itoa(int(ButtonCancel)),
 		})
 		buttonCount++
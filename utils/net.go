@@ -6,44 +6,153 @@
 package utils
 
 import (
+	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 )
 
+var (
+	trustedProxies      []*net.IPNet
+	trustedProxiesMutex sync.RWMutex
+)
+
+// SetTrustedProxies sets the proxy networks in CIDR notation which are
+// allowed to pass the client address with the Forwarded, X-Forwarded-For
+// or X-Real-Ip http headers. Single IP addresses without a network mask
+// are also accepted. An empty slice disables all forwarding headers.
+func SetTrustedProxies(cidrs []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if len(c) == 0 {
+			continue
+		}
+
+		// Append the full network mask to single IP addresses.
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy address: '%s'", c)
+			}
+
+			if ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy network '%s': %v", c, err)
+		}
+
+		nets = append(nets, n)
+	}
+
+	// Lock the mutex
+	trustedProxiesMutex.Lock()
+	defer trustedProxiesMutex.Unlock()
+
+	// Set the new trusted networks
+	trustedProxies = nets
+
+	return nil
+}
+
+// IsTrustedProxy returns a boolean whenever the address is part
+// of a trusted proxy network.
+func IsTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	// Lock the mutex
+	trustedProxiesMutex.RLock()
+	defer trustedProxiesMutex.RUnlock()
+
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // RemoteAddress returns the IP address of the request.
-// If the X-Forwarded-For or X-Real-Ip http headers are set, then
-// they are used to obtain the remote address.
+// The Forwarded, X-Forwarded-For and X-Real-Ip http headers are only
+// respected, if the request was received from a trusted proxy (See SetTrustedProxies).
+// Forwarding chains are walked from right to left and the first address,
+// which is not a trusted proxy, is returned as client address.
 // The boolean is true, if the remote address is obtained using the
 // request RemoteAddr() method.
 func RemoteAddress(r *http.Request) (string, bool) {
+	// Get the address of the direct peer.
+	peer := RemovePortFromRemoteAddr(r.RemoteAddr)
+
+	// Never trust the forwarding headers of untrusted peers.
+	// They could be spoofed easily.
+	if !IsTrustedProxy(peer) {
+		return peer, true
+	}
+
 	hdr := r.Header
 
-	// Try to obtain the ip from the X-Forwarded-For header
-	ip := hdr.Get("X-Forwarded-For")
-	if ip != "" {
-		// X-Forwarded-For is potentially a list of addresses separated with ","
-		parts := strings.Split(ip, ",")
-		if len(parts) > 0 {
-			ip = strings.TrimSpace(parts[0])
+	// Obtain the forwarding chain. The standardized Forwarded
+	// header is preferred over the X-Forwarded-For header.
+	chain := parseForwarded(hdr["Forwarded"])
+	if len(chain) == 0 {
+		chain = parseXForwardedFor(hdr["X-Forwarded-For"])
+	}
+
+	if len(chain) > 0 {
+		// Walk the chain from the nearest to the farthest hop.
+		// The address of the last trusted hop is used as fallback.
+		addr := peer
+		for i := len(chain) - 1; i >= 0; i-- {
+			ip := chain[i]
+
+			// Stop on invalid or obfuscated addresses.
+			// The chain can't be followed any further.
+			if net.ParseIP(ip) == nil {
+				break
+			}
+
+			addr = ip
 
-			if ip != "" {
-				return ip, false
+			// The first untrusted address is the client address.
+			if !IsTrustedProxy(ip) {
+				break
 			}
 		}
+
+		return addr, false
 	}
 
 	// Try to obtain the ip from the X-Real-Ip header
-	ip = strings.TrimSpace(hdr.Get("X-Real-Ip"))
-	if ip != "" {
+	ip := trimAddrPort(hdr.Get("X-Real-Ip"))
+	if net.ParseIP(ip) != nil {
 		return ip, false
 	}
 
 	// Fallback to the request remote address
-	return RemovePortFromRemoteAddr(r.RemoteAddr), true
+	return peer, true
 }
 
 // RemovePortFromRemoteAddr removes the port if present from the remote address.
 func RemovePortFromRemoteAddr(remoteAddr string) string {
+	// Handle IPv6 addresses with and without ports.
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	} else if net.ParseIP(strings.Trim(remoteAddr, "[]")) != nil {
+		return strings.Trim(remoteAddr, "[]")
+	}
+
 	pos := strings.LastIndex(remoteAddr, ":")
 	if pos < 0 {
 		return remoteAddr
@@ -51,3 +160,76 @@ func RemovePortFromRemoteAddr(remoteAddr string) string {
 
 	return remoteAddr[:pos]
 }
+
+// parseXForwardedFor returns the addresses of the X-Forwarded-For headers.
+// Multiple headers are combined in order.
+func parseXForwardedFor(values []string) (chain []string) {
+	for _, v := range values {
+		// X-Forwarded-For is a list of addresses separated with ","
+		for _, ip := range strings.Split(v, ",") {
+			chain = append(chain, trimAddrPort(ip))
+		}
+	}
+
+	return chain
+}
+
+// parseForwarded returns the "for" addresses of the
+// RFC 7239 Forwarded headers. Multiple headers are combined in order.
+// Elements without a "for" address are added as empty address to keep
+// the hop positions. They end the walk through the chain.
+// Example: Forwarded: for=192.0.2.60;proto=http, for="[2001:db8::17]:4711"
+func parseForwarded(values []string) (chain []string) {
+	for _, v := range values {
+		// Each forwarded element is separated with ","
+		for _, element := range strings.Split(v, ",") {
+			// Skip empty list elements.
+			if len(strings.TrimSpace(element)) == 0 {
+				continue
+			}
+
+			chain = append(chain, parseForwardedFor(element))
+		}
+	}
+
+	return chain
+}
+
+// parseForwardedFor returns the "for" address of the forwarded element
+// or an empty string if not present.
+func parseForwardedFor(element string) string {
+	// Each element contains pairs separated with ";"
+	for _, pair := range strings.Split(element, ";") {
+		pos := strings.Index(pair, "=")
+		if pos < 0 {
+			continue
+		}
+
+		if !strings.EqualFold(strings.TrimSpace(pair[:pos]), "for") {
+			continue
+		}
+
+		// Remove the optional quotes.
+		value := strings.Trim(strings.TrimSpace(pair[pos+1:]), "\"")
+
+		return trimAddrPort(value)
+	}
+
+	return ""
+}
+
+// trimAddrPort removes surrounding spaces, IPv6 brackets and
+// a possible port from a forwarded address.
+func trimAddrPort(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if len(addr) == 0 {
+		return addr
+	}
+
+	// Plain IPv4 or IPv6 address without port.
+	if net.ParseIP(addr) != nil {
+		return addr
+	}
+
+	return RemovePortFromRemoteAddr(addr)
+}
@@ -0,0 +1,196 @@
+/*
+ *  Bulldozer Framework
+ *  Copyright (C) DesertBit
+ */
+
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRemoteAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		header     http.Header
+		addr       string
+		direct     bool
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "192.0.2.1:1234",
+			addr:       "192.0.2.1",
+			direct:     true,
+		},
+		{
+			name:       "ipv6 peer",
+			remoteAddr: "[2001:db8::1]:1234",
+			addr:       "2001:db8::1",
+			direct:     true,
+		},
+		{
+			name:       "untrusted peer with spoofed headers",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "192.0.2.1:1234",
+			header: http.Header{
+				"X-Forwarded-For": {"198.51.100.1"},
+				"Forwarded":       {"for=198.51.100.2"},
+				"X-Real-Ip":       {"198.51.100.3"},
+			},
+			addr:   "192.0.2.1",
+			direct: true,
+		},
+		{
+			name:       "x-forwarded-for",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for trusted chain",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.9, 198.51.100.1, 10.0.0.2"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for multiple headers",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1", "10.0.0.3, 10.0.0.2"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for only trusted hops",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			addr:       "10.0.0.3",
+		},
+		{
+			name:       "x-forwarded-for invalid hop",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1, unknown, 10.0.0.2"}},
+			addr:       "10.0.0.2",
+		},
+		{
+			name:       "forwarded",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {"for=198.51.100.1;proto=https"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "forwarded quoted ipv6 with port",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {`For="[2001:db8::17]:4711"`}},
+			addr:       "2001:db8::17",
+		},
+		{
+			name:       "forwarded trusted chain",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {"for=203.0.113.9, for=198.51.100.1", "for=10.0.0.2;proto=http"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "forwarded is preferred",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {"for=198.51.100.1"},
+				"X-Forwarded-For": {"198.51.100.2"},
+			},
+			addr: "198.51.100.1",
+		},
+		{
+			name:       "forwarded hop without for ends the walk",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {"for=198.51.100.1, proto=https, for=10.0.0.2"}},
+			addr:       "10.0.0.2",
+		},
+		{
+			name:       "forwarded obfuscated hop",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {"for=198.51.100.1, for=_hidden"}},
+			addr:       "10.0.0.1",
+		},
+		{
+			name:       "forwarded empty list elements",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {"for=198.51.100.1, , for=10.0.0.2,"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "x-real-ip",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Real-Ip": {"198.51.100.1"}},
+			addr:       "198.51.100.1",
+		},
+		{
+			name:       "trusted peer without headers",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			addr:       "10.0.0.1",
+			direct:     true,
+		},
+	}
+
+	defer SetTrustedProxies(nil)
+
+	for _, test := range tests {
+		if err := SetTrustedProxies(test.trusted); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		r := &http.Request{
+			RemoteAddr: test.remoteAddr,
+			Header:     test.header,
+		}
+		if r.Header == nil {
+			r.Header = http.Header{}
+		}
+
+		addr, direct := RemoteAddress(r)
+		if addr != test.addr || direct != test.direct {
+			t.Errorf("%s: got (%s, %v), want (%s, %v)", test.name, addr, direct, test.addr, test.direct)
+		}
+	}
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	defer SetTrustedProxies(nil)
+
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1 ", "2001:db8::/32", ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, trusted := range map[string]bool{
+		"10.1.2.3":     true,
+		"192.0.2.1":    true,
+		"192.0.2.2":    false,
+		"2001:db8::17": true,
+		"2001:db9::17": false,
+		"invalid":      false,
+	} {
+		if IsTrustedProxy(addr) != trusted {
+			t.Errorf("IsTrustedProxy(%s): want %v", addr, trusted)
+		}
+	}
+
+	for _, c := range []string{"10.0.0.0/33", "no-ip"} {
+		if err := SetTrustedProxies([]string{c}); err == nil {
+			t.Errorf("SetTrustedProxies(%s): expected an error", c)
+		}
+	}
+}